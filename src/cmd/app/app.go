@@ -9,32 +9,94 @@ import (
 )
 
 func NewStartCmd() *cobra.Command {
-	return &cobra.Command{
+	var network string
+
+	cmd := &cobra.Command{
 		Use:   "start",
 		Short: "start Rest server",
 		RunE: func(cmd *cobra.Command, args []string) error {
+			var walletHelper helpers.WalletHelper = helpers.NewWalletHelper()
+			if _, err := walletHelper.DeriveNetworkParams(network); err != nil {
+				return err
+			}
+
 			r := gin.Default()
 			r.Use(gin.Recovery())
 
 			var (
-				walletHelper  helpers.WalletHelper   = helpers.NewWalletHelper()
 				walletManager managers.WalletManager = managers.NewWalletManager(walletHelper)
-				walletHandler handlers.WalletHandler = handlers.NewWalletHandler(walletManager)
+				walletHandler handlers.WalletHandler = handlers.NewWalletHandler(walletManager, network)
+
+				recoveryManager managers.RecoveryManager = managers.NewRecoveryManager(walletHelper)
+				recoveryHandler handlers.RecoveryHandler = handlers.NewRecoveryHandler(recoveryManager, network)
+
+				psbtManager managers.PsbtManager = managers.NewPsbtManager(walletHelper)
+				psbtHandler handlers.PsbtHandler = handlers.NewPsbtHandler(psbtManager, network)
 			)
 			util := r.Group("/util")
 			{
 				util.GET("/new-mnemonic", func(ctx *gin.Context) {
 					walletHandler.GenerateMnemonic(ctx)
 				})
+				util.POST("/new-mnemonic", func(ctx *gin.Context) {
+					walletHandler.GenerateMnemonic(ctx)
+				})
+				util.POST("/mnemonic-to-seed", func(ctx *gin.Context) {
+					walletHandler.MnemonicToSeed(ctx)
+				})
+				util.POST("/slip39/split", func(ctx *gin.Context) {
+					walletHandler.GenerateSlip39Shares(ctx)
+				})
+				util.POST("/slip39/combine", func(ctx *gin.Context) {
+					walletHandler.CombineSlip39Shares(ctx)
+				})
+				util.POST("/recover-mnemonic", func(ctx *gin.Context) {
+					recoveryHandler.RecoverMnemonic(ctx)
+				})
 				util.POST("/hd-wallet", func(ctx *gin.Context) {
 					walletHandler.GenerateHdWallet(ctx)
 				})
+				util.POST("/hd-wallet/range", func(ctx *gin.Context) {
+					walletHandler.GenerateAddressRange(ctx)
+				})
+				util.POST("/watch-only", func(ctx *gin.Context) {
+					walletHandler.GenerateWatchOnly(ctx)
+				})
+				util.POST("/descriptor/addresses", func(ctx *gin.Context) {
+					walletHandler.DeriveDescriptorAddresses(ctx)
+				})
 				util.POST("/multi-sig-p2sh", func(ctx *gin.Context) {
 					walletHandler.GenerateMultisignature(ctx)
 				})
+				util.POST("/multi-sig/decode", func(ctx *gin.Context) {
+					walletHandler.DecodeMultisignatureScript(ctx)
+				})
+				util.POST("/miniscript", func(ctx *gin.Context) {
+					walletHandler.CompileMiniscript(ctx)
+				})
+				util.POST("/psbt/create", func(ctx *gin.Context) {
+					psbtHandler.CreatePsbt(ctx)
+				})
+				util.POST("/psbt/sign", func(ctx *gin.Context) {
+					psbtHandler.SignPsbt(ctx)
+				})
+				util.POST("/psbt/combine", func(ctx *gin.Context) {
+					psbtHandler.CombinePsbt(ctx)
+				})
+				util.POST("/psbt/finalize", func(ctx *gin.Context) {
+					psbtHandler.FinalizePsbt(ctx)
+				})
+				util.POST("/psbt/extract", func(ctx *gin.Context) {
+					psbtHandler.ExtractPsbt(ctx)
+				})
+				util.POST("/fee/estimate", func(ctx *gin.Context) {
+					psbtHandler.EstimateFee(ctx)
+				})
 			}
 			r.Run()
 			return nil
 		},
 	}
+	cmd.Flags().StringVar(&network, "network", "mainnet", "default network when a request does not select one (mainnet, testnet3, signet, regtest)")
+	return cmd
 }
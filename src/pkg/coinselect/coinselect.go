@@ -0,0 +1,482 @@
+// Package coinselect selects the UTXOs that fund a payment, the way Bitcoin
+// Core does: a changeless Branch and Bound search first, then knapsack and
+// single random draw selections with a change output, keeping the one that
+// wastes the least.
+package coinselect
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+
+	"btcwallet.com/src/pkg/helpers"
+	"btcwallet.com/src/pkg/psbt"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// ErrInsufficientFunds is returned when the UTXOs can not pay the target and
+// the fee of spending them.
+var ErrInsufficientFunds = errors.New("insufficient funds")
+
+// Algorithm is the selection algorithm that found a selection.
+type Algorithm string
+
+const (
+	// BranchAndBound searches for a selection that needs no change output.
+	BranchAndBound Algorithm = "bnb"
+	// Knapsack approximates the smallest selection over the target.
+	Knapsack Algorithm = "knapsack"
+	// SingleRandomDraw adds random UTXOs until the target is reached.
+	SingleRandomDraw Algorithm = "srd"
+)
+
+// bnbTries bounds the Branch and Bound search like Bitcoin Core's TOTAL_TRIES.
+const bnbTries = 100000
+
+// knapsackIterations is the number of random subsets the knapsack tries.
+const knapsackIterations = 1000
+
+// Utxo is an output that can fund the payment. AddressType is the kind of
+// address it pays to, which sets the weight of the input spending it.
+type Utxo struct {
+	TxId        string
+	Vout        uint32
+	Amount      int64
+	AddressType helpers.AddressType
+}
+
+// Request is what to select for. Target is the amount of the payment outputs
+// in satoshis. FeeRate is the rate of the transaction and LongTermFeeRate the
+// rate the UTXOs are expected to cost later on, both in sat/vB: spending more
+// inputs now is cheaper when the fee rate is under the long term one.
+// Outputs are the address types of the payment outputs, a single P2WPKH output
+// when empty, and ChangeType is the type of the change output, P2WPKH when
+// empty.
+type Request struct {
+	Utxos           []Utxo
+	Target          int64
+	FeeRate         float64
+	LongTermFeeRate float64
+	Outputs         []helpers.AddressType
+	ChangeType      helpers.AddressType
+}
+
+// Selection is the UTXOs selected with the fee the transaction pays and the
+// change it returns, zero without a change output. Waste is the cost of the
+// inputs at the fee rate over their cost at the long term fee rate, plus the
+// cost of the change output or the excess paid to fees without one.
+type Selection struct {
+	Utxos     []Utxo
+	Algorithm Algorithm
+	Amount    int64
+	Fee       int64
+	Change    int64
+	Waste     int64
+	Weight    int
+	VSize     int
+}
+
+// coin is a UTXO with the fee of spending it at both fee rates.
+type coin struct {
+	utxo        Utxo
+	weight      int
+	witness     bool
+	fee         int64
+	longTermFee int64
+	effective   int64
+}
+
+// selector holds what a selection algorithm needs.
+type selector struct {
+	coins   []coin
+	payment int64
+	// target is the payment with the fee of the transaction without inputs.
+	target int64
+	// changeFee is the fee of the change output, costOfChange adds the fee of
+	// spending it later and minChange is the smallest change that is kept.
+	changeFee    int64
+	costOfChange int64
+	minChange    int64
+	// baseWeight counts a one byte input count and no marker and flag bytes,
+	// selection adds what the selected coins need.
+	baseWeight   int
+	changeWeight int
+	feeRate      float64
+	rand         *rand.Rand
+}
+
+// Select selects the UTXOs paying the target of the request.
+func Select(request Request) (*Selection, error) {
+	return selectCoins(request, rand.New(rand.NewSource(time.Now().UnixNano())))
+}
+
+func selectCoins(request Request, rng *rand.Rand) (*Selection, error) {
+	s, err := newSelector(request, rng)
+	if err != nil {
+		return nil, err
+	}
+	var available int64
+	for _, c := range s.coins {
+		available += c.effective
+	}
+	if available < s.target {
+		return nil, fmt.Errorf("%w: the utxos are worth %d sats after their fees, %d needed", ErrInsufficientFunds, available, s.target)
+	}
+
+	if selected := s.branchAndBound(); selected != nil {
+		if selection := s.selection(selected, BranchAndBound); selection != nil {
+			return selection, nil
+		}
+	}
+	var best *Selection
+	for _, candidate := range []struct {
+		selected  []coin
+		algorithm Algorithm
+	}{
+		{s.knapsack(), Knapsack},
+		{s.singleRandomDraw(), SingleRandomDraw},
+	} {
+		if candidate.selected == nil {
+			continue
+		}
+		selection := s.selection(candidate.selected, candidate.algorithm)
+		if selection == nil {
+			continue
+		}
+		// ties go to the selection with more inputs, which consolidates
+		if best == nil || selection.Waste < best.Waste || (selection.Waste == best.Waste && len(selection.Utxos) > len(best.Utxos)) {
+			best = selection
+		}
+	}
+	if best == nil {
+		return nil, fmt.Errorf("%w: no selection pays %d sats", ErrInsufficientFunds, s.target)
+	}
+	return best, nil
+}
+
+func newSelector(request Request, rng *rand.Rand) (*selector, error) {
+	if request.Target <= 0 {
+		return nil, fmt.Errorf("target must be positive. got %d", request.Target)
+	}
+	if request.FeeRate <= 0 || request.LongTermFeeRate < 0 {
+		return nil, fmt.Errorf("fee rates must be positive. got %v and %v", request.FeeRate, request.LongTermFeeRate)
+	}
+	outputs := request.Outputs
+	if len(outputs) == 0 {
+		outputs = []helpers.AddressType{helpers.AddressTypeP2WPKH}
+	}
+	changeType := request.ChangeType
+	if changeType == "" {
+		changeType = helpers.AddressTypeP2WPKH
+	}
+	changeScript, err := templateScript(changeType)
+	if err != nil {
+		return nil, err
+	}
+
+	coins := make([]coin, 0, len(request.Utxos))
+	for _, utxo := range request.Utxos {
+		weight, witness, err := InputWeight(utxo.AddressType)
+		if err != nil {
+			return nil, fmt.Errorf("utxo %s:%d: %w", utxo.TxId, utxo.Vout, err)
+		}
+		c := coin{
+			utxo:        utxo,
+			weight:      weight,
+			witness:     witness,
+			fee:         fee(weight, request.FeeRate),
+			longTermFee: fee(weight, request.LongTermFeeRate),
+		}
+		c.effective = utxo.Amount - c.fee
+		// a UTXO that costs more than it is worth is never selected
+		if c.effective <= 0 {
+			continue
+		}
+		coins = append(coins, c)
+	}
+
+	// version, locktime, the counts of a transaction with a change output and
+	// the payment outputs
+	baseWeight := 4 * (4 + 4 + 1 + wire.VarIntSerializeSize(uint64(len(outputs)+1)))
+	for _, output := range outputs {
+		script, err := templateScript(output)
+		if err != nil {
+			return nil, err
+		}
+		baseWeight += 4 * psbt.OutputSize(script)
+	}
+	changeWeight := 4 * psbt.OutputSize(changeScript)
+	changeInputWeight, _, _ := InputWeight(changeType)
+
+	s := &selector{
+		coins:        coins,
+		payment:      request.Target,
+		target:       request.Target + fee(baseWeight, request.FeeRate),
+		changeFee:    fee(changeWeight, request.FeeRate),
+		baseWeight:   baseWeight,
+		changeWeight: changeWeight,
+		feeRate:      request.FeeRate,
+		rand:         rng,
+	}
+	s.costOfChange = s.changeFee + fee(changeInputWeight, request.LongTermFeeRate)
+	s.minChange = psbt.DustThreshold(changeScript)
+	return s, nil
+}
+
+// branchAndBound searches depth first, largest coins first, for the
+// selection with the least waste whose effective value is within the cost of
+// a change output over the target, so the excess can go to the fee instead.
+func (s *selector) branchAndBound() []coin {
+	coins := append([]coin{}, s.coins...)
+	sort.SliceStable(coins, func(i, j int) bool { return coins[i].effective > coins[j].effective })
+	if len(coins) == 0 {
+		return nil
+	}
+	var available int64
+	for _, c := range coins {
+		available += c.effective
+	}
+	// when the fee rate is high every extra input adds waste, so a branch
+	// already wasting more than the best selection can be cut
+	highFeeRate := coins[0].fee > coins[0].longTermFee
+
+	var value, waste int64
+	bestWaste := int64(math.MaxInt64)
+	var selected, best []int
+	for try, i := 0, 0; try < bnbTries; try, i = try+1, i+1 {
+		backtrack := false
+		if value+available < s.target || value > s.target+s.costOfChange || (waste > bestWaste && highFeeRate) {
+			backtrack = true
+		} else if value >= s.target {
+			if excess := waste + value - s.target; excess <= bestWaste {
+				best = append(best[:0], selected...)
+				bestWaste = excess
+			}
+			backtrack = true
+		}
+
+		if backtrack {
+			if len(selected) == 0 {
+				break
+			}
+			// put the coins after the last selected one back and try the
+			// branch without it
+			last := selected[len(selected)-1]
+			for i--; i > last; i-- {
+				available += coins[i].effective
+			}
+			value -= coins[i].effective
+			waste -= coins[i].fee - coins[i].longTermFee
+			selected = selected[:len(selected)-1]
+			continue
+		}
+
+		c := coins[i]
+		available -= c.effective
+		// a coin like the one just left out makes the same selections
+		if len(selected) == 0 || selected[len(selected)-1] == i-1 ||
+			c.effective != coins[i-1].effective || c.fee != coins[i-1].fee {
+			selected = append(selected, i)
+			value += c.effective
+			waste += c.fee - c.longTermFee
+		}
+	}
+	if best == nil {
+		return nil
+	}
+	result := make([]coin, len(best))
+	for n, i := range best {
+		result[n] = coins[i]
+	}
+	return result
+}
+
+// knapsack is Bitcoin Core's knapsack solver: a coin that pays the target
+// exactly, else the smallest random subset of the smaller coins over the
+// target and a change, else the smallest larger coin.
+func (s *selector) knapsack() []coin {
+	target := s.target
+	changeTarget := s.changeFee + s.minChange
+	coins := append([]coin{}, s.coins...)
+	s.rand.Shuffle(len(coins), func(i, j int) { coins[i], coins[j] = coins[j], coins[i] })
+
+	var smaller []coin
+	var lowestLarger *coin
+	var totalLower int64
+	for i := range coins {
+		c := coins[i]
+		switch {
+		case c.effective == target:
+			return []coin{c}
+		case c.effective < target+changeTarget:
+			smaller = append(smaller, c)
+			totalLower += c.effective
+		case lowestLarger == nil || c.effective < lowestLarger.effective:
+			lowestLarger = &coins[i]
+		}
+	}
+	if totalLower == target {
+		return smaller
+	}
+	if totalLower < target {
+		if lowestLarger == nil {
+			return nil
+		}
+		return []coin{*lowestLarger}
+	}
+
+	sort.SliceStable(smaller, func(i, j int) bool { return smaller[i].effective > smaller[j].effective })
+	included, best := s.approximateBestSubset(smaller, totalLower, target)
+	if best != target && totalLower >= target+changeTarget {
+		included, best = s.approximateBestSubset(smaller, totalLower, target+changeTarget)
+	}
+	if lowestLarger != nil && ((best != target && best < target+changeTarget) || lowestLarger.effective <= best) {
+		return []coin{*lowestLarger}
+	}
+	var result []coin
+	for i, c := range smaller {
+		if included[i] {
+			result = append(result, c)
+		}
+	}
+	return result
+}
+
+// approximateBestSubset draws random subsets of the coins and keeps the one
+// closest over the target.
+func (s *selector) approximateBestSubset(coins []coin, total int64, target int64) ([]bool, int64) {
+	best := make([]bool, len(coins))
+	for i := range best {
+		best[i] = true
+	}
+	bestValue := total
+	included := make([]bool, len(coins))
+	for iteration := 0; iteration < knapsackIterations && bestValue != target; iteration++ {
+		for i := range included {
+			included[i] = false
+		}
+		var value int64
+		reached := false
+		for pass := 0; pass < 2 && !reached; pass++ {
+			for i, c := range coins {
+				// the first pass draws coins at random, the second adds the
+				// rest in order until the target is reached
+				if (pass == 0 && s.rand.Intn(2) == 1) || (pass == 1 && !included[i]) {
+					value += c.effective
+					included[i] = true
+					if value >= target {
+						reached = true
+						if value < bestValue {
+							bestValue = value
+							copy(best, included)
+						}
+						value -= c.effective
+						included[i] = false
+					}
+				}
+			}
+		}
+	}
+	return best, bestValue
+}
+
+// singleRandomDraw adds coins in a random order until they pay the target,
+// the change output and a change that is not dust.
+func (s *selector) singleRandomDraw() []coin {
+	coins := append([]coin{}, s.coins...)
+	s.rand.Shuffle(len(coins), func(i, j int) { coins[i], coins[j] = coins[j], coins[i] })
+	var value int64
+	for i, c := range coins {
+		value += c.effective
+		if value >= s.target+s.changeFee+s.minChange {
+			return coins[:i+1]
+		}
+	}
+	return nil
+}
+
+// selection adds up the selected coins. It keeps a change output when the
+// change left after its fee is not dust, otherwise the excess goes to the fee.
+// It is nil when the coins do not pay the input count, the marker and flag
+// bytes and the empty witnesses they need on top of the target.
+func (s *selector) selection(selected []coin, algorithm Algorithm) *Selection {
+	result := &Selection{Algorithm: algorithm, Weight: s.baseWeight}
+	var effective, inputWaste int64
+	witness, withoutWitness := false, 0
+	for _, c := range selected {
+		result.Utxos = append(result.Utxos, c.utxo)
+		result.Amount += c.utxo.Amount
+		result.Weight += c.weight
+		effective += c.effective
+		inputWaste += c.fee - c.longTermFee
+		witness = witness || c.witness
+		if !c.witness {
+			withoutWitness++
+		}
+	}
+	// the base weight has a one byte input count and no marker and flag, with
+	// them every input without a witness has an empty one
+	overhead := 4 * (wire.VarIntSerializeSize(uint64(len(selected))) - 1)
+	if witness {
+		overhead += 2 + withoutWitness
+	}
+	result.Weight += overhead
+	excess := effective - s.target - fee(overhead, s.feeRate)
+	if excess < 0 {
+		return nil
+	}
+	if change := excess - s.changeFee; algorithm != BranchAndBound && change >= s.minChange {
+		result.Change = change
+		result.Weight += s.changeWeight
+		result.Waste = inputWaste + s.costOfChange
+	} else {
+		result.Waste = inputWaste + excess
+	}
+	result.Fee = result.Amount - s.payment - result.Change
+	result.VSize = psbt.VirtualSize(result.Weight)
+	return result
+}
+
+// InputWeight is the weight of an input spending an output of the address
+// type once it is signed and whether it has a witness. The witness weight
+// includes its stack item count.
+func InputWeight(addressType helpers.AddressType) (weight int, witness bool, err error) {
+	script, err := templateScript(addressType)
+	if err != nil {
+		return 0, false, err
+	}
+	var redeemScript []byte
+	if addressType == helpers.AddressTypeP2SHP2WPKH {
+		redeemScript = append([]byte{0x00, 0x14}, make([]byte, 20)...)
+	}
+	scriptSig, witnessSize, err := psbt.InputSize(script, redeemScript, nil)
+	if err != nil {
+		return 0, false, err
+	}
+	return 4*(32+4+wire.VarIntSerializeSize(uint64(scriptSig))+scriptSig+4) + witnessSize, witnessSize > 0, nil
+}
+
+// templateScript is an output script of the address type with a zero hash or
+// key, which has the size of any other.
+func templateScript(addressType helpers.AddressType) ([]byte, error) {
+	switch addressType {
+	case helpers.AddressTypeP2PKH:
+		return append(append([]byte{0x76, 0xa9, 0x14}, make([]byte, 20)...), 0x88, 0xac), nil
+	case helpers.AddressTypeP2SHP2WPKH:
+		return append(append([]byte{0xa9, 0x14}, make([]byte, 20)...), 0x87), nil
+	case helpers.AddressTypeP2WPKH:
+		return append([]byte{0x00, 0x14}, make([]byte, 20)...), nil
+	case helpers.AddressTypeP2TR:
+		return append([]byte{0x51, 0x20}, make([]byte, 32)...), nil
+	default:
+		return nil, fmt.Errorf("unknown address type %q", addressType)
+	}
+}
+
+// fee is the fee of the weight at the rate in sat/vB, rounded up.
+func fee(weight int, rate float64) int64 {
+	return int64(math.Ceil(float64(weight) * rate / 4))
+}
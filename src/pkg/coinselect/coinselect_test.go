@@ -0,0 +1,207 @@
+package coinselect
+
+import (
+	"errors"
+	"math/rand"
+	"testing"
+
+	"btcwallet.com/src/pkg/helpers"
+	"btcwallet.com/src/pkg/psbt"
+	"github.com/btcsuite/btcd/wire"
+)
+
+func TestInputWeight(t *testing.T) {
+	tests := []struct {
+		addressType helpers.AddressType
+		weight      int
+		witness     bool
+	}{
+		{helpers.AddressTypeP2PKH, 592, false},
+		{helpers.AddressTypeP2SHP2WPKH, 364, true},
+		{helpers.AddressTypeP2WPKH, 272, true},
+		{helpers.AddressTypeP2TR, 230, true},
+	}
+	for _, test := range tests {
+		weight, witness, err := InputWeight(test.addressType)
+		if err != nil {
+			t.Fatalf("Test failed: unexpected error: %v", err)
+		}
+		if weight != test.weight || witness != test.witness {
+			t.Errorf("Test failed:  expected: %d %v received: %d %v ", test.weight, test.witness, weight, witness)
+		}
+	}
+	if _, _, err := InputWeight("p2wsh"); err == nil {
+		t.Errorf("Test failed:  expected: an error for an unknown address type ")
+	}
+}
+
+func TestSelectBranchAndBound(t *testing.T) {
+	utxos := []Utxo{
+		{TxId: "a", Amount: 100000, AddressType: helpers.AddressTypeP2WPKH},
+		{TxId: "b", Amount: 500000, AddressType: helpers.AddressTypeP2WPKH},
+		{TxId: "c", Amount: 50200, AddressType: helpers.AddressTypeP2WPKH},
+		{TxId: "d", Amount: 30000, AddressType: helpers.AddressTypeP2WPKH},
+	}
+	selection, err := selectCoins(Request{Utxos: utxos, Target: 150000, FeeRate: 1, LongTermFeeRate: 1}, rand.New(rand.NewSource(1)))
+	if err != nil {
+		t.Fatalf("Test failed: unexpected error: %v", err)
+	}
+	if selection.Algorithm != BranchAndBound {
+		t.Errorf("Test failed:  expected: %s received: %s ", BranchAndBound, selection.Algorithm)
+	}
+	if len(selection.Utxos) != 2 || selection.Utxos[0].TxId != "a" || selection.Utxos[1].TxId != "c" {
+		t.Errorf("Test failed:  expected: utxos a and c received: %v ", selection.Utxos)
+	}
+	if selection.Change != 0 || selection.Fee != 200 {
+		t.Errorf("Test failed:  expected: no change and a fee of 200 received: %d and %d ", selection.Change, selection.Fee)
+	}
+	// the waste is the excess over the fee needed at equal fee rates: two
+	// inputs of 68 vB and 42 vB without inputs, rounded up
+	if selection.Waste != 200-68-68-42 {
+		t.Errorf("Test failed:  expected: %d received: %d ", 200-68-68-42, selection.Waste)
+	}
+	if selection.Weight != 2*272+166 || selection.VSize != 178 {
+		t.Errorf("Test failed:  expected: %d WU %d vB received: %d WU %d vB ", 2*272+166, 178, selection.Weight, selection.VSize)
+	}
+}
+
+func TestSelectWithChange(t *testing.T) {
+	utxos := []Utxo{
+		{TxId: "a", Amount: 100000, AddressType: helpers.AddressTypeP2PKH},
+		{TxId: "b", Amount: 100000, AddressType: helpers.AddressTypeP2SHP2WPKH},
+		{TxId: "c", Amount: 300000, AddressType: helpers.AddressTypeP2TR},
+		{TxId: "d", Amount: 1000, AddressType: helpers.AddressTypeP2WPKH},
+	}
+	for seed := int64(0); seed < 20; seed++ {
+		request := Request{
+			Utxos:           utxos,
+			Target:          140000,
+			FeeRate:         5,
+			LongTermFeeRate: 10,
+			Outputs:         []helpers.AddressType{helpers.AddressTypeP2PKH, helpers.AddressTypeP2TR},
+			ChangeType:      helpers.AddressTypeP2TR,
+		}
+		selection, err := selectCoins(request, rand.New(rand.NewSource(seed)))
+		if err != nil {
+			t.Fatalf("Test failed: unexpected error: %v", err)
+		}
+		if selection.Algorithm == BranchAndBound {
+			t.Errorf("Test failed:  expected: a selection with change received: %s ", selection.Algorithm)
+		}
+		if selection.Change < 330 {
+			t.Errorf("Test failed:  expected: a change over the dust threshold received: %d ", selection.Change)
+		}
+		if selection.Amount != request.Target+selection.Fee+selection.Change {
+			t.Errorf("Test failed:  expected: %d received: %d ", selection.Amount, request.Target+selection.Fee+selection.Change)
+		}
+		if needed := fee(selection.Weight, request.FeeRate); selection.Fee < needed {
+			t.Errorf("Test failed:  expected: a fee of at least %d received: %d ", needed, selection.Fee)
+		}
+		var amount int64
+		for _, utxo := range selection.Utxos {
+			amount += utxo.Amount
+		}
+		if amount != selection.Amount {
+			t.Errorf("Test failed:  expected: %d received: %d ", amount, selection.Amount)
+		}
+	}
+}
+
+func TestSelectDustChange(t *testing.T) {
+	// 200 sats over the target and the fee without change, too little for a
+	// change output that is not dust and too much for Branch and Bound
+	utxos := []Utxo{{TxId: "a", Amount: 40000 + 68 + 42 + 200, AddressType: helpers.AddressTypeP2WPKH}}
+	selection, err := selectCoins(Request{Utxos: utxos, Target: 40000, FeeRate: 1, LongTermFeeRate: 1}, rand.New(rand.NewSource(1)))
+	if err != nil {
+		t.Fatalf("Test failed: unexpected error: %v", err)
+	}
+	if selection.Algorithm == BranchAndBound || selection.Change != 0 {
+		t.Errorf("Test failed:  expected: a fallback selection without change received: %s with %d ", selection.Algorithm, selection.Change)
+	}
+	if selection.Fee != 310 || selection.Waste != 200 {
+		t.Errorf("Test failed:  expected: a fee of 310 and a waste of 200 received: %d and %d ", selection.Fee, selection.Waste)
+	}
+}
+
+func TestSelectLegacyPool(t *testing.T) {
+	// more UTXOs than a one byte input count holds, none with a witness
+	utxos := make([]Utxo, 300)
+	for i := range utxos {
+		utxos[i] = Utxo{TxId: "a", Vout: uint32(i), Amount: 10000, AddressType: helpers.AddressTypeP2PKH}
+	}
+	selection, err := selectCoins(Request{Utxos: utxos, Target: 25000, FeeRate: 2, LongTermFeeRate: 2}, rand.New(rand.NewSource(1)))
+	if err != nil {
+		t.Fatalf("Test failed: unexpected error: %v", err)
+	}
+	// a one byte input count and no marker and flag bytes: 41 vB without
+	// inputs and 31 vB of change
+	expected := 164 + 592*len(selection.Utxos)
+	if selection.Change > 0 {
+		expected += 124
+	}
+	if len(selection.Utxos) > 252 || selection.Weight != expected {
+		t.Errorf("Test failed:  expected: %d WU received: %d WU for %d utxos ", expected, selection.Weight, len(selection.Utxos))
+	}
+	if needed := fee(selection.Weight, 2); selection.Fee < needed {
+		t.Errorf("Test failed:  expected: a fee of at least %d received: %d ", needed, selection.Fee)
+	}
+
+	// a single segwit UTXO adds the marker and flag bytes once it is selected
+	utxos = append(utxos, Utxo{TxId: "b", Amount: 30000, AddressType: helpers.AddressTypeP2WPKH})
+	selection, err = selectCoins(Request{Utxos: utxos, Target: 29700, FeeRate: 2, LongTermFeeRate: 2}, rand.New(rand.NewSource(1)))
+	if err != nil {
+		t.Fatalf("Test failed: unexpected error: %v", err)
+	}
+	if len(selection.Utxos) != 1 || selection.Utxos[0].TxId != "b" || selection.Weight != 164+272+2 {
+		t.Errorf("Test failed:  expected: utxo b in %d WU received: %v in %d WU ", 164+272+2, selection.Utxos, selection.Weight)
+	}
+}
+
+func TestSelectMixedWeight(t *testing.T) {
+	utxos := []Utxo{
+		{TxId: "a", Amount: 20000, AddressType: helpers.AddressTypeP2PKH},
+		{TxId: "b", Amount: 20000, AddressType: helpers.AddressTypeP2PKH},
+		{TxId: "c", Amount: 20000, AddressType: helpers.AddressTypeP2WPKH},
+	}
+	request := Request{Utxos: utxos, Target: 55000, FeeRate: 3, LongTermFeeRate: 3}
+	selection, err := selectCoins(request, rand.New(rand.NewSource(1)))
+	if err != nil {
+		t.Fatalf("Test failed: unexpected error: %v", err)
+	}
+	if len(selection.Utxos) != 3 {
+		t.Fatalf("Test failed:  expected: 3 utxos received: %v ", selection.Utxos)
+	}
+
+	// the same transaction as the PSBT package estimates it
+	tx := wire.NewMsgTx(wire.TxVersion)
+	packet := &psbt.Packet{UnsignedTx: tx}
+	for _, utxo := range selection.Utxos {
+		script, _ := templateScript(utxo.AddressType)
+		tx.AddTxIn(wire.NewTxIn(&wire.OutPoint{}, nil, nil))
+		packet.Inputs = append(packet.Inputs, psbt.Input{WitnessUtxo: wire.NewTxOut(utxo.Amount, script)})
+	}
+	payment, _ := templateScript(helpers.AddressTypeP2WPKH)
+	tx.AddTxOut(wire.NewTxOut(request.Target, payment))
+	if selection.Change > 0 {
+		tx.AddTxOut(wire.NewTxOut(selection.Change, payment))
+	}
+	expected, err := packet.EstimateWeight()
+	if err != nil {
+		t.Fatalf("Test failed: unexpected error: %v", err)
+	}
+	if selection.Weight != expected {
+		t.Errorf("Test failed:  expected: %d WU received: %d WU ", expected, selection.Weight)
+	}
+}
+
+func TestSelectInsufficientFunds(t *testing.T) {
+	utxos := []Utxo{
+		{TxId: "a", Amount: 10000, AddressType: helpers.AddressTypeP2WPKH},
+		// worth less than the fee of spending it
+		{TxId: "b", Amount: 500, AddressType: helpers.AddressTypeP2PKH},
+	}
+	_, err := Select(Request{Utxos: utxos, Target: 10000, FeeRate: 5, LongTermFeeRate: 5})
+	if !errors.Is(err, ErrInsufficientFunds) {
+		t.Errorf("Test failed:  expected: %v received: %v ", ErrInsufficientFunds, err)
+	}
+}
@@ -0,0 +1,361 @@
+package descriptors
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"btcwallet.com/src/pkg/helpers"
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcutil"
+)
+
+// maxRedeemScriptSize is the consensus limit of a P2SH redeem script.
+const maxRedeemScriptSize = 520
+
+// Script is one function of a descriptor, with either its keys or the script
+// it wraps. Tree is the optional script tree of tr().
+type Script struct {
+	Function  string
+	Keys      []*Key
+	Threshold int
+	Sub       *Script
+	Tree      *TapTree
+}
+
+// Descriptor is a parsed output descriptor of the network.
+type Descriptor struct {
+	Script   *Script
+	Checksum string
+	// text is the descriptor without its checksum.
+	text         string
+	net          *chaincfg.Params
+	walletHelper helpers.WalletHelper
+}
+
+// allowedScripts lists the functions allowed at the top level and inside
+// sh() and wsh().
+var allowedScripts = map[string][]string{
+	"":    {"pkh", "wpkh", "sh", "wsh", "tr"},
+	"sh":  {"pkh", "wpkh", "wsh", "multi", "sortedmulti"},
+	"wsh": {"pkh", "multi", "sortedmulti"},
+}
+
+// Parse verifies the checksum of a descriptor when it has one and parses its
+// pkh, wpkh, sh, wsh, tr, multi and sortedmulti functions and the script tree
+// of tr().
+func Parse(walletHelper helpers.WalletHelper, descriptor string, net *chaincfg.Params) (*Descriptor, error) {
+	text := strings.TrimSpace(descriptor)
+	checksum, err := helpers.DescriptorChecksum(strings.SplitN(text, "#", 2)[0])
+	if err != nil {
+		return nil, err
+	}
+	if i := strings.Index(text, "#"); i >= 0 {
+		if text[i+1:] != checksum {
+			return nil, fmt.Errorf("%w: checksum %s does not match, expected %s", helpers.ErrInvalidDescriptor, text[i+1:], checksum)
+		}
+		text = text[:i]
+	}
+
+	script, err := parseScript(text, "", net)
+	if err != nil {
+		return nil, err
+	}
+	d := &Descriptor{
+		Script:       script,
+		Checksum:     checksum,
+		text:         text,
+		net:          net,
+		walletHelper: walletHelper,
+	}
+	if err := d.checkMultipath(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func parseScript(text string, parent string, net *chaincfg.Params) (*Script, error) {
+	open := strings.Index(text, "(")
+	if open < 0 || !strings.HasSuffix(text, ")") {
+		return nil, fmt.Errorf("%w: expected function(...) got %s", helpers.ErrInvalidDescriptor, text)
+	}
+	function, inner := text[:open], text[open+1:len(text)-1]
+	allowed := false
+	for _, name := range allowedScripts[parent] {
+		allowed = allowed || name == function
+	}
+	if !allowed {
+		if parent == "" {
+			return nil, fmt.Errorf("%w: %s() is not supported at the top level", helpers.ErrInvalidDescriptor, function)
+		}
+		return nil, fmt.Errorf("%w: %s() is not allowed inside %s()", helpers.ErrInvalidDescriptor, function, parent)
+	}
+
+	script := &Script{Function: function}
+	args, err := splitArgs(inner)
+	if err != nil {
+		return nil, err
+	}
+	switch function {
+	case "sh", "wsh":
+		if len(args) != 1 {
+			return nil, fmt.Errorf("%w: %s() takes a single script", helpers.ErrInvalidDescriptor, function)
+		}
+		script.Sub, err = parseScript(args[0], function, net)
+		if err != nil {
+			return nil, err
+		}
+	case "pkh", "wpkh", "tr":
+		if function == "tr" && len(args) == 2 {
+			if script.Tree, err = parseTapTree(args[1], net); err != nil {
+				return nil, err
+			}
+			args = args[:1]
+		}
+		if len(args) != 1 {
+			if function == "tr" {
+				return nil, fmt.Errorf("%w: tr() takes a key and an optional script tree", helpers.ErrInvalidDescriptor)
+			}
+			return nil, fmt.Errorf("%w: %s() takes a single key", helpers.ErrInvalidDescriptor, function)
+		}
+		key, err := parseKey(args[0], net, function == "tr")
+		if err != nil {
+			return nil, err
+		}
+		script.Keys = []*Key{key}
+	case "multi", "sortedmulti":
+		if len(args) < 2 {
+			return nil, fmt.Errorf("%w: %s() takes a threshold and keys", helpers.ErrInvalidDescriptor, function)
+		}
+		threshold, err := strconv.Atoi(args[0])
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s() threshold %s", helpers.ErrInvalidDescriptor, function, args[0])
+		}
+		for _, arg := range args[1:] {
+			key, err := parseKey(arg, net, false)
+			if err != nil {
+				return nil, err
+			}
+			script.Keys = append(script.Keys, key)
+		}
+		if threshold < 1 || threshold > len(script.Keys) || len(script.Keys) > helpers.MaxMultisigKeys {
+			return nil, fmt.Errorf("%w: %s() needs 1 <= threshold <= keys <= %d. got %d of %d", helpers.ErrInvalidDescriptor, function, helpers.MaxMultisigKeys, threshold, len(script.Keys))
+		}
+		script.Threshold = threshold
+	}
+
+	// segwit and taproot scripts only commit to compressed or x-only keys
+	if function == "wpkh" || function == "tr" || parent == "wsh" {
+		for _, key := range script.Keys {
+			if !key.Compressed {
+				return nil, fmt.Errorf("%w: uncompressed key in %s()", helpers.ErrInvalidDescriptor, function)
+			}
+		}
+	}
+	return script, nil
+}
+
+// splitArgs splits the arguments of a function at the commas that are not
+// nested in another function or key origin.
+func splitArgs(inner string) ([]string, error) {
+	var args []string
+	depth, start := 0, 0
+	for i, ch := range inner {
+		switch ch {
+		case '(', '[', '{':
+			depth++
+		case ')', ']', '}':
+			depth--
+			if depth < 0 {
+				return nil, fmt.Errorf("%w: unbalanced brackets in %s", helpers.ErrInvalidDescriptor, inner)
+			}
+		case ',':
+			if depth == 0 {
+				args = append(args, inner[start:i])
+				start = i + 1
+			}
+		}
+	}
+	if depth != 0 {
+		return nil, fmt.Errorf("%w: unbalanced brackets in %s", helpers.ErrInvalidDescriptor, inner)
+	}
+	return append(args, inner[start:]), nil
+}
+
+// keys lists every key of the descriptor, the internal key of tr() first.
+func (s *Script) keys() []*Key {
+	if s.Sub != nil {
+		return s.Sub.keys()
+	}
+	if s.Tree != nil {
+		return append(append([]*Key{}, s.Keys...), s.Tree.keys()...)
+	}
+	return s.Keys
+}
+
+// checkMultipath requires every multipath key to have the same number of paths.
+func (d *Descriptor) checkMultipath() error {
+	for _, key := range d.Script.keys() {
+		if key.Multipath == nil {
+			continue
+		}
+		if d.Multipath() != len(key.Multipath) {
+			return fmt.Errorf("%w: multipath steps of different lengths", helpers.ErrInvalidDescriptor)
+		}
+	}
+	return nil
+}
+
+// String is the descriptor with its checksum.
+func (d *Descriptor) String() string {
+	return d.text + "#" + d.Checksum
+}
+
+// IsRange reports whether the descriptor has a wildcard and derives a
+// different output for every index.
+func (d *Descriptor) IsRange() bool {
+	for _, key := range d.Script.keys() {
+		if key.Wildcard {
+			return true
+		}
+	}
+	return false
+}
+
+// Multipath is the number of descriptors a BIP389 multipath descriptor stands
+// for, 1 when it has no multipath step.
+func (d *Descriptor) Multipath() int {
+	for _, key := range d.Script.keys() {
+		if key.Multipath != nil {
+			return len(key.Multipath)
+		}
+	}
+	return 1
+}
+
+// Address derives the address at the index of a ranged descriptor, the
+// index is ignored by a descriptor without wildcard. multipathIndex selects
+// one of the paths of a multipath descriptor.
+func (d *Descriptor) Address(index uint32, multipathIndex int) (string, error) {
+	if multipathIndex < 0 || multipathIndex >= d.Multipath() {
+		return "", fmt.Errorf("%w: multipath index %d out of %d paths", helpers.ErrInvalidDescriptor, multipathIndex, d.Multipath())
+	}
+	script := d.Script
+	if script.Tree != nil {
+		_, outputKey, _, _, err := script.taproot(index, multipathIndex)
+		if err != nil {
+			return "", err
+		}
+		return helpers.TaprootAddress(outputKey, d.net)
+	}
+	switch script.Function {
+	case "pkh", "wpkh", "tr":
+		pubKey, err := script.Keys[0].derive(index, multipathIndex)
+		if err != nil {
+			return "", err
+		}
+		if !script.Keys[0].Compressed {
+			address, err := btcutil.NewAddressPubKeyHash(btcutil.Hash160(pubKey.SerializeUncompressed()), d.net)
+			if err != nil {
+				return "", err
+			}
+			return address.EncodeAddress(), nil
+		}
+		addressType := map[string]helpers.AddressType{"pkh": helpers.AddressTypeP2PKH, "wpkh": helpers.AddressTypeP2WPKH, "tr": helpers.AddressTypeP2TR}[script.Function]
+		return d.walletHelper.DeriveAddressForType(pubKey, d.net, addressType)
+	case "wsh":
+		witnessScript, err := script.Sub.script(index, multipathIndex)
+		if err != nil {
+			return "", err
+		}
+		scriptHash := sha256.Sum256(witnessScript)
+		address, err := btcutil.NewAddressWitnessScriptHash(scriptHash[:], d.net)
+		if err != nil {
+			return "", err
+		}
+		return address.EncodeAddress(), nil
+	case "sh":
+		if script.Sub.Function == "wpkh" {
+			pubKey, err := script.Sub.Keys[0].derive(index, multipathIndex)
+			if err != nil {
+				return "", err
+			}
+			return d.walletHelper.DeriveAddressForType(pubKey, d.net, helpers.AddressTypeP2SHP2WPKH)
+		}
+		redeemScript, err := script.Sub.script(index, multipathIndex)
+		if err != nil {
+			return "", err
+		}
+		if len(redeemScript) > maxRedeemScriptSize {
+			return "", fmt.Errorf("%w: redeem script of %d bytes exceeds %d", helpers.ErrInvalidDescriptor, len(redeemScript), maxRedeemScriptSize)
+		}
+		address, err := btcutil.NewAddressScriptHash(redeemScript, d.net)
+		if err != nil {
+			return "", err
+		}
+		return address.EncodeAddress(), nil
+	default:
+		return "", fmt.Errorf("%w: %s() has no address", helpers.ErrInvalidDescriptor, script.Function)
+	}
+}
+
+// script builds the redeem or witness script of a function nested in sh()
+// or wsh().
+func (s *Script) script(index uint32, multipathIndex int) ([]byte, error) {
+	switch s.Function {
+	case "wsh":
+		witnessScript, err := s.Sub.script(index, multipathIndex)
+		if err != nil {
+			return nil, err
+		}
+		scriptHash := sha256.Sum256(witnessScript)
+		return txscript.NewScriptBuilder().AddOp(txscript.OP_0).AddData(scriptHash[:]).Script()
+	case "wpkh":
+		pubKey, err := s.Keys[0].derive(index, multipathIndex)
+		if err != nil {
+			return nil, err
+		}
+		return txscript.NewScriptBuilder().AddOp(txscript.OP_0).AddData(btcutil.Hash160(pubKey.SerializeCompressed())).Script()
+	case "pkh":
+		pubKey, err := s.Keys[0].derive(index, multipathIndex)
+		if err != nil {
+			return nil, err
+		}
+		return txscript.NewScriptBuilder().AddOp(txscript.OP_DUP).AddOp(txscript.OP_HASH160).
+			AddData(btcutil.Hash160(serializePubKey(pubKey, s.Keys[0].Compressed))).
+			AddOp(txscript.OP_EQUALVERIFY).AddOp(txscript.OP_CHECKSIG).Script()
+	case "multi", "sortedmulti":
+		pubKeys := make([][]byte, len(s.Keys))
+		for i, key := range s.Keys {
+			pubKey, err := key.derive(index, multipathIndex)
+			if err != nil {
+				return nil, err
+			}
+			pubKeys[i] = serializePubKey(pubKey, key.Compressed)
+		}
+		if s.Function == "sortedmulti" {
+			sort.Slice(pubKeys, func(i, j int) bool {
+				return bytes.Compare(pubKeys[i], pubKeys[j]) < 0
+			})
+		}
+		builder := txscript.NewScriptBuilder().AddInt64(int64(s.Threshold))
+		for _, pubKey := range pubKeys {
+			builder.AddData(pubKey)
+		}
+		return builder.AddInt64(int64(len(pubKeys))).AddOp(txscript.OP_CHECKMULTISIG).Script()
+	default:
+		return nil, fmt.Errorf("%w: %s() has no script", helpers.ErrInvalidDescriptor, s.Function)
+	}
+}
+
+func serializePubKey(pubKey *btcec.PublicKey, compressed bool) []byte {
+	if compressed {
+		return pubKey.SerializeCompressed()
+	}
+	return pubKey.SerializeUncompressed()
+}
@@ -0,0 +1,261 @@
+package descriptors
+
+import (
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"btcwallet.com/src/pkg/helpers"
+	"github.com/btcsuite/btcd/chaincfg"
+)
+
+func TestParseDescriptor(t *testing.T) {
+	var walletHelper helpers.WalletHelper = helpers.NewWalletHelper()
+	tests := []struct {
+		descriptor     string
+		index          uint32
+		multipathIndex int
+		expected       string
+	}{
+		// BIP381
+		{"pkh(02c6047f9441ed7d6d3045406e95c07cd85c778e4b8cef3ca7abac09b95c709ee5)", 0, 0, "1cMh228HTCiwS8ZsaakH8A8wze1JR5ZsP"},
+		// BIP383 sortedmulti, the keys are sorted in the script
+		{"sh(sortedmulti(2,03acd484e2f0c7f65309ad178a9f559abde09796974c57e714c35f110dfc27ccbe,022f01e5e15cca351daff3843fb70f3c2f0a1bdd05e5af888a67784ef3e10a2a01))", 0, 0, "3GtEB3yg3r5de2cDJG48SkQwxfxJumKQdN"},
+		// BIP84 and BIP86 vectors of the "abandon abandon ... about" mnemonic
+		{"wpkh([73c5da0a/84'/0'/0']xpub6CatWdiZiodmUeTDp8LT5or8nmbKNcuyvz7WyksVFkKB4RHwCD3XyuvPEbvqAQY3rAPshWcMLoP2fMFMKHPJ4ZeZXYVUhLv1VMrjPC7PW6V/0/*)#wc3n3van", 0, 0, "bc1qcr8te4kr609gcawutmrza0j4xv80jy8z306fyu"},
+		{"wpkh([73c5da0a/84'/0'/0']xpub6CatWdiZiodmUeTDp8LT5or8nmbKNcuyvz7WyksVFkKB4RHwCD3XyuvPEbvqAQY3rAPshWcMLoP2fMFMKHPJ4ZeZXYVUhLv1VMrjPC7PW6V/<0;1>/*)", 1, 0, "bc1qnjg0jd8228aq7egyzacy8cys3knf9xvrerkf9g"},
+		{"wpkh([73c5da0a/84'/0'/0']xpub6CatWdiZiodmUeTDp8LT5or8nmbKNcuyvz7WyksVFkKB4RHwCD3XyuvPEbvqAQY3rAPshWcMLoP2fMFMKHPJ4ZeZXYVUhLv1VMrjPC7PW6V/<0;1>/*)", 0, 1, "bc1q8c6fshw2dlwun7ekn9qwf37cu2rn755upcp6el"},
+		{"tr([73c5da0a/86'/0'/0']xpub6BgBgsespWvERF3LHQu6CnqdvfEvtMcQjYrcRzx53QJjSxarj2afYWcLteoGVky7D3UKDP9QyrLprQ3VCECoY49yfdDEHGCtMMj92pReUsQ/0/*)", 1, 0, "bc1p4qhjn9zdvkux4e44uhx8tc55attvtyu358kutcqkudyccelu0was9fqzwh"},
+		// BIP49 vector, derived from the root key with hardened steps
+		{"sh(wpkh(xprv9s21ZrQH143K3GJpoapnV8SFfukcVBSfeCficPSGfubmSFDxo1kuHnLisriDvSnRRuL2Qrg5ggqHKNVpxR86QEC8w35uxmGoggxtQTPvfUu/49h/0h/0h/0/*))", 0, 0, "37VucYSaXLCAsxYyAPfbSi9eh4iEcbShgf"},
+	}
+	for _, test := range tests {
+		descriptor, err := Parse(walletHelper, test.descriptor, &chaincfg.MainNetParams)
+		if err != nil {
+			t.Fatalf("Test failed: input: %s unexpected error: %v", test.descriptor, err)
+		}
+		address, err := descriptor.Address(test.index, test.multipathIndex)
+		if err != nil {
+			t.Fatalf("Test failed: input: %s unexpected error: %v", test.descriptor, err)
+		}
+		if address != test.expected {
+			t.Errorf("Test failed:  expected: %s received: %s ", test.expected, address)
+		}
+	}
+}
+
+func TestParseDescriptorWsh(t *testing.T) {
+	var walletHelper helpers.WalletHelper = helpers.NewWalletHelper()
+	multi := "multi(1,03a0434d9e47f3c86235477c7b1ae6ae5d3442d49b1943c2b752a68e2a47e247c7,03774ae7f858a9411e5ef4246b70c65aac5649980be5c17891bbec17895da008cb)"
+
+	wsh, err := Parse(walletHelper, "wsh("+multi+")", &chaincfg.TestNet3Params)
+	if err != nil {
+		t.Fatalf("Test failed: unexpected error: %v", err)
+	}
+	nested, err := Parse(walletHelper, "sh(wsh("+multi+"))", &chaincfg.TestNet3Params)
+	if err != nil {
+		t.Fatalf("Test failed: unexpected error: %v", err)
+	}
+	if wsh.IsRange() || nested.IsRange() {
+		t.Errorf("Test failed: descriptors without wildcard are not ranged")
+	}
+	address, _ := wsh.Address(0, 0)
+	if len(address) != 62 || address[:4] != "tb1q" {
+		t.Errorf("Test failed:  expected a P2WSH address received: %s ", address)
+	}
+	address, _ = nested.Address(0, 0)
+	if address[:1] != "2" {
+		t.Errorf("Test failed:  expected a P2SH address received: %s ", address)
+	}
+}
+
+func TestParseDescriptorInvalid(t *testing.T) {
+	var walletHelper helpers.WalletHelper = helpers.NewWalletHelper()
+	xpub := "xpub6CatWdiZiodmUeTDp8LT5or8nmbKNcuyvz7WyksVFkKB4RHwCD3XyuvPEbvqAQY3rAPshWcMLoP2fMFMKHPJ4ZeZXYVUhLv1VMrjPC7PW6V"
+	descriptors := []string{
+		// checksum mismatch
+		"wpkh(" + xpub + "/0/*)#wc3n3vaa",
+		// hardened step from a public key
+		"wpkh(" + xpub + "/0h/*)",
+		// unknown and misplaced functions
+		"foo(" + xpub + ")",
+		"wpkh(wpkh(" + xpub + "))",
+		"multi(1," + xpub + ")",
+		// threshold above the number of keys
+		"wsh(multi(3," + xpub + "/0/*," + xpub + "/1/*))",
+		// uncompressed key in segwit
+		"wpkh(04a0434d9e47f3c86235477c7b1ae6ae5d3442d49b1943c2b752a68e2a47e247c7893aba425419bc27a3b6c7e693a24c696f794c2ed877a1593cbee53b037368d7)",
+		// uncompressed hex and WIF keys in tr()
+		"tr(04a0434d9e47f3c86235477c7b1ae6ae5d3442d49b1943c2b752a68e2a47e247c7893aba425419bc27a3b6c7e693a24c696f794c2ed877a1593cbee53b037368d7)",
+		"tr(5HueCGU8rMjxEXxiPuD5BDku4MkFqeZyd4dZ1jvhTVqvbTLvyTJ)",
+		// x-only key outside of tr()
+		"wpkh(a0434d9e47f3c86235477c7b1ae6ae5d3442d49b1943c2b752a68e2a47e247c7)",
+		// testnet key on mainnet
+		"wpkh(tpubD6NzVbkrYhZ4XgiXtGrdW5XDAPFCL9h7we1vwNCpn8tGbBcgfVYjXyhWo4E1xkh56hjod1RhGjxbaTLV3X4FyWuejifB9jusQ46QzG87VKp/0/*)",
+		// multipath steps of different lengths
+		"wsh(multi(1," + xpub + "/<0;1>/*," + xpub + "/<0;1;2>/*))",
+		// script trees: a branch of one child, a non-tapscript leaf, an
+		// uncompressed leaf key and a third tr() argument
+		"tr(a0434d9e47f3c86235477c7b1ae6ae5d3442d49b1943c2b752a68e2a47e247c7,{pk(" + xpub + "/0/*)})",
+		"tr(a0434d9e47f3c86235477c7b1ae6ae5d3442d49b1943c2b752a68e2a47e247c7,multi(1," + xpub + "/0/*))",
+		"tr(a0434d9e47f3c86235477c7b1ae6ae5d3442d49b1943c2b752a68e2a47e247c7,pk(5HueCGU8rMjxEXxiPuD5BDku4MkFqeZyd4dZ1jvhTVqvbTLvyTJ))",
+		"tr(a0434d9e47f3c86235477c7b1ae6ae5d3442d49b1943c2b752a68e2a47e247c7,pk(" + xpub + "/0/*),pk(" + xpub + "/1/*))",
+		"wpkh(" + xpub + "/0/*",
+	}
+	for _, descriptor := range descriptors {
+		if _, err := Parse(walletHelper, descriptor, &chaincfg.MainNetParams); !errors.Is(err, helpers.ErrInvalidDescriptor) {
+			t.Errorf("Test failed: input: %s expected: %v received: %v ", descriptor, helpers.ErrInvalidDescriptor, err)
+		}
+	}
+}
+
+func TestDescriptorOutput(t *testing.T) {
+	var walletHelper helpers.WalletHelper = helpers.NewWalletHelper()
+	tests := []struct {
+		descriptor     string
+		index          uint32
+		multipathIndex int
+		scriptPubKey   string
+		pubKey         string
+		path           string
+	}{
+		// BIP84 and BIP86 vectors of the "abandon abandon ... about" mnemonic
+		{"wpkh([73c5da0a/84'/0'/0']xpub6CatWdiZiodmUeTDp8LT5or8nmbKNcuyvz7WyksVFkKB4RHwCD3XyuvPEbvqAQY3rAPshWcMLoP2fMFMKHPJ4ZeZXYVUhLv1VMrjPC7PW6V/<0;1>/*)", 1, 0, "00149c90f934ea51fa0f6504177043e0908da6929983", "03e775fd51f0dfb8cd865d9ff1cca2a158cf651fe997fdc9fee9c1d3b5e995ea77", "m/84'/0'/0'/0/1"},
+		{"tr([73c5da0a/86'/0'/0']xpub6BgBgsespWvERF3LHQu6CnqdvfEvtMcQjYrcRzx53QJjSxarj2afYWcLteoGVky7D3UKDP9QyrLprQ3VCECoY49yfdDEHGCtMMj92pReUsQ/0/*)", 0, 0, "5120a60869f0dbcf1dc659c9cecbaf8050135ea9e8cdc487053f1dc6880949dc684c", "cc8a4bc64d897bddc5fbc2f670f7a8ba0b386779106cf1223c6fc5d7cd6fc115", "m/86'/0'/0'/0/0"},
+	}
+	for _, test := range tests {
+		descriptor, err := Parse(walletHelper, test.descriptor, &chaincfg.MainNetParams)
+		if err != nil {
+			t.Fatalf("Test failed: input: %s unexpected error: %v", test.descriptor, err)
+		}
+		output, err := descriptor.Output(test.index, test.multipathIndex)
+		if err != nil {
+			t.Fatalf("Test failed: input: %s unexpected error: %v", test.descriptor, err)
+		}
+		if result := hex.EncodeToString(output.ScriptPubKey); result != test.scriptPubKey {
+			t.Errorf("Test failed:  expected: %s received: %s ", test.scriptPubKey, result)
+		}
+		if len(output.Derivations) != 1 {
+			t.Fatalf("Test failed:  expected: 1 derivation received: %d ", len(output.Derivations))
+		}
+		derivation := output.Derivations[0]
+		if result := hex.EncodeToString(derivation.PubKey); result != test.pubKey {
+			t.Errorf("Test failed:  expected: %s received: %s ", test.pubKey, result)
+		}
+		if result := derivation.Origin.Path.String(); result != test.path {
+			t.Errorf("Test failed:  expected: %s received: %s ", test.path, result)
+		}
+
+		path, _ := helpers.ParseDerivationPath(test.path)
+		index, multipathIndex, ok := descriptor.Locate(derivation.Origin.Fingerprint, path, false)
+		if !ok || index != test.index || multipathIndex != test.multipathIndex {
+			t.Errorf("Test failed: input: %s expected: %d/%d received: %d/%d ", test.path, test.multipathIndex, test.index, multipathIndex, index)
+		}
+	}
+}
+
+func TestDescriptorLocate(t *testing.T) {
+	var walletHelper helpers.WalletHelper = helpers.NewWalletHelper()
+	descriptor, err := Parse(walletHelper, "wsh(sortedmulti(2,[73c5da0a/48'/1'/0'/2']tpubDFH9dgzveyD8zTbPUFuLrGmCydNvxehyNdUXKJAQN8x4aZ4j6UZqGfnqFrD4NqyaTVGKbvEW54tsvPTK2UoSbCC1PJY8iCNiwTL3RWZEheQ/<0;1>/*,tpubDFH9dgzveyD8zTbPUFuLrGmCydNvxehyNdUXKJAQN8x4aZ4j6UZqGfnqFrD4NqyaTVGKbvEW54tsvPTK2UoSbCC1PJY8iCNiwTL3RWZEheQ/<0;1>/*))", &chaincfg.TestNet3Params)
+	if err != nil {
+		t.Fatalf("Test failed: unexpected error: %v", err)
+	}
+	tests := []struct {
+		fingerprint []byte
+		path        string
+		relative    bool
+		found       bool
+	}{
+		{nil, "m/48'/1'/0'/2'/1/7", false, true},
+		{[]byte{0x73, 0xc5, 0xda, 0x0a}, "m/48'/1'/0'/2'/1/7", false, true},
+		{[]byte{0, 0, 0, 0}, "m/48'/1'/0'/2'/1/7", false, false},
+		{nil, "1/7", true, true},
+		{nil, "2/7", true, false},
+		{nil, "m/44'/1'/0'/1/7", false, false},
+	}
+	for _, test := range tests {
+		var path helpers.DerivationPath
+		if test.relative {
+			path, _ = helpers.ParseRelativePath(test.path)
+		} else {
+			path, _ = helpers.ParseDerivationPath(test.path)
+		}
+		index, multipathIndex, ok := descriptor.Locate(test.fingerprint, path, test.relative)
+		if ok != test.found || (ok && (index != 7 || multipathIndex != 1)) {
+			t.Errorf("Test failed: input: %s expected: %v received: %v %d/%d ", test.path, test.found, ok, multipathIndex, index)
+		}
+	}
+
+	output, err := descriptor.Output(7, 1)
+	if err != nil {
+		t.Fatalf("Test failed: unexpected error: %v", err)
+	}
+	if output.WitnessScript == nil || len(output.Derivations) != 2 {
+		t.Errorf("Test failed:  expected: a witness script and 2 derivations received: %x %d ", output.WitnessScript, len(output.Derivations))
+	}
+}
+
+func TestParseDescriptorTree(t *testing.T) {
+	var walletHelper helpers.WalletHelper = helpers.NewWalletHelper()
+	// the 1 of 2 taproot multisig, sortedmulti_a sorts the x-only keys
+	a, b := "65e6f7fb614a369c9230912a3bb09c33c5c5be2e1bcfc2293ecaed46708e0b5c", "f546edf7b434b50aa0115c1c82a0f9a96505d9eff55d2fe3b848c4b51c06b643"
+	var expected string = "tb1pga7yzdghw0d6at9jvnrpvps4ec9ffprvzenv7t54c42fn9f7r27qpghjmr"
+	for _, leaf := range []string{"sortedmulti_a(1," + a + "," + b + ")", "sortedmulti_a(1," + b + "," + a + ")", "multi_a(1," + a + "," + b + ")"} {
+		descriptor, err := Parse(walletHelper, "tr(736b665f9f35e8ce8d82abc95548b1a5f7178f5bc48d089900becd531c591528,"+leaf+")", &chaincfg.TestNet3Params)
+		if err != nil {
+			t.Fatalf("Test failed: input: %s unexpected error: %v", leaf, err)
+		}
+		if address, _ := descriptor.Address(0, 0); address != expected {
+			t.Errorf("Test failed:  expected: %s received: %s ", expected, address)
+		}
+	}
+
+	tpub := "[73c5da0a/86'/1'/0']tpubDDfvzhdVV4unsoKt5aE6dcsNsfeWbTgmLZPi8LQDYU2xixrYemMfWJ3BaVneH3u7DBQePdTwhpybaKRU95pi6PMUtLPBJLVQRpzEnjfjZzX"
+	descriptor, err := Parse(walletHelper, "tr("+tpub+"/<0;1>/*,{pk("+tpub+"/<2;3>/*),and_v(v:pk(03"+b+"),older(144))})", &chaincfg.TestNet3Params)
+	if err != nil {
+		t.Fatalf("Test failed: unexpected error: %v", err)
+	}
+	if !descriptor.IsRange() || descriptor.Multipath() != 2 {
+		t.Errorf("Test failed:  expected: a ranged descriptor of 2 paths received: %v %d ", descriptor.IsRange(), descriptor.Multipath())
+	}
+	output, err := descriptor.Output(1, 1)
+	if err != nil {
+		t.Fatalf("Test failed: unexpected error: %v", err)
+	}
+	// every control block proves its leaf against the merkle root, which
+	// tweaks the internal key into the output key
+	if len(output.LeafScripts) != 2 {
+		t.Fatalf("Test failed:  expected: 2 leaves received: %d ", len(output.LeafScripts))
+	}
+	for _, leaf := range output.LeafScripts {
+		node := helpers.TapLeafHash(leaf.Script)
+		for path := leaf.ControlBlock[33:]; len(path) > 0; path = path[32:] {
+			node = helpers.TapBranchHash(node, path[:32])
+		}
+		if hex.EncodeToString(node) != hex.EncodeToString(output.MerkleRoot) {
+			t.Errorf("Test failed:  expected: %x received: %x ", output.MerkleRoot, node)
+		}
+	}
+	internalKey, _ := helpers.ParseXOnlyPubKey(output.InternalKey)
+	outputKey, _ := helpers.TaprootOutputKey(internalKey, output.MerkleRoot)
+	if scriptPubKey := "5120" + hex.EncodeToString(helpers.XOnlyPubKey(outputKey)); hex.EncodeToString(output.ScriptPubKey) != scriptPubKey {
+		t.Errorf("Test failed:  expected: %s received: %x ", scriptPubKey, output.ScriptPubKey)
+	}
+
+	// the hex key of the second leaf has no origin
+	if len(output.Derivations) != 2 {
+		t.Fatalf("Test failed:  expected: 2 derivations received: %d ", len(output.Derivations))
+	}
+	internal, leafKey := output.Derivations[0], output.Derivations[1]
+	if internal.Origin.Path.String() != "m/86'/1'/0'/1/1" || len(internal.LeafHashes) != 0 {
+		t.Errorf("Test failed:  expected: m/86'/1'/0'/1/1 without leaves received: %s %d ", internal.Origin.Path, len(internal.LeafHashes))
+	}
+	leafHash := helpers.TapLeafHash(output.LeafScripts[0].Script)
+	if leafKey.Origin.Path.String() != "m/86'/1'/0'/3/1" || len(leafKey.LeafHashes) != 1 || hex.EncodeToString(leafKey.LeafHashes[0]) != hex.EncodeToString(leafHash) {
+		t.Errorf("Test failed:  expected: m/86'/1'/0'/3/1 in leaf %x received: %s %x ", leafHash, leafKey.Origin.Path, leafKey.LeafHashes)
+	}
+	path, _ := helpers.ParseDerivationPath("m/86'/1'/0'/3/1")
+	if index, multipathIndex, ok := descriptor.Locate(nil, path, false); !ok || index != 1 || multipathIndex != 1 {
+		t.Errorf("Test failed: input: %s expected: 1/1 received: %d/%d ", path, multipathIndex, index)
+	}
+}
@@ -0,0 +1,212 @@
+package descriptors
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"btcwallet.com/src/pkg/helpers"
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcutil"
+	"github.com/tyler-smith/go-bip32"
+)
+
+// Key is a key expression of a descriptor: an optional origin followed by a
+// hex public key, a WIF or an extended key with its derivation steps.
+type Key struct {
+	Origin *helpers.KeyOrigin
+	// PubKey is set for hex and WIF keys, which do not depend on the index.
+	PubKey *btcec.PublicKey
+	// XOnly marks a 32 byte hex key, only valid inside tr().
+	XOnly bool
+	// Compressed is false for uncompressed hex and WIF keys.
+	Compressed bool
+	ExtKey     *bip32.Key
+	// Path are the steps after the extended key, the multipath step holds
+	// the first of the Multipath indexes.
+	Path             helpers.DerivationPath
+	Multipath        []uint32
+	MultipathStep    int
+	Wildcard         bool
+	HardenedWildcard bool
+}
+
+// parseKey parses a key expression, xOnly allows the 32 byte keys of tr().
+func parseKey(expression string, net *chaincfg.Params, xOnly bool) (*Key, error) {
+	key := &Key{MultipathStep: -1, Compressed: true}
+	if strings.HasPrefix(expression, "[") {
+		end := strings.Index(expression, "]")
+		if end < 0 {
+			return nil, fmt.Errorf("%w: key origin %s is not closed", helpers.ErrInvalidDescriptor, expression)
+		}
+		origin, err := parseOrigin(expression[1:end])
+		if err != nil {
+			return nil, err
+		}
+		key.Origin = origin
+		expression = expression[end+1:]
+	}
+
+	fields := strings.Split(expression, "/")
+	if len(fields) == 1 {
+		if pubKey, err := parseHexKey(expression, xOnly); err == nil {
+			key.PubKey = pubKey
+			key.XOnly = len(expression) == 64
+			key.Compressed = len(expression) != 130
+			return key, nil
+		}
+		if wif, err := btcutil.DecodeWIF(expression); err == nil {
+			if !wif.IsForNet(net) {
+				return nil, fmt.Errorf("%w: WIF is not for network %s", helpers.ErrInvalidDescriptor, net.Name)
+			}
+			key.PubKey = wif.PrivKey.PubKey()
+			key.Compressed = wif.CompressPubKey
+			return key, nil
+		}
+	}
+
+	extKey, err := helpers.DecodeExtendedKey(fields[0], net)
+	if err != nil {
+		return nil, fmt.Errorf("%w: key %s: %v", helpers.ErrInvalidDescriptor, fields[0], err)
+	}
+	key.ExtKey = extKey
+	for i, field := range fields[1:] {
+		if i == len(fields)-2 && (field == "*" || isHardenedWildcard(field)) {
+			key.Wildcard = true
+			key.HardenedWildcard = field != "*"
+			break
+		}
+		if strings.HasPrefix(field, "<") && strings.HasSuffix(field, ">") {
+			if key.Multipath != nil {
+				return nil, fmt.Errorf("%w: key %s has more than one multipath step", helpers.ErrInvalidDescriptor, fields[0])
+			}
+			indexes, err := parseMultipath(field[1 : len(field)-1])
+			if err != nil {
+				return nil, err
+			}
+			key.Multipath = indexes
+			key.MultipathStep = len(key.Path)
+			key.Path = append(key.Path, indexes[0])
+			continue
+		}
+		index, err := helpers.ParseRelativePath(field)
+		if err != nil {
+			return nil, fmt.Errorf("%w: step %s: %v", helpers.ErrInvalidDescriptor, field, err)
+		}
+		key.Path = append(key.Path, index...)
+	}
+	if !extKey.IsPrivate && (key.HardenedWildcard || key.Path.IsHardened()) {
+		return nil, fmt.Errorf("%w: hardened derivation from extended public key %s", helpers.ErrInvalidDescriptor, fields[0])
+	}
+	return key, nil
+}
+
+func parseOrigin(origin string) (*helpers.KeyOrigin, error) {
+	fields := strings.SplitN(origin, "/", 2)
+	fingerprint, err := hex.DecodeString(fields[0])
+	if err != nil || len(fingerprint) != 4 {
+		return nil, fmt.Errorf("%w: key origin fingerprint must be 8 hex characters. got %s", helpers.ErrInvalidDescriptor, fields[0])
+	}
+	keyOrigin := &helpers.KeyOrigin{Fingerprint: fingerprint, Path: helpers.DerivationPath{}}
+	if len(fields) == 2 {
+		path, err := helpers.ParseRelativePath(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("%w: key origin path: %v", helpers.ErrInvalidDescriptor, err)
+		}
+		keyOrigin.Path = path
+	}
+	return keyOrigin, nil
+}
+
+func parseHexKey(key string, xOnly bool) (*btcec.PublicKey, error) {
+	decoded, err := hex.DecodeString(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(decoded) == 32 {
+		if !xOnly {
+			return nil, fmt.Errorf("%w: x-only key %s outside of tr()", helpers.ErrInvalidDescriptor, key)
+		}
+		return helpers.ParseXOnlyPubKey(decoded)
+	}
+	return btcec.ParsePubKey(decoded, btcec.S256())
+}
+
+func parseMultipath(multipath string) ([]uint32, error) {
+	fields := strings.Split(multipath, ";")
+	if len(fields) < 2 {
+		return nil, fmt.Errorf("%w: multipath step <%s> needs at least two indexes", helpers.ErrInvalidDescriptor, multipath)
+	}
+	indexes := make([]uint32, 0, len(fields))
+	seen := map[uint32]bool{}
+	for _, field := range fields {
+		index, err := helpers.ParseRelativePath(field)
+		if err != nil || len(index) != 1 {
+			return nil, fmt.Errorf("%w: multipath index %s", helpers.ErrInvalidDescriptor, field)
+		}
+		if seen[index[0]] {
+			return nil, fmt.Errorf("%w: multipath step <%s> repeats index %s", helpers.ErrInvalidDescriptor, multipath, field)
+		}
+		seen[index[0]] = true
+		indexes = append(indexes, index[0])
+	}
+	return indexes, nil
+}
+
+func isHardenedWildcard(field string) bool {
+	return field == "*'" || field == "*h" || field == "*H"
+}
+
+// derive computes the public key at the index of a ranged key, for the
+// multipath index of a multipath key.
+func (k *Key) derive(index uint32, multipathIndex int) (*btcec.PublicKey, error) {
+	if k.PubKey != nil {
+		return k.PubKey, nil
+	}
+	child := k.ExtKey
+	for _, step := range k.path(index, multipathIndex) {
+		var err error
+		child, err = child.NewChildKey(step)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if child.IsPrivate {
+		_, pubKey := btcec.PrivKeyFromBytes(btcec.S256(), child.Key)
+		return pubKey, nil
+	}
+	return btcec.ParsePubKey(child.Key, btcec.S256())
+}
+
+// path are the steps from the extended key to the key at the index.
+func (k *Key) path(index uint32, multipathIndex int) helpers.DerivationPath {
+	path := k.Path
+	if k.Multipath != nil {
+		path = k.Path.Append()
+		path[k.MultipathStep] = k.Multipath[multipathIndex]
+	}
+	if k.Wildcard {
+		if k.HardenedWildcard {
+			index += bip32.FirstHardenedChild
+		}
+		path = path.Append(index)
+	}
+	return path
+}
+
+// origin is the master key fingerprint and full path of the key at the
+// index. An extended key without origin is its own master key, a hex or WIF
+// key without origin has none.
+func (k *Key) origin(index uint32, multipathIndex int) (helpers.KeyOrigin, bool) {
+	switch {
+	case k.Origin != nil && k.ExtKey == nil:
+		return *k.Origin, true
+	case k.Origin != nil:
+		return helpers.KeyOrigin{Fingerprint: k.Origin.Fingerprint, Path: k.Origin.Path.Append(k.path(index, multipathIndex)...)}, true
+	case k.ExtKey != nil:
+		return helpers.KeyOrigin{Fingerprint: helpers.KeyFingerprint(k.ExtKey), Path: k.path(index, multipathIndex)}, true
+	default:
+		return helpers.KeyOrigin{}, false
+	}
+}
@@ -0,0 +1,173 @@
+package descriptors
+
+import (
+	"bytes"
+
+	"btcwallet.com/src/pkg/helpers"
+	"github.com/tyler-smith/go-bip32"
+)
+
+// Output is the output of a descriptor at an index with what a signer needs
+// to spend it. RedeemScript is set for sh(), WitnessScript for wsh() and
+// InternalKey for tr(), with MerkleRoot and LeafScripts when it has a script
+// tree. Derivations are the origins of the keys the output is derived from,
+// x-only keys for tr().
+type Output struct {
+	ScriptPubKey  []byte
+	RedeemScript  []byte
+	WitnessScript []byte
+	InternalKey   []byte
+	MerkleRoot    []byte
+	LeafScripts   []LeafScript
+	Derivations   []KeyDerivation
+}
+
+// LeafScript is a tapscript leaf of a tr() output with the control block
+// spending it.
+type LeafScript struct {
+	Script       []byte
+	ControlBlock []byte
+}
+
+// KeyDerivation is a public key with the fingerprint and path it is derived
+// at. LeafHashes are the tr() leaves the key signs in.
+type KeyDerivation struct {
+	PubKey     []byte
+	Origin     helpers.KeyOrigin
+	LeafHashes [][]byte
+}
+
+// Output derives the output at the index of a ranged descriptor, for the
+// multipath index of a multipath descriptor.
+func (d *Descriptor) Output(index uint32, multipathIndex int) (*Output, error) {
+	address, err := d.Address(index, multipathIndex)
+	if err != nil {
+		return nil, err
+	}
+	scriptPubKey, err := helpers.AddressScript(address, d.net)
+	if err != nil {
+		return nil, err
+	}
+	output := &Output{ScriptPubKey: scriptPubKey}
+
+	script := d.Script
+	if script.Function == "sh" {
+		if output.RedeemScript, err = script.Sub.script(index, multipathIndex); err != nil {
+			return nil, err
+		}
+		script = script.Sub
+	}
+	if script.Function == "wsh" {
+		if output.WitnessScript, err = script.Sub.script(index, multipathIndex); err != nil {
+			return nil, err
+		}
+	}
+
+	if d.Script.Function == "tr" {
+		return output, d.Script.taprootFields(output, index, multipathIndex)
+	}
+	for _, key := range d.Script.keys() {
+		pubKey, err := key.derive(index, multipathIndex)
+		if err != nil {
+			return nil, err
+		}
+		if origin, ok := key.origin(index, multipathIndex); ok {
+			output.Derivations = append(output.Derivations, KeyDerivation{PubKey: serializePubKey(pubKey, key.Compressed), Origin: origin})
+		}
+	}
+	return output, nil
+}
+
+// taprootFields sets the internal key of a tr() output, the leaves of its
+// script tree and the x-only derivations of its keys, a key of several
+// leaves listing all their hashes.
+func (s *Script) taprootFields(output *Output, index uint32, multipathIndex int) error {
+	internalKey, err := s.Keys[0].derive(index, multipathIndex)
+	if err != nil {
+		return err
+	}
+	output.InternalKey = helpers.XOnlyPubKey(internalKey)
+	if origin, ok := s.Keys[0].origin(index, multipathIndex); ok {
+		output.Derivations = append(output.Derivations, KeyDerivation{PubKey: output.InternalKey, Origin: origin})
+	}
+	if s.Tree == nil {
+		return nil
+	}
+
+	internalKey, outputKey, merkleRoot, leaves, err := s.taproot(index, multipathIndex)
+	if err != nil {
+		return err
+	}
+	output.MerkleRoot = merkleRoot
+	for _, leaf := range leaves {
+		output.LeafScripts = append(output.LeafScripts, LeafScript{
+			Script:       leaf.script,
+			ControlBlock: helpers.TaprootControlBlock(internalKey, outputKey, leaf.path...),
+		})
+		leafHash := helpers.TapLeafHash(leaf.script)
+		for _, key := range leaf.tree.Keys {
+			origin, ok := key.origin(index, multipathIndex)
+			if !ok {
+				continue
+			}
+			pubKey, err := key.derive(index, multipathIndex)
+			if err != nil {
+				return err
+			}
+			xOnly := helpers.XOnlyPubKey(pubKey)
+			found := false
+			for i := range output.Derivations {
+				if bytes.Equal(output.Derivations[i].PubKey, xOnly) {
+					output.Derivations[i].LeafHashes = append(output.Derivations[i].LeafHashes, leafHash)
+					found = true
+				}
+			}
+			if !found {
+				output.Derivations = append(output.Derivations, KeyDerivation{PubKey: xOnly, Origin: origin, LeafHashes: [][]byte{leafHash}})
+			}
+		}
+	}
+	return nil
+}
+
+// Locate finds the index and multipath index at which a key of the descriptor
+// is derived at the path. An absolute path is compared with the key origins,
+// a nil fingerprint matching any of them, a relative path with the steps
+// after the extended keys.
+func (d *Descriptor) Locate(fingerprint []byte, path helpers.DerivationPath, relative bool) (index uint32, multipathIndex int, ok bool) {
+	for _, key := range d.Script.keys() {
+		for multipathIndex := 0; multipathIndex < d.Multipath(); multipathIndex++ {
+			index := uint32(0)
+			if key.Wildcard {
+				if len(path) == 0 {
+					continue
+				}
+				index = path[len(path)-1]
+				if key.HardenedWildcard {
+					index -= bip32.FirstHardenedChild
+				}
+				// the index wrapped around or is hardened for an unhardened wildcard
+				if index >= bip32.FirstHardenedChild {
+					continue
+				}
+			}
+			var expected helpers.DerivationPath
+			if relative {
+				if key.ExtKey == nil {
+					continue
+				}
+				expected = key.path(index, multipathIndex)
+			} else {
+				origin, ok := key.origin(index, multipathIndex)
+				if !ok || (fingerprint != nil && !bytes.Equal(fingerprint, origin.Fingerprint)) {
+					continue
+				}
+				expected = origin.Path
+			}
+			if expected.String() == path.String() {
+				return index, multipathIndex, true
+			}
+		}
+	}
+	return 0, 0, false
+}
@@ -0,0 +1,168 @@
+package descriptors
+
+import (
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+
+	"btcwallet.com/src/pkg/helpers"
+	"btcwallet.com/src/pkg/miniscript"
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg"
+)
+
+// TapTree is the script tree of tr(), either a leaf with its tapscript
+// expression and keys or a branch of two trees.
+type TapTree struct {
+	Leaf        string
+	Keys        []*Key
+	Left, Right *TapTree
+	// expressions maps the key expressions of the leaf to their keys.
+	expressions map[string]*Key
+}
+
+// tapLeaf is a leaf script of a tree with its merkle path, the sibling
+// hashes from the leaf to the merkle root.
+type tapLeaf struct {
+	tree   *TapTree
+	script []byte
+	path   [][]byte
+}
+
+// parseTapTree parses the {A,B} branches of a tr() script tree and its
+// leaves, which are miniscript expressions or sortedmulti_a().
+func parseTapTree(text string, net *chaincfg.Params) (*TapTree, error) {
+	if strings.HasPrefix(text, "{") {
+		if !strings.HasSuffix(text, "}") {
+			return nil, fmt.Errorf("%w: script tree branch %s is not closed", helpers.ErrInvalidDescriptor, text)
+		}
+		children, err := splitArgs(text[1 : len(text)-1])
+		if err != nil {
+			return nil, err
+		}
+		if len(children) != 2 {
+			return nil, fmt.Errorf("%w: script tree branch %s needs two children", helpers.ErrInvalidDescriptor, text)
+		}
+		tree := &TapTree{}
+		if tree.Left, err = parseTapTree(children[0], net); err != nil {
+			return nil, err
+		}
+		if tree.Right, err = parseTapTree(children[1], net); err != nil {
+			return nil, err
+		}
+		return tree, nil
+	}
+
+	tree := &TapTree{Leaf: text, expressions: map[string]*Key{}}
+	resolve := func(expression string) ([]byte, error) {
+		key, err := parseKey(expression, net, true)
+		if err != nil {
+			return nil, err
+		}
+		if !key.Compressed {
+			return nil, fmt.Errorf("%w: uncompressed key in tr()", helpers.ErrInvalidDescriptor)
+		}
+		tree.Keys = append(tree.Keys, key)
+		tree.expressions[expression] = key
+		pubKey, err := key.derive(0, 0)
+		if err != nil {
+			return nil, err
+		}
+		return pubKey.SerializeCompressed(), nil
+	}
+	if _, err := leafScript(text, resolve); err != nil {
+		return nil, err
+	}
+	return tree, nil
+}
+
+// leafScript builds the tapscript of a leaf with the keys the resolver
+// derives. The miniscript package has no sortedmulti_a(), which is the
+// multi_a() of its x-only keys in lexicographic order.
+func leafScript(expression string, resolve miniscript.KeyResolver) ([]byte, error) {
+	if strings.HasPrefix(expression, "sortedmulti_a(") && strings.HasSuffix(expression, ")") {
+		args, err := splitArgs(expression[len("sortedmulti_a(") : len(expression)-1])
+		if err != nil {
+			return nil, err
+		}
+		if len(args) < 2 {
+			return nil, fmt.Errorf("%w: sortedmulti_a() takes a threshold and keys", helpers.ErrInvalidDescriptor)
+		}
+		xOnlyKeys := make([]string, len(args)-1)
+		for i, arg := range args[1:] {
+			pubKey, err := resolve(arg)
+			if err != nil {
+				return nil, err
+			}
+			xOnlyKeys[i] = hex.EncodeToString(pubKey[1:])
+		}
+		sort.Strings(xOnlyKeys)
+		expression = "multi_a(" + args[0] + "," + strings.Join(xOnlyKeys, ",") + ")"
+	}
+	node, err := miniscript.Parse(expression, miniscript.Tapscript, resolve)
+	if err != nil {
+		return nil, fmt.Errorf("%w: tr() leaf %s: %v", helpers.ErrInvalidDescriptor, expression, err)
+	}
+	script, err := node.Script()
+	if err != nil {
+		return nil, fmt.Errorf("%w: tr() leaf %s: %v", helpers.ErrInvalidDescriptor, expression, err)
+	}
+	return script, nil
+}
+
+// keys lists the keys of every leaf of the tree.
+func (t *TapTree) keys() []*Key {
+	if t.Left == nil {
+		return t.Keys
+	}
+	return append(append([]*Key{}, t.Left.keys()...), t.Right.keys()...)
+}
+
+// merkle builds the leaf scripts of the tree at the index and returns its
+// merkle root with every leaf and its merkle path.
+func (t *TapTree) merkle(index uint32, multipathIndex int) ([]byte, []tapLeaf, error) {
+	if t.Left == nil {
+		script, err := leafScript(t.Leaf, func(expression string) ([]byte, error) {
+			pubKey, err := t.expressions[expression].derive(index, multipathIndex)
+			if err != nil {
+				return nil, err
+			}
+			return pubKey.SerializeCompressed(), nil
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		return helpers.TapLeafHash(script), []tapLeaf{{tree: t, script: script}}, nil
+	}
+	left, leftLeaves, err := t.Left.merkle(index, multipathIndex)
+	if err != nil {
+		return nil, nil, err
+	}
+	right, rightLeaves, err := t.Right.merkle(index, multipathIndex)
+	if err != nil {
+		return nil, nil, err
+	}
+	for i := range leftLeaves {
+		leftLeaves[i].path = append(leftLeaves[i].path, right)
+	}
+	for i := range rightLeaves {
+		rightLeaves[i].path = append(rightLeaves[i].path, left)
+	}
+	return helpers.TapBranchHash(left, right), append(leftLeaves, rightLeaves...), nil
+}
+
+// taproot derives the internal key of tr() at the index and tweaks it with
+// the merkle root of its script tree into the output key.
+func (s *Script) taproot(index uint32, multipathIndex int) (internalKey *btcec.PublicKey, outputKey *btcec.PublicKey, merkleRoot []byte, leaves []tapLeaf, err error) {
+	if internalKey, err = s.Keys[0].derive(index, multipathIndex); err != nil {
+		return nil, nil, nil, nil, err
+	}
+	if merkleRoot, leaves, err = s.Tree.merkle(index, multipathIndex); err != nil {
+		return nil, nil, nil, nil, err
+	}
+	if outputKey, err = helpers.TaprootOutputKey(internalKey, merkleRoot); err != nil {
+		return nil, nil, nil, nil, err
+	}
+	return internalKey, outputKey, merkleRoot, leaves, nil
+}
@@ -0,0 +1,295 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+
+	"btcwallet.com/src/pkg/helpers"
+	"btcwallet.com/src/pkg/managers"
+	"btcwallet.com/src/pkg/psbt"
+	"github.com/gin-gonic/gin"
+)
+
+type PsbtHandler interface {
+	CreatePsbt(ctx *gin.Context)
+	SignPsbt(ctx *gin.Context)
+	CombinePsbt(ctx *gin.Context)
+	FinalizePsbt(ctx *gin.Context)
+	ExtractPsbt(ctx *gin.Context)
+	EstimateFee(ctx *gin.Context)
+}
+
+type psbtHandler struct {
+	psbtManager managers.PsbtManager
+	network     string
+}
+
+type PsbtUtxo struct {
+	TxId          string `form:"txid" json:"txid" binding:"required,len=64,hexadecimal"`
+	Vout          uint32 `form:"vout" json:"vout"`
+	Amount        int64  `form:"amount" json:"amount" binding:"required,gt=0"`
+	ScriptPubKey  string `form:"scriptPubKey" json:"scriptPubKey" binding:"required,hexadecimal"`
+	RedeemScript  string `form:"redeemScript" json:"redeemScript" binding:"omitempty,hexadecimal"`
+	WitnessScript string `form:"witnessScript" json:"witnessScript" binding:"omitempty,hexadecimal"`
+	PreviousTx    string `form:"previousTx" json:"previousTx" binding:"omitempty,hexadecimal"`
+	PubKey        string `form:"pubKey" json:"pubKey" binding:"omitempty,hexadecimal"`
+	Fingerprint   string `form:"fingerprint" json:"fingerprint" binding:"omitempty,len=8,hexadecimal"`
+	Path          string `form:"path" json:"path"`
+}
+
+type PsbtPayment struct {
+	Address string `form:"address" json:"address" binding:"required"`
+	Amount  int64  `form:"amount" json:"amount" binding:"required,gt=0"`
+}
+
+type PsbtCreate struct {
+	Inputs        []PsbtUtxo    `form:"inputs" json:"inputs" binding:"required,min=1,dive"`
+	Outputs       []PsbtPayment `form:"outputs" json:"outputs" binding:"required,min=1,dive"`
+	FeeRate       float64       `form:"feeRate" json:"feeRate" binding:"required,gt=0"`
+	ChangeAddress string        `form:"changeAddress" json:"changeAddress"`
+	Descriptor    string        `form:"descriptor" json:"descriptor"`
+	ExtPubKeys    []string      `form:"extPubKeys" json:"extPubKeys"`
+	Network       string        `form:"network" json:"network" binding:"omitempty,oneof=mainnet testnet3 testnet signet regtest"`
+}
+
+type PsbtSign struct {
+	Psbt       string   `form:"psbt" json:"psbt" binding:"required,base64"`
+	Seed       string   `form:"seed" json:"seed" binding:"omitempty,hexadecimal"`
+	Mnemonic   string   `form:"mnemonic" json:"mnemonic"`
+	Passphrase string   `form:"passphrase" json:"passphrase"`
+	Language   string   `form:"language" json:"language"`
+	ExtPrvKeys []string `form:"extPrvKeys" json:"extPrvKeys"`
+	Wif        []string `form:"wif" json:"wif"`
+	Network    string   `form:"network" json:"network" binding:"omitempty,oneof=mainnet testnet3 testnet signet regtest"`
+}
+
+type PsbtList struct {
+	Psbts []string `form:"psbts" json:"psbts" binding:"required,min=1,dive,required,base64"`
+}
+
+type FeeEstimateInput struct {
+	Type    string `form:"type" json:"type" binding:"required,oneof=p2pkh p2sh-p2wpkh p2wpkh p2tr p2sh p2sh-p2wsh p2wsh"`
+	M       int    `form:"m" json:"m" binding:"min=0"`
+	N       int    `form:"n" json:"n" binding:"min=0"`
+	KeyPath bool   `form:"keyPath" json:"keyPath"`
+	Count   int    `form:"count" json:"count" binding:"min=0,max=10000"`
+}
+
+type FeeEstimateOutput struct {
+	Type  string `form:"type" json:"type" binding:"required,oneof=p2pkh p2sh p2sh-p2wpkh p2wpkh p2sh-p2wsh p2wsh p2tr"`
+	Count int    `form:"count" json:"count" binding:"min=0,max=10000"`
+}
+
+type FeeEstimate struct {
+	Inputs   []FeeEstimateInput  `form:"inputs" json:"inputs" binding:"required,min=1,dive"`
+	Outputs  []FeeEstimateOutput `form:"outputs" json:"outputs" binding:"required,min=1,dive"`
+	FeeRates []float64           `form:"feeRates" json:"feeRates" binding:"required,min=1,dive,gt=0"`
+}
+
+func (ph *psbtHandler) CreatePsbt(ctx *gin.Context) {
+	var json PsbtCreate
+
+	if err := ctx.ShouldBindJSON(&json); err != nil {
+		fmt.Println(err)
+		ctx.JSON(422, gin.H{"error": "Unprocessable Entity"})
+		return
+	}
+
+	inputs := make([]managers.PsbtInput, len(json.Inputs))
+	for i, input := range json.Inputs {
+		inputs[i] = managers.PsbtInput{
+			TxId:          input.TxId,
+			Vout:          input.Vout,
+			Amount:        input.Amount,
+			ScriptPubKey:  input.ScriptPubKey,
+			RedeemScript:  input.RedeemScript,
+			WitnessScript: input.WitnessScript,
+			PreviousTx:    input.PreviousTx,
+			PubKey:        input.PubKey,
+			Fingerprint:   input.Fingerprint,
+			Path:          input.Path,
+		}
+	}
+	outputs := make([]managers.PsbtOutput, len(json.Outputs))
+	for i, output := range json.Outputs {
+		outputs[i] = managers.PsbtOutput{Address: output.Address, Amount: output.Amount}
+	}
+	created, err := ph.psbtManager.CreatePsbt(inputs, outputs, json.FeeRate, json.ChangeAddress, json.Descriptor, json.ExtPubKeys, ph.networkOrDefault(json.Network))
+	if errors.Is(err, psbt.ErrInvalidPsbt) || errors.Is(err, helpers.ErrInvalidDescriptor) {
+		fmt.Println(err)
+		ctx.JSON(422, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		fmt.Println(err)
+		ctx.JSON(404, gin.H{
+			"error": "Unable to create psbt",
+		})
+		return
+	}
+
+	ctx.JSON(200, created)
+}
+
+func (ph *psbtHandler) SignPsbt(ctx *gin.Context) {
+	var json PsbtSign
+
+	if err := ctx.ShouldBindJSON(&json); err != nil {
+		fmt.Println(err)
+		ctx.JSON(422, gin.H{"error": "Unprocessable Entity"})
+		return
+	}
+
+	keys := managers.PsbtSigningKeys{
+		Seed:       json.Seed,
+		Mnemonic:   json.Mnemonic,
+		Passphrase: json.Passphrase,
+		Language:   json.Language,
+		ExtPrvKeys: json.ExtPrvKeys,
+		Wifs:       json.Wif,
+	}
+	signed, err := ph.psbtManager.SignPsbt(json.Psbt, keys, ph.networkOrDefault(json.Network))
+	if errors.Is(err, psbt.ErrInvalidPsbt) || errors.Is(err, helpers.ErrInvalidKeyInput) || errors.Is(err, helpers.ErrKeyNetworkMismatch) ||
+		errors.Is(err, helpers.ErrInvalidMnemonic) || errors.Is(err, helpers.ErrUnsupportedLanguage) {
+		fmt.Println(err)
+		ctx.JSON(422, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		fmt.Println(err)
+		ctx.JSON(404, gin.H{
+			"error": "Unable to sign psbt",
+		})
+		return
+	}
+
+	ctx.JSON(200, signed)
+}
+
+func (ph *psbtHandler) CombinePsbt(ctx *gin.Context) {
+	var json PsbtList
+
+	if err := ctx.ShouldBindJSON(&json); err != nil {
+		fmt.Println(err)
+		ctx.JSON(422, gin.H{"error": "Unprocessable Entity"})
+		return
+	}
+
+	combined, err := ph.psbtManager.CombinePsbt(json.Psbts)
+	if errors.Is(err, psbt.ErrInvalidPsbt) {
+		fmt.Println(err)
+		ctx.JSON(422, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		fmt.Println(err)
+		ctx.JSON(404, gin.H{
+			"error": "Unable to combine psbt",
+		})
+		return
+	}
+
+	ctx.JSON(200, combined)
+}
+
+func (ph *psbtHandler) FinalizePsbt(ctx *gin.Context) {
+	var json PsbtList
+
+	if err := ctx.ShouldBindJSON(&json); err != nil {
+		fmt.Println(err)
+		ctx.JSON(422, gin.H{"error": "Unprocessable Entity"})
+		return
+	}
+
+	finalized, err := ph.psbtManager.FinalizePsbt(json.Psbts)
+	if errors.Is(err, psbt.ErrInvalidPsbt) {
+		fmt.Println(err)
+		ctx.JSON(422, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		fmt.Println(err)
+		ctx.JSON(404, gin.H{
+			"error": "Unable to finalize psbt",
+		})
+		return
+	}
+
+	ctx.JSON(200, finalized)
+}
+
+func (ph *psbtHandler) ExtractPsbt(ctx *gin.Context) {
+	var json PsbtList
+
+	if err := ctx.ShouldBindJSON(&json); err != nil {
+		fmt.Println(err)
+		ctx.JSON(422, gin.H{"error": "Unprocessable Entity"})
+		return
+	}
+
+	extracted, err := ph.psbtManager.ExtractPsbt(json.Psbts)
+	if errors.Is(err, psbt.ErrInvalidPsbt) {
+		fmt.Println(err)
+		ctx.JSON(422, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		fmt.Println(err)
+		ctx.JSON(404, gin.H{
+			"error": "Unable to extract psbt",
+		})
+		return
+	}
+
+	ctx.JSON(200, extracted)
+}
+
+func (ph *psbtHandler) EstimateFee(ctx *gin.Context) {
+	var json FeeEstimate
+
+	if err := ctx.ShouldBindJSON(&json); err != nil {
+		fmt.Println(err)
+		ctx.JSON(422, gin.H{"error": "Unprocessable Entity"})
+		return
+	}
+
+	inputs := make([]managers.FeeInput, len(json.Inputs))
+	for i, input := range json.Inputs {
+		inputs[i] = managers.FeeInput{Type: input.Type, M: input.M, N: input.N, KeyPath: input.KeyPath, Count: input.Count}
+	}
+	outputs := make([]managers.FeeOutput, len(json.Outputs))
+	for i, output := range json.Outputs {
+		outputs[i] = managers.FeeOutput{Type: output.Type, Count: output.Count}
+	}
+	estimate, err := ph.psbtManager.EstimateFee(inputs, outputs, json.FeeRates)
+	var inputErr *helpers.InputError
+	if errors.As(err, &inputErr) {
+		fmt.Println(err)
+		ctx.JSON(422, gin.H{"error": err.Error(), "code": inputErr.Code, "field": inputErr.Field})
+		return
+	}
+	if err != nil {
+		fmt.Println(err)
+		ctx.JSON(404, gin.H{
+			"error": "Unable to estimate fee",
+		})
+		return
+	}
+
+	ctx.JSON(200, estimate)
+}
+
+// networkOrDefault falls back to the server default when a request does not select a network.
+func (ph *psbtHandler) networkOrDefault(network string) string {
+	if network == "" {
+		return ph.network
+	}
+	return network
+}
+
+func NewPsbtHandler(psbtManager managers.PsbtManager, network string) PsbtHandler {
+	return &psbtHandler{
+		psbtManager,
+		network,
+	}
+}
@@ -0,0 +1,253 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"btcwallet.com/src/pkg/helpers"
+	"btcwallet.com/src/pkg/managers"
+	"github.com/gin-gonic/gin"
+)
+
+func TestCreatePsbt(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var walletHelper helpers.WalletHelper = helpers.NewWalletHelper()
+	var psbtManager managers.PsbtManager = managers.NewPsbtManager(walletHelper)
+	var psbtHandler PsbtHandler = NewPsbtHandler(psbtManager, "mainnet")
+	var url string = "/util/psbt/create"
+	descriptor := "wpkh([73c5da0a/84'/0'/0']xpub6CatWdiZiodmUeTDp8LT5or8nmbKNcuyvz7WyksVFkKB4RHwCD3XyuvPEbvqAQY3rAPshWcMLoP2fMFMKHPJ4ZeZXYVUhLv1VMrjPC7PW6V/<0;1>/*)"
+	txid := "0101010101010101010101010101010101010101010101010101010101010101"
+	// the scriptPubKey of bc1qcr8te4kr609gcawutmrza0j4xv80jy8z306fyu
+	utxo := PsbtUtxo{TxId: txid, Amount: 100000, ScriptPubKey: "0014c0cebcd6c3d3ca8c75dc5ec62ebe55330ef910e2", Path: "m/84'/0'/0'/0/0"}
+	payment := []PsbtPayment{{Address: "bc1qnjg0jd8228aq7egyzacy8cys3knf9xvrerkf9g", Amount: 60000}}
+	change := "bc1q8c6fshw2dlwun7ekn9qwf37cu2rn755upcp6el"
+	otherIndex := utxo
+	otherIndex.Path = "m/84'/0'/0'/0/1"
+	withoutPath := utxo
+	withoutPath.Path = ""
+
+	tests := []struct {
+		body     PsbtCreate
+		expected int
+	}{
+		{PsbtCreate{Inputs: []PsbtUtxo{utxo}, Outputs: payment, FeeRate: 2, ChangeAddress: change, Descriptor: descriptor}, http.StatusOK},
+		{PsbtCreate{Inputs: []PsbtUtxo{withoutPath}, Outputs: payment, FeeRate: 2, ChangeAddress: change}, http.StatusOK},
+		{PsbtCreate{Inputs: []PsbtUtxo{utxo}, Outputs: payment, FeeRate: 2, ChangeAddress: change}, http.StatusUnprocessableEntity},
+		{PsbtCreate{Inputs: []PsbtUtxo{otherIndex}, Outputs: payment, FeeRate: 2, ChangeAddress: change, Descriptor: descriptor}, http.StatusUnprocessableEntity},
+		{PsbtCreate{Inputs: []PsbtUtxo{utxo}, Outputs: payment, FeeRate: 2}, http.StatusUnprocessableEntity},
+		{PsbtCreate{Inputs: []PsbtUtxo{utxo}, Outputs: payment, FeeRate: 2, ChangeAddress: change, Descriptor: "wpkh(xpub)"}, http.StatusUnprocessableEntity},
+		// the path of a multisig input is relative to its extended public keys
+		{PsbtCreate{Inputs: []PsbtUtxo{utxo}, Outputs: payment, FeeRate: 2, ChangeAddress: change, ExtPubKeys: []string{"xpub6CatWdiZiodmUeTDp8LT5or8nmbKNcuyvz7WyksVFkKB4RHwCD3XyuvPEbvqAQY3rAPshWcMLoP2fMFMKHPJ4ZeZXYVUhLv1VMrjPC7PW6V"}}, http.StatusUnprocessableEntity},
+		{PsbtCreate{Inputs: []PsbtUtxo{utxo}, Outputs: payment}, http.StatusUnprocessableEntity},
+		{PsbtCreate{Inputs: []PsbtUtxo{{TxId: "01", Amount: 100000, ScriptPubKey: utxo.ScriptPubKey}}, Outputs: payment, FeeRate: 2}, http.StatusUnprocessableEntity},
+		{PsbtCreate{Outputs: payment, FeeRate: 2}, http.StatusUnprocessableEntity},
+	}
+
+	r := gin.Default()
+	r.POST(url, psbtHandler.CreatePsbt)
+
+	for _, test := range tests {
+		payloadBuf := new(bytes.Buffer)
+		json.NewEncoder(payloadBuf).Encode(&test.body)
+		req, err := http.NewRequest(http.MethodPost, url, payloadBuf)
+		if err != nil {
+			t.Fatalf("Couldn't create request: %v\n", err)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != test.expected {
+			t.Fatalf("Expected to get status %d but instead got %d\n", test.expected, w.Code)
+		}
+		var created managers.CreatedPsbt
+		json.NewDecoder(w.Body).Decode(&created)
+		if test.expected == http.StatusOK && (created.Psbt == "" || created.ChangeIndex != 1) {
+			t.Fatalf("Expected to get a psbt with change but instead got %v\n", created)
+		}
+	}
+}
+
+func TestSignPsbt(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var walletHelper helpers.WalletHelper = helpers.NewWalletHelper()
+	var psbtManager managers.PsbtManager = managers.NewPsbtManager(walletHelper)
+	var psbtHandler PsbtHandler = NewPsbtHandler(psbtManager, "mainnet")
+	var url string = "/util/psbt/sign"
+	seed := "5eb00bbddcf069084889a8ab9155568165f5c453ccb85e70811aaed6f6da5fc19a5ac40b389cd370d086206dec8aa6c43daea6690f20ad3d8d48b2d2ce9e38e4"
+	mnemonic := "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
+
+	// the first BIP84 receive address with its key derivation
+	inputs := []managers.PsbtInput{{
+		TxId:         "0101010101010101010101010101010101010101010101010101010101010101",
+		Amount:       100000,
+		ScriptPubKey: "0014c0cebcd6c3d3ca8c75dc5ec62ebe55330ef910e2",
+		PubKey:       "0330d54fd0dd420a6e5f8d3624f5f3482cae350f79d5f0753bf5beef9c2d91af3c",
+		Fingerprint:  "73c5da0a",
+		Path:         "m/84'/0'/0'/0/0",
+	}}
+	outputs := []managers.PsbtOutput{{Address: "bc1qnjg0jd8228aq7egyzacy8cys3knf9xvrerkf9g", Amount: 99800}}
+	created, err := psbtManager.CreatePsbt(inputs, outputs, 1, "", "", nil, "mainnet")
+	if err != nil {
+		t.Fatalf("Couldn't create psbt: %v\n", err)
+	}
+
+	tests := []struct {
+		body     PsbtSign
+		expected int
+		signed   int
+	}{
+		{PsbtSign{Psbt: created.Psbt, Seed: seed}, http.StatusOK, 1},
+		{PsbtSign{Psbt: created.Psbt, Mnemonic: mnemonic}, http.StatusOK, 1},
+		{PsbtSign{Psbt: created.Psbt, Mnemonic: mnemonic, Passphrase: "TREZOR"}, http.StatusOK, 0},
+		{PsbtSign{Psbt: created.Psbt}, http.StatusUnprocessableEntity, 0},
+		{PsbtSign{Psbt: created.Psbt, Mnemonic: "abandon abandon abandon"}, http.StatusUnprocessableEntity, 0},
+		{PsbtSign{Psbt: created.Psbt, Wif: []string{"not a wif"}}, http.StatusUnprocessableEntity, 0},
+		{PsbtSign{Psbt: "cHNidP8=", Seed: seed}, http.StatusUnprocessableEntity, 0},
+		{PsbtSign{Seed: seed}, http.StatusUnprocessableEntity, 0},
+	}
+
+	r := gin.Default()
+	r.POST(url, psbtHandler.SignPsbt)
+
+	for _, test := range tests {
+		payloadBuf := new(bytes.Buffer)
+		json.NewEncoder(payloadBuf).Encode(&test.body)
+		req, err := http.NewRequest(http.MethodPost, url, payloadBuf)
+		if err != nil {
+			t.Fatalf("Couldn't create request: %v\n", err)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != test.expected {
+			t.Fatalf("Expected to get status %d but instead got %d\n", test.expected, w.Code)
+		}
+		var signed managers.SignedPsbt
+		json.NewDecoder(w.Body).Decode(&signed)
+		if test.expected == http.StatusOK && (signed.Signed != test.signed || len(signed.Inputs) != 1) {
+			t.Fatalf("Expected to get %d signed inputs but instead got %v\n", test.signed, signed)
+		}
+	}
+}
+
+func TestCombineFinalizeExtractPsbt(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var walletHelper helpers.WalletHelper = helpers.NewWalletHelper()
+	var psbtManager managers.PsbtManager = managers.NewPsbtManager(walletHelper)
+	var psbtHandler PsbtHandler = NewPsbtHandler(psbtManager, "mainnet")
+	seed := "5eb00bbddcf069084889a8ab9155568165f5c453ccb85e70811aaed6f6da5fc19a5ac40b389cd370d086206dec8aa6c43daea6690f20ad3d8d48b2d2ce9e38e4"
+
+	// the first BIP84 receive address with its key derivation
+	inputs := []managers.PsbtInput{{
+		TxId:         "0101010101010101010101010101010101010101010101010101010101010101",
+		Amount:       100000,
+		ScriptPubKey: "0014c0cebcd6c3d3ca8c75dc5ec62ebe55330ef910e2",
+		PubKey:       "0330d54fd0dd420a6e5f8d3624f5f3482cae350f79d5f0753bf5beef9c2d91af3c",
+		Fingerprint:  "73c5da0a",
+		Path:         "m/84'/0'/0'/0/0",
+	}}
+	var unsigned []string
+	for _, amount := range []int64{99800, 99700} {
+		outputs := []managers.PsbtOutput{{Address: "bc1qnjg0jd8228aq7egyzacy8cys3knf9xvrerkf9g", Amount: amount}}
+		created, err := psbtManager.CreatePsbt(inputs, outputs, 1, "", "", nil, "mainnet")
+		if err != nil {
+			t.Fatalf("Couldn't create psbt: %v\n", err)
+		}
+		unsigned = append(unsigned, created.Psbt)
+	}
+	signed, err := psbtManager.SignPsbt(unsigned[0], managers.PsbtSigningKeys{Seed: seed}, "mainnet")
+	if err != nil {
+		t.Fatalf("Couldn't sign psbt: %v\n", err)
+	}
+
+	tests := []struct {
+		url      string
+		body     PsbtList
+		expected int
+	}{
+		{"/util/psbt/combine", PsbtList{[]string{unsigned[0], signed.Psbt}}, http.StatusOK},
+		{"/util/psbt/combine", PsbtList{unsigned}, http.StatusUnprocessableEntity},
+		{"/util/psbt/combine", PsbtList{}, http.StatusUnprocessableEntity},
+		{"/util/psbt/finalize", PsbtList{[]string{unsigned[0], signed.Psbt}}, http.StatusOK},
+		{"/util/psbt/finalize", PsbtList{[]string{unsigned[0]}}, http.StatusOK},
+		{"/util/psbt/finalize", PsbtList{[]string{"not a psbt"}}, http.StatusUnprocessableEntity},
+		{"/util/psbt/extract", PsbtList{[]string{signed.Psbt}}, http.StatusOK},
+		{"/util/psbt/extract", PsbtList{[]string{unsigned[0]}}, http.StatusUnprocessableEntity},
+		{"/util/psbt/extract", PsbtList{[]string{"cHNidP8="}}, http.StatusUnprocessableEntity},
+	}
+
+	r := gin.Default()
+	r.POST("/util/psbt/combine", psbtHandler.CombinePsbt)
+	r.POST("/util/psbt/finalize", psbtHandler.FinalizePsbt)
+	r.POST("/util/psbt/extract", psbtHandler.ExtractPsbt)
+
+	for _, test := range tests {
+		payloadBuf := new(bytes.Buffer)
+		json.NewEncoder(payloadBuf).Encode(&test.body)
+		req, err := http.NewRequest(http.MethodPost, test.url, payloadBuf)
+		if err != nil {
+			t.Fatalf("Couldn't create request: %v\n", err)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != test.expected {
+			t.Fatalf("Expected to get status %d from %s but instead got %d\n", test.expected, test.url, w.Code)
+		}
+	}
+}
+
+func TestEstimateFee(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var walletHelper helpers.WalletHelper = helpers.NewWalletHelper()
+	var psbtManager managers.PsbtManager = managers.NewPsbtManager(walletHelper)
+	var psbtHandler PsbtHandler = NewPsbtHandler(psbtManager, "mainnet")
+	var url string = "/util/fee/estimate"
+	outputs := []FeeEstimateOutput{{Type: "p2wpkh", Count: 2}}
+	feeRates := []float64{1, 10}
+
+	tests := []struct {
+		body     FeeEstimate
+		expected int
+		vsize    int
+	}{
+		{FeeEstimate{Inputs: []FeeEstimateInput{{Type: "p2wpkh"}}, Outputs: outputs, FeeRates: feeRates}, http.StatusOK, 141},
+		{FeeEstimate{Inputs: []FeeEstimateInput{{Type: "p2wsh", M: 2, N: 3}, {Type: "p2tr", M: 2, N: 3, Count: 2}}, Outputs: outputs, FeeRates: feeRates}, http.StatusOK, 0},
+		{FeeEstimate{Inputs: []FeeEstimateInput{{Type: "p2wsh", M: 3, N: 2}}, Outputs: outputs, FeeRates: feeRates}, http.StatusUnprocessableEntity, 0},
+		{FeeEstimate{Inputs: []FeeEstimateInput{{Type: "p2wsh"}}, Outputs: outputs, FeeRates: feeRates}, http.StatusUnprocessableEntity, 0},
+		{FeeEstimate{Inputs: []FeeEstimateInput{{Type: "p2sh", M: 16, N: 16}}, Outputs: outputs, FeeRates: feeRates}, http.StatusUnprocessableEntity, 0},
+		{FeeEstimate{Inputs: []FeeEstimateInput{{Type: "p2wsh-p2sh", M: 2, N: 3}}, Outputs: outputs, FeeRates: feeRates}, http.StatusUnprocessableEntity, 0},
+		{FeeEstimate{Inputs: []FeeEstimateInput{{Type: "p2wpkh"}}, Outputs: outputs}, http.StatusUnprocessableEntity, 0},
+		{FeeEstimate{Inputs: []FeeEstimateInput{{Type: "p2wpkh"}}, Outputs: outputs, FeeRates: []float64{0}}, http.StatusUnprocessableEntity, 0},
+		{FeeEstimate{Outputs: outputs, FeeRates: feeRates}, http.StatusUnprocessableEntity, 0},
+	}
+
+	r := gin.Default()
+	r.POST(url, psbtHandler.EstimateFee)
+
+	for _, test := range tests {
+		payloadBuf := new(bytes.Buffer)
+		json.NewEncoder(payloadBuf).Encode(&test.body)
+		req, err := http.NewRequest(http.MethodPost, url, payloadBuf)
+		if err != nil {
+			t.Fatalf("Couldn't create request: %v\n", err)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != test.expected {
+			t.Fatalf("Expected to get status %d but instead got %d\n", test.expected, w.Code)
+		}
+		var estimate managers.FeeEstimate
+		json.NewDecoder(w.Body).Decode(&estimate)
+		if test.expected == http.StatusOK && (len(estimate.Fees) != 2 || estimate.Fees[1].Fee != int64(10*estimate.VSize)) {
+			t.Fatalf("Expected to get the fees at 1 and 10 sat/vB but instead got %v\n", estimate.Fees)
+		}
+		if test.vsize != 0 && estimate.VSize != test.vsize {
+			t.Fatalf("Expected to get a vsize of %d but instead got %d\n", test.vsize, estimate.VSize)
+		}
+	}
+}
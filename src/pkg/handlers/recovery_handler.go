@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+
+	"btcwallet.com/src/pkg/helpers"
+	"btcwallet.com/src/pkg/managers"
+	"github.com/gin-gonic/gin"
+)
+
+type RecoveryHandler interface {
+	RecoverMnemonic(ctx *gin.Context)
+}
+
+type recoveryHandler struct {
+	recoveryManager managers.RecoveryManager
+	network         string
+}
+
+type MnemonicRecovery struct {
+	Mnemonic    string `form:"mnemonic" json:"mnemonic" binding:"required"`
+	Passphrase  string `form:"passphrase" json:"passphrase"`
+	Language    string `form:"language" json:"language"`
+	MaxDistance *int   `form:"maxDistance" json:"maxDistance" binding:"omitempty,min=0,max=3"`
+	Address     string `form:"address" json:"address" binding:"excluded_with=ExtPubKey"`
+	ExtPubKey   string `form:"extPubKey" json:"extPubKey" binding:"excluded_with=Address"`
+	Path        string `form:"path" json:"path"`
+	AddressGap  uint32 `form:"addressGap" json:"addressGap" binding:"max=1000"`
+	Network     string `form:"network" json:"network" binding:"omitempty,oneof=mainnet testnet3 testnet signet regtest"`
+}
+
+func (rh *recoveryHandler) RecoverMnemonic(ctx *gin.Context) {
+	var json MnemonicRecovery
+
+	if err := ctx.ShouldBindJSON(&json); err != nil {
+		fmt.Println(err)
+		ctx.JSON(422, gin.H{"error": "Unprocessable Entity"})
+		return
+	}
+
+	// a missing distance selects the default, zero disables fuzzy matching
+	maxDistance := -1
+	if json.MaxDistance != nil {
+		maxDistance = *json.MaxDistance
+	}
+	target := managers.RecoveryTarget{
+		Address:    json.Address,
+		ExtPubKey:  json.ExtPubKey,
+		Path:       json.Path,
+		AddressGap: json.AddressGap,
+	}
+	network := json.Network
+	if network == "" {
+		network = rh.network
+	}
+	report, err := rh.recoveryManager.RecoverMnemonic(ctx.Request.Context(), json.Mnemonic, json.Language, json.Passphrase, maxDistance, target, network)
+
+	if errors.Is(err, helpers.ErrInvalidMnemonic) || errors.Is(err, helpers.ErrUnsupportedLanguage) || errors.Is(err, managers.ErrTooManyCandidates) {
+		fmt.Println(err)
+		ctx.JSON(422, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		fmt.Println(err)
+		ctx.JSON(404, gin.H{
+			"error": "Unable to recover mnemonic",
+		})
+		return
+	}
+
+	ctx.JSON(200, report)
+}
+
+func NewRecoveryHandler(recoveryManager managers.RecoveryManager, network string) RecoveryHandler {
+	return &recoveryHandler{
+		recoveryManager,
+		network,
+	}
+}
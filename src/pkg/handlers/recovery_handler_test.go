@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"btcwallet.com/src/pkg/helpers"
+	"btcwallet.com/src/pkg/managers"
+	"github.com/gin-gonic/gin"
+)
+
+func TestRecoverMnemonic(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var walletHelper helpers.WalletHelper = helpers.NewWalletHelper()
+	var recoveryManager managers.RecoveryManager = managers.NewRecoveryManager(walletHelper)
+	var recoveryHandler RecoveryHandler = NewRecoveryHandler(recoveryManager, "mainnet")
+	var url string = "/util/recover-mnemonic"
+	zero := 0
+
+	tests := []struct {
+		body     MnemonicRecovery
+		expected int
+		matches  int
+	}{
+		{MnemonicRecovery{Mnemonic: "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon ?", Address: "bc1qcr8te4kr609gcawutmrza0j4xv80jy8z306fyu"}, http.StatusOK, 1},
+		{MnemonicRecovery{Mnemonic: "legal winner thank year wave sausage worth usefull legal winner thank yellow"}, http.StatusOK, 1},
+		{MnemonicRecovery{Mnemonic: "legal winner thank year wave sausage worth usefull legal winner thank yellow", MaxDistance: &zero}, http.StatusUnprocessableEntity, 0},
+		{MnemonicRecovery{Mnemonic: "abandon ? ? ? abandon abandon abandon abandon abandon abandon abandon about"}, http.StatusUnprocessableEntity, 0},
+		{MnemonicRecovery{Mnemonic: "abandon abandon abandon", Address: "bc1qcr8te4kr609gcawutmrza0j4xv80jy8z306fyu", ExtPubKey: "xpub"}, http.StatusUnprocessableEntity, 0},
+		{MnemonicRecovery{}, http.StatusUnprocessableEntity, 0},
+	}
+
+	r := gin.Default()
+	r.POST(url, recoveryHandler.RecoverMnemonic)
+
+	for _, test := range tests {
+		payloadBuf := new(bytes.Buffer)
+		json.NewEncoder(payloadBuf).Encode(&test.body)
+		req, err := http.NewRequest(http.MethodPost, url, payloadBuf)
+		if err != nil {
+			t.Fatalf("Couldn't create request: %v\n", err)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != test.expected {
+			t.Fatalf("Expected to get status %d but instead got %d\n", test.expected, w.Code)
+		}
+		var report managers.RecoveryReport
+		json.NewDecoder(w.Body).Decode(&report)
+		if len(report.Matches) != test.matches {
+			t.Fatalf("Expected to get %d matches but instead got %d\n", test.matches, len(report.Matches))
+		}
+	}
+}
@@ -1,31 +1,130 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 
+	"btcwallet.com/src/pkg/helpers"
 	"btcwallet.com/src/pkg/managers"
+	"btcwallet.com/src/pkg/miniscript"
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 )
 
 type WalletHandler interface {
 	GenerateMnemonic(ctx *gin.Context)
+	MnemonicToSeed(ctx *gin.Context)
+	GenerateSlip39Shares(ctx *gin.Context)
+	CombineSlip39Shares(ctx *gin.Context)
 	GenerateHdWallet(ctx *gin.Context)
 	GenerateMultisignature(ctx *gin.Context)
+	GenerateAddressRange(ctx *gin.Context)
+	GenerateWatchOnly(ctx *gin.Context)
+	DeriveDescriptorAddresses(ctx *gin.Context)
+	DecodeMultisignatureScript(ctx *gin.Context)
+	CompileMiniscript(ctx *gin.Context)
 }
 
 type walletHandler struct {
 	walletManager managers.WalletManager
+	network       string
+}
+
+type Mnemonic struct {
+	Words      int    `form:"words" json:"words" binding:"omitempty,oneof=12 15 18 21 24"`
+	Passphrase string `form:"passphrase" json:"passphrase"`
+	Entropy    bool   `form:"entropy" json:"entropy"`
+	Language   string `form:"language" json:"language"`
+}
+
+type MnemonicSeed struct {
+	Mnemonic   string `form:"mnemonic" json:"mnemonic" binding:"required"`
+	Passphrase string `form:"passphrase" json:"passphrase"`
+	Language   string `form:"language" json:"language"`
+}
+
+type Slip39Group struct {
+	MemberThreshold int `form:"memberThreshold" json:"memberThreshold" binding:"required,min=1,max=16"`
+	MemberCount     int `form:"memberCount" json:"memberCount" binding:"required,min=1,max=16"`
+}
+
+type Slip39Shares struct {
+	MasterSecret      string        `form:"masterSecret" json:"masterSecret"`
+	Strength          int           `form:"strength" json:"strength"`
+	Passphrase        string        `form:"passphrase" json:"passphrase"`
+	GroupThreshold    int           `form:"groupThreshold" json:"groupThreshold" binding:"required,min=1,max=16"`
+	Groups            []Slip39Group `form:"groups" json:"groups" binding:"required,min=1,max=16,dive"`
+	IterationExponent int           `form:"iterationExponent" json:"iterationExponent" binding:"min=0,max=15"`
+	Extendable        bool          `form:"extendable" json:"extendable"`
+}
+
+type Slip39Combine struct {
+	Mnemonics  []string `form:"mnemonics" json:"mnemonics" binding:"required,min=1"`
+	Passphrase string   `form:"passphrase" json:"passphrase"`
 }
 
 type HdWallet struct {
-	Path string `form:"path" json:"path" binding:"required"`
-	Seed string `form:"seed" json:"seed" binding:"required"`
+	Path       string `form:"path" json:"path" binding:"required"`
+	Seed       string `form:"seed" json:"seed" binding:"required_without=Mnemonic,excluded_with=Mnemonic"`
+	Mnemonic   string `form:"mnemonic" json:"mnemonic" binding:"required_without=Seed,excluded_with=Seed"`
+	Passphrase string `form:"passphrase" json:"passphrase"`
+	Language   string `form:"language" json:"language"`
+	Network    string `form:"network" json:"network" binding:"omitempty,oneof=mainnet testnet3 testnet signet regtest"`
+	Mixed      bool   `form:"mixed" json:"mixed"`
+}
+
+type AddressRange struct {
+	Path       string `form:"path" json:"path" binding:"required"`
+	Seed       string `form:"seed" json:"seed" binding:"required"`
+	Change     uint32 `form:"change" json:"change" binding:"max=1"`
+	Start      uint32 `form:"start" json:"start"`
+	Count      uint32 `form:"count" json:"count" binding:"required,min=1,max=1000"`
+	IncludeWif bool   `form:"includeWif" json:"includeWif"`
+	Network    string `form:"network" json:"network" binding:"omitempty,oneof=mainnet testnet3 testnet signet regtest"`
+}
+
+type WatchOnly struct {
+	ExtPubKey string `form:"extPubKey" json:"extPubKey" binding:"required"`
+	Path      string `form:"path" json:"path" binding:"required"`
+	Count     uint32 `form:"count" json:"count" binding:"max=1000"`
+	Network   string `form:"network" json:"network" binding:"omitempty,oneof=mainnet testnet3 testnet signet regtest"`
+}
+
+type DescriptorRange struct {
+	Descriptor     string `form:"descriptor" json:"descriptor" binding:"required"`
+	Start          uint32 `form:"start" json:"start"`
+	Count          uint32 `form:"count" json:"count" binding:"required,min=1,max=1000"`
+	MultipathIndex int    `form:"multipathIndex" json:"multipathIndex" binding:"min=0"`
+	Network        string `form:"network" json:"network" binding:"omitempty,oneof=mainnet testnet3 testnet signet regtest"`
 }
 
 type Multisignature struct {
-	N   int8     `form:"n" json:"n" binding:"required"`
-	M   int8     `form:"m" json:"m" binding:"required"`
-	Wif []string `form:"wif" json:"wif" binding:"required"`
+	N             int      `form:"n" json:"n"`
+	M             int      `form:"m" json:"m"`
+	Wif           []string `form:"wif" json:"wif" binding:"excluded_with=ExtPubKeys"`
+	Keys          []string `form:"keys" json:"keys" binding:"excluded_with=ExtPubKeys"`
+	ExtPubKeys    []string `form:"extPubKeys" json:"extPubKeys" binding:"required_without_all=Wif Keys"`
+	Change        uint32   `form:"change" json:"change" binding:"max=1"`
+	Index         uint32   `form:"index" json:"index"`
+	Count         uint32   `form:"count" json:"count" binding:"max=1000"`
+	PreserveOrder bool     `form:"preserveOrder" json:"preserveOrder"`
+	SubsetLeaves  bool     `form:"subsetLeaves" json:"subsetLeaves"`
+	MultisigType  string   `form:"multisigType" json:"multisigType" binding:"omitempty,oneof=p2sh p2wsh p2sh-p2wsh p2tr"`
+	Network       string   `form:"network" json:"network" binding:"omitempty,oneof=mainnet testnet3 testnet signet regtest"`
+}
+
+type MultisigScript struct {
+	Script  string `form:"script" json:"script" binding:"required,hexadecimal"`
+	Network string `form:"network" json:"network" binding:"omitempty,oneof=mainnet testnet3 testnet signet regtest"`
+}
+
+type Miniscript struct {
+	Policy      string            `form:"policy" json:"policy" binding:"required_without=Miniscript,excluded_with=Miniscript"`
+	Miniscript  string            `form:"miniscript" json:"miniscript" binding:"required_without=Policy"`
+	Context     string            `form:"context" json:"context" binding:"omitempty,oneof=wsh tr"`
+	Keys        map[string]string `form:"keys" json:"keys"`
+	InternalKey string            `form:"internalKey" json:"internalKey"`
+	Network     string            `form:"network" json:"network" binding:"omitempty,oneof=mainnet testnet3 testnet signet regtest"`
 }
 
 func (wh *walletHandler) GenerateMultisignature(ctx *gin.Context) {
@@ -36,7 +135,35 @@ func (wh *walletHandler) GenerateMultisignature(ctx *gin.Context) {
 		ctx.JSON(422, gin.H{"error": "Unprocessable Entity"})
 		return
 	}
-	address, err := wh.walletManager.GenerateMultisignature(json.N, json.M, json.Wif)
+	if len(json.ExtPubKeys) > 0 {
+		count := json.Count
+		if count == 0 {
+			count = 1
+		}
+		entries, err := wh.walletManager.GenerateMultisignatureFromExtPubKeys(ctx.Request.Context(), json.N, json.M, json.ExtPubKeys, json.Change, json.Index, count, !json.PreserveOrder, json.SubsetLeaves, helpers.MultisigType(json.MultisigType), wh.networkOrDefault(json.Network))
+		if multisigInputError(ctx, err, &json) {
+			return
+		}
+		if err != nil {
+			fmt.Println(err)
+			ctx.JSON(404, gin.H{
+				"error": "Unable to generate address",
+			})
+			return
+		}
+		ctx.JSON(200, gin.H{
+			"address":   entries[0].Address,
+			"addresses": entries,
+		})
+		return
+	}
+
+	// WIFs are resolved to their public keys like the entries of keys
+	keys := append(append([]string{}, json.Wif...), json.Keys...)
+	multisig, err := wh.walletManager.GenerateMultisignature(json.N, json.M, keys, !json.PreserveOrder, json.SubsetLeaves, helpers.MultisigType(json.MultisigType), wh.networkOrDefault(json.Network))
+	if multisigInputError(ctx, err, &json) {
+		return
+	}
 	if err != nil {
 		fmt.Println(err)
 		ctx.JSON(404, gin.H{
@@ -45,15 +172,53 @@ func (wh *walletHandler) GenerateMultisignature(ctx *gin.Context) {
 		return
 	}
 
-	ctx.JSON(200, gin.H{
-		"address": address,
-	})
+	ctx.JSON(200, multisig)
 
 }
+
+// multisigInputError answers an error of the input error catalog with its
+// code and the request field at fault: 400 for keys that can not be read on
+// the network, 422 for a policy the keys break.
+func multisigInputError(ctx *gin.Context, err error, json *Multisignature) bool {
+	var inputErr *helpers.InputError
+	if !errors.As(err, &inputErr) {
+		return false
+	}
+	status := 422
+	if errors.Is(err, helpers.ErrInvalidKeyInput) || errors.Is(err, helpers.ErrKeyNetworkMismatch) {
+		status = 400
+	}
+	field := inputErr.Field
+	if field == "keys" && len(json.ExtPubKeys) > 0 {
+		field = "extPubKeys"
+	} else if field == "keys" && len(json.Keys) == 0 {
+		field = "wif"
+	}
+	fmt.Println(err)
+	ctx.JSON(status, gin.H{"error": err.Error(), "code": inputErr.Code, "field": field})
+	return true
+}
 func (wh *walletHandler) GenerateMnemonic(ctx *gin.Context) {
-	var passphrase string = ""
-	mnemonic, seed, err := wh.walletManager.GenerateMnemonic(passphrase)
+	var json Mnemonic
+
+	// the passphrase can be sent in a json body to keep it out of the url
+	bind := ctx.ShouldBindQuery
+	if ctx.ContentType() == binding.MIMEJSON {
+		bind = ctx.ShouldBindJSON
+	}
+	if err := bind(&json); err != nil {
+		fmt.Println(err)
+		ctx.JSON(422, gin.H{"error": "Unprocessable Entity"})
+		return
+	}
+
+	mnemonic, seed, entropy, err := wh.walletManager.GenerateMnemonic(json.Passphrase, json.Words, json.Language)
 
+	if errors.Is(err, managers.ErrInvalidWordCount) || errors.Is(err, helpers.ErrUnsupportedLanguage) {
+		fmt.Println(err)
+		ctx.JSON(422, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		fmt.Println(err)
 		ctx.JSON(404, gin.H{
@@ -62,9 +227,112 @@ func (wh *walletHandler) GenerateMnemonic(ctx *gin.Context) {
 		return
 	}
 
+	language := json.Language
+	if language == "" {
+		language = string(helpers.LanguageEnglish)
+	}
+	response := gin.H{
+		"BIP39Mnemonic": mnemonic,
+		"BIP39Seed":     seed,
+		"language":      language,
+	}
+	if json.Entropy {
+		response["BIP39Entropy"] = entropy
+	}
+	ctx.JSON(200, response)
+}
+
+func (wh *walletHandler) MnemonicToSeed(ctx *gin.Context) {
+	var json MnemonicSeed
+
+	if err := ctx.ShouldBindJSON(&json); err != nil {
+		fmt.Println(err)
+		ctx.JSON(422, gin.H{"error": "Unprocessable Entity"})
+		return
+	}
+
+	mnemonic, seed, language, err := wh.walletManager.MnemonicToSeed(json.Mnemonic, json.Passphrase, json.Language)
+
+	if errors.Is(err, helpers.ErrInvalidMnemonic) || errors.Is(err, helpers.ErrUnsupportedLanguage) {
+		fmt.Println(err)
+		ctx.JSON(422, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		fmt.Println(err)
+		ctx.JSON(404, gin.H{
+			"error": "Unable to convert mnemonic",
+		})
+		return
+	}
+
 	ctx.JSON(200, gin.H{
 		"BIP39Mnemonic": mnemonic,
 		"BIP39Seed":     seed,
+		"language":      language,
+	})
+}
+
+func (wh *walletHandler) GenerateSlip39Shares(ctx *gin.Context) {
+	var json Slip39Shares
+
+	if err := ctx.ShouldBindJSON(&json); err != nil {
+		fmt.Println(err)
+		ctx.JSON(422, gin.H{"error": "Unprocessable Entity"})
+		return
+	}
+
+	groups := make([]helpers.Slip39Group, len(json.Groups))
+	for i, group := range json.Groups {
+		groups[i] = helpers.Slip39Group{MemberThreshold: group.MemberThreshold, MemberCount: group.MemberCount}
+	}
+	shares, seed, err := wh.walletManager.GenerateSlip39Shares(json.MasterSecret, json.Strength, json.Passphrase, json.GroupThreshold, groups, json.IterationExponent, json.Extendable)
+
+	if errors.Is(err, helpers.ErrInvalidSharing) {
+		fmt.Println(err)
+		ctx.JSON(422, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		fmt.Println(err)
+		ctx.JSON(404, gin.H{
+			"error": "Unable to generate shares",
+		})
+		return
+	}
+
+	ctx.JSON(200, gin.H{
+		"groups": shares,
+		"seed":   seed,
+	})
+}
+
+func (wh *walletHandler) CombineSlip39Shares(ctx *gin.Context) {
+	var json Slip39Combine
+
+	if err := ctx.ShouldBindJSON(&json); err != nil {
+		fmt.Println(err)
+		ctx.JSON(422, gin.H{"error": "Unprocessable Entity"})
+		return
+	}
+
+	seed, err := wh.walletManager.CombineSlip39Shares(json.Mnemonics, json.Passphrase)
+
+	if errors.Is(err, helpers.ErrInvalidShare) || errors.Is(err, helpers.ErrInvalidSharing) {
+		fmt.Println(err)
+		ctx.JSON(422, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		fmt.Println(err)
+		ctx.JSON(404, gin.H{
+			"error": "Unable to combine shares",
+		})
+		return
+	}
+
+	ctx.JSON(200, gin.H{
+		"seed": seed,
 	})
 }
 
@@ -77,7 +345,17 @@ func (wh *walletHandler) GenerateHdWallet(ctx *gin.Context) {
 		return
 	}
 
-	extPrvKey, extPubKey, rootKey, wif, p2pkhAddress, segwitBech32, segwitNested, err := wh.walletManager.GenerateHdWallet(json.Seed, json.Path)
+	seed := json.Seed
+	if json.Mnemonic != "" {
+		var err error
+		if _, seed, _, err = wh.walletManager.MnemonicToSeed(json.Mnemonic, json.Passphrase, json.Language); err != nil {
+			fmt.Println(err)
+			ctx.JSON(422, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	wallet, err := wh.walletManager.GenerateHdWallet(seed, json.Path, wh.networkOrDefault(json.Network), json.Mixed)
 
 	if err != nil {
 		fmt.Println(err)
@@ -87,19 +365,159 @@ func (wh *walletHandler) GenerateHdWallet(ctx *gin.Context) {
 		return
 	}
 
+	// PureJSON keeps the <0;1> of multipath descriptors readable
+	ctx.PureJSON(200, wallet)
+}
+
+func (wh *walletHandler) GenerateAddressRange(ctx *gin.Context) {
+	var json AddressRange
+
+	if err := ctx.ShouldBindJSON(&json); err != nil {
+		fmt.Println(err)
+		ctx.JSON(422, gin.H{"error": "Unprocessable Entity"})
+		return
+	}
+
+	addresses, err := wh.walletManager.GenerateAddressRange(ctx.Request.Context(), json.Seed, json.Path, json.Change, json.Start, json.Count, json.IncludeWif, wh.networkOrDefault(json.Network))
+	if err != nil {
+		fmt.Println(err)
+		ctx.JSON(404, gin.H{
+			"error": "Unable to generate address range",
+		})
+		return
+	}
+
+	ctx.JSON(200, gin.H{
+		"addresses": addresses,
+	})
+}
+
+func (wh *walletHandler) GenerateWatchOnly(ctx *gin.Context) {
+	var json WatchOnly
+
+	if err := ctx.ShouldBindJSON(&json); err != nil {
+		fmt.Println(err)
+		ctx.JSON(422, gin.H{"error": "Unprocessable Entity"})
+		return
+	}
+
+	addresses, err := wh.walletManager.GenerateWatchOnly(ctx.Request.Context(), json.ExtPubKey, json.Path, json.Count, wh.networkOrDefault(json.Network))
+	if errors.Is(err, managers.ErrHardenedFromPublicKey) {
+		fmt.Println(err)
+		ctx.JSON(422, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		fmt.Println(err)
+		ctx.JSON(404, gin.H{
+			"error": "Unable to generate watch-only addresses",
+		})
+		return
+	}
+
 	ctx.JSON(200, gin.H{
-		"bip32ExtendedPublicKey":  extPubKey,
-		"bip32ExtendedPrivateKey": extPrvKey,
-		"bip32RootKey":            rootKey,
-		"WIF":                     wif,
-		"p2pkhAddress":            p2pkhAddress,
-		"segwitBech32":            segwitBech32,
-		"segwitNested":            segwitNested,
+		"addresses": addresses,
 	})
 }
 
-func NewWalletHandler(walletManager managers.WalletManager) WalletHandler {
+func (wh *walletHandler) DeriveDescriptorAddresses(ctx *gin.Context) {
+	var json DescriptorRange
+
+	if err := ctx.ShouldBindJSON(&json); err != nil {
+		fmt.Println(err)
+		ctx.JSON(422, gin.H{"error": "Unprocessable Entity"})
+		return
+	}
+
+	addresses, err := wh.walletManager.DeriveDescriptorAddresses(ctx.Request.Context(), json.Descriptor, json.Start, json.Count, json.MultipathIndex, wh.networkOrDefault(json.Network))
+	if errors.Is(err, helpers.ErrInvalidDescriptor) {
+		fmt.Println(err)
+		ctx.JSON(422, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		fmt.Println(err)
+		ctx.JSON(404, gin.H{
+			"error": "Unable to derive descriptor addresses",
+		})
+		return
+	}
+
+	// PureJSON keeps the <0;1> of multipath descriptors readable
+	ctx.PureJSON(200, addresses)
+}
+
+func (wh *walletHandler) DecodeMultisignatureScript(ctx *gin.Context) {
+	var json MultisigScript
+
+	if err := ctx.ShouldBindJSON(&json); err != nil {
+		fmt.Println(err)
+		ctx.JSON(422, gin.H{"error": "Unprocessable Entity"})
+		return
+	}
+
+	multisig, err := wh.walletManager.DecodeMultisignatureScript(json.Script, wh.networkOrDefault(json.Network))
+	if errors.Is(err, helpers.ErrInvalidMultisigScript) {
+		fmt.Println(err)
+		ctx.JSON(422, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		fmt.Println(err)
+		ctx.JSON(404, gin.H{
+			"error": "Unable to decode multisig script",
+		})
+		return
+	}
+
+	ctx.JSON(200, multisig)
+}
+
+func (wh *walletHandler) CompileMiniscript(ctx *gin.Context) {
+	var json Miniscript
+
+	if err := ctx.ShouldBindJSON(&json); err != nil {
+		fmt.Println(err)
+		ctx.JSON(422, gin.H{"error": "Unprocessable Entity"})
+		return
+	}
+
+	expression, isPolicy := json.Miniscript, json.Policy != ""
+	if isPolicy {
+		expression = json.Policy
+	}
+	scriptContext := miniscript.Segwit
+	if json.Context != "" {
+		scriptContext = miniscript.Context(json.Context)
+	}
+	output, err := wh.walletManager.CompileMiniscript(expression, isPolicy, scriptContext, json.Keys, json.InternalKey, wh.networkOrDefault(json.Network))
+	if errors.Is(err, miniscript.ErrInvalidMiniscript) {
+		fmt.Println(err)
+		ctx.JSON(422, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		fmt.Println(err)
+		ctx.JSON(404, gin.H{
+			"error": "Unable to compile miniscript",
+		})
+		return
+	}
+
+	ctx.JSON(200, output)
+}
+
+// networkOrDefault falls back to the server default when a request does not select a network.
+func (wh *walletHandler) networkOrDefault(network string) string {
+	if network == "" {
+		return wh.network
+	}
+	return network
+}
+
+func NewWalletHandler(walletManager managers.WalletManager, network string) WalletHandler {
 	return &walletHandler{
 		walletManager,
+		network,
 	}
 }
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"bytes"
+	"encoding/hex"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
@@ -9,6 +10,7 @@ import (
 
 	"btcwallet.com/src/pkg/helpers"
 	"btcwallet.com/src/pkg/managers"
+	"github.com/btcsuite/btcd/btcec"
 	"github.com/gin-gonic/gin"
 )
 
@@ -16,7 +18,7 @@ func TestGenerateMnemonic(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	var walletHelper helpers.WalletHelper = helpers.NewWalletHelper()
 	var walletManager managers.WalletManager = managers.NewWalletManager(walletHelper)
-	var walletHandler WalletHandler = NewWalletHandler(walletManager)
+	var walletHandler WalletHandler = NewWalletHandler(walletManager, "mainnet")
 	var url string = "/util/mnemonic"
 
 	r := gin.Default()
@@ -36,11 +38,67 @@ func TestGenerateMnemonic(t *testing.T) {
 	}
 }
 
+func TestGenerateMnemonicOptions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var walletHelper helpers.WalletHelper = helpers.NewWalletHelper()
+	var walletManager managers.WalletManager = managers.NewWalletManager(walletHelper)
+	var walletHandler WalletHandler = NewWalletHandler(walletManager, "mainnet")
+	var url string = "/util/new-mnemonic"
+	tests := []struct {
+		query    string
+		expected int
+	}{
+		{"?words=12", http.StatusOK},
+		{"?words=15&passphrase=TREZOR&entropy=true", http.StatusOK},
+		{"?words=13", http.StatusUnprocessableEntity},
+		{"?words=12&language=japanese", http.StatusOK},
+		{"?words=24&language=portuguese", http.StatusOK},
+		{"?language=klingon", http.StatusUnprocessableEntity},
+	}
+
+	r := gin.Default()
+	r.GET(url, walletHandler.GenerateMnemonic)
+
+	for _, test := range tests {
+		req, err := http.NewRequest(http.MethodGet, url+test.query, nil)
+		if err != nil {
+			t.Fatalf("Couldn't create request: %v\n", err)
+		}
+		w := httptest.NewRecorder()
+
+		r.ServeHTTP(w, req)
+
+		if w.Code != test.expected {
+			t.Fatalf("Expected to get status %d but instead got %d\n", test.expected, w.Code)
+		}
+	}
+
+	body := &Mnemonic{Words: 18, Passphrase: "TREZOR", Entropy: true}
+	payloadBuf := new(bytes.Buffer)
+	json.NewEncoder(payloadBuf).Encode(body)
+	r.POST(url, walletHandler.GenerateMnemonic)
+
+	req, err := http.NewRequest(http.MethodPost, url, payloadBuf)
+	req.Header.Add("Content-Type", "application/json")
+	if err != nil {
+		t.Fatalf("Couldn't create request: %v\n", err)
+	}
+	w := httptest.NewRecorder()
+
+	r.ServeHTTP(w, req)
+
+	var response map[string]string
+	json.NewDecoder(w.Body).Decode(&response)
+	if w.Code != http.StatusOK || len(response["BIP39Entropy"]) != 48 {
+		t.Fatalf("Expected to get status %d with 24 bytes of entropy but instead got %d %s\n", http.StatusOK, w.Code, response["BIP39Entropy"])
+	}
+}
+
 func TestGenerateHdWallet(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	var walletHelper helpers.WalletHelper = helpers.NewWalletHelper()
 	var walletManager managers.WalletManager = managers.NewWalletManager(walletHelper)
-	var walletHandler WalletHandler = NewWalletHandler(walletManager)
+	var walletHandler WalletHandler = NewWalletHandler(walletManager, "mainnet")
 	var url string = "/util/hd-wallet"
 	body := &HdWallet{
 		Path: "m / 44' / 0' / 0' / 0 / 0",
@@ -70,15 +128,16 @@ func TestGenerateMultisignature(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	var walletHelper helpers.WalletHelper = helpers.NewWalletHelper()
 	var walletManager managers.WalletManager = managers.NewWalletManager(walletHelper)
-	var walletHandler WalletHandler = NewWalletHandler(walletManager)
+	var walletHandler WalletHandler = NewWalletHandler(walletManager, "mainnet")
 	var url string = "/util/multi-sig-p2sh"
 	var wif []string
 	wif = append(wif, "cQpHXfs91s5eR9PWXui6qo2xjoJb2X3VdUspwKXe4A8Dybvut2rL")
 	wif = append(wif, "cVgxEkRBtnfvd41ssd4PCsiemahAHidFrLWYoDBMNojUeME8dojZ")
 	body := &Multisignature{
-		N:   1,
-		M:   2,
-		Wif: wif,
+		N:       2,
+		M:       2,
+		Wif:     wif,
+		Network: "testnet3",
 	}
 	payloadBuf := new(bytes.Buffer)
 	json.NewEncoder(payloadBuf).Encode(body)
@@ -100,3 +159,634 @@ func TestGenerateMultisignature(t *testing.T) {
 	}
 
 }
+
+func TestGenerateMultisignatureFromKeys(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var walletHelper helpers.WalletHelper = helpers.NewWalletHelper()
+	var walletManager managers.WalletManager = managers.NewWalletManager(walletHelper)
+	var walletHandler WalletHandler = NewWalletHandler(walletManager, "mainnet")
+	var url string = "/util/multi-sig-p2sh"
+	pubKey := "0265e6f7fb614a369c9230912a3bb09c33c5c5be2e1bcfc2293ecaed46708e0b5c"
+	wif := []string{"cVgxEkRBtnfvd41ssd4PCsiemahAHidFrLWYoDBMNojUeME8dojZ"}
+	extPubKey := "xpub6CatWdiZiodmUeTDp8LT5or8nmbKNcuyvz7WyksVFkKB4RHwCD3XyuvPEbvqAQY3rAPshWcMLoP2fMFMKHPJ4ZeZXYVUhLv1VMrjPC7PW6V"
+	tests := []struct {
+		body     Multisignature
+		expected int
+	}{
+		{Multisignature{N: 2, M: 1, Keys: []string{pubKey, "03f546edf7b434b50aa0115c1c82a0f9a96505d9eff55d2fe3b848c4b51c06b643"}, Network: "testnet3"}, http.StatusOK},
+		{Multisignature{N: 2, M: 1, Keys: []string{pubKey}, Wif: wif, MultisigType: "p2wsh", Network: "testnet3"}, http.StatusOK},
+		{Multisignature{N: 2, M: 2, Keys: []string{pubKey, extPubKey + "/0/0"}}, http.StatusOK},
+		{Multisignature{N: 2, M: 2, Keys: []string{pubKey, extPubKey + "/0'"}}, http.StatusBadRequest},
+		{Multisignature{N: 2, M: 2, Keys: []string{pubKey}, ExtPubKeys: []string{extPubKey}}, http.StatusUnprocessableEntity},
+	}
+
+	r := gin.Default()
+	r.POST(url, walletHandler.GenerateMultisignature)
+
+	for _, test := range tests {
+		payloadBuf := new(bytes.Buffer)
+		json.NewEncoder(payloadBuf).Encode(&test.body)
+		req, err := http.NewRequest(http.MethodPost, url, payloadBuf)
+		if err != nil {
+			t.Fatalf("Couldn't create request: %v\n", err)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != test.expected {
+			t.Fatalf("Expected to get status %d but instead got %d\n", test.expected, w.Code)
+		}
+	}
+}
+
+func TestGenerateMultisignatureKeyOrder(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var walletHelper helpers.WalletHelper = helpers.NewWalletHelper()
+	var walletManager managers.WalletManager = managers.NewWalletManager(walletHelper)
+	var walletHandler WalletHandler = NewWalletHandler(walletManager, "mainnet")
+	var url string = "/util/multi-sig-p2sh"
+	keys := []string{"03f546edf7b434b50aa0115c1c82a0f9a96505d9eff55d2fe3b848c4b51c06b643", "0265e6f7fb614a369c9230912a3bb09c33c5c5be2e1bcfc2293ecaed46708e0b5c"}
+	tests := []struct {
+		body  Multisignature
+		first string
+	}{
+		// keys are sorted unless preserveOrder is set, like extPubKeys
+		{Multisignature{N: 2, M: 1, Keys: keys, Network: "testnet3"}, keys[1]},
+		{Multisignature{N: 2, M: 1, Keys: keys, PreserveOrder: true, Network: "testnet3"}, keys[0]},
+	}
+
+	r := gin.Default()
+	r.POST(url, walletHandler.GenerateMultisignature)
+
+	for _, test := range tests {
+		payloadBuf := new(bytes.Buffer)
+		json.NewEncoder(payloadBuf).Encode(&test.body)
+		req, err := http.NewRequest(http.MethodPost, url, payloadBuf)
+		if err != nil {
+			t.Fatalf("Couldn't create request: %v\n", err)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected to get status %d but instead got %d\n", http.StatusOK, w.Code)
+		}
+		var response struct {
+			PublicKeys []string `json:"publicKeys"`
+		}
+		json.NewDecoder(w.Body).Decode(&response)
+		if len(response.PublicKeys) != 2 || response.PublicKeys[0] != test.first {
+			t.Fatalf("Expected to get %s first but instead got %v\n", test.first, response.PublicKeys)
+		}
+	}
+}
+
+func TestGenerateMultisignatureInvalidPolicy(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var walletHelper helpers.WalletHelper = helpers.NewWalletHelper()
+	var walletManager managers.WalletManager = managers.NewWalletManager(walletHelper)
+	var walletHandler WalletHandler = NewWalletHandler(walletManager, "mainnet")
+	var url string = "/util/multi-sig-p2sh"
+	wif := []string{"cQpHXfs91s5eR9PWXui6qo2xjoJb2X3VdUspwKXe4A8Dybvut2rL", "cVgxEkRBtnfvd41ssd4PCsiemahAHidFrLWYoDBMNojUeME8dojZ"}
+	tests := []struct {
+		body     Multisignature
+		expected int
+		code     string
+		field    string
+	}{
+		{Multisignature{N: 1, M: 2, Wif: wif, Network: "testnet3"}, http.StatusUnprocessableEntity, "required_exceeds_total", "m"},
+		{Multisignature{N: 3, M: 2, Wif: wif, Network: "testnet3"}, http.StatusUnprocessableEntity, "key_count_mismatch", "wif"},
+		{Multisignature{M: 1, Wif: wif, Network: "testnet3"}, http.StatusUnprocessableEntity, "invalid_total", "n"},
+		{Multisignature{N: 2, M: 1, Wif: wif[:1], Keys: []string{"0265e6f7fb614a369c9230912a3bb09c33c5c5be2e1bcfc2293ecaed46708e0b5c"}, Network: "testnet3"}, http.StatusUnprocessableEntity, "duplicate_key", "keys"},
+		{Multisignature{N: 2, M: 2, Wif: wif}, http.StatusBadRequest, "network_mismatch", "wif"},
+		{Multisignature{N: 2, M: 2, Keys: []string{"02abcd", "02abcd"}}, http.StatusBadRequest, "invalid_key", "keys"},
+	}
+
+	r := gin.Default()
+	r.POST(url, walletHandler.GenerateMultisignature)
+
+	for _, test := range tests {
+		payloadBuf := new(bytes.Buffer)
+		json.NewEncoder(payloadBuf).Encode(&test.body)
+		req, err := http.NewRequest(http.MethodPost, url, payloadBuf)
+		if err != nil {
+			t.Fatalf("Couldn't create request: %v\n", err)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != test.expected {
+			t.Fatalf("Expected to get status %d but instead got %d\n", test.expected, w.Code)
+		}
+		var response struct {
+			Code  string `json:"code"`
+			Field string `json:"field"`
+		}
+		json.NewDecoder(w.Body).Decode(&response)
+		if response.Code != test.code || response.Field != test.field {
+			t.Fatalf("Expected to get %s on %s but instead got %s on %s\n", test.code, test.field, response.Code, response.Field)
+		}
+	}
+}
+
+func TestGenerateMultisignatureFromExtPubKeys(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var walletHelper helpers.WalletHelper = helpers.NewWalletHelper()
+	var walletManager managers.WalletManager = managers.NewWalletManager(walletHelper)
+	var walletHandler WalletHandler = NewWalletHandler(walletManager, "mainnet")
+	var url string = "/util/multi-sig-p2sh"
+	extPubKeys := []string{
+		"xpub6CatWdiZiodmUeTDp8LT5or8nmbKNcuyvz7WyksVFkKB4RHwCD3XyuvPEbvqAQY3rAPshWcMLoP2fMFMKHPJ4ZeZXYVUhLv1VMrjPC7PW6V",
+		"xpub6BgBgsespWvERF3LHQu6CnqdvfEvtMcQjYrcRzx53QJjSxarj2afYWcLteoGVky7D3UKDP9QyrLprQ3VCECoY49yfdDEHGCtMMj92pReUsQ",
+	}
+	tests := []struct {
+		body      Multisignature
+		expected  int
+		addresses int
+	}{
+		{Multisignature{N: 2, M: 2, ExtPubKeys: extPubKeys, Count: 5}, http.StatusOK, 5},
+		{Multisignature{N: 2, M: 1, ExtPubKeys: extPubKeys, Change: 1, Index: 7, PreserveOrder: true}, http.StatusOK, 1},
+		{Multisignature{N: 2, M: 2, ExtPubKeys: extPubKeys, MultisigType: "p2wsh"}, http.StatusOK, 1},
+		{Multisignature{N: 2, M: 2, ExtPubKeys: extPubKeys, MultisigType: "p2tr"}, http.StatusOK, 1},
+		{Multisignature{N: 2, M: 2, ExtPubKeys: extPubKeys, MultisigType: "p2pk"}, http.StatusUnprocessableEntity, 0},
+		{Multisignature{N: 2, M: 2, ExtPubKeys: extPubKeys, Change: 2}, http.StatusUnprocessableEntity, 0},
+		{Multisignature{N: 2, M: 2}, http.StatusUnprocessableEntity, 0},
+	}
+
+	r := gin.Default()
+	r.POST(url, walletHandler.GenerateMultisignature)
+
+	for _, test := range tests {
+		payloadBuf := new(bytes.Buffer)
+		json.NewEncoder(payloadBuf).Encode(&test.body)
+		req, err := http.NewRequest(http.MethodPost, url, payloadBuf)
+		if err != nil {
+			t.Fatalf("Couldn't create request: %v\n", err)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != test.expected {
+			t.Fatalf("Expected to get status %d but instead got %d\n", test.expected, w.Code)
+		}
+		var response struct {
+			Address   string                   `json:"address"`
+			Addresses []managers.MultisigEntry `json:"addresses"`
+		}
+		json.NewDecoder(w.Body).Decode(&response)
+		if w.Code == http.StatusOK && (response.Address == "" || len(response.Addresses) != test.addresses) {
+			t.Fatalf("Expected to get %d addresses but instead got %v\n", test.addresses, response.Addresses)
+		}
+	}
+}
+
+func TestGenerateTaprootMultisignature(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var walletHelper helpers.WalletHelper = helpers.NewWalletHelper()
+	var walletManager managers.WalletManager = managers.NewWalletManager(walletHelper)
+	var walletHandler WalletHandler = NewWalletHandler(walletManager, "mainnet")
+	var url string = "/util/multi-sig-p2sh"
+	// 21 keys, over the 20 key limit of CHECKMULTISIG
+	keys := make([]string, 21)
+	for i := range keys {
+		_, publicKey := btcec.PrivKeyFromBytes(btcec.S256(), []byte{byte(i + 1)})
+		keys[i] = hex.EncodeToString(publicKey.SerializeCompressed())
+	}
+	tests := []struct {
+		body     Multisignature
+		expected int
+	}{
+		{Multisignature{N: 21, M: 11, Keys: keys, MultisigType: "p2tr"}, http.StatusOK},
+		{Multisignature{N: 2, M: 2, Wif: []string{"cQpHXfs91s5eR9PWXui6qo2xjoJb2X3VdUspwKXe4A8Dybvut2rL", "cVgxEkRBtnfvd41ssd4PCsiemahAHidFrLWYoDBMNojUeME8dojZ"}, MultisigType: "p2tr", Network: "testnet3"}, http.StatusOK},
+		{Multisignature{N: 20, M: 11, Keys: keys[:20], MultisigType: "p2wsh"}, http.StatusOK},
+		{Multisignature{N: 21, M: 11, Keys: keys, MultisigType: "p2wsh"}, http.StatusUnprocessableEntity},
+		{Multisignature{N: 16, M: 11, Keys: keys[:16], MultisigType: "p2sh"}, http.StatusUnprocessableEntity},
+	}
+
+	r := gin.Default()
+	r.POST(url, walletHandler.GenerateMultisignature)
+
+	for _, test := range tests {
+		payloadBuf := new(bytes.Buffer)
+		json.NewEncoder(payloadBuf).Encode(&test.body)
+		req, err := http.NewRequest(http.MethodPost, url, payloadBuf)
+		if err != nil {
+			t.Fatalf("Couldn't create request: %v\n", err)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != test.expected {
+			t.Fatalf("Expected to get status %d but instead got %d\n", test.expected, w.Code)
+		}
+	}
+}
+
+func TestDecodeMultisignatureScript(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var walletHelper helpers.WalletHelper = helpers.NewWalletHelper()
+	var walletManager managers.WalletManager = managers.NewWalletManager(walletHelper)
+	var walletHandler WalletHandler = NewWalletHandler(walletManager, "mainnet")
+	var url string = "/util/multi-sig/decode"
+	tests := []struct {
+		body     MultisigScript
+		expected int
+	}{
+		{MultisigScript{Script: "51210265e6f7fb614a369c9230912a3bb09c33c5c5be2e1bcfc2293ecaed46708e0b5c2103f546edf7b434b50aa0115c1c82a0f9a96505d9eff55d2fe3b848c4b51c06b64352ae", Network: "testnet3"}, http.StatusOK},
+		{MultisigScript{Script: "76a914d6fe8455e2a6b6411ab9c4365c66a8c84ba4cc9f88ac"}, http.StatusUnprocessableEntity},
+		{MultisigScript{Script: "not hex"}, http.StatusUnprocessableEntity},
+		{MultisigScript{}, http.StatusUnprocessableEntity},
+	}
+
+	r := gin.Default()
+	r.POST(url, walletHandler.DecodeMultisignatureScript)
+
+	for _, test := range tests {
+		payloadBuf := new(bytes.Buffer)
+		json.NewEncoder(payloadBuf).Encode(&test.body)
+		req, err := http.NewRequest(http.MethodPost, url, payloadBuf)
+		if err != nil {
+			t.Fatalf("Couldn't create request: %v\n", err)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != test.expected {
+			t.Fatalf("Expected to get status %d but instead got %d\n", test.expected, w.Code)
+		}
+	}
+}
+
+func TestCompileMiniscript(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var walletHelper helpers.WalletHelper = helpers.NewWalletHelper()
+	var walletManager managers.WalletManager = managers.NewWalletManager(walletHelper)
+	var walletHandler WalletHandler = NewWalletHandler(walletManager, "mainnet")
+	var url string = "/util/miniscript"
+	keys := map[string]string{"A": "0265e6f7fb614a369c9230912a3bb09c33c5c5be2e1bcfc2293ecaed46708e0b5c", "B": "03f546edf7b434b50aa0115c1c82a0f9a96505d9eff55d2fe3b848c4b51c06b643"}
+	tests := []struct {
+		body     Miniscript
+		expected int
+	}{
+		{Miniscript{Policy: "or(9@pk(A),1@and(pk(B),older(144)))", Keys: keys}, http.StatusOK},
+		{Miniscript{Miniscript: "and_v(v:pk(A),pk(B))", Context: "tr", Keys: keys, InternalKey: "A", Network: "testnet3"}, http.StatusOK},
+		{Miniscript{Miniscript: "and_v(pk(A),pk(B))", Keys: keys}, http.StatusUnprocessableEntity},
+		{Miniscript{Miniscript: "pk(A)", InternalKey: "B", Keys: keys}, http.StatusUnprocessableEntity},
+		{Miniscript{Policy: "pk(A)", Miniscript: "pk(A)", Keys: keys}, http.StatusUnprocessableEntity},
+		{Miniscript{Policy: "pk(A)", Context: "sh", Keys: keys}, http.StatusUnprocessableEntity},
+		{Miniscript{}, http.StatusUnprocessableEntity},
+	}
+
+	r := gin.Default()
+	r.POST(url, walletHandler.CompileMiniscript)
+
+	for _, test := range tests {
+		payloadBuf := new(bytes.Buffer)
+		json.NewEncoder(payloadBuf).Encode(&test.body)
+		req, err := http.NewRequest(http.MethodPost, url, payloadBuf)
+		if err != nil {
+			t.Fatalf("Couldn't create request: %v\n", err)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != test.expected {
+			t.Fatalf("Expected to get status %d but instead got %d\n", test.expected, w.Code)
+		}
+	}
+}
+
+func TestGenerateHdWalletUnknownNetwork(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var walletHelper helpers.WalletHelper = helpers.NewWalletHelper()
+	var walletManager managers.WalletManager = managers.NewWalletManager(walletHelper)
+	var walletHandler WalletHandler = NewWalletHandler(walletManager, "mainnet")
+	var url string = "/util/hd-wallet"
+	body := &HdWallet{
+		Path:    "m / 44' / 0' / 0' / 0 / 0",
+		Seed:    "3fdf3c7c40ef678dd8950caac27f8006e27fdfab5e379ff7e3cef34a0226df830a49ca85476e7873e096ca6127d365995f6f135c71c27e8efe6cd1c497f6003f",
+		Network: "simnet",
+	}
+	payloadBuf := new(bytes.Buffer)
+	json.NewEncoder(payloadBuf).Encode(body)
+
+	r := gin.Default()
+	r.POST(url, walletHandler.GenerateHdWallet)
+
+	req, err := http.NewRequest(http.MethodPost, url, payloadBuf)
+	if err != nil {
+		t.Fatalf("Couldn't create request: %v\n", err)
+	}
+	w := httptest.NewRecorder()
+
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("Expected to get status %d but instead got %d\n", http.StatusUnprocessableEntity, w.Code)
+	}
+}
+
+func TestGenerateHdWalletSeedOrMnemonic(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var walletHelper helpers.WalletHelper = helpers.NewWalletHelper()
+	var walletManager managers.WalletManager = managers.NewWalletManager(walletHelper)
+	var walletHandler WalletHandler = NewWalletHandler(walletManager, "mainnet")
+	var url string = "/util/hd-wallet"
+	seed := "3fdf3c7c40ef678dd8950caac27f8006e27fdfab5e379ff7e3cef34a0226df830a49ca85476e7873e096ca6127d365995f6f135c71c27e8efe6cd1c497f6003f"
+	mnemonic := "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
+	tests := []struct {
+		body     HdWallet
+		expected int
+	}{
+		{HdWallet{Path: "m/84'/0'/0'/0/0", Seed: seed}, http.StatusOK},
+		{HdWallet{Path: "m/84'/0'/0'/0/0", Mnemonic: mnemonic}, http.StatusOK},
+		// the seed would be ignored
+		{HdWallet{Path: "m/84'/0'/0'/0/0", Seed: seed, Mnemonic: mnemonic}, http.StatusUnprocessableEntity},
+		{HdWallet{Path: "m/84'/0'/0'/0/0"}, http.StatusUnprocessableEntity},
+	}
+
+	r := gin.Default()
+	r.POST(url, walletHandler.GenerateHdWallet)
+
+	for _, test := range tests {
+		payloadBuf := new(bytes.Buffer)
+		json.NewEncoder(payloadBuf).Encode(&test.body)
+		req, err := http.NewRequest(http.MethodPost, url, payloadBuf)
+		if err != nil {
+			t.Fatalf("Couldn't create request: %v\n", err)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != test.expected {
+			t.Fatalf("Expected to get status %d but instead got %d\n", test.expected, w.Code)
+		}
+	}
+}
+
+func TestGenerateAddressRange(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var walletHelper helpers.WalletHelper = helpers.NewWalletHelper()
+	var walletManager managers.WalletManager = managers.NewWalletManager(walletHelper)
+	var walletHandler WalletHandler = NewWalletHandler(walletManager, "mainnet")
+	var url string = "/util/hd-wallet/range"
+	tests := []struct {
+		count    uint32
+		expected int
+	}{
+		{20, http.StatusOK},
+		{managers.MaxAddressRangeCount + 1, http.StatusUnprocessableEntity},
+	}
+
+	r := gin.Default()
+	r.POST(url, walletHandler.GenerateAddressRange)
+
+	for _, test := range tests {
+		body := &AddressRange{
+			Path:  "m/84'/0'/0'",
+			Seed:  "3fdf3c7c40ef678dd8950caac27f8006e27fdfab5e379ff7e3cef34a0226df830a49ca85476e7873e096ca6127d365995f6f135c71c27e8efe6cd1c497f6003f",
+			Count: test.count,
+		}
+		payloadBuf := new(bytes.Buffer)
+		json.NewEncoder(payloadBuf).Encode(body)
+
+		req, err := http.NewRequest(http.MethodPost, url, payloadBuf)
+		if err != nil {
+			t.Fatalf("Couldn't create request: %v\n", err)
+		}
+		w := httptest.NewRecorder()
+
+		r.ServeHTTP(w, req)
+
+		if w.Code != test.expected {
+			t.Fatalf("Expected to get status %d but instead got %d\n", test.expected, w.Code)
+		}
+	}
+}
+
+func TestGenerateWatchOnly(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var walletHelper helpers.WalletHelper = helpers.NewWalletHelper()
+	var walletManager managers.WalletManager = managers.NewWalletManager(walletHelper)
+	var walletHandler WalletHandler = NewWalletHandler(walletManager, "mainnet")
+	var url string = "/util/watch-only"
+	tests := []struct {
+		path     string
+		expected int
+	}{
+		{"0/15", http.StatusOK},
+		{"0'/15", http.StatusUnprocessableEntity},
+	}
+
+	r := gin.Default()
+	r.POST(url, walletHandler.GenerateWatchOnly)
+
+	for _, test := range tests {
+		body := &WatchOnly{
+			ExtPubKey: "xpub6BznHD2sccQawPyqKJ4wE89HKi4Tf88zLbgGRJXeiVHpJTWJDG191vCpMBYBY13vD7Zp8XaF5F31gPQMSZCXt2kJhzV2hXJyk98AUFvqeLv",
+			Path:      test.path,
+			Count:     5,
+		}
+		payloadBuf := new(bytes.Buffer)
+		json.NewEncoder(payloadBuf).Encode(body)
+
+		req, err := http.NewRequest(http.MethodPost, url, payloadBuf)
+		if err != nil {
+			t.Fatalf("Couldn't create request: %v\n", err)
+		}
+		w := httptest.NewRecorder()
+
+		r.ServeHTTP(w, req)
+
+		if w.Code != test.expected {
+			t.Fatalf("Expected to get status %d but instead got %d\n", test.expected, w.Code)
+		}
+	}
+}
+
+func TestMnemonicToSeed(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var walletHelper helpers.WalletHelper = helpers.NewWalletHelper()
+	var walletManager managers.WalletManager = managers.NewWalletManager(walletHelper)
+	var walletHandler WalletHandler = NewWalletHandler(walletManager, "mainnet")
+	var url string = "/util/mnemonic-to-seed"
+	tests := []struct {
+		mnemonic string
+		language string
+		expected int
+	}{
+		{"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about", "", http.StatusOK},
+		{"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon", "", http.StatusUnprocessableEntity},
+		{"abaisser abaisser abaisser abaisser abaisser abaisser abaisser abaisser abaisser abaisser abaisser abeille", "", http.StatusOK},
+		{"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about", "french", http.StatusUnprocessableEntity},
+	}
+
+	r := gin.Default()
+	r.POST(url, walletHandler.MnemonicToSeed)
+
+	for _, test := range tests {
+		body := &MnemonicSeed{Mnemonic: test.mnemonic, Language: test.language}
+		payloadBuf := new(bytes.Buffer)
+		json.NewEncoder(payloadBuf).Encode(body)
+
+		req, err := http.NewRequest(http.MethodPost, url, payloadBuf)
+		if err != nil {
+			t.Fatalf("Couldn't create request: %v\n", err)
+		}
+		w := httptest.NewRecorder()
+
+		r.ServeHTTP(w, req)
+
+		if w.Code != test.expected {
+			t.Fatalf("Expected to get status %d but instead got %d\n", test.expected, w.Code)
+		}
+	}
+}
+
+func TestGenerateHdWalletFromMnemonic(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var walletHelper helpers.WalletHelper = helpers.NewWalletHelper()
+	var walletManager managers.WalletManager = managers.NewWalletManager(walletHelper)
+	var walletHandler WalletHandler = NewWalletHandler(walletManager, "mainnet")
+	var url string = "/util/hd-wallet"
+	body := &HdWallet{
+		Path:     "m/84'/0'/0'/0/0",
+		Mnemonic: "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about",
+	}
+	payloadBuf := new(bytes.Buffer)
+	json.NewEncoder(payloadBuf).Encode(body)
+
+	r := gin.Default()
+	r.POST(url, walletHandler.GenerateHdWallet)
+
+	req, err := http.NewRequest(http.MethodPost, url, payloadBuf)
+	if err != nil {
+		t.Fatalf("Couldn't create request: %v\n", err)
+	}
+	w := httptest.NewRecorder()
+
+	r.ServeHTTP(w, req)
+
+	var response managers.HdWallet
+	json.NewDecoder(w.Body).Decode(&response)
+	if w.Code != http.StatusOK || response.Address != "bc1qcr8te4kr609gcawutmrza0j4xv80jy8z306fyu" {
+		t.Fatalf("Expected to get status %d with the BIP84 test vector address but instead got %d %s\n", http.StatusOK, w.Code, response.Address)
+	}
+}
+
+func TestSlip39Shares(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var walletHelper helpers.WalletHelper = helpers.NewWalletHelper()
+	var walletManager managers.WalletManager = managers.NewWalletManager(walletHelper)
+	var walletHandler WalletHandler = NewWalletHandler(walletManager, "mainnet")
+	var splitUrl string = "/util/slip39/split"
+	var combineUrl string = "/util/slip39/combine"
+
+	r := gin.Default()
+	r.POST(splitUrl, walletHandler.GenerateSlip39Shares)
+	r.POST(combineUrl, walletHandler.CombineSlip39Shares)
+
+	body := &Slip39Shares{
+		MasterSecret:   "bb54aac4b89dc868ba37d9cc21b2cece",
+		Passphrase:     "TREZOR",
+		GroupThreshold: 1,
+		Groups:         []Slip39Group{{MemberThreshold: 2, MemberCount: 3}},
+	}
+	payloadBuf := new(bytes.Buffer)
+	json.NewEncoder(payloadBuf).Encode(body)
+	req, err := http.NewRequest(http.MethodPost, splitUrl, payloadBuf)
+	if err != nil {
+		t.Fatalf("Couldn't create request: %v\n", err)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	var split struct {
+		Groups [][]string `json:"groups"`
+		Seed   string     `json:"seed"`
+	}
+	json.NewDecoder(w.Body).Decode(&split)
+	if w.Code != http.StatusOK || len(split.Groups) != 1 || len(split.Groups[0]) != 3 {
+		t.Fatalf("Expected to get status %d with 3 shares but instead got %d %v\n", http.StatusOK, w.Code, split.Groups)
+	}
+
+	tests := []struct {
+		mnemonics []string
+		expected  int
+	}{
+		{split.Groups[0][:2], http.StatusOK},
+		{split.Groups[0][:1], http.StatusUnprocessableEntity},
+		{[]string{}, http.StatusUnprocessableEntity},
+	}
+	for _, test := range tests {
+		payloadBuf := new(bytes.Buffer)
+		json.NewEncoder(payloadBuf).Encode(&Slip39Combine{Mnemonics: test.mnemonics, Passphrase: "TREZOR"})
+		req, err := http.NewRequest(http.MethodPost, combineUrl, payloadBuf)
+		if err != nil {
+			t.Fatalf("Couldn't create request: %v\n", err)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		var response map[string]string
+		json.NewDecoder(w.Body).Decode(&response)
+		if w.Code != test.expected {
+			t.Fatalf("Expected to get status %d but instead got %d\n", test.expected, w.Code)
+		}
+		if w.Code == http.StatusOK && response["seed"] != body.MasterSecret {
+			t.Fatalf("Expected to get seed %s but instead got %s\n", body.MasterSecret, response["seed"])
+		}
+	}
+
+	// a threshold 1 group with several members is rejected
+	body.Groups = []Slip39Group{{MemberThreshold: 1, MemberCount: 2}}
+	payloadBuf = new(bytes.Buffer)
+	json.NewEncoder(payloadBuf).Encode(body)
+	req, _ = http.NewRequest(http.MethodPost, splitUrl, payloadBuf)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("Expected to get status %d but instead got %d\n", http.StatusUnprocessableEntity, w.Code)
+	}
+}
+
+func TestDeriveDescriptorAddresses(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var walletHelper helpers.WalletHelper = helpers.NewWalletHelper()
+	var walletManager managers.WalletManager = managers.NewWalletManager(walletHelper)
+	var walletHandler WalletHandler = NewWalletHandler(walletManager, "mainnet")
+	var url string = "/util/descriptor/addresses"
+	var descriptor string = "wpkh([73c5da0a/84'/0'/0']xpub6CatWdiZiodmUeTDp8LT5or8nmbKNcuyvz7WyksVFkKB4RHwCD3XyuvPEbvqAQY3rAPshWcMLoP2fMFMKHPJ4ZeZXYVUhLv1VMrjPC7PW6V/0/*)#wc3n3van"
+	tests := []struct {
+		body     DescriptorRange
+		expected int
+	}{
+		{DescriptorRange{Descriptor: descriptor, Count: 3}, http.StatusOK},
+		{DescriptorRange{Descriptor: descriptor[:len(descriptor)-1] + "a", Count: 3}, http.StatusUnprocessableEntity},
+		{DescriptorRange{Descriptor: descriptor, Count: 0}, http.StatusUnprocessableEntity},
+		{DescriptorRange{Descriptor: descriptor, Count: 3, Network: "testnet3"}, http.StatusUnprocessableEntity},
+	}
+
+	r := gin.Default()
+	r.POST(url, walletHandler.DeriveDescriptorAddresses)
+
+	for _, test := range tests {
+		payloadBuf := new(bytes.Buffer)
+		json.NewEncoder(payloadBuf).Encode(&test.body)
+		req, err := http.NewRequest(http.MethodPost, url, payloadBuf)
+		if err != nil {
+			t.Fatalf("Couldn't create request: %v\n", err)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != test.expected {
+			t.Fatalf("Expected to get status %d but instead got %d\n", test.expected, w.Code)
+		}
+		var response managers.DescriptorAddresses
+		json.NewDecoder(w.Body).Decode(&response)
+		if w.Code == http.StatusOK && len(response.Addresses) != int(test.body.Count) {
+			t.Fatalf("Expected to get %d addresses but instead got %d\n", test.body.Count, len(response.Addresses))
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package helpers
+
+import (
+	"fmt"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcutil"
+	"github.com/tyler-smith/go-bip32"
+)
+
+// AddressType is the kind of single key output script an address pays to.
+type AddressType string
+
+const (
+	AddressTypeP2PKH      AddressType = "p2pkh"
+	AddressTypeP2SHP2WPKH AddressType = "p2sh-p2wpkh"
+	AddressTypeP2WPKH     AddressType = "p2wpkh"
+	AddressTypeP2TR       AddressType = "p2tr"
+)
+
+// addressTypeVersions maps an address type to its SLIP-132 private and public
+// extended key prefixes on mainnet and on the test networks.
+var addressTypeVersions = map[AddressType][4]string{
+	AddressTypeP2PKH:      {"xprv", "xpub", "tprv", "tpub"},
+	AddressTypeP2SHP2WPKH: {"yprv", "ypub", "uprv", "upub"},
+	AddressTypeP2WPKH:     {"zprv", "zpub", "vprv", "vpub"},
+	AddressTypeP2TR:       {"xprv", "xpub", "tprv", "tpub"},
+}
+
+// AddressTypeForPurpose selects the address type of a BIP44 style purpose,
+// 44' is P2PKH, 49' is P2SH-P2WPKH, 84' is P2WPKH and 86' is P2TR.
+func AddressTypeForPurpose(purpose uint32) (AddressType, error) {
+	switch purpose {
+	case bip32.FirstHardenedChild + 44:
+		return AddressTypeP2PKH, nil
+	case bip32.FirstHardenedChild + 49:
+		return AddressTypeP2SHP2WPKH, nil
+	case bip32.FirstHardenedChild + 84:
+		return AddressTypeP2WPKH, nil
+	case bip32.FirstHardenedChild + 86:
+		return AddressTypeP2TR, nil
+	default:
+		return "", fmt.Errorf("no address type for purpose %s", formatChildIndex(purpose))
+	}
+}
+
+// AddressType returns the address type selected by the purpose field.
+func (params *BIP44Params) AddressType() (AddressType, error) {
+	return AddressTypeForPurpose(params.Purpose)
+}
+
+// encodeSlip132ExtendedKey serializes an extended key with the SLIP-132 version
+// of the address type, e.g. zpub for P2WPKH on mainnet.
+func encodeSlip132ExtendedKey(key *bip32.Key, net *chaincfg.Params, addressType AddressType) (string, error) {
+	prefixes, ok := addressTypeVersions[addressType]
+	if !ok {
+		return "", fmt.Errorf("unknown address type %s", addressType)
+	}
+	i := 0
+	if net.Name != chaincfg.MainNetParams.Name {
+		i += 2
+	}
+	if !key.IsPrivate {
+		i++
+	}
+	for _, version := range extendedKeyVersions {
+		if version.prefix == prefixes[i] {
+			serialized := *key
+			serialized.Version = version.version[:]
+			return serialized.String(), nil
+		}
+	}
+	return "", fmt.Errorf("unknown extended key prefix %s", prefixes[i])
+}
+
+func (wh *walletHelper) EncodeSlip132ExtendedKey(key *bip32.Key, net *chaincfg.Params, addressType AddressType) (string, error) {
+	return encodeSlip132ExtendedKey(key, net, addressType)
+}
+
+func (wh *walletHelper) DeriveAddressForType(pubKey *btcec.PublicKey, net *chaincfg.Params, addressType AddressType) (string, error) {
+	if addressType == AddressTypeP2TR {
+		p2trAddress, _, _, err := wh.DeriveTaprootAddress(pubKey, net)
+		return p2trAddress, err
+	}
+	p2pkhAddress, segwitBech32, segwitNested, err := wh.DeriveAddressFromPubKey(pubKey, net)
+	if err != nil {
+		return "", err
+	}
+	switch addressType {
+	case AddressTypeP2PKH:
+		return p2pkhAddress, nil
+	case AddressTypeP2SHP2WPKH:
+		return segwitNested, nil
+	case AddressTypeP2WPKH:
+		return segwitBech32, nil
+	default:
+		return "", fmt.Errorf("unknown address type %s", addressType)
+	}
+}
+
+// AddressScript is the output script an address of the network pays to,
+// segwit addresses of any witness version included.
+func AddressScript(address string, net *chaincfg.Params) ([]byte, error) {
+	if version, program, err := DecodeSegwitAddress(net.Bech32HRPSegwit, address); err == nil {
+		builder := txscript.NewScriptBuilder()
+		if version == 0 {
+			builder.AddOp(txscript.OP_0)
+		} else {
+			builder.AddOp(txscript.OP_1 + version - 1)
+		}
+		return builder.AddData(program).Script()
+	}
+	decoded, err := btcutil.DecodeAddress(address, net)
+	if err != nil {
+		return nil, err
+	}
+	if !decoded.IsForNet(net) {
+		return nil, fmt.Errorf("address %s is not for network %s", address, net.Name)
+	}
+	return txscript.PayToAddrScript(decoded)
+}
@@ -0,0 +1,75 @@
+package helpers
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/tyler-smith/go-bip32"
+)
+
+func TestAddressTypeForPurpose(t *testing.T) {
+	tests := map[uint32]AddressType{
+		bip32.FirstHardenedChild + 44: AddressTypeP2PKH,
+		bip32.FirstHardenedChild + 49: AddressTypeP2SHP2WPKH,
+		bip32.FirstHardenedChild + 84: AddressTypeP2WPKH,
+	}
+
+	for purpose, expected := range tests {
+		params := &BIP44Params{Purpose: purpose}
+		result, err := params.AddressType()
+		if err != nil || result != expected {
+			t.Errorf("Test failed: input: %s expected: %s received: %s ", formatChildIndex(purpose), expected, result)
+		}
+	}
+	if _, err := AddressTypeForPurpose(84); err == nil {
+		t.Errorf("Test failed: input: 84 expected an error for a non hardened purpose")
+	}
+}
+
+func TestEncodeSlip132ExtendedKey(t *testing.T) {
+	var walletHelper WalletHelper = NewWalletHelper()
+	// BIP84 test vector account 0
+	var zpub string = "zpub6rFR7y4Q2AijBEqTUquhVz398htDFrtymD9xYYfG1m4wAcvPhXNfE3EfH1r1ADqtfSdVCToUG868RvUUkgDKf31mGDtKsAYz2oz2AGutZYs"
+	key, _, err := decodeExtendedKey(zpub, &chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("Test failed: unexpected error: %v", err)
+	}
+
+	result, _ := walletHelper.EncodeSlip132ExtendedKey(key, &chaincfg.MainNetParams, AddressTypeP2WPKH)
+	if result != zpub {
+		t.Errorf("Test failed:  expected: %s received: %s ", zpub, result)
+	}
+	result, _ = walletHelper.EncodeSlip132ExtendedKey(key, &chaincfg.TestNet3Params, AddressTypeP2WPKH)
+	if result[:4] != "vpub" {
+		t.Errorf("Test failed:  expected: %s received: %s ", "vpub", result[:4])
+	}
+	result, _ = walletHelper.EncodeSlip132ExtendedKey(key, &chaincfg.TestNet3Params, AddressTypeP2SHP2WPKH)
+	if result[:4] != "upub" {
+		t.Errorf("Test failed:  expected: %s received: %s ", "upub", result[:4])
+	}
+}
+
+func TestAddressScript(t *testing.T) {
+	tests := []struct {
+		address  string
+		expected string
+	}{
+		{"mipcBbFg9gMiCh81Kj8tqqdgoZub1ZJRfn", "76a914243f1394f44554f4ce3fd68649c19adc483ce92488ac"},
+		{"2N2JD6wb56AfK4tfmM6PwdVmoYk2dCKf4Br", "a9146349a418fc4578d10a372b54b45c280cc8c4382f87"},
+		{"tb1qw508d6qejxtdg4y5r3zarvary0c5xw7kxpjzsx", "0014751e76e8199196d454941c45d1b3a323f1433bd6"},
+		{"tb1pqqqqp399et2xygdj5xreqhjjvcmzhxw4aywxecjdzew6hylgvsesf3hn0c", "5120000000c4a5cad46221b2a187905e5266362b99d5e91c6ce24d165dab93e86433"},
+	}
+	for _, test := range tests {
+		script, err := AddressScript(test.address, &chaincfg.TestNet3Params)
+		if err != nil {
+			t.Fatalf("Test failed: input: %s unexpected error: %v", test.address, err)
+		}
+		if result := hex.EncodeToString(script); result != test.expected {
+			t.Errorf("Test failed: input: %s expected: %s received: %s ", test.address, test.expected, result)
+		}
+	}
+	if _, err := AddressScript("bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4", &chaincfg.TestNet3Params); err == nil {
+		t.Errorf("Test failed:  expected: an error received: nil ")
+	}
+}
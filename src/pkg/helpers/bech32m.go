@@ -0,0 +1,132 @@
+package helpers
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/btcsuite/btcutil/bech32"
+)
+
+// The bech32 package of btcutil only knows the BIP173 checksum. Segwit version 1
+// and later outputs are encoded with the BIP350 bech32m checksum implemented here.
+
+const (
+	bech32Charset   = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+	bech32Const     = 1
+	bech32mConst    = 0x2bc830a3
+	bech32MaxLength = 90
+)
+
+var bech32Generator = [5]uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+
+func bech32Polymod(values []byte) uint32 {
+	chk := uint32(1)
+	for _, v := range values {
+		top := chk >> 25
+		chk = (chk&0x1ffffff)<<5 ^ uint32(v)
+		for i := 0; i < 5; i++ {
+			if (top>>uint(i))&1 == 1 {
+				chk ^= bech32Generator[i]
+			}
+		}
+	}
+	return chk
+}
+
+func bech32HrpExpand(hrp string) []byte {
+	expanded := make([]byte, 0, len(hrp)*2+1)
+	for i := 0; i < len(hrp); i++ {
+		expanded = append(expanded, hrp[i]>>5)
+	}
+	expanded = append(expanded, 0)
+	for i := 0; i < len(hrp); i++ {
+		expanded = append(expanded, hrp[i]&31)
+	}
+	return expanded
+}
+
+func bech32Checksum(hrp string, data []byte, constant uint32) []byte {
+	values := append(bech32HrpExpand(hrp), data...)
+	values = append(values, 0, 0, 0, 0, 0, 0)
+	polymod := bech32Polymod(values) ^ constant
+	checksum := make([]byte, 6)
+	for i := range checksum {
+		checksum[i] = byte((polymod >> uint(5*(5-i))) & 31)
+	}
+	return checksum
+}
+
+// EncodeSegwitAddress encodes a witness program as a bech32 (version 0) or
+// bech32m (version 1 and later) address.
+func EncodeSegwitAddress(hrp string, version byte, program []byte) (string, error) {
+	if version > 16 {
+		return "", fmt.Errorf("invalid witness version %d", version)
+	}
+	if len(program) < 2 || len(program) > 40 {
+		return "", fmt.Errorf("invalid witness program length %d", len(program))
+	}
+	converted, err := bech32.ConvertBits(program, 8, 5, true)
+	if err != nil {
+		return "", err
+	}
+	data := append([]byte{version}, converted...)
+	constant := uint32(bech32mConst)
+	if version == 0 {
+		constant = bech32Const
+	}
+	data = append(data, bech32Checksum(hrp, data, constant)...)
+
+	var b strings.Builder
+	b.WriteString(hrp)
+	b.WriteString("1")
+	for _, v := range data {
+		b.WriteByte(bech32Charset[v])
+	}
+	return b.String(), nil
+}
+
+// DecodeSegwitAddress decodes a bech32 or bech32m segwit address and checks the
+// checksum variant matches the witness version.
+func DecodeSegwitAddress(hrp string, address string) (version byte, program []byte, err error) {
+	if len(address) > bech32MaxLength {
+		return 0, nil, fmt.Errorf("address too long")
+	}
+	if strings.ToLower(address) != address && strings.ToUpper(address) != address {
+		return 0, nil, fmt.Errorf("address has mixed case")
+	}
+	address = strings.ToLower(address)
+	separator := strings.LastIndex(address, "1")
+	if separator < 1 || separator+7 > len(address) {
+		return 0, nil, fmt.Errorf("invalid address separator")
+	}
+	if address[:separator] != hrp {
+		return 0, nil, fmt.Errorf("address prefix %s does not match %s", address[:separator], hrp)
+	}
+	data := make([]byte, 0, len(address)-separator-1)
+	for _, c := range address[separator+1:] {
+		i := strings.IndexRune(bech32Charset, c)
+		if i < 0 {
+			return 0, nil, fmt.Errorf("invalid address character %c", c)
+		}
+		data = append(data, byte(i))
+	}
+	polymod := bech32Polymod(append(bech32HrpExpand(hrp), data...))
+	if len(data) < 7 {
+		return 0, nil, fmt.Errorf("address is too short")
+	}
+	version = data[0]
+	if (version == 0 && polymod != bech32Const) || (version != 0 && polymod != bech32mConst) {
+		return 0, nil, fmt.Errorf("invalid address checksum")
+	}
+	program, err = bech32.ConvertBits(data[1:len(data)-6], 5, 8, false)
+	if err != nil {
+		return 0, nil, err
+	}
+	if version > 16 || len(program) < 2 || len(program) > 40 {
+		return 0, nil, fmt.Errorf("invalid witness program")
+	}
+	if version == 0 && len(program) != 20 && len(program) != 32 {
+		return 0, nil, fmt.Errorf("invalid witness program length %d for version 0", len(program))
+	}
+	return version, program, nil
+}
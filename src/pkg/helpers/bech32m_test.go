@@ -0,0 +1,54 @@
+package helpers
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/btcsuite/btcutil/bech32"
+)
+
+func TestSegwitAddressRoundTrip(t *testing.T) {
+	// BIP173 and BIP350 test vectors
+	tests := []struct {
+		hrp     string
+		address string
+		version byte
+		program string
+	}{
+		{"bc", "bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4", 0, "751e76e8199196d454941c45d1b3a323f1433bd6"},
+		{"bc", "bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqzk5jj0", 1, "79be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798"},
+	}
+
+	for _, test := range tests {
+		version, program, err := DecodeSegwitAddress(test.hrp, test.address)
+		if err != nil {
+			t.Errorf("Test failed: input: %s unexpected error: %v", test.address, err)
+			continue
+		}
+		if version != test.version || hex.EncodeToString(program) != test.program {
+			t.Errorf("Test failed: input: %s expected: %d %s received: %d %x ", test.address, test.version, test.program, version, program)
+		}
+		address, _ := EncodeSegwitAddress(test.hrp, version, program)
+		if address != test.address {
+			t.Errorf("Test failed:  expected: %s received: %s ", test.address, address)
+		}
+	}
+}
+
+func TestDecodeSegwitAddressWrongChecksum(t *testing.T) {
+	// version 1 program with a bech32 instead of a bech32m checksum
+	program, _ := hex.DecodeString("79be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798")
+	converted, _ := bech32.ConvertBits(program, 8, 5, true)
+	data := append([]byte{1}, converted...)
+	data = append(data, bech32Checksum("bc", data, bech32Const)...)
+	address := "bc1"
+	for _, v := range data {
+		address += string(bech32Charset[v])
+	}
+	if _, _, err := DecodeSegwitAddress("bc", address); err == nil {
+		t.Errorf("Test failed: input: %s expected a checksum error", address)
+	}
+	if _, _, err := DecodeSegwitAddress("tb", "bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4"); err == nil {
+		t.Errorf("Test failed: expected a prefix error")
+	}
+}
@@ -0,0 +1,125 @@
+package helpers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/tyler-smith/go-bip32"
+)
+
+// DerivationPath is a BIP32 derivation path of any depth. Every element is a
+// child index, hardened children already carry the bip32.FirstHardenedChild offset.
+type DerivationPath []uint32
+
+// ParseDerivationPath parses an absolute path such as "m/48'/0'/0'/2'/0/5".
+// Hardened children may be marked with ', h or H and white spaces around the
+// separators are ignored. "m" on its own is the master key itself.
+func ParseDerivationPath(path string) (DerivationPath, error) {
+	spl := strings.Split(path, "/")
+	for i := range spl {
+		spl[i] = strings.TrimSpace(spl[i])
+	}
+	if spl[0] != "m" {
+		return nil, fmt.Errorf("Invalid path. must start with m, got %s", spl[0])
+	}
+	return parseChildIndexes(spl[1:])
+}
+
+// ParseRelativePath parses a path relative to an extended key such as "0/15".
+func ParseRelativePath(path string) (DerivationPath, error) {
+	spl := strings.Split(path, "/")
+	for i := range spl {
+		spl[i] = strings.TrimSpace(spl[i])
+	}
+	if spl[0] == "m" || spl[0] == "M" {
+		return nil, fmt.Errorf("relative path must not start with %s", spl[0])
+	}
+	return parseChildIndexes(spl)
+}
+
+// String formats the path the same way it is accepted, using ' as hardened marker.
+func (p DerivationPath) String() string {
+	var b strings.Builder
+	b.WriteString("m")
+	for _, index := range p {
+		b.WriteString("/")
+		b.WriteString(formatChildIndex(index))
+	}
+	return b.String()
+}
+
+// RelativeString formats the path without the leading m, as accepted by ParseRelativePath.
+func (p DerivationPath) RelativeString() string {
+	return strings.TrimPrefix(strings.TrimPrefix(p.String(), "m"), "/")
+}
+
+// IsHardened reports whether any step of the path is a hardened child.
+func (p DerivationPath) IsHardened() bool {
+	for _, index := range p {
+		if index >= bip32.FirstHardenedChild {
+			return true
+		}
+	}
+	return false
+}
+
+// Append returns a new path with the given child indexes added to the end.
+func (p DerivationPath) Append(indexes ...uint32) DerivationPath {
+	path := make(DerivationPath, 0, len(p)+len(indexes))
+	path = append(path, p...)
+	return append(path, indexes...)
+}
+
+// Path returns the BIP44 params as a generic derivation path.
+func (params *BIP44Params) Path() DerivationPath {
+	return DerivationPath{params.Purpose, params.CoinType, params.Account, params.Change, params.AddressIndex}
+}
+
+func parseChildIndexes(fields []string) (DerivationPath, error) {
+	path := make(DerivationPath, 0, len(fields))
+	for _, field := range fields {
+		index, err := hardenedInt(field)
+		if err != nil {
+			return nil, err
+		}
+		path = append(path, index)
+	}
+	return path, nil
+}
+
+func formatChildIndex(index uint32) string {
+	if index >= bip32.FirstHardenedChild {
+		return strconv.FormatUint(uint64(index-bip32.FirstHardenedChild), 10) + "'"
+	}
+	return strconv.FormatUint(uint64(index), 10)
+}
+
+func isHardened(field string) bool {
+	return strings.HasSuffix(field, "'") || strings.HasSuffix(field, "h") || strings.HasSuffix(field, "H")
+}
+
+func hardenedInt(field string) (uint32, error) {
+	hasSuffix := isHardened(field)
+	if hasSuffix {
+		field = field[:len(field)-1]
+	}
+	if field == "" {
+		return 0, fmt.Errorf("path contains an empty field")
+	}
+	i, err := strconv.ParseInt(field, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	if i < 0 {
+		return 0, fmt.Errorf("fields must not be negative. got %d", i)
+	}
+	if i >= int64(bip32.FirstHardenedChild) {
+		return 0, fmt.Errorf("fields must be less than %d. got %d", bip32.FirstHardenedChild, i)
+	}
+	if hasSuffix {
+		hardenedInt := bip32.FirstHardenedChild + uint32(i)
+		return hardenedInt, nil
+	}
+	return uint32(i), nil
+}
@@ -0,0 +1,102 @@
+package helpers
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/tyler-smith/go-bip32"
+)
+
+func TestParseDerivationPath(t *testing.T) {
+	tests := []struct {
+		path     string
+		expected string
+		depth    int
+	}{
+		{"m", "m", 0},
+		{"m/0'", "m/0'", 1},
+		{"m / 44' / 0' / 0' / 0 / 0", "m/44'/0'/0'/0/0", 5},
+		{"m/48'/0'/0'/2'/0/5", "m/48'/0'/0'/2'/0/5", 6},
+		{"m/86h/0h/0h", "m/86'/0'/0'", 3},
+		{"m/84H/1H/0H/1/7", "m/84'/1'/0'/1/7", 5},
+	}
+
+	for _, test := range tests {
+		result, err := ParseDerivationPath(test.path)
+		if err != nil {
+			t.Errorf("Test failed: input: %s unexpected error: %v", test.path, err)
+			continue
+		}
+		if len(result) != test.depth {
+			t.Errorf("Test failed: input: %s expected depth: %d received: %d ", test.path, test.depth, len(result))
+		}
+		if result.String() != test.expected {
+			t.Errorf("Test failed: input: %s expected: %s received: %s ", test.path, test.expected, result.String())
+		}
+	}
+}
+
+func TestParseDerivationPathInvalid(t *testing.T) {
+	paths := []string{"", "44'/0'", "m/", "m/x'", "m/-1", "m/2147483648", "m/0''"}
+
+	for _, path := range paths {
+		if _, err := ParseDerivationPath(path); err == nil {
+			t.Errorf("Test failed: input: %s expected an error", path)
+		}
+	}
+}
+
+func TestDeriveExtendedKeysFromPath(t *testing.T) {
+	var walletHelper WalletHelper = NewWalletHelper()
+	// BIP32 test vector 1
+	decodeSeed, _ := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+	master, _ := bip32.NewMasterKey(decodeSeed)
+
+	tests := []struct {
+		path           string
+		expectedPrvKey string
+		expectedPubKey string
+	}{
+		{
+			"m/0H",
+			"xprv9uHRZZhk6KAJC1avXpDAp4MDc3sQKNxDiPvvkX8Br5ngLNv1TxvUxt4cV1rGL5hj6KCesnDYUhd7oWgT11eZG7XnxHrnYeSvkzY7d2bhkJ7",
+			"xpub68Gmy5EdvgibQVfPdqkBBCHxA5htiqg55crXYuXoQRKfDBFA1WEjWgP6LHhwBZeNK1VTsfTFUHCdrfp1bgwQ9xv5ski8PX9rL2dZXvgGDnw",
+		},
+		{
+			"m/0H/1/2H/2/1000000000",
+			"xprvA41z7zogVVwxVSgdKUHDy1SKmdb533PjDz7J6N6mV6uS3ze1ai8FHa8kmHScGpWmj4WggLyQjgPie1rFSruoUihUZREPSL39UNdE3BBDu76",
+			"xpub6H1LXWLaKsWFhvm6RVpEL9P4KfRZSW7abD2ttkWP3SSQvnyA8FSVqNTEcYFgJS2UaFcxupHiYkro49S8yGasTvXEYBVPamhGW6cFJodrTHy",
+		},
+	}
+
+	for _, test := range tests {
+		path, _ := walletHelper.DerivePath(test.path)
+		xPrvKey, xPubKey, _ := walletHelper.DeriveExtendedKeysFromPath(master, path)
+
+		if xPrvKey.String() != test.expectedPrvKey {
+			t.Errorf("Test failed: input: %s expected: %s received: %s ", test.path, test.expectedPrvKey, xPrvKey.String())
+		}
+		if xPubKey.String() != test.expectedPubKey {
+			t.Errorf("Test failed: input: %s expected: %s received: %s ", test.path, test.expectedPubKey, xPubKey.String())
+		}
+	}
+}
+
+func TestParseRelativePath(t *testing.T) {
+	result, err := ParseRelativePath("0 / 15")
+	if err != nil {
+		t.Fatalf("Test failed: unexpected error: %v", err)
+	}
+	if result.RelativeString() != "0/15" || result.IsHardened() {
+		t.Errorf("Test failed: expected: %s received: %s ", "0/15", result.RelativeString())
+	}
+
+	result, _ = ParseRelativePath("1h/2")
+	if !result.IsHardened() {
+		t.Errorf("Test failed: input: 1h/2 expected a hardened path")
+	}
+
+	if _, err := ParseRelativePath("m/0/1"); err == nil {
+		t.Errorf("Test failed: input: m/0/1 expected an error")
+	}
+}
@@ -0,0 +1,152 @@
+package helpers
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcutil"
+	"github.com/tyler-smith/go-bip32"
+)
+
+// ErrInvalidDescriptor is returned for an output descriptor that can not be
+// checksummed or parsed.
+var ErrInvalidDescriptor = errors.New("invalid descriptor")
+
+// descriptorInputCharset are the characters allowed in a descriptor, in the
+// order of the BIP380 checksum symbols.
+const descriptorInputCharset = "0123456789()[],'/*abcdefgh@:$%{}" +
+	"IJKLMNOPQRSTUVWXYZ&+-.;<=>?!^_|~" +
+	"ijklmnopqrstuvwxyzABCDEFGH`#\"\\ "
+
+const descriptorChecksumCharset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+
+// descriptorTemplates wrap a key expression in the script of an address type.
+var descriptorTemplates = map[AddressType]string{
+	AddressTypeP2PKH:      "pkh(%s)",
+	AddressTypeP2SHP2WPKH: "sh(wpkh(%s))",
+	AddressTypeP2WPKH:     "wpkh(%s)",
+	AddressTypeP2TR:       "tr(%s)",
+}
+
+// KeyOrigin is the [fingerprint/path] of a descriptor key, the fingerprint of
+// the master key and the path from the master key to the key.
+type KeyOrigin struct {
+	Fingerprint []byte
+	Path        DerivationPath
+}
+
+// String formats the origin as it is written in front of a descriptor key.
+func (o KeyOrigin) String() string {
+	path := o.Path.RelativeString()
+	if path != "" {
+		path = "/" + path
+	}
+	return "[" + hex.EncodeToString(o.Fingerprint) + path + "]"
+}
+
+// AccountDescriptors are the receive, change and BIP389 multipath descriptors
+// of an account, with their checksum.
+type AccountDescriptors struct {
+	Receive   string `json:"receive"`
+	Change    string `json:"change"`
+	Multipath string `json:"multipath"`
+}
+
+func descriptorPolymod(c uint64, val uint64) uint64 {
+	c0 := c >> 35
+	c = ((c & 0x7ffffffff) << 5) ^ val
+	generators := [5]uint64{0xf5dee51989, 0xa9fdca3312, 0x1bab10e32d, 0x3706b1677a, 0x644d626ffd}
+	for i, generator := range generators {
+		if (c0>>uint(i))&1 == 1 {
+			c ^= generator
+		}
+	}
+	return c
+}
+
+// DescriptorChecksum computes the BIP380 checksum of a descriptor without
+// its #checksum suffix.
+func DescriptorChecksum(descriptor string) (string, error) {
+	c := uint64(1)
+	cls, clsCount := uint64(0), 0
+	for _, ch := range descriptor {
+		pos := strings.IndexRune(descriptorInputCharset, ch)
+		if pos < 0 {
+			return "", fmt.Errorf("%w: character %q is not allowed", ErrInvalidDescriptor, ch)
+		}
+		c = descriptorPolymod(c, uint64(pos&31))
+		cls = cls*3 + uint64(pos>>5)
+		clsCount++
+		if clsCount == 3 {
+			c = descriptorPolymod(c, cls)
+			cls, clsCount = 0, 0
+		}
+	}
+	if clsCount > 0 {
+		c = descriptorPolymod(c, cls)
+	}
+	for i := 0; i < 8; i++ {
+		c = descriptorPolymod(c, 0)
+	}
+	c ^= 1
+
+	checksum := make([]byte, 8)
+	for i := range checksum {
+		checksum[i] = descriptorChecksumCharset[(c>>(5*(7-uint(i))))&31]
+	}
+	return string(checksum), nil
+}
+
+// AddDescriptorChecksum appends #checksum to a descriptor.
+func AddDescriptorChecksum(descriptor string) (string, error) {
+	checksum, err := DescriptorChecksum(descriptor)
+	if err != nil {
+		return "", err
+	}
+	return descriptor + "#" + checksum, nil
+}
+
+// KeyFingerprint is the first 4 bytes of the hash160 of the public key of an
+// extended key, as used in key origins and child keys.
+func KeyFingerprint(key *bip32.Key) []byte {
+	pubKey := key.Key
+	if key.IsPrivate {
+		_, public := btcec.PrivKeyFromBytes(btcec.S256(), key.Key)
+		pubKey = public.SerializeCompressed()
+	}
+	return btcutil.Hash160(pubKey)[:4]
+}
+
+// accountDescriptors builds the descriptors of the address type for an
+// account key, serialized as xpub or xprv whatever the address type.
+func accountDescriptors(key *bip32.Key, origin KeyOrigin, net *chaincfg.Params, addressType AddressType) (*AccountDescriptors, error) {
+	template, ok := descriptorTemplates[addressType]
+	if !ok {
+		return nil, fmt.Errorf("no descriptor for address type %s", addressType)
+	}
+	keyExpression := origin.String() + encodeExtendedKey(key, net)
+	descriptors := &AccountDescriptors{}
+	for _, d := range []struct {
+		descriptor *string
+		steps      string
+	}{
+		{&descriptors.Receive, "/0/*"},
+		{&descriptors.Change, "/1/*"},
+		{&descriptors.Multipath, "/<0;1>/*"},
+	} {
+		descriptor, err := AddDescriptorChecksum(fmt.Sprintf(template, keyExpression+d.steps))
+		if err != nil {
+			return nil, err
+		}
+		*d.descriptor = descriptor
+	}
+	return descriptors, nil
+}
+
+func (wh *walletHelper) DeriveAccountDescriptors(key *bip32.Key, origin KeyOrigin, net *chaincfg.Params, addressType AddressType) (*AccountDescriptors, error) {
+	return accountDescriptors(key, origin, net, addressType)
+}
@@ -0,0 +1,29 @@
+package helpers
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestDescriptorChecksum(t *testing.T) {
+	// BIP380 test vectors
+	tests := []struct {
+		descriptor string
+		expected   string
+	}{
+		{"raw(deadbeef)", "89f8spxm"},
+		{"pkh([d34db33f/44'/0'/0']xpub6ERApfZwUNrhLCkDtcHTcxd75RbzS1ed54G1LkBUHQVHQKqhMkhgbmJbZRkrgZw4koxb5JaHWkY4ALHY2grBGRjaDMzQLcgJvLJuZZvRcEL/1/*)", "ml40v0wf"},
+	}
+	for _, test := range tests {
+		checksum, err := DescriptorChecksum(test.descriptor)
+		if err != nil {
+			t.Fatalf("Test failed: unexpected error: %v", err)
+		}
+		if checksum != test.expected {
+			t.Errorf("Test failed:  expected: %s received: %s ", test.expected, checksum)
+		}
+	}
+	if _, err := DescriptorChecksum("raw(deadbeef)\n"); !errors.Is(err, ErrInvalidDescriptor) {
+		t.Errorf("Test failed:  expected: %v received: %v ", ErrInvalidDescriptor, err)
+	}
+}
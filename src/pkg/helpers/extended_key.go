@@ -0,0 +1,82 @@
+package helpers
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/tyler-smith/go-bip32"
+)
+
+// extendedKeyVersion describes one of the BIP32 / SLIP-132 serialization versions.
+type extendedKeyVersion struct {
+	prefix  string
+	version [4]byte
+	private bool
+	mainnet bool
+}
+
+var extendedKeyVersions = []extendedKeyVersion{
+	{"xprv", [4]byte{0x04, 0x88, 0xad, 0xe4}, true, true},
+	{"xpub", [4]byte{0x04, 0x88, 0xb2, 0x1e}, false, true},
+	{"yprv", [4]byte{0x04, 0x9d, 0x78, 0x78}, true, true},
+	{"ypub", [4]byte{0x04, 0x9d, 0x7c, 0xb2}, false, true},
+	{"zprv", [4]byte{0x04, 0xb2, 0x43, 0x0c}, true, true},
+	{"zpub", [4]byte{0x04, 0xb2, 0x47, 0x46}, false, true},
+	{"Yprv", [4]byte{0x02, 0x95, 0xb0, 0x05}, true, true},
+	{"Ypub", [4]byte{0x02, 0x95, 0xb4, 0x3f}, false, true},
+	{"Zprv", [4]byte{0x02, 0xaa, 0x7a, 0x99}, true, true},
+	{"Zpub", [4]byte{0x02, 0xaa, 0x7e, 0xd3}, false, true},
+	{"tprv", [4]byte{0x04, 0x35, 0x83, 0x94}, true, false},
+	{"tpub", [4]byte{0x04, 0x35, 0x87, 0xcf}, false, false},
+	{"uprv", [4]byte{0x04, 0x4a, 0x4e, 0x28}, true, false},
+	{"upub", [4]byte{0x04, 0x4a, 0x52, 0x62}, false, false},
+	{"vprv", [4]byte{0x04, 0x5f, 0x18, 0xbc}, true, false},
+	{"vpub", [4]byte{0x04, 0x5f, 0x1c, 0xf6}, false, false},
+	{"Uprv", [4]byte{0x02, 0x42, 0x85, 0xb5}, true, false},
+	{"Upub", [4]byte{0x02, 0x42, 0x89, 0xef}, false, false},
+	{"Vprv", [4]byte{0x02, 0x57, 0x50, 0x48}, true, false},
+	{"Vpub", [4]byte{0x02, 0x57, 0x54, 0x83}, false, false},
+}
+
+func lookupExtendedKeyVersion(version []byte) (*extendedKeyVersion, error) {
+	for i := range extendedKeyVersions {
+		if bytes.Equal(extendedKeyVersions[i].version[:], version) {
+			return &extendedKeyVersions[i], nil
+		}
+	}
+	return nil, fmt.Errorf("unknown extended key version %x", version)
+}
+
+// DecodeExtendedKey parses any supported private or public extended key
+// serialization of the network.
+func DecodeExtendedKey(key string, net *chaincfg.Params) (*bip32.Key, error) {
+	decoded, _, err := decodeExtendedKey(key, net)
+	return decoded, err
+}
+
+// decodeExtendedKey parses any supported extended key serialization and checks
+// that it belongs to the network.
+func decodeExtendedKey(key string, net *chaincfg.Params) (*bip32.Key, *extendedKeyVersion, error) {
+	decoded, err := bip32.B58Deserialize(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	version, err := lookupExtendedKeyVersion(decoded.Version)
+	if err != nil {
+		return nil, nil, err
+	}
+	if version.private != decoded.IsPrivate {
+		return nil, nil, fmt.Errorf("extended key version %s does not match the key data", version.prefix)
+	}
+	if version.mainnet != (net.Name == chaincfg.MainNetParams.Name) {
+		return nil, nil, fmt.Errorf("%w: extended key %s is not for network %s", ErrKeyNetworkMismatch, version.prefix, net.Name)
+	}
+	if !decoded.IsPrivate {
+		if _, err := btcec.ParsePubKey(decoded.Key, btcec.S256()); err != nil {
+			return nil, nil, err
+		}
+	}
+	return decoded, version, nil
+}
@@ -0,0 +1,69 @@
+package helpers
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcutil"
+)
+
+// ResolvePublicKey resolves a key input to the serialized public key it
+// stands for. A key input is a compressed or uncompressed hex public key, a
+// WIF, or an extended public or private key followed by a relative path such
+// as xpub.../0/5. Hex keys keep their serialization, WIFs follow their
+// compression flag and extended keys are compressed.
+func ResolvePublicKey(key string, net *chaincfg.Params) ([]byte, error) {
+	key = strings.TrimSpace(key)
+	if decoded, err := hex.DecodeString(key); err == nil {
+		if _, err := btcec.ParsePubKey(decoded, btcec.S256()); err != nil {
+			return nil, fmt.Errorf("%w: public key %s: %v", ErrInvalidKeyInput, key, err)
+		}
+		return decoded, nil
+	}
+	if wif, err := btcutil.DecodeWIF(key); err == nil {
+		if !wif.IsForNet(net) {
+			return nil, fmt.Errorf("%w: wif is not for network %s", ErrKeyNetworkMismatch, net.Name)
+		}
+		return wif.SerializePubKey(), nil
+	}
+
+	fields := strings.SplitN(key, "/", 2)
+	extKey, err := DecodeExtendedKey(fields[0], net)
+	if errors.Is(err, ErrKeyNetworkMismatch) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidKeyInput, err)
+	}
+	if len(fields) == 2 {
+		path, err := ParseRelativePath(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("%w: path of %s: %v", ErrInvalidKeyInput, fields[0], err)
+		}
+		if !extKey.IsPrivate && path.IsHardened() {
+			return nil, fmt.Errorf("%w: hardened path from extended public key %s", ErrInvalidKeyInput, fields[0])
+		}
+		for _, index := range path {
+			if extKey, err = extKey.NewChildKey(index); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return extKey.PublicKey().Key, nil
+}
+
+func (wh *walletHelper) ResolvePublicKeys(keys []string, net *chaincfg.Params) ([][]byte, error) {
+	publicKeys := make([][]byte, len(keys))
+	for i, key := range keys {
+		publicKey, err := ResolvePublicKey(key, net)
+		if err != nil {
+			return nil, fmt.Errorf("key %d: %w", i, err)
+		}
+		publicKeys[i] = publicKey
+	}
+	return publicKeys, nil
+}
@@ -0,0 +1,40 @@
+package helpers
+
+import (
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg"
+)
+
+func TestResolvePublicKey(t *testing.T) {
+	// BIP32 test vector 1, m/0H/1 is the child at /1 of the m/0H keys
+	xprv := "xprv9uHRZZhk6KAJC1avXpDAp4MDc3sQKNxDiPvvkX8Br5ngLNv1TxvUxt4cV1rGL5hj6KCesnDYUhd7oWgT11eZG7XnxHrnYeSvkzY7d2bhkJ7"
+	xpub := "xpub68Gmy5EdvgibQVfPdqkBBCHxA5htiqg55crXYuXoQRKfDBFA1WEjWgP6LHhwBZeNK1VTsfTFUHCdrfp1bgwQ9xv5ski8PX9rL2dZXvgGDnw"
+	expected := "03501e454bf00751f24b1b489aa925215d66af2234e3891c3b21a52bedb3cd711c"
+	tests := []struct {
+		key      string
+		expected string
+		err      error
+	}{
+		{xprv + "/1", expected, nil},
+		{xpub + "/1", expected, nil},
+		{expected, expected, nil},
+		{"cVgxEkRBtnfvd41ssd4PCsiemahAHidFrLWYoDBMNojUeME8dojZ", "", ErrKeyNetworkMismatch},
+		{"tpubD6NzVbkrYhZ4XgiXtGrdW5XDAPFCL9h7we1vwNCpn8tGbBcgfVYjXyhWo4E1xkh56hjod1RhGjxbaTLV3X4FyWuejifB9jusQ46QzG87VKp", "", ErrKeyNetworkMismatch},
+		{xpub + "/1'", "", ErrInvalidKeyInput},
+		{xpub + "/x", "", ErrInvalidKeyInput},
+		{"03501e45", "", ErrInvalidKeyInput},
+	}
+
+	for _, test := range tests {
+		publicKey, err := ResolvePublicKey(test.key, &chaincfg.MainNetParams)
+		if !errors.Is(err, test.err) {
+			t.Fatalf("Test failed:  expected: %v received: %v ", test.err, err)
+		}
+		if err == nil && hex.EncodeToString(publicKey) != test.expected {
+			t.Errorf("Test failed:  expected: %s received: %x ", test.expected, publicKey)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package helpers
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/text/unicode/norm"
+)
+
+// ErrInvalidMnemonic is wrapped by every mnemonic validation error.
+var ErrInvalidMnemonic = errors.New("invalid mnemonic")
+
+// NormalizeMnemonic applies the BIP39 NFKD normalization and collapses any
+// run of white spaces into a single space.
+func NormalizeMnemonic(mnemonic string) string {
+	return strings.Join(strings.Fields(norm.NFKD.String(mnemonic)), " ")
+}
+
+// NewMnemonic encodes 128 to 256 bits of entropy as a mnemonic of the word
+// list of the language, english when the language is empty.
+func NewMnemonic(entropy []byte, language Language) (string, error) {
+	list, err := lookupWordList(language)
+	if err != nil {
+		return "", err
+	}
+	if len(entropy) < 16 || len(entropy) > 32 || len(entropy)%4 != 0 {
+		return "", fmt.Errorf("entropy must be 16, 20, 24, 28 or 32 bytes. got %d", len(entropy))
+	}
+	hash := sha256.Sum256(entropy)
+	bits := make([]byte, 0, len(entropy)*8+len(entropy)/4)
+	for _, b := range entropy {
+		for i := 7; i >= 0; i-- {
+			bits = append(bits, b>>uint(i)&1)
+		}
+	}
+	for i := 0; i < len(entropy)/4; i++ {
+		bits = append(bits, hash[0]>>uint(7-i)&1)
+	}
+	words := make([]string, len(bits)/11)
+	for i := range words {
+		index := 0
+		for _, bit := range bits[i*11 : i*11+11] {
+			index = index<<1 | int(bit)
+		}
+		words[i] = list.words[index]
+	}
+	if language == "" {
+		language = LanguageEnglish
+	}
+	return strings.Join(words, language.separator()), nil
+}
+
+// ValidateMnemonic checks the length, the word list membership and the
+// checksum of a mnemonic. An empty language detects the word list.
+func ValidateMnemonic(mnemonic string, language Language) error {
+	_, err := resolveMnemonicLanguage(mnemonic, language)
+	return err
+}
+
+// MnemonicToSeed validates a mnemonic and derives its BIP39 seed for the
+// passphrase. It returns the language of the mnemonic, which is detected when
+// the language is empty.
+func MnemonicToSeed(mnemonic string, passphrase string, language Language) ([]byte, Language, error) {
+	language, err := resolveMnemonicLanguage(mnemonic, language)
+	if err != nil {
+		return nil, "", err
+	}
+	return mnemonicSeed(NormalizeMnemonic(mnemonic), passphrase), language, nil
+}
+
+func resolveMnemonicLanguage(mnemonic string, language Language) (Language, error) {
+	if language == "" {
+		detected, err := DetectMnemonicLanguage(mnemonic)
+		if err != nil {
+			return "", err
+		}
+		language = detected
+	}
+	if _, err := mnemonicEntropy(strings.Fields(NormalizeMnemonic(mnemonic)), language); err != nil {
+		return "", err
+	}
+	return language, nil
+}
+
+// mnemonicSeed runs the BIP39 PBKDF2 key stretching without any validation.
+func mnemonicSeed(mnemonic string, passphrase string) []byte {
+	salt := "mnemonic" + norm.NFKD.String(passphrase)
+	return pbkdf2.Key([]byte(mnemonic), []byte(salt), 2048, 64, sha512.New)
+}
+
+// mnemonicEntropy maps the words back to their entropy and verifies the checksum.
+func mnemonicEntropy(words []string, language Language) ([]byte, error) {
+	list, err := lookupWordList(language)
+	if err != nil {
+		return nil, err
+	}
+	if len(words) < 12 || len(words) > 24 || len(words)%3 != 0 {
+		return nil, fmt.Errorf("%w: word count must be one of 12, 15, 18, 21 or 24. got %d", ErrInvalidMnemonic, len(words))
+	}
+	indexes := make([]int, len(words))
+	for i, word := range words {
+		index, ok := list.indexes[word]
+		if !ok {
+			return nil, fmt.Errorf("%w: word %d %q is not in the %s word list", ErrInvalidMnemonic, i+1, word, language)
+		}
+		indexes[i] = index
+	}
+	entropy, ok := entropyFromIndexes(indexes)
+	if !ok {
+		return nil, fmt.Errorf("%w: checksum mismatch", ErrInvalidMnemonic)
+	}
+	return entropy, nil
+}
+
+// entropyFromIndexes packs the 11 bit word indexes and splits them into the
+// entropy and its checksum, which is the first len(entropy)/4 bits of its sha256.
+func entropyFromIndexes(indexes []int) ([]byte, bool) {
+	bits := make([]byte, 0, len(indexes)*11)
+	for _, index := range indexes {
+		for i := 10; i >= 0; i-- {
+			bits = append(bits, byte(index>>uint(i)&1))
+		}
+	}
+	checksumBits := len(bits) / 33
+	entropy := make([]byte, (len(bits)-checksumBits)/8)
+	for i := range entropy {
+		for j := 0; j < 8; j++ {
+			entropy[i] = entropy[i]<<1 | bits[i*8+j]
+		}
+	}
+	hash := sha256.Sum256(entropy)
+	for i := 0; i < checksumBits; i++ {
+		if hash[0]>>uint(7-i)&1 != bits[len(entropy)*8+i] {
+			return nil, false
+		}
+	}
+	return entropy, true
+}
+
+// ValidMnemonicIndexes tells whether word indexes of a valid length carry a
+// valid checksum.
+func ValidMnemonicIndexes(indexes []int) bool {
+	if len(indexes) < 12 || len(indexes) > 24 || len(indexes)%3 != 0 {
+		return false
+	}
+	_, ok := entropyFromIndexes(indexes)
+	return ok
+}
@@ -0,0 +1,52 @@
+package helpers
+
+import (
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"github.com/tyler-smith/go-bip39"
+)
+
+func TestMnemonicToSeed(t *testing.T) {
+	var walletHelper WalletHelper = NewWalletHelper()
+	var mnemonic string = "  abandon abandon abandon abandon abandon abandon\tabandon abandon abandon abandon abandon   about \n"
+	var expectedSeed string = "5eb00bbddcf069084889a8ab9155568165f5c453ccb85e70811aaed6f6da5fc19a5ac40b389cd370d086206dec8aa6c43daea6690f20ad3d8d48b2d2ce9e38e4"
+
+	seed, language, err := walletHelper.DeriveSeedFromMnemonic(mnemonic, "", "")
+	if err != nil {
+		t.Fatalf("Test failed: unexpected error: %v", err)
+	}
+	if hex.EncodeToString(seed) != expectedSeed {
+		t.Errorf("Test failed:  expected: %s received: %x ", expectedSeed, seed)
+	}
+	if language != LanguageEnglish {
+		t.Errorf("Test failed:  expected: %s received: %s ", LanguageEnglish, language)
+	}
+
+	normalized := NormalizeMnemonic(mnemonic)
+	seed, _, _ = walletHelper.DeriveSeedFromMnemonic(mnemonic, "TREZOR", LanguageEnglish)
+	if expected := bip39.NewSeed(normalized, "TREZOR"); hex.EncodeToString(seed) != hex.EncodeToString(expected) {
+		t.Errorf("Test failed:  expected: %x received: %x ", expected, seed)
+	}
+}
+
+func TestValidateMnemonic(t *testing.T) {
+	mnemonics := []string{
+		// wrong length
+		"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about",
+		// unknown word
+		"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandonn about",
+		// checksum mismatch
+		"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon",
+	}
+
+	for _, mnemonic := range mnemonics {
+		if err := ValidateMnemonic(mnemonic, LanguageEnglish); !errors.Is(err, ErrInvalidMnemonic) {
+			t.Errorf("Test failed: input: %s expected: %v received: %v ", mnemonic, ErrInvalidMnemonic, err)
+		}
+	}
+	if err := ValidateMnemonic("legal winner thank year wave sausage worth useful legal winner thank yellow", ""); err != nil {
+		t.Errorf("Test failed: unexpected error: %v", err)
+	}
+}
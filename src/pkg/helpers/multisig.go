@@ -0,0 +1,183 @@
+package helpers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcutil"
+)
+
+// SortPublicKeys orders serialized public keys as BIP67 requires, so every
+// cosigner builds the same redeem script whatever order the keys were given
+// in. The input slice is left untouched.
+func SortPublicKeys(publicKeys [][]byte) [][]byte {
+	sorted := make([][]byte, len(publicKeys))
+	copy(sorted, publicKeys)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return bytes.Compare(sorted[i], sorted[j]) < 0
+	})
+	return sorted
+}
+
+// SerializePublicKeys serializes public keys in their compressed form.
+func SerializePublicKeys(publicKeys []*btcec.PublicKey) [][]byte {
+	serialized := make([][]byte, len(publicKeys))
+	for i, publicKey := range publicKeys {
+		serialized[i] = publicKey.SerializeCompressed()
+	}
+	return serialized
+}
+
+// MultisigType is the kind of output a multisig script is paid to.
+type MultisigType string
+
+const (
+	MultisigTypeP2SH      MultisigType = "p2sh"
+	MultisigTypeP2WSH     MultisigType = "p2wsh"
+	MultisigTypeP2SHP2WSH MultisigType = "p2sh-p2wsh"
+	// MultisigTypeP2TR is a Taproot output with a MuSig2 key path for all n
+	// keys and a multi_a tapscript leaf for m of them.
+	MultisigTypeP2TR MultisigType = "p2tr"
+)
+
+// MultisignatureScript builds the m of n CHECKMULTISIG script of the
+// serialized keys, in the order they are given. m and n up to 16 are the
+// OP_1 to OP_16 opcodes, larger ones a one byte push.
+func MultisignatureScript(n int, m int, publicKeys [][]byte) ([]byte, error) {
+	builder := txscript.NewScriptBuilder()
+	builder.AddInt64(int64(m))
+	for _, publicKey := range publicKeys {
+		builder.AddData(publicKey)
+	}
+	builder.AddInt64(int64(n))
+	builder.AddOp(txscript.OP_CHECKMULTISIG)
+	return builder.Script()
+}
+
+// MultisignatureScriptSize is the size of an m of n CHECKMULTISIG script of
+// keys of keySize bytes, without the keys when keySize is 0.
+func MultisignatureScriptSize(n int, m int, keySize int) int {
+	size := 1
+	for _, number := range []int{m, n} {
+		size++
+		if number > 16 {
+			size++
+		}
+	}
+	if keySize > 0 {
+		size += n * (1 + keySize)
+	}
+	return size
+}
+
+// ErrInvalidMultisigScript is returned for a script that is not a standard m
+// of n CHECKMULTISIG script.
+var ErrInvalidMultisigScript = errors.New("invalid multisig script")
+
+// DecodeMultisignatureScript reads the required signatures and the serialized
+// public keys, in script order, from an m of n CHECKMULTISIG script.
+func DecodeMultisignatureScript(script []byte) (required int, publicKeys [][]byte, err error) {
+	// txscript only classifies scripts of up to 16 keys as multisig
+	required, rest, ok := readMultisigNumber(script)
+	for ok && len(rest) > 0 && (rest[0] == txscript.OP_DATA_33 || rest[0] == txscript.OP_DATA_65) && len(rest) > int(rest[0]) {
+		publicKeys = append(publicKeys, rest[1:1+rest[0]])
+		rest = rest[1+rest[0]:]
+	}
+	total, rest, totalOk := readMultisigNumber(rest)
+	if !ok || !totalOk || len(rest) != 1 || rest[0] != txscript.OP_CHECKMULTISIG || total != len(publicKeys) {
+		return 0, nil, fmt.Errorf("%w: script is not a bare m of n CHECKMULTISIG script", ErrInvalidMultisigScript)
+	}
+	if required < 1 || required > total {
+		return 0, nil, fmt.Errorf("%w: %d of %d signatures can not be satisfied", ErrInvalidMultisigScript, required, total)
+	}
+	for i, publicKey := range publicKeys {
+		if _, err := btcec.ParsePubKey(publicKey, btcec.S256()); err != nil {
+			return 0, nil, fmt.Errorf("%w: key %d: %v", ErrInvalidMultisigScript, i, err)
+		}
+	}
+	return required, publicKeys, nil
+}
+
+// readMultisigNumber reads the m or n at the start of a multisig script, an
+// OP_1 to OP_16 opcode or the one byte push of 17 to MaxMultisigKeys.
+func readMultisigNumber(script []byte) (int, []byte, bool) {
+	switch {
+	case len(script) > 0 && script[0] >= txscript.OP_1 && script[0] <= txscript.OP_16:
+		return int(script[0]-txscript.OP_1) + 1, script[1:], true
+	case len(script) > 1 && script[0] == txscript.OP_DATA_1 && script[1] > 16 && script[1] <= MaxMultisigKeys:
+		return int(script[1]), script[2:], true
+	}
+	return 0, script, false
+}
+
+// MultisignatureScriptHash is the hash of a multisig script an output of the
+// multisig type commits to, HASH160 for P2SH and SHA256 for the P2WSH types.
+func MultisignatureScriptHash(script []byte, multisigType MultisigType) []byte {
+	if multisigType == MultisigTypeP2SH || multisigType == "" {
+		return btcutil.Hash160(script)
+	}
+	scriptHash := sha256.Sum256(script)
+	return scriptHash[:]
+}
+
+// MultisignatureRedeemScript is the script revealed in the P2SH input: the
+// multisig script itself for P2SH and the witness program of the script for
+// P2SH-P2WSH. Native P2WSH outputs have no redeem script.
+func MultisignatureRedeemScript(script []byte, multisigType MultisigType) ([]byte, error) {
+	switch multisigType {
+	case MultisigTypeP2SH, "":
+		return script, nil
+	case MultisigTypeP2SHP2WSH:
+		scriptHash := sha256.Sum256(script)
+		return txscript.NewScriptBuilder().AddOp(txscript.OP_0).AddData(scriptHash[:]).Script()
+	}
+	return nil, nil
+}
+
+// MultisignatureScriptAddress pays to a multisig script, as the redeem script
+// of a P2SH output, the witness script of a P2WSH output or the witness script
+// of a P2WSH output nested in P2SH.
+func MultisignatureScriptAddress(script []byte, net *chaincfg.Params, multisigType MultisigType) (string, error) {
+	var address btcutil.Address
+	var err error
+	switch multisigType {
+	case MultisigTypeP2SH, "":
+		address, err = btcutil.NewAddressScriptHash(script, net)
+	case MultisigTypeP2WSH:
+		scriptHash := sha256.Sum256(script)
+		address, err = btcutil.NewAddressWitnessScriptHash(scriptHash[:], net)
+	case MultisigTypeP2SHP2WSH:
+		witnessProgram, err := MultisignatureRedeemScript(script, multisigType)
+		if err != nil {
+			return "", err
+		}
+		address, err = btcutil.NewAddressScriptHash(witnessProgram, net)
+		if err != nil {
+			return "", err
+		}
+	default:
+		return "", fmt.Errorf("%w %s", ErrMultisigType, multisigType)
+	}
+	if err != nil {
+		return "", err
+	}
+	return address.EncodeAddress(), nil
+}
+
+func (wh *walletHelper) GenerateMultisignatureAddress(n int, m int, publicKeys [][]byte, net *chaincfg.Params, multisigType MultisigType) (address string, script []byte, err error) {
+	script, err = MultisignatureScript(n, m, publicKeys)
+	if err != nil {
+		return "", nil, err
+	}
+	address, err = MultisignatureScriptAddress(script, net, multisigType)
+	if err != nil {
+		return "", nil, err
+	}
+	return address, script, nil
+}
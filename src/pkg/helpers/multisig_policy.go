@@ -0,0 +1,109 @@
+package helpers
+
+import (
+	"fmt"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/txscript"
+)
+
+// InputError is a rejected key or multisig policy. Code is a stable machine
+// readable identifier and Field names the input at fault, so clients can
+// tell the errors of the catalog below apart without parsing messages.
+type InputError struct {
+	Code    string
+	Field   string
+	Message string
+}
+
+func (e *InputError) Error() string {
+	return e.Message
+}
+
+// The catalog of input errors, they are wrapped with the details of the
+// rejected input.
+var (
+	// ErrInvalidKeyInput is returned for a key that is neither a hex public
+	// key, a WIF nor an extended key with a derivation path.
+	ErrInvalidKeyInput = &InputError{Code: "invalid_key", Field: "keys", Message: "invalid key"}
+	// ErrKeyNetworkMismatch is returned for a WIF or an extended key of
+	// another network than the selected one.
+	ErrKeyNetworkMismatch = &InputError{Code: "network_mismatch", Field: "keys", Message: "key is not for the selected network"}
+
+	ErrMultisigTotal          = &InputError{Code: "invalid_total", Field: "n", Message: "n is out of range"}
+	ErrMultisigRequired       = &InputError{Code: "invalid_required", Field: "m", Message: "m is out of range"}
+	ErrMultisigRequiredOverN  = &InputError{Code: "required_exceeds_total", Field: "m", Message: "m must not exceed n"}
+	ErrMultisigKeyCount       = &InputError{Code: "key_count_mismatch", Field: "keys", Message: "the number of keys must equal n"}
+	ErrMultisigDuplicateKey   = &InputError{Code: "duplicate_key", Field: "keys", Message: "keys must be distinct"}
+	ErrMultisigUncompressed   = &InputError{Code: "uncompressed_key", Field: "keys", Message: "segwit scripts need compressed keys"}
+	ErrMultisigScriptTooLarge = &InputError{Code: "script_too_large", Field: "keys", Message: "redeem script exceeds the 520 byte push limit of P2SH"}
+	ErrMultisigType           = &InputError{Code: "unknown_multisig_type", Field: "multisigType", Message: "unknown multisig type"}
+	ErrMultisigTooManyLeaves  = &InputError{Code: "too_many_leaves", Field: "subsetLeaves", Message: "too many m of n subsets for a leaf each"}
+)
+
+// MaxMultisigKeys is the largest n of a CHECKMULTISIG script, the limit of
+// multisig addresses, multi() descriptors and miniscript alike. P2WSH scripts
+// can use all of them, a P2SH redeem script is also bound by the 520 byte
+// push limit, which leaves room for 15 compressed keys.
+const MaxMultisigKeys = 20
+
+// MaxTaprootMultisigKeys is the largest n of a multi_a tapscript leaf.
+const MaxTaprootMultisigKeys = 999
+
+// MaxTaprootSubsetLeaves is the largest number of m of m leaves, one for
+// every subset of m keys, a Taproot multisig tree is built with.
+const MaxTaprootSubsetLeaves = 1000
+
+// ValidateMultisigPolicy checks an m of n policy over the serialized public
+// keys before its script is built for the multisig type. The keys are
+// compared by point, so a key given compressed and uncompressed is a
+// duplicate, and by x coordinate for Taproot which drops the y parity.
+func ValidateMultisigPolicy(n int, m int, publicKeys [][]byte, multisigType MultisigType) error {
+	segwit, limit := false, MaxMultisigKeys
+	switch multisigType {
+	case MultisigTypeP2SH, "":
+	case MultisigTypeP2WSH, MultisigTypeP2SHP2WSH:
+		segwit = true
+	case MultisigTypeP2TR:
+		segwit, limit = true, MaxTaprootMultisigKeys
+	default:
+		return fmt.Errorf("%w %s", ErrMultisigType, multisigType)
+	}
+	if n < 1 || n > limit {
+		return fmt.Errorf("%w, it must be between 1 and %d. got %d", ErrMultisigTotal, limit, n)
+	}
+	if m < 1 || m > limit {
+		return fmt.Errorf("%w, it must be between 1 and %d. got %d", ErrMultisigRequired, limit, m)
+	}
+	if m > n {
+		return fmt.Errorf("%w. got %d of %d", ErrMultisigRequiredOverN, m, n)
+	}
+	if len(publicKeys) != n {
+		return fmt.Errorf("%w. got %d keys for n %d", ErrMultisigKeyCount, len(publicKeys), n)
+	}
+
+	seen := map[string]int{}
+	scriptSize := MultisignatureScriptSize(n, m, 0)
+	for i, publicKey := range publicKeys {
+		parsed, err := btcec.ParsePubKey(publicKey, btcec.S256())
+		if err != nil {
+			return fmt.Errorf("key %d: %w: %v", i, ErrInvalidKeyInput, err)
+		}
+		if segwit && len(publicKey) != btcec.PubKeyBytesLenCompressed {
+			return fmt.Errorf("key %d: %w", i, ErrMultisigUncompressed)
+		}
+		point := string(parsed.SerializeCompressed())
+		if multisigType == MultisigTypeP2TR {
+			point = string(XOnlyPubKey(parsed))
+		}
+		if j, ok := seen[point]; ok {
+			return fmt.Errorf("%w. keys %d and %d are the same", ErrMultisigDuplicateKey, j, i)
+		}
+		seen[point] = i
+		scriptSize += 1 + len(publicKey)
+	}
+	if !segwit && scriptSize > txscript.MaxScriptElementSize {
+		return fmt.Errorf("%w. got %d bytes", ErrMultisigScriptTooLarge, scriptSize)
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package helpers
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcec"
+)
+
+func TestValidateMultisigPolicy(t *testing.T) {
+	// distinct keys of the private keys 1 to 20
+	keys := make([][]byte, 20)
+	uncompressed := make([][]byte, 20)
+	for i := range keys {
+		_, publicKey := btcec.PrivKeyFromBytes(btcec.S256(), []byte{byte(i + 1)})
+		keys[i] = publicKey.SerializeCompressed()
+		uncompressed[i] = publicKey.SerializeUncompressed()
+	}
+	// the negation of key 0 has the same x-only key
+	negated := append([]byte{keys[0][0] ^ 1}, keys[0][1:]...)
+	tests := []struct {
+		n            int
+		m            int
+		publicKeys   [][]byte
+		multisigType MultisigType
+		expected     *InputError
+	}{
+		{2, 2, keys[:2], MultisigTypeP2SH, nil},
+		{15, 1, keys[:15], "", nil},
+		{16, 16, keys[:16], MultisigTypeP2WSH, nil},
+		{20, 20, keys, MultisigTypeP2WSH, nil},
+		{2, 1, [][]byte{uncompressed[0], keys[1]}, MultisigTypeP2SH, nil},
+		{0, 1, keys[:0], MultisigTypeP2SH, ErrMultisigTotal},
+		{21, 1, append(keys, negated), MultisigTypeP2WSH, ErrMultisigTotal},
+		{2, 0, keys[:2], MultisigTypeP2SH, ErrMultisigRequired},
+		{1, 2, keys[:2], MultisigTypeP2SH, ErrMultisigRequiredOverN},
+		{3, 2, keys[:2], MultisigTypeP2SH, ErrMultisigKeyCount},
+		{2, 1, [][]byte{keys[0], keys[0]}, MultisigTypeP2SH, ErrMultisigDuplicateKey},
+		{2, 1, [][]byte{uncompressed[0], keys[0]}, MultisigTypeP2SH, ErrMultisigDuplicateKey},
+		{2, 1, [][]byte{uncompressed[0], keys[1]}, MultisigTypeP2WSH, ErrMultisigUncompressed},
+		{2, 1, [][]byte{keys[0], uncompressed[1]}, MultisigTypeP2SHP2WSH, ErrMultisigUncompressed},
+		{2, 1, [][]byte{keys[0], keys[1][:32]}, MultisigTypeP2SH, ErrInvalidKeyInput},
+		{16, 1, keys[:16], MultisigTypeP2SH, ErrMultisigScriptTooLarge},
+		{20, 1, keys, MultisigTypeP2SH, ErrMultisigScriptTooLarge},
+		{2, 1, keys[:2], "p2pk", ErrMultisigType},
+		{20, 20, keys, MultisigTypeP2TR, nil},
+		{17, 2, keys, MultisigTypeP2TR, ErrMultisigKeyCount},
+		{1000, 2, keys, MultisigTypeP2TR, ErrMultisigTotal},
+		{2, 1, [][]byte{keys[0], negated}, MultisigTypeP2WSH, nil},
+		{2, 1, [][]byte{keys[0], negated}, MultisigTypeP2TR, ErrMultisigDuplicateKey},
+		{2, 1, [][]byte{keys[0], uncompressed[1]}, MultisigTypeP2TR, ErrMultisigUncompressed},
+	}
+
+	for _, test := range tests {
+		err := ValidateMultisigPolicy(test.n, test.m, test.publicKeys, test.multisigType)
+		if test.expected == nil && err != nil {
+			t.Errorf("Test failed: unexpected error: %v", err)
+		}
+		if test.expected != nil && !errors.Is(err, test.expected) {
+			t.Errorf("Test failed:  expected: %s received: %v ", test.expected.Code, err)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package helpers
+
+import (
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcec"
+)
+
+func TestSortPublicKeys(t *testing.T) {
+	// BIP67 test vector
+	keys := []string{
+		"02fe6f0a5a297eb38c391581c4413e084773ea23954d93f7753db7dc0adc188b2f",
+		"02ff12471208c14bd580709cb2358d98975247d8765f92bc25eab3b2763ed605f8",
+	}
+	unsorted := []string{keys[1], keys[0]}
+	publicKeys := make([]*btcec.PublicKey, len(unsorted))
+	for i, key := range unsorted {
+		decoded, _ := hex.DecodeString(key)
+		publicKeys[i], _ = btcec.ParsePubKey(decoded, btcec.S256())
+	}
+
+	sorted := SortPublicKeys(SerializePublicKeys(publicKeys))
+	for i, expected := range keys {
+		if received := hex.EncodeToString(sorted[i]); received != expected {
+			t.Errorf("Test failed:  expected: %s received: %s ", expected, received)
+		}
+	}
+	if received := hex.EncodeToString(publicKeys[0].SerializeCompressed()); received != unsorted[0] {
+		t.Errorf("Test failed: input reordered, expected: %s received: %s ", unsorted[0], received)
+	}
+}
+
+func TestDecodeMultisignatureScript(t *testing.T) {
+	keys := []string{
+		"0265e6f7fb614a369c9230912a3bb09c33c5c5be2e1bcfc2293ecaed46708e0b5c",
+		"03f546edf7b434b50aa0115c1c82a0f9a96505d9eff55d2fe3b848c4b51c06b643",
+	}
+	script, _ := hex.DecodeString("5121" + keys[0] + "21" + keys[1] + "52ae")
+
+	required, publicKeys, err := DecodeMultisignatureScript(script)
+	if err != nil {
+		t.Fatalf("Test failed: unexpected error: %v", err)
+	}
+	if required != 1 || len(publicKeys) != len(keys) {
+		t.Fatalf("Test failed:  expected: 1 of %d received: %d of %d ", len(keys), required, len(publicKeys))
+	}
+	for i, publicKey := range publicKeys {
+		if hex.EncodeToString(publicKey) != keys[i] {
+			t.Errorf("Test failed:  expected: %s received: %x ", keys[i], publicKey)
+		}
+	}
+
+	invalid := []string{
+		// more signatures than keys
+		"5321" + keys[0] + "21" + keys[1] + "52ae",
+		// key count does not match the keys
+		"5121" + keys[0] + "21" + keys[1] + "51ae",
+		// not a point on the curve
+		"5121" + "02" + strings.Repeat("00", 32) + "51ae",
+		// P2PKH
+		"76a914d6fe8455e2a6b6411ab9c4365c66a8c84ba4cc9f88ac",
+		// 17 pushed as a two byte number
+		"020f00" + "21" + keys[0] + "51ae",
+		// trailing data after CHECKMULTISIG
+		"5121" + keys[0] + "51ae51",
+	}
+	for _, test := range invalid {
+		decoded, _ := hex.DecodeString(test)
+		if _, _, err := DecodeMultisignatureScript(decoded); !errors.Is(err, ErrInvalidMultisigScript) {
+			t.Errorf("Test failed:  expected: %v received: %v ", ErrInvalidMultisigScript, err)
+		}
+	}
+}
+
+func TestMultisignatureScriptOverSixteenKeys(t *testing.T) {
+	// 17 of 20, m and n are one byte pushes above OP_16
+	publicKeys := make([][]byte, 20)
+	for i := range publicKeys {
+		_, publicKey := btcec.PrivKeyFromBytes(btcec.S256(), []byte{byte(i + 1)})
+		publicKeys[i] = publicKey.SerializeCompressed()
+	}
+	script, err := MultisignatureScript(20, 17, publicKeys)
+	if err != nil {
+		t.Fatalf("Test failed: unexpected error: %v", err)
+	}
+	if size := MultisignatureScriptSize(20, 17, 33); len(script) != size {
+		t.Errorf("Test failed:  expected: %d received: %d ", size, len(script))
+	}
+	required, decoded, err := DecodeMultisignatureScript(script)
+	if err != nil {
+		t.Fatalf("Test failed: unexpected error: %v", err)
+	}
+	if required != 17 || len(decoded) != 20 {
+		t.Errorf("Test failed:  expected: 17 of 20 received: %d of %d ", required, len(decoded))
+	}
+}
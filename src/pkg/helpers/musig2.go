@@ -0,0 +1,60 @@
+package helpers
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+
+	"github.com/btcsuite/btcd/btcec"
+)
+
+// MuSig2AggregateKey aggregates compressed public keys into the BIP327
+// KeyAgg key, the key an n of n MuSig2 signing session signs for. The keys
+// are aggregated in the given order, sort them first for an aggregate that
+// does not depend on the order the cosigners are listed in.
+func MuSig2AggregateKey(publicKeys [][]byte) (*btcec.PublicKey, error) {
+	if len(publicKeys) == 0 {
+		return nil, fmt.Errorf("musig2 needs at least one public key")
+	}
+	curve := btcec.S256()
+	points := make([]*btcec.PublicKey, len(publicKeys))
+	for i, publicKey := range publicKeys {
+		if len(publicKey) != btcec.PubKeyBytesLenCompressed {
+			return nil, fmt.Errorf("key %d: %w", i, ErrMultisigUncompressed)
+		}
+		point, err := btcec.ParsePubKey(publicKey, curve)
+		if err != nil {
+			return nil, fmt.Errorf("key %d: %w: %v", i, ErrInvalidKeyInput, err)
+		}
+		points[i] = point
+	}
+
+	keyList := TaggedHash("KeyAgg list", bytes.Join(publicKeys, nil))
+	// the first key that differs from the first one has a coefficient of 1
+	var secondKey []byte
+	for _, publicKey := range publicKeys[1:] {
+		if !bytes.Equal(publicKey, publicKeys[0]) {
+			secondKey = publicKey
+			break
+		}
+	}
+
+	var qx, qy *big.Int
+	for i, publicKey := range publicKeys {
+		coefficient := []byte{1}
+		if secondKey == nil || !bytes.Equal(publicKey, secondKey) {
+			hash := new(big.Int).SetBytes(TaggedHash("KeyAgg coefficient", keyList, publicKey))
+			coefficient = hash.Mod(hash, curve.N).Bytes()
+		}
+		x, y := curve.ScalarMult(points[i].X, points[i].Y, coefficient)
+		if qx == nil {
+			qx, qy = x, y
+			continue
+		}
+		qx, qy = curve.Add(qx, qy, x, y)
+	}
+	if qx.Sign() == 0 && qy.Sign() == 0 {
+		return nil, fmt.Errorf("musig2 aggregate key is the point at infinity")
+	}
+	return &btcec.PublicKey{Curve: curve, X: qx, Y: qy}, nil
+}
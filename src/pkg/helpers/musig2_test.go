@@ -0,0 +1,44 @@
+package helpers
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestMuSig2AggregateKey(t *testing.T) {
+	// BIP327 key aggregation vectors
+	keys := []string{
+		"02f9308a019258c31049344f85f89d5229b531c845836f99b08601f113bce036f9",
+		"03dff1d77f2a671c5f36183726db2341be58feae1da2deced843240f7b502ba659",
+		"023590a94e768f8e1815c2f24b4d80a8e3149316c3518ce7b7ad338368d038ca66",
+	}
+	tests := []struct {
+		indexes  []int
+		expected string
+	}{
+		{[]int{0, 1, 2}, "90539eede565f5d054f32cc0c220126889ed1e5d193baf15aef344fe59d4610c"},
+		{[]int{2, 1, 0}, "6204de8b083426dc6eaf9502d27024d53fc826bf7d2012148a0575435df54b2b"},
+		{[]int{0, 0, 0}, "b436e3bad62b8cd409969a224731c193d051162d8c5ae8b109306127da3aa935"},
+		{[]int{0, 0, 1, 1}, "69bc22bfa5d106306e48a20679de1d7389386124d07571d0d872686028c26a3e"},
+	}
+	for _, test := range tests {
+		var publicKeys [][]byte
+		for _, index := range test.indexes {
+			publicKey, _ := hex.DecodeString(keys[index])
+			publicKeys = append(publicKeys, publicKey)
+		}
+		aggregate, err := MuSig2AggregateKey(publicKeys)
+		if err != nil {
+			t.Fatalf("Test failed: unexpected error: %v", err)
+		}
+		if result := hex.EncodeToString(XOnlyPubKey(aggregate)); result != test.expected {
+			t.Errorf("Test failed:  expected: %s received: %s ", test.expected, result)
+		}
+	}
+
+	uncompressed, _ := hex.DecodeString("04" + strings.Repeat("00", 64))
+	if _, err := MuSig2AggregateKey([][]byte{uncompressed}); err == nil {
+		t.Errorf("Test failed:  expected: an error received: nil ")
+	}
+}
@@ -0,0 +1,59 @@
+package helpers
+
+import (
+	"fmt"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/tyler-smith/go-bip32"
+)
+
+// SigNetParams defines the network parameters for the default signet. The
+// btcd release in use predates signet, it shares every encoding with testnet3.
+var SigNetParams = signetParams()
+
+func signetParams() chaincfg.Params {
+	params := chaincfg.TestNet3Params
+	params.Name = "signet"
+	params.Net = wire.BitcoinNet(0x40cf030a)
+	params.DefaultPort = "38333"
+	params.DNSSeeds = nil
+	params.Checkpoints = nil
+	return params
+}
+
+// Networks lists the supported network names.
+var Networks = []string{
+	chaincfg.MainNetParams.Name,
+	chaincfg.TestNet3Params.Name,
+	SigNetParams.Name,
+	chaincfg.RegressionNetParams.Name,
+}
+
+// NetworkParams resolves a network name to its chain parameters. An empty
+// name selects mainnet.
+func NetworkParams(network string) (*chaincfg.Params, error) {
+	switch network {
+	case "", chaincfg.MainNetParams.Name:
+		return &chaincfg.MainNetParams, nil
+	case chaincfg.TestNet3Params.Name, "testnet":
+		return &chaincfg.TestNet3Params, nil
+	case SigNetParams.Name:
+		return &SigNetParams, nil
+	case chaincfg.RegressionNetParams.Name:
+		return &chaincfg.RegressionNetParams, nil
+	default:
+		return nil, fmt.Errorf("unknown network %s. must be one of %v", network, Networks)
+	}
+}
+
+// encodeExtendedKey serializes an extended key with the version bytes of the network.
+func encodeExtendedKey(key *bip32.Key, net *chaincfg.Params) string {
+	version := net.HDPublicKeyID
+	if key.IsPrivate {
+		version = net.HDPrivateKeyID
+	}
+	serialized := *key
+	serialized.Version = version[:]
+	return serialized.String()
+}
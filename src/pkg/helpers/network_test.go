@@ -0,0 +1,77 @@
+package helpers
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/tyler-smith/go-bip32"
+)
+
+func TestNetworkParams(t *testing.T) {
+	tests := map[string]string{
+		"":         "mainnet",
+		"mainnet":  "mainnet",
+		"testnet":  "testnet3",
+		"testnet3": "testnet3",
+		"signet":   "signet",
+		"regtest":  "regtest",
+	}
+
+	for network, expected := range tests {
+		result, err := NetworkParams(network)
+		if err != nil {
+			t.Errorf("Test failed: input: %s unexpected error: %v", network, err)
+			continue
+		}
+		if result.Name != expected {
+			t.Errorf("Test failed: input: %s expected: %s received: %s ", network, expected, result.Name)
+		}
+	}
+	if _, err := NetworkParams("simnet"); err == nil {
+		t.Errorf("Test failed: input: simnet expected an error")
+	}
+}
+
+func TestDeriveAddressRegtest(t *testing.T) {
+	var walletHelper WalletHelper = NewWalletHelper()
+	var seed string = "3fdf3c7c40ef678dd8950caac27f8006e27fdfab5e379ff7e3cef34a0226df830a49ca85476e7873e096ca6127d365995f6f135c71c27e8efe6cd1c497f6003f"
+	decodeSeed, _ := hex.DecodeString(seed)
+	master, _ := bip32.NewMasterKey(decodeSeed)
+	path, _ := walletHelper.DerivePath("m/44'/1'/0'/0/0")
+	xPrvKey, xPubKey, _ := walletHelper.DeriveExtendedKeysFromPath(master, path)
+	prvKey := walletHelper.DerivePrivateKeyFromBytes(xPrvKey.Key)
+	wif, p2pkhAddress, segwitBech32, segwitNested, _ := walletHelper.DeriveAddress(prvKey, &chaincfg.RegressionNetParams)
+
+	var expectedExtPubKey string = "tpubDHdBV6YiHDcRSb3GkWsB8TLsD7YWHm7ifJDbHuBE33ZU4G34CeZbMTTvFP3WB54M1JWTF4jJVUzXRgKaPJ9LQ2PpsV1gPWc4yKJJSvP7Zhb"
+	var expectedWif string = "cNyWws7c168xnzwXV2R9PM4dT9WnEkoKaZ5HTmDPQZbGB8QPRThQ"
+	var expectedP2pkhAddress string = "mrv6LJvYkBYXuJnRBiGQM3BcV85xSG5q9S"
+	var expectedSegwitBech32 string = "bcrt1q05y6jjf00n0gt96gh0lgzhpsgg5xwssvzgqutp"
+	var expectedSegwitNested string = "2N9oDphjK9W2VMwxRK51AEebuo3v7T6xCvv"
+
+	if extPubKey := walletHelper.EncodeExtendedKey(xPubKey, &chaincfg.RegressionNetParams); extPubKey != expectedExtPubKey {
+		t.Errorf("Test failed:  expected: %s received: %s ", expectedExtPubKey, extPubKey)
+	}
+	if wif != expectedWif {
+		t.Errorf("Test failed:  expected: %s received: %s ", expectedWif, wif)
+	}
+	if p2pkhAddress != expectedP2pkhAddress {
+		t.Errorf("Test failed:  expected: %s received: %s ", expectedP2pkhAddress, p2pkhAddress)
+	}
+	if segwitBech32 != expectedSegwitBech32 {
+		t.Errorf("Test failed:  expected: %s received: %s ", expectedSegwitBech32, segwitBech32)
+	}
+	if segwitNested != expectedSegwitNested {
+		t.Errorf("Test failed:  expected: %s received: %s ", expectedSegwitNested, segwitNested)
+	}
+}
+
+func TestDerivePubKeyFromWifWrongNetwork(t *testing.T) {
+	var walletHelper WalletHelper = NewWalletHelper()
+	var wif []string
+	wif = append(wif, "cQpHXfs91s5eR9PWXui6qo2xjoJb2X3VdUspwKXe4A8Dybvut2rL")
+
+	if _, err := walletHelper.DerivePubKeyFromWif(wif, &chaincfg.MainNetParams); err == nil {
+		t.Errorf("Test failed: testnet wif expected to be rejected on mainnet")
+	}
+}
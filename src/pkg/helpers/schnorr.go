@@ -0,0 +1,85 @@
+package helpers
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+
+	"github.com/btcsuite/btcd/btcec"
+)
+
+// SchnorrSign signs a 32 byte message hash with the BIP340 Schnorr signature
+// scheme for the x-only public key of privKey. aux is the 32 bytes of
+// auxiliary randomness mixed into the nonce.
+func SchnorrSign(privKey *btcec.PrivateKey, hash []byte, aux []byte) ([]byte, error) {
+	if len(hash) != 32 || len(aux) != 32 {
+		return nil, fmt.Errorf("schnorr signing needs a 32 byte hash and 32 bytes of auxiliary randomness")
+	}
+	curve := btcec.S256()
+	d := new(big.Int).Set(privKey.D)
+	if d.Sign() == 0 || d.Cmp(curve.N) >= 0 {
+		return nil, fmt.Errorf("private key is out of range")
+	}
+	pubKey := privKey.PubKey()
+	if pubKey.Y.Bit(0) == 1 {
+		d.Sub(curve.N, d)
+	}
+	secret := d.FillBytes(make([]byte, 32))
+
+	t := TaggedHash("BIP0340/aux", aux)
+	for i := range t {
+		t[i] ^= secret[i]
+	}
+	k := new(big.Int).SetBytes(TaggedHash("BIP0340/nonce", t, XOnlyPubKey(pubKey), hash))
+	k.Mod(k, curve.N)
+	if k.Sign() == 0 {
+		return nil, fmt.Errorf("schnorr nonce is zero")
+	}
+	rx, ry := curve.ScalarBaseMult(k.FillBytes(make([]byte, 32)))
+	if ry.Bit(0) == 1 {
+		k.Sub(curve.N, k)
+	}
+	r := rx.FillBytes(make([]byte, 32))
+
+	e := new(big.Int).SetBytes(TaggedHash("BIP0340/challenge", r, XOnlyPubKey(pubKey), hash))
+	e.Mod(e, curve.N)
+	s := e.Mul(e, d)
+	s.Add(s, k)
+	s.Mod(s, curve.N)
+	signature := append(r, s.FillBytes(make([]byte, 32))...)
+
+	if !SchnorrVerify(XOnlyPubKey(pubKey), hash, signature) {
+		return nil, fmt.Errorf("schnorr signature does not verify")
+	}
+	return signature, nil
+}
+
+// SchnorrVerify checks a 64 byte BIP340 signature of a 32 byte message hash
+// for an x-only public key.
+func SchnorrVerify(pubKey []byte, hash []byte, signature []byte) bool {
+	if len(hash) != 32 || len(signature) != 64 {
+		return false
+	}
+	curve := btcec.S256()
+	point, err := ParseXOnlyPubKey(pubKey)
+	if err != nil {
+		return false
+	}
+	r := new(big.Int).SetBytes(signature[:32])
+	s := new(big.Int).SetBytes(signature[32:])
+	if r.Cmp(curve.P) >= 0 || s.Cmp(curve.N) >= 0 {
+		return false
+	}
+	e := new(big.Int).SetBytes(TaggedHash("BIP0340/challenge", signature[:32], pubKey, hash))
+	e.Mod(e, curve.N)
+
+	// R = s*G - e*P
+	sx, sy := curve.ScalarBaseMult(signature[32:])
+	e.Sub(curve.N, e)
+	ex, ey := curve.ScalarMult(point.X, point.Y, e.Bytes())
+	rx, ry := curve.Add(sx, sy, ex, ey)
+	if (rx.Sign() == 0 && ry.Sign() == 0) || ry.Bit(0) == 1 {
+		return false
+	}
+	return bytes.Equal(rx.FillBytes(make([]byte, 32)), signature[:32])
+}
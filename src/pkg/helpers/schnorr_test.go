@@ -0,0 +1,84 @@
+package helpers
+
+import (
+	"bytes"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcec"
+)
+
+func TestSchnorrSign(t *testing.T) {
+	// BIP340 test vectors 0 and 1
+	tests := []struct {
+		secretKey string
+		publicKey string
+		aux       string
+		message   string
+		signature string
+	}{
+		{
+			"0000000000000000000000000000000000000000000000000000000000000003",
+			"f9308a019258c31049344f85f89d5229b531c845836f99b08601f113bce036f9",
+			"0000000000000000000000000000000000000000000000000000000000000000",
+			"0000000000000000000000000000000000000000000000000000000000000000",
+			"e907831f80848d1069a5371b402410364bdf1c5f8307b0084c55f1ce2dca821525f66a4a85ea8b71e482a74f382d2ce5ebeee8fdb2172f477df4900d310536c0",
+		},
+		{
+			"b7e151628aed2a6abf7158809cf4f3c762e7160f38b4da56a784d9045190cfef",
+			"dff1d77f2a671c5f36183726db2341be58feae1da2deced843240f7b502ba659",
+			"0000000000000000000000000000000000000000000000000000000000000001",
+			"243f6a8885a308d313198a2e03707344a4093822299f31d0082efa98ec4e6c89",
+			"6896bd60eeae296db48a229ff71dfe071bde413e6d43f917dc8dcf8c78de33418906d11ac976abccb20b091292bff4ea897efcb639ea871cfa95f6de339e4b0a",
+		},
+	}
+	for _, test := range tests {
+		secretKey, _ := hex.DecodeString(test.secretKey)
+		publicKey, _ := hex.DecodeString(test.publicKey)
+		aux, _ := hex.DecodeString(test.aux)
+		message, _ := hex.DecodeString(test.message)
+		privKey, _ := btcec.PrivKeyFromBytes(btcec.S256(), secretKey)
+
+		signature, err := SchnorrSign(privKey, message, aux)
+		if err != nil {
+			t.Fatalf("Test failed: unexpected error: %v", err)
+		}
+		if hex.EncodeToString(signature) != test.signature {
+			t.Errorf("Test failed:  expected: %s received: %x ", test.signature, signature)
+		}
+		if !SchnorrVerify(publicKey, message, signature) {
+			t.Errorf("Test failed:  expected: the signature of %s to verify ", test.publicKey)
+		}
+		signature[63] ^= 0x01
+		if SchnorrVerify(publicKey, message, signature) {
+			t.Errorf("Test failed:  expected: a modified signature of %s to fail ", test.publicKey)
+		}
+	}
+
+	// BIP340 test vector 5, the public key is not on the curve
+	publicKey, _ := hex.DecodeString("eefdea4cdb677750a420fee807eacf21eb9898ae79b9768766e4faa04a2d4a34")
+	message, _ := hex.DecodeString("243f6a8885a308d313198a2e03707344a4093822299f31d0082efa98ec4e6c89")
+	signature, _ := hex.DecodeString("6cff5c3ba86c69ea4b7376f31a9bcb4f74c1976089b2d9963da2e5543e17776969e89b4c5564d00349106b8497785dd7d1d713a8ae82b32fa79d5f7fc407d39b")
+	if SchnorrVerify(publicKey, message, signature) {
+		t.Errorf("Test failed:  expected: a public key off the curve to fail ")
+	}
+}
+
+func TestTaprootTweakPrivKey(t *testing.T) {
+	merkleRoot := TapLeafHash([]byte{0x51})
+	for _, secret := range []string{"03", "b7e151628aed2a6abf7158809cf4f3c762e7160f38b4da56a784d9045190cfef"} {
+		secretKey, _ := hex.DecodeString(strings.Repeat("0", 64-len(secret)) + secret)
+		privKey, pubKey := btcec.PrivKeyFromBytes(btcec.S256(), secretKey)
+		for _, root := range [][]byte{nil, merkleRoot} {
+			tweaked, err := TaprootTweakPrivKey(privKey, root)
+			if err != nil {
+				t.Fatalf("Test failed: unexpected error: %v", err)
+			}
+			outputKey, _ := TaprootOutputKey(pubKey, root)
+			if !bytes.Equal(XOnlyPubKey(tweaked.PubKey()), XOnlyPubKey(outputKey)) {
+				t.Errorf("Test failed:  expected: %x received: %x ", XOnlyPubKey(outputKey), XOnlyPubKey(tweaked.PubKey()))
+			}
+		}
+	}
+}
@@ -0,0 +1,462 @@
+package helpers
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// SLIP-39 splits a master secret into mnemonic shares with a two level
+// threshold scheme: the secret is split into groups, and every group share is
+// split again into member shares. The master secret recovered from the shares
+// is used as a BIP32 seed.
+
+// ErrInvalidShare is wrapped by every SLIP-39 mnemonic decoding and recovery error.
+var ErrInvalidShare = errors.New("invalid slip39 share")
+
+// ErrInvalidSharing is wrapped by every error about the SLIP-39 split parameters.
+var ErrInvalidSharing = errors.New("invalid slip39 sharing")
+
+const (
+	slip39MaxShareCount   = 16
+	slip39MinWordCount    = 20
+	slip39ChecksumWords   = 3
+	slip39DigestLength    = 4
+	slip39DigestIndex     = 254
+	slip39SecretIndex     = 255
+	slip39BaseIterations  = 10000
+	slip39RoundCount      = 4
+	slip39MaxExponent     = 15
+	slip39MinSecretLength = 16
+)
+
+// Slip39Group is the member threshold and the member count of a group.
+type Slip39Group struct {
+	MemberThreshold int
+	MemberCount     int
+}
+
+type slip39Share struct {
+	identifier        uint16
+	extendable        bool
+	iterationExponent int
+	groupIndex        int
+	groupThreshold    int
+	groupCount        int
+	memberIndex       int
+	memberThreshold   int
+	value             []byte
+}
+
+// slip39Point is a share value at x on the polynomials, one per byte of the secret.
+type slip39Point struct {
+	x byte
+	y []byte
+}
+
+var slip39WordIndexes = func() map[string]int {
+	indexes := make(map[string]int, len(slip39WordList))
+	for i, word := range slip39WordList {
+		indexes[word] = i
+	}
+	return indexes
+}()
+
+// slip39Exp and slip39Log are the exponent and logarithm tables of GF(256)
+// with the Rijndael polynomial x^8 + x^4 + x^3 + x + 1 and the generator x + 1.
+var slip39Exp, slip39Log = func() (exp [255]byte, log [256]int) {
+	poly := 1
+	for i := 0; i < 255; i++ {
+		exp[i] = byte(poly)
+		log[poly] = i
+		poly = poly<<1 ^ poly
+		if poly&0x100 != 0 {
+			poly ^= 0x11b
+		}
+	}
+	return exp, log
+}()
+
+// GenerateSlip39Shares splits a master secret into groupThreshold of
+// len(groups) groups and returns the member mnemonics of every group. The
+// master secret is encrypted with the passphrase before it is split.
+func GenerateSlip39Shares(masterSecret []byte, passphrase string, groupThreshold int, groups []Slip39Group, iterationExponent int, extendable bool) ([][]string, error) {
+	if len(masterSecret) < slip39MinSecretLength || len(masterSecret)%2 != 0 {
+		return nil, fmt.Errorf("%w: master secret must be at least 128 bits and a multiple of 16 bits. got %d bits", ErrInvalidSharing, len(masterSecret)*8)
+	}
+	if err := checkSlip39Passphrase(passphrase); err != nil {
+		return nil, err
+	}
+	if iterationExponent < 0 || iterationExponent > slip39MaxExponent {
+		return nil, fmt.Errorf("%w: iteration exponent must be between 0 and %d. got %d", ErrInvalidSharing, slip39MaxExponent, iterationExponent)
+	}
+	if len(groups) < 1 || len(groups) > slip39MaxShareCount {
+		return nil, fmt.Errorf("%w: group count must be between 1 and %d. got %d", ErrInvalidSharing, slip39MaxShareCount, len(groups))
+	}
+	if groupThreshold < 1 || groupThreshold > len(groups) {
+		return nil, fmt.Errorf("%w: group threshold must be between 1 and the group count %d. got %d", ErrInvalidSharing, len(groups), groupThreshold)
+	}
+	for i, group := range groups {
+		if group.MemberCount < 1 || group.MemberCount > slip39MaxShareCount {
+			return nil, fmt.Errorf("%w: member count of group %d must be between 1 and %d. got %d", ErrInvalidSharing, i+1, slip39MaxShareCount, group.MemberCount)
+		}
+		if group.MemberThreshold < 1 || group.MemberThreshold > group.MemberCount {
+			return nil, fmt.Errorf("%w: member threshold of group %d must be between 1 and the member count %d. got %d", ErrInvalidSharing, i+1, group.MemberCount, group.MemberThreshold)
+		}
+		if group.MemberThreshold == 1 && group.MemberCount > 1 {
+			return nil, fmt.Errorf("%w: group %d shares a secret with threshold 1 between %d members, use a 1 of 1 group instead", ErrInvalidSharing, i+1, group.MemberCount)
+		}
+	}
+
+	random := make([]byte, 2)
+	if _, err := rand.Read(random); err != nil {
+		return nil, err
+	}
+	identifier := (uint16(random[0])<<8 | uint16(random[1])) & 0x7fff
+	encrypted := slip39Feistel(masterSecret, passphrase, iterationExponent, identifier, extendable, false)
+
+	groupPoints, err := slip39SplitSecret(groupThreshold, len(groups), encrypted)
+	if err != nil {
+		return nil, err
+	}
+	mnemonics := make([][]string, len(groups))
+	for i, group := range groups {
+		memberPoints, err := slip39SplitSecret(group.MemberThreshold, group.MemberCount, groupPoints[i].y)
+		if err != nil {
+			return nil, err
+		}
+		for _, point := range memberPoints {
+			share := &slip39Share{
+				identifier:        identifier,
+				extendable:        extendable,
+				iterationExponent: iterationExponent,
+				groupIndex:        i,
+				groupThreshold:    groupThreshold,
+				groupCount:        len(groups),
+				memberIndex:       int(point.x),
+				memberThreshold:   group.MemberThreshold,
+				value:             point.y,
+			}
+			mnemonics[i] = append(mnemonics[i], share.mnemonic())
+		}
+	}
+	return mnemonics, nil
+}
+
+// CombineSlip39Shares recovers the master secret from enough member shares of
+// enough groups. Shares past the thresholds are ignored.
+func CombineSlip39Shares(mnemonics []string, passphrase string) ([]byte, error) {
+	if len(mnemonics) == 0 {
+		return nil, fmt.Errorf("%w: no shares", ErrInvalidShare)
+	}
+	if err := checkSlip39Passphrase(passphrase); err != nil {
+		return nil, err
+	}
+	shares := make([]*slip39Share, len(mnemonics))
+	for i, mnemonic := range mnemonics {
+		share, err := decodeSlip39Share(mnemonic)
+		if err != nil {
+			return nil, fmt.Errorf("share %d: %w", i+1, err)
+		}
+		shares[i] = share
+	}
+
+	first := shares[0]
+	groups := make([][]*slip39Share, first.groupCount)
+	for i, share := range shares {
+		if share.identifier != first.identifier || share.extendable != first.extendable || share.iterationExponent != first.iterationExponent {
+			return nil, fmt.Errorf("%w: share %d belongs to another secret", ErrInvalidShare, i+1)
+		}
+		if share.groupThreshold != first.groupThreshold || share.groupCount != first.groupCount {
+			return nil, fmt.Errorf("%w: share %d has another group threshold or group count", ErrInvalidShare, i+1)
+		}
+		if len(share.value) != len(first.value) {
+			return nil, fmt.Errorf("%w: share %d has another length", ErrInvalidShare, i+1)
+		}
+		if share.groupIndex >= share.groupCount {
+			return nil, fmt.Errorf("%w: share %d has group index %d of %d groups", ErrInvalidShare, i+1, share.groupIndex+1, share.groupCount)
+		}
+		members := groups[share.groupIndex]
+		duplicate := false
+		for _, member := range members {
+			if member.memberThreshold != share.memberThreshold {
+				return nil, fmt.Errorf("%w: share %d has another member threshold than its group", ErrInvalidShare, i+1)
+			}
+			if member.memberIndex == share.memberIndex {
+				if !bytes.Equal(member.value, share.value) {
+					return nil, fmt.Errorf("%w: share %d repeats member index %d with another value", ErrInvalidShare, i+1, share.memberIndex+1)
+				}
+				duplicate = true
+			}
+		}
+		if !duplicate {
+			groups[share.groupIndex] = append(members, share)
+		}
+	}
+
+	var groupPoints []slip39Point
+	for i, members := range groups {
+		if len(members) == 0 || len(members) < members[0].memberThreshold {
+			continue
+		}
+		threshold := members[0].memberThreshold
+		memberPoints := make([]slip39Point, threshold)
+		for j, member := range members[:threshold] {
+			memberPoints[j] = slip39Point{x: byte(member.memberIndex), y: member.value}
+		}
+		secret, err := slip39RecoverSecret(threshold, memberPoints)
+		if err != nil {
+			return nil, fmt.Errorf("group %d: %w", i+1, err)
+		}
+		groupPoints = append(groupPoints, slip39Point{x: byte(i), y: secret})
+	}
+	if len(groupPoints) < first.groupThreshold {
+		return nil, fmt.Errorf("%w: %d of the %d required groups are complete", ErrInvalidShare, len(groupPoints), first.groupThreshold)
+	}
+	encrypted, err := slip39RecoverSecret(first.groupThreshold, groupPoints[:first.groupThreshold])
+	if err != nil {
+		return nil, err
+	}
+	return slip39Feistel(encrypted, passphrase, first.iterationExponent, first.identifier, first.extendable, true), nil
+}
+
+// checkSlip39Passphrase allows the printable ASCII characters only, as SLIP-39 requires.
+func checkSlip39Passphrase(passphrase string) error {
+	for _, c := range passphrase {
+		if c < 32 || c > 126 {
+			return fmt.Errorf("%w: passphrase must only contain printable ASCII characters", ErrInvalidSharing)
+		}
+	}
+	return nil
+}
+
+// mnemonic encodes the share as the identifier and iteration exponent, the
+// share parameters, the left padded value and the RS1024 checksum.
+func (share *slip39Share) mnemonic() string {
+	valueWords := (len(share.value)*8 + 9) / 10
+	indexes := make([]int, 0, 4+valueWords+slip39ChecksumWords)
+
+	header := int(share.identifier)<<5 | share.iterationExponent
+	if share.extendable {
+		header |= 1 << 4
+	}
+	params := share.groupIndex<<16 | (share.groupThreshold-1)<<12 | (share.groupCount-1)<<8 | share.memberIndex<<4 | (share.memberThreshold - 1)
+	indexes = append(indexes, header>>10, header&1023, params>>10, params&1023)
+
+	value := new(big.Int).SetBytes(share.value)
+	word := new(big.Int)
+	for i := valueWords - 1; i >= 0; i-- {
+		word.Rsh(value, uint(i*10))
+		indexes = append(indexes, int(word.Uint64()&1023))
+	}
+	indexes = append(indexes, slip39Checksum(slip39Customization(share.extendable), indexes)...)
+
+	words := make([]string, len(indexes))
+	for i, index := range indexes {
+		words[i] = slip39WordList[index]
+	}
+	return strings.Join(words, " ")
+}
+
+func decodeSlip39Share(mnemonic string) (*slip39Share, error) {
+	words := strings.Fields(strings.ToLower(mnemonic))
+	if len(words) < slip39MinWordCount {
+		return nil, fmt.Errorf("%w: mnemonic must have at least %d words. got %d", ErrInvalidShare, slip39MinWordCount, len(words))
+	}
+	indexes := make([]int, len(words))
+	for i, word := range words {
+		index, ok := slip39WordIndexes[word]
+		if !ok {
+			return nil, fmt.Errorf("%w: word %d %q is not in the word list", ErrInvalidShare, i+1, word)
+		}
+		indexes[i] = index
+	}
+
+	header := indexes[0]<<10 | indexes[1]
+	share := &slip39Share{
+		identifier:        uint16(header >> 5),
+		extendable:        header>>4&1 == 1,
+		iterationExponent: header & 0xf,
+	}
+	if slip39Polymod(slip39Customization(share.extendable), indexes) != 1 {
+		return nil, fmt.Errorf("%w: checksum mismatch", ErrInvalidShare)
+	}
+
+	paddingBits := 10 * (len(words) - 7) % 16
+	if paddingBits > 8 {
+		return nil, fmt.Errorf("%w: invalid mnemonic length %d", ErrInvalidShare, len(words))
+	}
+	valueIndexes := indexes[4 : len(indexes)-slip39ChecksumWords]
+	value := new(big.Int)
+	for _, index := range valueIndexes {
+		value.Lsh(value, 10).Or(value, big.NewInt(int64(index)))
+	}
+	valueLength := (10*len(valueIndexes) - paddingBits) / 8
+	if value.BitLen() > valueLength*8 {
+		return nil, fmt.Errorf("%w: invalid padding", ErrInvalidShare)
+	}
+	share.value = value.FillBytes(make([]byte, valueLength))
+
+	params := indexes[2]<<10 | indexes[3]
+	share.groupIndex = params >> 16
+	share.groupThreshold = params>>12&0xf + 1
+	share.groupCount = params>>8&0xf + 1
+	share.memberIndex = params >> 4 & 0xf
+	share.memberThreshold = params&0xf + 1
+	if share.groupThreshold > share.groupCount {
+		return nil, fmt.Errorf("%w: group threshold %d is greater than the group count %d", ErrInvalidShare, share.groupThreshold, share.groupCount)
+	}
+	return share, nil
+}
+
+func slip39Customization(extendable bool) string {
+	if extendable {
+		return "shamir_extendable"
+	}
+	return "shamir"
+}
+
+// slip39Polymod is the RS1024 checksum over GF(1024) of the customization
+// string followed by the word indexes.
+func slip39Polymod(customization string, values []int) uint32 {
+	generator := [10]uint32{0xe0e040, 0x1c1c080, 0x3838100, 0x7070200, 0xe0e0009, 0x1c0c2412, 0x38086c24, 0x3090fc48, 0x21b1f890, 0x3f3f120}
+	chk := uint32(1)
+	update := func(v uint32) {
+		b := chk >> 20
+		chk = (chk&0xfffff)<<10 ^ v
+		for i := 0; i < 10; i++ {
+			if b>>uint(i)&1 == 1 {
+				chk ^= generator[i]
+			}
+		}
+	}
+	for i := 0; i < len(customization); i++ {
+		update(uint32(customization[i]))
+	}
+	for _, v := range values {
+		update(uint32(v))
+	}
+	return chk
+}
+
+func slip39Checksum(customization string, values []int) []int {
+	polymod := slip39Polymod(customization, append(append([]int{}, values...), 0, 0, 0)) ^ 1
+	return []int{int(polymod >> 20 & 1023), int(polymod >> 10 & 1023), int(polymod & 1023)}
+}
+
+// slip39Feistel encrypts or decrypts the master secret with the four round
+// Feistel network of SLIP-39, the round function is PBKDF2-HMAC-SHA256.
+func slip39Feistel(secret []byte, passphrase string, iterationExponent int, identifier uint16, extendable bool, decrypt bool) []byte {
+	half := len(secret) / 2
+	l := append([]byte{}, secret[:half]...)
+	r := append([]byte{}, secret[half:]...)
+	var salt []byte
+	if !extendable {
+		salt = []byte{'s', 'h', 'a', 'm', 'i', 'r', byte(identifier >> 8), byte(identifier)}
+	}
+	iterations := (slip39BaseIterations << uint(iterationExponent)) / slip39RoundCount
+	for round := 0; round < slip39RoundCount; round++ {
+		i := round
+		if decrypt {
+			i = slip39RoundCount - 1 - round
+		}
+		password := append([]byte{byte(i)}, passphrase...)
+		f := pbkdf2.Key(password, append(append([]byte{}, salt...), r...), iterations, len(r), sha256.New)
+		for j := range f {
+			f[j] ^= l[j]
+		}
+		l, r = r, f
+	}
+	return append(r, l...)
+}
+
+// slip39SplitSecret evaluates a random polynomial of degree threshold-1 through
+// the secret at x=255 and its digest at x=254 for the count shares.
+func slip39SplitSecret(threshold int, count int, secret []byte) ([]slip39Point, error) {
+	if threshold < 1 || threshold > count || count > slip39MaxShareCount {
+		return nil, fmt.Errorf("%w: threshold %d of %d shares", ErrInvalidSharing, threshold, count)
+	}
+	points := make([]slip39Point, 0, count)
+	if threshold == 1 {
+		for i := 0; i < count; i++ {
+			points = append(points, slip39Point{x: byte(i), y: append([]byte{}, secret...)})
+		}
+		return points, nil
+	}
+
+	base := make([]slip39Point, 0, threshold)
+	for i := 0; i < threshold-2; i++ {
+		random := make([]byte, len(secret))
+		if _, err := rand.Read(random); err != nil {
+			return nil, err
+		}
+		base = append(base, slip39Point{x: byte(i), y: random})
+	}
+	randomPart := make([]byte, len(secret)-slip39DigestLength)
+	if _, err := rand.Read(randomPart); err != nil {
+		return nil, err
+	}
+	digest := append(slip39Digest(randomPart, secret), randomPart...)
+	points = append(points, base...)
+	base = append(base, slip39Point{x: slip39DigestIndex, y: digest}, slip39Point{x: slip39SecretIndex, y: secret})
+	for i := threshold - 2; i < count; i++ {
+		points = append(points, slip39Point{x: byte(i), y: slip39Interpolate(base, byte(i))})
+	}
+	return points, nil
+}
+
+// slip39RecoverSecret interpolates the secret and checks it against the digest.
+func slip39RecoverSecret(threshold int, points []slip39Point) ([]byte, error) {
+	if threshold == 1 {
+		return points[0].y, nil
+	}
+	secret := slip39Interpolate(points, slip39SecretIndex)
+	digest := slip39Interpolate(points, slip39DigestIndex)
+	if !hmac.Equal(digest[:slip39DigestLength], slip39Digest(digest[slip39DigestLength:], secret)) {
+		return nil, fmt.Errorf("%w: digest mismatch", ErrInvalidShare)
+	}
+	return secret, nil
+}
+
+func slip39Digest(randomPart []byte, secret []byte) []byte {
+	mac := hmac.New(sha256.New, randomPart)
+	mac.Write(secret)
+	return mac.Sum(nil)[:slip39DigestLength]
+}
+
+// slip39Interpolate evaluates at x the Lagrange polynomial through the points,
+// byte by byte in GF(256). The points must have distinct x.
+func slip39Interpolate(points []slip39Point, x byte) []byte {
+	for _, point := range points {
+		if point.x == x {
+			return append([]byte{}, point.y...)
+		}
+	}
+	logProduct := 0
+	for _, point := range points {
+		logProduct += slip39Log[point.x^x]
+	}
+	result := make([]byte, len(points[0].y))
+	for _, point := range points {
+		logBasis := logProduct - slip39Log[point.x^x]
+		for _, other := range points {
+			if other.x != point.x {
+				logBasis -= slip39Log[point.x^other.x]
+			}
+		}
+		logBasis = (logBasis%255 + 255) % 255
+		for i, b := range point.y {
+			if b != 0 {
+				result[i] ^= slip39Exp[(slip39Log[b]+logBasis)%255]
+			}
+		}
+	}
+	return result
+}
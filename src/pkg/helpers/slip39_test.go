@@ -0,0 +1,113 @@
+package helpers
+
+import (
+	"encoding/hex"
+	"errors"
+	"testing"
+)
+
+// official SLIP-39 test vectors, all of them use the passphrase TREZOR
+func TestCombineSlip39Shares(t *testing.T) {
+	tests := []struct {
+		mnemonics []string
+		expected  string
+	}{
+		{[]string{"duckling enlarge academic academic agency result length solution fridge kidney coal piece deal husband erode duke ajar critical decision keyboard"}, "bb54aac4b89dc868ba37d9cc21b2cece"},
+		{[]string{
+			"shadow pistol academic always adequate wildlife fancy gross oasis cylinder mustang wrist rescue view short owner flip making coding armed",
+			"shadow pistol academic acid actress prayer class unknown daughter sweater depict flip twice unkind craft early superior advocate guest smoking",
+		}, "b43ceb7e57a0ea8766221624d01b0864"},
+		{[]string{"theory painting academic academic armed sweater year military elder discuss acne wildlife boring employer fused large satoshi bundle carbon diagnose anatomy hamster leaves tracks paces beyond phantom capital marvel lips brave detect luck"}, "989baf9dcaad5b10ca33dfd8cc75e42477025dce88ae83e75a230086a0e00e92"},
+		{[]string{"testify swimming academic academic column loyalty smear include exotic bedroom exotic wrist lobe cover grief golden smart junior estimate learn"}, "1679b4516e0ee5954351d288a838f45e"},
+	}
+
+	for _, test := range tests {
+		secret, err := CombineSlip39Shares(test.mnemonics, "TREZOR")
+		if err != nil {
+			t.Errorf("Test failed: input: %s unexpected error: %v", test.mnemonics[0], err)
+			continue
+		}
+		if hex.EncodeToString(secret) != test.expected {
+			t.Errorf("Test failed:  expected: %s received: %x ", test.expected, secret)
+		}
+	}
+}
+
+func TestCombineSlip39SharesInvalid(t *testing.T) {
+	tests := [][]string{
+		// invalid checksum
+		{"duckling enlarge academic academic agency result length solution fridge kidney coal piece deal husband erode duke ajar critical decision kidney"},
+		// invalid padding
+		{"duckling enlarge academic academic email result length solution fridge kidney coal piece deal husband erode duke ajar music cargo fitness"},
+		// below the member threshold
+		{"shadow pistol academic always adequate wildlife fancy gross oasis cylinder mustang wrist rescue view short owner flip making coding armed"},
+		// different identifiers
+		{
+			"adequate smoking academic acid debut wine petition glen cluster slow rhyme slow simple epidemic rumor junk tracks treat olympic tolerate",
+			"adequate stay academic agency agency formal party ting frequent learn upstairs remember smear leaf damage anatomy ladle market hush corner",
+		},
+		// mismatching member thresholds
+		{
+			"hour painting academic academic device formal evoke guitar random modern justice filter withdraw trouble identify mailman insect general cover oven",
+			"hour painting academic agency artist again daisy capital beaver fiber much enjoy suitable symbolic identify photo editor romp float echo",
+		},
+		// invalid digest
+		{
+			"guilt walnut academic acid deliver remove equip listen vampire tactics nylon rhythm failure husband fatigue alive blind enemy teaspoon rebound",
+			"guilt walnut academic agency brave hamster hobo declare herd taste alpha slim criminal mild arcade formal romp branch pink ambition",
+		},
+	}
+
+	for _, mnemonics := range tests {
+		if _, err := CombineSlip39Shares(mnemonics, "TREZOR"); !errors.Is(err, ErrInvalidShare) {
+			t.Errorf("Test failed: input: %s expected: %v received: %v ", mnemonics[0], ErrInvalidShare, err)
+		}
+	}
+}
+
+func TestGenerateSlip39Shares(t *testing.T) {
+	masterSecret, _ := hex.DecodeString("bb54aac4b89dc868ba37d9cc21b2cece")
+	groups := []Slip39Group{{1, 1}, {2, 3}, {3, 5}}
+
+	for _, extendable := range []bool{false, true} {
+		shares, err := GenerateSlip39Shares(masterSecret, "TREZOR", 2, groups, 0, extendable)
+		if err != nil {
+			t.Fatalf("Test failed: unexpected error: %v", err)
+		}
+		if len(shares) != 3 || len(shares[1]) != 3 || len(shares[2]) != 5 {
+			t.Fatalf("Test failed:  expected: 1, 3 and 5 shares received: %d groups ", len(shares))
+		}
+
+		// the single share group and two of the second group, plus an
+		// incomplete third group that is ignored
+		mnemonics := []string{shares[0][0], shares[1][2], shares[1][0], shares[2][4]}
+		secret, err := CombineSlip39Shares(mnemonics, "TREZOR")
+		if err != nil {
+			t.Fatalf("Test failed: unexpected error: %v", err)
+		}
+		if hex.EncodeToString(secret) != hex.EncodeToString(masterSecret) {
+			t.Errorf("Test failed:  expected: %x received: %x ", masterSecret, secret)
+		}
+
+		if _, err := CombineSlip39Shares([]string{shares[0][0], shares[1][2]}, "TREZOR"); !errors.Is(err, ErrInvalidShare) {
+			t.Errorf("Test failed:  expected: %v received: %v ", ErrInvalidShare, err)
+		}
+	}
+
+	invalid := [][]Slip39Group{
+		{{2, 1}},
+		{{1, 2}},
+		{{1, 17}},
+	}
+	for _, groups := range invalid {
+		if _, err := GenerateSlip39Shares(masterSecret, "", 1, groups, 0, false); !errors.Is(err, ErrInvalidSharing) {
+			t.Errorf("Test failed: input: %v expected: %v received: %v ", groups, ErrInvalidSharing, err)
+		}
+	}
+	if _, err := GenerateSlip39Shares(masterSecret, "", 2, []Slip39Group{{1, 1}}, 0, false); !errors.Is(err, ErrInvalidSharing) {
+		t.Errorf("Test failed:  expected: %v received: %v ", ErrInvalidSharing, err)
+	}
+	if _, err := GenerateSlip39Shares(masterSecret[:15], "", 1, []Slip39Group{{1, 1}}, 0, false); !errors.Is(err, ErrInvalidSharing) {
+		t.Errorf("Test failed:  expected: %v received: %v ", ErrInvalidSharing, err)
+	}
+}
@@ -0,0 +1,174 @@
+package helpers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// TapLeafVersion is the BIP342 leaf version of tapscript.
+const TapLeafVersion = 0xc0
+
+// numsKey is the x coordinate of the BIP341 point H, which has no known
+// discrete logarithm. An internal key of H leaves only the script path.
+const numsKey = "50929b74c1a04954b78b4b6035e97a5e078a5a0f28ec96d547bfee9ace803ac0"
+
+// TaggedHash is the BIP340 tagged hash sha256(sha256(tag) || sha256(tag) || msgs).
+func TaggedHash(tag string, msgs ...[]byte) []byte {
+	tagHash := sha256.Sum256([]byte(tag))
+	h := sha256.New()
+	h.Write(tagHash[:])
+	h.Write(tagHash[:])
+	for _, msg := range msgs {
+		h.Write(msg)
+	}
+	return h.Sum(nil)
+}
+
+// XOnlyPubKey serializes a public key as its 32 byte x coordinate.
+func XOnlyPubKey(pubKey *btcec.PublicKey) []byte {
+	return pubKey.SerializeCompressed()[1:]
+}
+
+// ParseXOnlyPubKey lifts a 32 byte x coordinate to the point with an even y.
+func ParseXOnlyPubKey(key []byte) (*btcec.PublicKey, error) {
+	if len(key) != 32 {
+		return nil, fmt.Errorf("x-only public key must be 32 bytes. got %d", len(key))
+	}
+	return btcec.ParsePubKey(append([]byte{0x02}, key...), btcec.S256())
+}
+
+// TaprootOutputKey tweaks an internal key with the BIP341 TapTweak of the
+// script tree merkle root. A nil merkle root commits to no script path, as
+// BIP86 requires for single key outputs.
+func TaprootOutputKey(internalKey *btcec.PublicKey, merkleRoot []byte) (*btcec.PublicKey, error) {
+	curve := btcec.S256()
+	internal, err := ParseXOnlyPubKey(XOnlyPubKey(internalKey))
+	if err != nil {
+		return nil, err
+	}
+	tweak := TaggedHash("TapTweak", XOnlyPubKey(internal), merkleRoot)
+	if new(big.Int).SetBytes(tweak).Cmp(curve.N) >= 0 {
+		return nil, fmt.Errorf("taproot tweak is out of range")
+	}
+	tx, ty := curve.ScalarBaseMult(tweak)
+	qx, qy := curve.Add(internal.X, internal.Y, tx, ty)
+	if qx.Sign() == 0 && qy.Sign() == 0 {
+		return nil, fmt.Errorf("taproot output key is the point at infinity")
+	}
+	return &btcec.PublicKey{Curve: curve, X: qx, Y: qy}, nil
+}
+
+// TaprootTweakPrivKey tweaks a private key like TaprootOutputKey tweaks its
+// public key, the result signs for the output key with a key path spend.
+func TaprootTweakPrivKey(privKey *btcec.PrivateKey, merkleRoot []byte) (*btcec.PrivateKey, error) {
+	curve := btcec.S256()
+	d := new(big.Int).Set(privKey.D)
+	pubKey := privKey.PubKey()
+	if pubKey.Y.Bit(0) == 1 {
+		d.Sub(curve.N, d)
+	}
+	tweak := new(big.Int).SetBytes(TaggedHash("TapTweak", XOnlyPubKey(pubKey), merkleRoot))
+	if tweak.Cmp(curve.N) >= 0 {
+		return nil, fmt.Errorf("taproot tweak is out of range")
+	}
+	d.Add(d, tweak)
+	d.Mod(d, curve.N)
+	if d.Sign() == 0 {
+		return nil, fmt.Errorf("taproot tweaked private key is zero")
+	}
+	tweaked, _ := btcec.PrivKeyFromBytes(curve, d.FillBytes(make([]byte, 32)))
+	return tweaked, nil
+}
+
+// UnspendableInternalKey is the BIP341 point H, an internal key without a
+// key path spend.
+func UnspendableInternalKey() *btcec.PublicKey {
+	key, _ := hex.DecodeString(numsKey)
+	pubKey, _ := ParseXOnlyPubKey(key)
+	return pubKey
+}
+
+// TapLeafHash is the BIP341 TapLeaf hash of a tapscript leaf.
+func TapLeafHash(script []byte) []byte {
+	var leaf bytes.Buffer
+	leaf.WriteByte(TapLeafVersion)
+	wire.WriteVarBytes(&leaf, 0, script)
+	return TaggedHash("TapLeaf", leaf.Bytes())
+}
+
+// TapBranchHash is the BIP341 TapBranch hash of two child hashes, which are
+// hashed in lexicographic order.
+func TapBranchHash(a []byte, b []byte) []byte {
+	if bytes.Compare(a, b) > 0 {
+		a, b = b, a
+	}
+	return TaggedHash("TapBranch", a, b)
+}
+
+// TaprootMerkleTree builds a balanced script tree over the leaf hashes and
+// returns its merkle root with the merkle path of every leaf, the sibling
+// hashes from the leaf to the root as a control block lists them.
+func TaprootMerkleTree(leafHashes [][]byte) (merkleRoot []byte, paths [][][]byte) {
+	switch len(leafHashes) {
+	case 0:
+		return nil, nil
+	case 1:
+		return leafHashes[0], [][][]byte{{}}
+	}
+	half := (len(leafHashes) + 1) / 2
+	left, leftPaths := TaprootMerkleTree(leafHashes[:half])
+	right, rightPaths := TaprootMerkleTree(leafHashes[half:])
+	for i := range leftPaths {
+		leftPaths[i] = append(leftPaths[i], right)
+	}
+	for i := range rightPaths {
+		rightPaths[i] = append(rightPaths[i], left)
+	}
+	return TapBranchHash(left, right), append(leftPaths, rightPaths...)
+}
+
+// TaprootTreeDescriptor writes the leaves as the script tree of a tr()
+// descriptor, in the balanced shape TaprootMerkleTree builds.
+func TaprootTreeDescriptor(leaves []string) string {
+	if len(leaves) == 1 {
+		return leaves[0]
+	}
+	half := (len(leaves) + 1) / 2
+	return "{" + TaprootTreeDescriptor(leaves[:half]) + "," + TaprootTreeDescriptor(leaves[half:]) + "}"
+}
+
+// TaprootControlBlock is the control block spending a leaf of the output key
+// tweaked from the internal key, path lists the hashes from the leaf to the
+// merkle root.
+func TaprootControlBlock(internalKey *btcec.PublicKey, outputKey *btcec.PublicKey, path ...[]byte) []byte {
+	controlBlock := []byte{TapLeafVersion | byte(outputKey.Y.Bit(0))}
+	controlBlock = append(controlBlock, XOnlyPubKey(internalKey)...)
+	for _, hash := range path {
+		controlBlock = append(controlBlock, hash...)
+	}
+	return controlBlock
+}
+
+// TaprootAddress encodes the x-only output key as a segwit version 1 address.
+func TaprootAddress(outputKey *btcec.PublicKey, net *chaincfg.Params) (string, error) {
+	return EncodeSegwitAddress(net.Bech32HRPSegwit, 1, XOnlyPubKey(outputKey))
+}
+
+func (wh *walletHelper) DeriveTaprootAddress(pubKey *btcec.PublicKey, net *chaincfg.Params) (p2trAddress string, internalKey []byte, outputKey []byte, err error) {
+	tweaked, err := TaprootOutputKey(pubKey, nil)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	p2trAddress, err = TaprootAddress(tweaked, net)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	return p2trAddress, XOnlyPubKey(pubKey), XOnlyPubKey(tweaked), nil
+}
@@ -0,0 +1,120 @@
+package helpers
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/tyler-smith/go-bip32"
+)
+
+func TestDeriveTaprootAddress(t *testing.T) {
+	var walletHelper WalletHelper = NewWalletHelper()
+	// BIP86 test vectors for the "abandon abandon ... about" reference mnemonic
+	var seed string = "5eb00bbddcf069084889a8ab9155568165f5c453ccb85e70811aaed6f6da5fc19a5ac40b389cd370d086206dec8aa6c43daea6690f20ad3d8d48b2d2ce9e38e4"
+	decodeSeed, _ := hex.DecodeString(seed)
+	master, _ := bip32.NewMasterKey(decodeSeed)
+	tests := []struct {
+		path                string
+		expectedInternalKey string
+		expectedOutputKey   string
+		expectedAddress     string
+	}{
+		{
+			"m/86'/0'/0'/0/0",
+			"cc8a4bc64d897bddc5fbc2f670f7a8ba0b386779106cf1223c6fc5d7cd6fc115",
+			"a60869f0dbcf1dc659c9cecbaf8050135ea9e8cdc487053f1dc6880949dc684c",
+			"bc1p5cyxnuxmeuwuvkwfem96lqzszd02n6xdcjrs20cac6yqjjwudpxqkedrcr",
+		},
+		{
+			"m/86'/0'/0'/0/1",
+			"83dfe85a3151d2517290da461fe2815591ef69f2b18a2ce63f01697a8b313145",
+			"a82f29944d65b86ae6b5e5cc75e294ead6c59391a1edc5e016e3498c67fc7bbb",
+			"bc1p4qhjn9zdvkux4e44uhx8tc55attvtyu358kutcqkudyccelu0was9fqzwh",
+		},
+		{
+			"m/86'/0'/0'/1/0",
+			"399f1b2f4393f29a18c937859c5dd8a77350103157eb880f02e8c08214277cef",
+			"882d74e5d0572d5a816cef0041a96b6c1de832f6f9676d9605c44d5e9a97d3dc",
+			"bc1p3qkhfews2uk44qtvauqyr2ttdsw7svhkl9nkm9s9c3x4ax5h60wqwruhk7",
+		},
+	}
+
+	for _, test := range tests {
+		path, _ := walletHelper.DerivePath(test.path)
+		xPrvKey, _, _ := walletHelper.DeriveExtendedKeysFromPath(master, path)
+		prvKey := walletHelper.DerivePrivateKeyFromBytes(xPrvKey.Key)
+		address, internalKey, outputKey, err := walletHelper.DeriveTaprootAddress(prvKey.PubKey(), &chaincfg.MainNetParams)
+
+		if err != nil {
+			t.Errorf("Test failed: input: %s unexpected error: %v", test.path, err)
+			continue
+		}
+		if hex.EncodeToString(internalKey) != test.expectedInternalKey {
+			t.Errorf("Test failed: input: %s expected: %s received: %x ", test.path, test.expectedInternalKey, internalKey)
+		}
+		if hex.EncodeToString(outputKey) != test.expectedOutputKey {
+			t.Errorf("Test failed: input: %s expected: %s received: %x ", test.path, test.expectedOutputKey, outputKey)
+		}
+		if address != test.expectedAddress {
+			t.Errorf("Test failed: input: %s expected: %s received: %s ", test.path, test.expectedAddress, address)
+		}
+	}
+}
+
+func TestTaprootScriptPath(t *testing.T) {
+	// BIP341 wallet test vector with a single leaf script tree
+	internalKey, _ := hex.DecodeString("187791b6f712a8ea41c8ecdd0ee77fab3e85263b37e1ec18a3651926b3a6cf27")
+	script, _ := hex.DecodeString("20d85a959b0290bf19bb89ed43c916be835475d013da4b362117393e25a48229b8ac")
+	var expectedLeafHash string = "5b75adecf53548f3ec6ad7d78383bf84cc57b55a3127c72b9a2481752dd88b21"
+	var expectedAddress string = "bc1pz37fc4cn9ah8anwm4xqqhvxygjf9rjf2resrw8h8w4tmvcs0863sa2e586"
+	var expectedControlBlock string = "c1187791b6f712a8ea41c8ecdd0ee77fab3e85263b37e1ec18a3651926b3a6cf27"
+
+	leafHash := TapLeafHash(script)
+	if hex.EncodeToString(leafHash) != expectedLeafHash {
+		t.Errorf("Test failed:  expected: %s received: %x ", expectedLeafHash, leafHash)
+	}
+	internal, _ := ParseXOnlyPubKey(internalKey)
+	outputKey, err := TaprootOutputKey(internal, leafHash)
+	if err != nil {
+		t.Fatalf("Test failed: unexpected error: %v", err)
+	}
+	address, _ := TaprootAddress(outputKey, &chaincfg.MainNetParams)
+	if address != expectedAddress {
+		t.Errorf("Test failed:  expected: %s received: %s ", expectedAddress, address)
+	}
+	if controlBlock := TaprootControlBlock(internal, outputKey); hex.EncodeToString(controlBlock) != expectedControlBlock {
+		t.Errorf("Test failed:  expected: %s received: %x ", expectedControlBlock, controlBlock)
+	}
+}
+
+func TestTaprootMerkleTree(t *testing.T) {
+	leaves := [][]byte{
+		TapLeafHash([]byte{0x51}),
+		TapLeafHash([]byte{0x52}),
+		TapLeafHash([]byte{0x53}),
+	}
+	// three leaves split as ((0, 1), 2)
+	expected := TapBranchHash(TapBranchHash(leaves[0], leaves[1]), leaves[2])
+	merkleRoot, paths := TaprootMerkleTree(leaves)
+	if hex.EncodeToString(merkleRoot) != hex.EncodeToString(expected) {
+		t.Errorf("Test failed:  expected: %x received: %x ", expected, merkleRoot)
+	}
+	if len(paths) != 3 || len(paths[0]) != 2 || len(paths[2]) != 1 {
+		t.Fatalf("Test failed:  expected: paths of 2, 2 and 1 hashes received: %d paths ", len(paths))
+	}
+	// every path hashes its leaf up to the merkle root
+	for i, path := range paths {
+		hash := leaves[i]
+		for _, sibling := range path {
+			hash = TapBranchHash(hash, sibling)
+		}
+		if hex.EncodeToString(hash) != hex.EncodeToString(merkleRoot) {
+			t.Errorf("Test failed: input: leaf %d expected: %x received: %x ", i, merkleRoot, hash)
+		}
+	}
+
+	if merkleRoot, paths := TaprootMerkleTree(leaves[:1]); hex.EncodeToString(merkleRoot) != hex.EncodeToString(leaves[0]) || len(paths[0]) != 0 {
+		t.Errorf("Test failed:  expected: %x received: %x ", leaves[0], merkleRoot)
+	}
+}
@@ -2,8 +2,6 @@ package helpers
 
 import (
 	"fmt"
-	"strconv"
-	"strings"
 
 	"github.com/btcsuite/btcd/btcec"
 	"github.com/btcsuite/btcd/chaincfg"
@@ -14,12 +12,29 @@ import (
 
 type WalletHelper interface {
 	DeriveParamsFromPath(path string) (*BIP44Params, error)
+	DerivePath(path string) (DerivationPath, error)
+	DeriveRelativePath(path string) (DerivationPath, error)
+	DeriveExtendedPublicKey(key string, net *chaincfg.Params) (*bip32.Key, error)
 	DeriveExtendedKeys(master *bip32.Key, params *BIP44Params) (xPrvKey *bip32.Key, xPubKey *bip32.Key, err error)
+	DeriveExtendedKeysFromPath(master *bip32.Key, path DerivationPath) (xPrvKey *bip32.Key, xPubKey *bip32.Key, err error)
 	DerivePrivateKeyFromBytes(key []byte) *btcec.PrivateKey
-	DeriveAddress(prvKey *btcec.PrivateKey) (wif string, p2pkhAddress string, segwitBech32 string, segwitNested string, err error)
-	DerivePubKeyFromWif(wif []string) ([]*btcec.PublicKey, error)
+	DeriveAddress(prvKey *btcec.PrivateKey, net *chaincfg.Params) (wif string, p2pkhAddress string, segwitBech32 string, segwitNested string, err error)
+	DeriveAddressFromPubKey(pubKey *btcec.PublicKey, net *chaincfg.Params) (p2pkhAddress string, segwitBech32 string, segwitNested string, err error)
+	DeriveAddressForType(pubKey *btcec.PublicKey, net *chaincfg.Params, addressType AddressType) (string, error)
+	DeriveAccountDescriptors(key *bip32.Key, origin KeyOrigin, net *chaincfg.Params, addressType AddressType) (*AccountDescriptors, error)
+	DeriveTaprootAddress(pubKey *btcec.PublicKey, net *chaincfg.Params) (p2trAddress string, internalKey []byte, outputKey []byte, err error)
+	DerivePubKeyFromWif(wif []string, net *chaincfg.Params) ([]*btcec.PublicKey, error)
+	ResolvePublicKeys(keys []string, net *chaincfg.Params) ([][]byte, error)
 	DeriveOpcodes(n int8) (byte, error)
-	GenerateMultisignatureRedeemHash(n byte, m byte, publicKeys []*btcec.PublicKey) (string, error)
+	DeriveNetworkParams(network string) (*chaincfg.Params, error)
+	DeriveMnemonic(entropy []byte, language Language) (string, error)
+	DeriveSeedFromMnemonic(mnemonic string, passphrase string, language Language) ([]byte, Language, error)
+	DeriveSeedFromSlip39Shares(mnemonics []string, passphrase string) ([]byte, error)
+	EncodeExtendedKey(key *bip32.Key, net *chaincfg.Params) string
+	EncodeSlip132ExtendedKey(key *bip32.Key, net *chaincfg.Params, addressType AddressType) (string, error)
+	GenerateSlip39Shares(masterSecret []byte, passphrase string, groupThreshold int, groups []Slip39Group, iterationExponent int, extendable bool) ([][]string, error)
+	GenerateMultisignatureRedeemHash(n byte, m byte, publicKeys []*btcec.PublicKey, net *chaincfg.Params) (string, error)
+	GenerateMultisignatureAddress(n int, m int, publicKeys [][]byte, net *chaincfg.Params, multisigType MultisigType) (address string, script []byte, err error)
 }
 
 type walletHelper struct {
@@ -33,34 +48,10 @@ type BIP44Params struct {
 	AddressIndex uint32 `json:"addressIndex"`
 }
 
-func (wh *walletHelper) GenerateMultisignatureRedeemHash(n byte, m byte, publicKeys []*btcec.PublicKey) (string, error) {
-	// create redeem script for 2 of 3 multi-sig
-	builder := txscript.NewScriptBuilder()
-
-	// add the minimum number of needed signatures
-	builder.AddOp(m)
-	// add the 3 public key
-	for i, _ := range publicKeys {
-		builder = builder.AddData(publicKeys[i].SerializeCompressed())
-	}
-	// add the total number of public keys in the multi-sig screipt
-	builder.AddOp(n)
-
-	// add the check-multi-sig op-code
-	builder.AddOp(txscript.OP_CHECKMULTISIG)
-
-	redeemScript, err := builder.Script()
-	if err != nil {
-		return "", err
-	}
-	// calculate the hash160 of the redeem script
-	redeemHash := btcutil.Hash160(redeemScript)
-
-	addr, err := btcutil.NewAddressScriptHashFromHash(redeemHash, &chaincfg.MainNetParams)
-	if err != nil {
-		return "", err
-	}
-	return addr.EncodeAddress(), nil
+func (wh *walletHelper) GenerateMultisignatureRedeemHash(n byte, m byte, publicKeys []*btcec.PublicKey, net *chaincfg.Params) (string, error) {
+	// n and m are the OP_1 to OP_16 opcodes
+	address, _, err := wh.GenerateMultisignatureAddress(int(n-txscript.OP_1)+1, int(m-txscript.OP_1)+1, SerializePublicKeys(publicKeys), net, MultisigTypeP2SH)
+	return address, err
 }
 func (wh *walletHelper) DeriveOpcodes(i int8) (byte, error) {
 	switch i {
@@ -101,12 +92,39 @@ func (wh *walletHelper) DeriveOpcodes(i int8) (byte, error) {
 	}
 }
 
-func (wh *walletHelper) DerivePubKeyFromWif(wif []string) (publicKeys []*btcec.PublicKey, err error) {
+func (wh *walletHelper) DeriveNetworkParams(network string) (*chaincfg.Params, error) {
+	return NetworkParams(network)
+}
+
+func (wh *walletHelper) DeriveMnemonic(entropy []byte, language Language) (string, error) {
+	return NewMnemonic(entropy, language)
+}
+
+func (wh *walletHelper) DeriveSeedFromMnemonic(mnemonic string, passphrase string, language Language) ([]byte, Language, error) {
+	return MnemonicToSeed(mnemonic, passphrase, language)
+}
+
+func (wh *walletHelper) DeriveSeedFromSlip39Shares(mnemonics []string, passphrase string) ([]byte, error) {
+	return CombineSlip39Shares(mnemonics, passphrase)
+}
+
+func (wh *walletHelper) GenerateSlip39Shares(masterSecret []byte, passphrase string, groupThreshold int, groups []Slip39Group, iterationExponent int, extendable bool) ([][]string, error) {
+	return GenerateSlip39Shares(masterSecret, passphrase, groupThreshold, groups, iterationExponent, extendable)
+}
+
+func (wh *walletHelper) EncodeExtendedKey(key *bip32.Key, net *chaincfg.Params) string {
+	return encodeExtendedKey(key, net)
+}
+
+func (wh *walletHelper) DerivePubKeyFromWif(wif []string, net *chaincfg.Params) (publicKeys []*btcec.PublicKey, err error) {
 	for i, _ := range wif {
 		wif1, err := btcutil.DecodeWIF(wif[i])
 		if err != nil {
 			return nil, err
 		}
+		if !wif1.IsForNet(net) {
+			return nil, fmt.Errorf("wif %d is not for network %s", i, net.Name)
+		}
 		// public key extracted from wif.PrivKey
 		pk := wif1.PrivKey.PubKey()
 		publicKeys = append(publicKeys, pk)
@@ -114,41 +132,50 @@ func (wh *walletHelper) DerivePubKeyFromWif(wif []string) (publicKeys []*btcec.P
 	return publicKeys, nil
 }
 
-func (wh *walletHelper) DeriveAddress(prvKey *btcec.PrivateKey) (wif string, p2pkhAddress string, segwitBech32 string, segwitNested string, err error) {
+func (wh *walletHelper) DeriveAddress(prvKey *btcec.PrivateKey, net *chaincfg.Params) (wif string, p2pkhAddress string, segwitBech32 string, segwitNested string, err error) {
 	// generate the wif(wallet import format) string
-	btcwif, err := btcutil.NewWIF(prvKey, &chaincfg.MainNetParams, true)
+	btcwif, err := btcutil.NewWIF(prvKey, net, true)
 	if err != nil {
 		return "", "", "", "", err
 	}
 	wif = btcwif.String()
 
-	// generate a normal p2pkh address
-	serializedPubKey := btcwif.SerializePubKey()
-	addressPubKey, err := btcutil.NewAddressPubKey(serializedPubKey, &chaincfg.MainNetParams)
+	p2pkhAddress, segwitBech32, segwitNested, err = wh.DeriveAddressFromPubKey(prvKey.PubKey(), net)
 	if err != nil {
 		return "", "", "", "", err
 	}
+
+	return wif, p2pkhAddress, segwitBech32, segwitNested, nil
+}
+
+func (wh *walletHelper) DeriveAddressFromPubKey(pubKey *btcec.PublicKey, net *chaincfg.Params) (p2pkhAddress string, segwitBech32 string, segwitNested string, err error) {
+	// generate a normal p2pkh address
+	serializedPubKey := pubKey.SerializeCompressed()
+	addressPubKey, err := btcutil.NewAddressPubKey(serializedPubKey, net)
+	if err != nil {
+		return "", "", "", err
+	}
 	p2pkhAddress = addressPubKey.EncodeAddress()
 
 	// generate a normal p2wkh address from the pubkey hash
 	witnessProg := btcutil.Hash160(serializedPubKey)
-	addressWitnessPubKeyHash, err := btcutil.NewAddressWitnessPubKeyHash(witnessProg, &chaincfg.MainNetParams)
+	addressWitnessPubKeyHash, err := btcutil.NewAddressWitnessPubKeyHash(witnessProg, net)
 	if err != nil {
-		return "", "", "", "", err
+		return "", "", "", err
 	}
 	segwitBech32 = addressWitnessPubKeyHash.EncodeAddress()
 
 	serializedScript, err := txscript.PayToAddrScript(addressWitnessPubKeyHash)
 	if err != nil {
-		return "", "", "", "", err
+		return "", "", "", err
 	}
-	addressScriptHash, err := btcutil.NewAddressScriptHash(serializedScript, &chaincfg.MainNetParams)
+	addressScriptHash, err := btcutil.NewAddressScriptHash(serializedScript, net)
 	if err != nil {
-		return "", "", "", "", err
+		return "", "", "", err
 	}
 	segwitNested = addressScriptHash.EncodeAddress()
 
-	return wif, p2pkhAddress, segwitBech32, segwitNested, nil
+	return p2pkhAddress, segwitBech32, segwitNested, nil
 }
 
 func (wh *walletHelper) DerivePrivateKeyFromBytes(key []byte) *btcec.PrivateKey {
@@ -156,115 +183,75 @@ func (wh *walletHelper) DerivePrivateKeyFromBytes(key []byte) *btcec.PrivateKey
 	return privateKey
 }
 func (wh *walletHelper) DeriveParamsFromPath(path string) (*BIP44Params, error) {
-	// Split path into params
-	spl := strings.Split(path, "/")
-
-	// Trim white spaces
-	for i := range spl {
-		spl[i] = strings.TrimSpace(spl[i])
-	}
-
-	if len(spl) != 6 {
-		return nil, fmt.Errorf("path length is wrong. Expected 6, got %d", len(spl))
-	}
-
-	purpose, err := hardenedInt(spl[1])
-	if err != nil {
-		return nil, err
-	}
-	coinType, err := hardenedInt(spl[2])
-	if err != nil {
-		return nil, err
-	}
-	account, err := hardenedInt(spl[3])
-	if err != nil {
-		return nil, err
-	}
-	change, err := hardenedInt(spl[4])
-	if err != nil {
-		return nil, err
-	}
-	addressIdx, err := hardenedInt(spl[5])
+	derivationPath, err := ParseDerivationPath(path)
 	if err != nil {
 		return nil, err
 	}
 
-	if spl[0] != "m" {
-		return nil, fmt.Errorf("Invalid path")
+	if len(derivationPath) != 5 {
+		return nil, fmt.Errorf("path length is wrong. Expected 6, got %d", len(derivationPath)+1)
 	}
 
 	// Validate path values
-	// if spl[1] != "44'" {
-	// 	return nil, fmt.Errorf("first field in path must be 44', got %s", spl[0])
-	// }
-
-	if !isHardened(spl[2]) || !isHardened(spl[3]) {
+	if derivationPath[1] < bip32.FirstHardenedChild || derivationPath[2] < bip32.FirstHardenedChild {
 		return nil,
-			fmt.Errorf("second and third field in path must be hardened (ie. contain the suffix ', got %s and %s", spl[2], spl[3])
+			fmt.Errorf("second and third field in path must be hardened (ie. contain the suffix ', got %s and %s",
+				formatChildIndex(derivationPath[1]), formatChildIndex(derivationPath[2]))
 	}
 
-	if isHardened(spl[4]) || isHardened(spl[5]) {
+	if derivationPath[3] >= bip32.FirstHardenedChild || derivationPath[4] >= bip32.FirstHardenedChild {
 		return nil,
-			fmt.Errorf("fourth and fifth field in path must not be hardened (ie. not contain the suffix ', got %s and %s", spl[3], spl[4])
+			fmt.Errorf("fourth and fifth field in path must not be hardened (ie. not contain the suffix ', got %s and %s",
+				formatChildIndex(derivationPath[3]), formatChildIndex(derivationPath[4]))
 	}
 
-	if !(change == 0 || change == 1) {
+	if !(derivationPath[3] == 0 || derivationPath[3] == 1) {
 		return nil, fmt.Errorf("change field can only be 0 or 1")
 	}
 
 	return &BIP44Params{
-		Purpose:      purpose,
-		CoinType:     coinType,
-		Account:      account,
-		Change:       change,
-		AddressIndex: addressIdx,
+		Purpose:      derivationPath[0],
+		CoinType:     derivationPath[1],
+		Account:      derivationPath[2],
+		Change:       derivationPath[3],
+		AddressIndex: derivationPath[4],
 	}, nil
 }
-func (wh *walletHelper) DeriveExtendedKeys(master *bip32.Key, params *BIP44Params) (xPrvKey *bip32.Key, xPubKey *bip32.Key, err error) {
-	child, err := master.NewChildKey(params.Purpose)
-	if err != nil {
-		return nil, nil, err
-	}
-	child, err = child.NewChildKey(params.CoinType)
-	if err != nil {
-		return nil, nil, err
-	}
 
-	child, err = child.NewChildKey(params.Account)
-	if err != nil {
-		return nil, nil, err
-	}
+func (wh *walletHelper) DerivePath(path string) (DerivationPath, error) {
+	return ParseDerivationPath(path)
+}
 
-	child, err = child.NewChildKey(params.Change)
+func (wh *walletHelper) DeriveRelativePath(path string) (DerivationPath, error) {
+	return ParseRelativePath(path)
+}
+
+func (wh *walletHelper) DeriveExtendedPublicKey(key string, net *chaincfg.Params) (*bip32.Key, error) {
+	xPubKey, _, err := decodeExtendedKey(key, net)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
-	child, err = child.NewChildKey(params.AddressIndex)
-	if err != nil {
-		return nil, nil, err
+	if xPubKey.IsPrivate {
+		return nil, fmt.Errorf("expected an extended public key, got a private one")
 	}
-	return child, child.PublicKey(), nil
+	return xPubKey, nil
 }
-func isHardened(field string) bool {
-	return strings.HasSuffix(field, "'")
+
+func (wh *walletHelper) DeriveExtendedKeys(master *bip32.Key, params *BIP44Params) (xPrvKey *bip32.Key, xPubKey *bip32.Key, err error) {
+	return wh.DeriveExtendedKeysFromPath(master, params.Path())
 }
-func hardenedInt(field string) (uint32, error) {
-	hasSuffix := strings.HasSuffix(field, "'")
-	field = strings.TrimSuffix(field, "'")
-	i, err := strconv.Atoi(field)
 
-	if err != nil {
-		return 0, err
-	}
-	if i < 0 {
-		return 0, fmt.Errorf("fields must not be negative. got %d", i)
-	}
-	if hasSuffix {
-		hardenedInt := bip32.FirstHardenedChild + uint32(i)
-		return hardenedInt, nil
+func (wh *walletHelper) DeriveExtendedKeysFromPath(master *bip32.Key, path DerivationPath) (xPrvKey *bip32.Key, xPubKey *bip32.Key, err error) {
+	child := master
+	for _, index := range path {
+		child, err = child.NewChildKey(index)
+		if err != nil {
+			return nil, nil, err
+		}
 	}
-	return uint32(i), nil
+	return child, child.PublicKey(), nil
 }
+
 func NewWalletHelper() WalletHelper {
 	return &walletHelper{}
 }
@@ -4,6 +4,7 @@ import (
 	"encoding/hex"
 	"testing"
 
+	"github.com/btcsuite/btcd/chaincfg"
 	"github.com/btcsuite/btcd/txscript"
 	"github.com/tyler-smith/go-bip32"
 )
@@ -47,7 +48,7 @@ func TestDeriveAddress(t *testing.T) {
 	params, _ := walletHelper.DeriveParamsFromPath(path)
 	xPrvKey, _, _ := walletHelper.DeriveExtendedKeys(master, params)
 	prvKey := walletHelper.DerivePrivateKeyFromBytes(xPrvKey.Key)
-	wif, p2pkhAddress, segwitBech32, segwitNested, _ := walletHelper.DeriveAddress(prvKey)
+	wif, p2pkhAddress, segwitBech32, segwitNested, _ := walletHelper.DeriveAddress(prvKey, &chaincfg.MainNetParams)
 
 	var expectedWif string = "L1dbCB2GPDDzxNJJ7s7h7a84NSXViBNwJeLWTS2gJMtdapfkhmg8"
 	var expectedP2pkhAddress string = "1AKdhnB63swG2XpSuuXWP8MqP596zRJJCz"
@@ -84,17 +85,17 @@ func TestGenerateMultisignatureRedeemHash(t *testing.T) {
 	var wif []string
 	wif = append(wif, "cQpHXfs91s5eR9PWXui6qo2xjoJb2X3VdUspwKXe4A8Dybvut2rL")
 	wif = append(wif, "cVgxEkRBtnfvd41ssd4PCsiemahAHidFrLWYoDBMNojUeME8dojZ")
-	var n int8 = 1
+	var n int8 = 2
 	var m int8 = 2
 
 	numOfPubKeys, _ := walletHelper.DeriveOpcodes(n)
 	minSignature, _ := walletHelper.DeriveOpcodes(m)
 
-	publicKeys, _ := walletHelper.DerivePubKeyFromWif(wif)
+	publicKeys, _ := walletHelper.DerivePubKeyFromWif(wif, &chaincfg.TestNet3Params)
 
-	result, _ := walletHelper.GenerateMultisignatureRedeemHash(numOfPubKeys, minSignature, publicKeys)
+	result, _ := walletHelper.GenerateMultisignatureRedeemHash(numOfPubKeys, minSignature, publicKeys, &chaincfg.TestNet3Params)
 
-	var expectedResult string = "3MqSiHLbK6M8YUL8sXKiULeiRSvckJV74h"
+	var expectedResult string = "2N6h96DjYRR9YKTZWZePcft4FFfnSQooAr3"
 
 	if result != expectedResult {
 		t.Errorf("Test failed:  expected: %s received: %s ", expectedResult, result)
@@ -0,0 +1,207 @@
+package helpers
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/tyler-smith/go-bip39/wordlists"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Language names a BIP39 word list.
+type Language string
+
+const (
+	LanguageEnglish            Language = "english"
+	LanguageJapanese           Language = "japanese"
+	LanguageChineseSimplified  Language = "chinese_simplified"
+	LanguageChineseTraditional Language = "chinese_traditional"
+	LanguageKorean             Language = "korean"
+	LanguageSpanish            Language = "spanish"
+	LanguageFrench             Language = "french"
+	LanguageItalian            Language = "italian"
+	LanguageCzech              Language = "czech"
+	LanguagePortuguese         Language = "portuguese"
+)
+
+// ErrUnsupportedLanguage is returned for a language without a word list.
+var ErrUnsupportedLanguage = errors.New("unsupported mnemonic language")
+
+// Languages lists the supported word lists in the order auto-detection prefers
+// them when a mnemonic is valid in more than one list.
+var Languages = []Language{
+	LanguageEnglish,
+	LanguageJapanese,
+	LanguageChineseSimplified,
+	LanguageChineseTraditional,
+	LanguageKorean,
+	LanguageSpanish,
+	LanguageFrench,
+	LanguageItalian,
+	LanguageCzech,
+	LanguagePortuguese,
+}
+
+type wordList struct {
+	// words holds the composed NFC form used to write new mnemonics, go-bip39
+	// ships some lists decomposed
+	words []string
+	// indexes is keyed by the NFKD form of every word, which is the form
+	// NormalizeMnemonic produces
+	indexes map[string]int
+}
+
+var wordLists = map[Language]*wordList{
+	LanguageEnglish:            newWordList(wordlists.English),
+	LanguageJapanese:           newWordList(wordlists.Japanese),
+	LanguageChineseSimplified:  newWordList(wordlists.ChineseSimplified),
+	LanguageChineseTraditional: newWordList(wordlists.ChineseTraditional),
+	LanguageKorean:             newWordList(wordlists.Korean),
+	LanguageSpanish:            newWordList(wordlists.Spanish),
+	LanguageFrench:             newWordList(wordlists.French),
+	LanguageItalian:            newWordList(wordlists.Italian),
+	LanguageCzech:              newWordList(wordlists.Czech),
+	LanguagePortuguese:         newWordList(portugueseWordList),
+}
+
+func newWordList(words []string) *wordList {
+	list := &wordList{words: make([]string, len(words)), indexes: make(map[string]int, len(words))}
+	for i, word := range words {
+		list.words[i] = norm.NFC.String(word)
+		list.indexes[norm.NFKD.String(word)] = i
+	}
+	return list
+}
+
+func lookupWordList(language Language) (*wordList, error) {
+	if language == "" {
+		language = LanguageEnglish
+	}
+	list, ok := wordLists[language]
+	if !ok {
+		return nil, fmt.Errorf("%w %s. must be one of %v", ErrUnsupportedLanguage, language, Languages)
+	}
+	return list, nil
+}
+
+// separator joins the words of a mnemonic, Japanese uses the ideographic space.
+func (language Language) separator() string {
+	if language == LanguageJapanese {
+		return "\u3000"
+	}
+	return " "
+}
+
+// DetectMnemonicLanguage finds the word list every word of the mnemonic
+// belongs to. When several lists match, the one with a valid checksum wins.
+func DetectMnemonicLanguage(mnemonic string) (Language, error) {
+	words := strings.Fields(NormalizeMnemonic(mnemonic))
+	var candidates []Language
+	for _, language := range Languages {
+		list := wordLists[language]
+		found := true
+		for _, word := range words {
+			if _, ok := list.indexes[word]; !ok {
+				found = false
+				break
+			}
+		}
+		if found {
+			candidates = append(candidates, language)
+		}
+	}
+	if len(candidates) == 0 {
+		return "", fmt.Errorf("%w: words do not belong to any supported word list", ErrInvalidMnemonic)
+	}
+	for _, language := range candidates {
+		if _, err := mnemonicEntropy(words, language); err == nil {
+			return language, nil
+		}
+	}
+	return candidates[0], nil
+}
+
+// WordIndex returns the index of a word in the word list of the language.
+func WordIndex(word string, language Language) (int, error) {
+	list, err := lookupWordList(language)
+	if err != nil {
+		return 0, err
+	}
+	index, ok := list.indexes[norm.NFKD.String(word)]
+	if !ok {
+		return 0, fmt.Errorf("%w: word %q is not in the %s word list", ErrInvalidMnemonic, word, language)
+	}
+	return index, nil
+}
+
+// SimilarWords returns the indexes of the words of the list within maxDistance
+// edits of word, an edit being an insertion, a deletion, a substitution or the
+// transposition of two adjacent letters.
+func SimilarWords(word string, language Language, maxDistance int) ([]int, error) {
+	list, err := lookupWordList(language)
+	if err != nil {
+		return nil, err
+	}
+	word = norm.NFKD.String(word)
+	var indexes []int
+	for i, candidate := range list.words {
+		if EditDistance(word, norm.NFKD.String(candidate)) <= maxDistance {
+			indexes = append(indexes, i)
+		}
+	}
+	return indexes, nil
+}
+
+// MnemonicFromIndexes writes the words at the indexes of the word list.
+func MnemonicFromIndexes(indexes []int, language Language) (string, error) {
+	list, err := lookupWordList(language)
+	if err != nil {
+		return "", err
+	}
+	words := make([]string, len(indexes))
+	for i, index := range indexes {
+		if index < 0 || index >= len(list.words) {
+			return "", fmt.Errorf("word index %d is out of range", index)
+		}
+		words[i] = list.words[index]
+	}
+	if language == "" {
+		language = LanguageEnglish
+	}
+	return strings.Join(words, language.separator()), nil
+}
+
+// EditDistance is the optimal string alignment distance between two words,
+// counted in runes.
+func EditDistance(a string, b string) int {
+	s, t := []rune(a), []rune(b)
+	rows := make([][]int, len(s)+1)
+	for i := range rows {
+		rows[i] = make([]int, len(t)+1)
+		rows[i][0] = i
+	}
+	for j := range rows[0] {
+		rows[0][j] = j
+	}
+	for i := 1; i <= len(s); i++ {
+		for j := 1; j <= len(t); j++ {
+			cost := 1
+			if s[i-1] == t[j-1] {
+				cost = 0
+			}
+			d := rows[i-1][j-1] + cost
+			if rows[i-1][j]+1 < d {
+				d = rows[i-1][j] + 1
+			}
+			if rows[i][j-1]+1 < d {
+				d = rows[i][j-1] + 1
+			}
+			if i > 1 && j > 1 && s[i-1] == t[j-2] && s[i-2] == t[j-1] && rows[i-2][j-2]+1 < d {
+				d = rows[i-2][j-2] + 1
+			}
+			rows[i][j] = d
+		}
+	}
+	return rows[len(s)][len(t)]
+}
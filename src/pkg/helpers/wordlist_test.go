@@ -0,0 +1,135 @@
+package helpers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/tyler-smith/go-bip39"
+)
+
+func TestNewMnemonicJapanese(t *testing.T) {
+	var expected string = strings.Repeat("あいこくしん　", 11) + "あおぞら"
+
+	mnemonic, err := NewMnemonic(make([]byte, 16), LanguageJapanese)
+	if err != nil {
+		t.Fatalf("Test failed: unexpected error: %v", err)
+	}
+	if mnemonic != expected {
+		t.Errorf("Test failed:  expected: %s received: %s ", expected, mnemonic)
+	}
+
+	// BIP39 japanese test vector, the ideographic space is normalized to a space
+	var expectedSeed string = "a262d6fb6122ecf45be09c50492b31f92e9beb7d9a845987a02cefda57a15f9c467a17872029a9e92299b5cbdf306e3a0ee620245cbd508959b6cb7ca637bd55"
+	seed, language, err := MnemonicToSeed(mnemonic, "㍍ガバヴァぱばぐゞちぢ十人十色", "")
+	if err != nil {
+		t.Fatalf("Test failed: unexpected error: %v", err)
+	}
+	if hex.EncodeToString(seed) != expectedSeed {
+		t.Errorf("Test failed:  expected: %s received: %x ", expectedSeed, seed)
+	}
+	if language != LanguageJapanese {
+		t.Errorf("Test failed:  expected: %s received: %s ", LanguageJapanese, language)
+	}
+}
+
+func TestNewMnemonicEnglish(t *testing.T) {
+	entropy, _ := hex.DecodeString("7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f")
+	expected, _ := bip39.NewMnemonic(entropy)
+
+	mnemonic, err := NewMnemonic(entropy, "")
+	if err != nil {
+		t.Fatalf("Test failed: unexpected error: %v", err)
+	}
+	if mnemonic != expected {
+		t.Errorf("Test failed:  expected: %s received: %s ", expected, mnemonic)
+	}
+}
+
+func TestDetectMnemonicLanguage(t *testing.T) {
+	entropy, _ := hex.DecodeString("9e885d952ad362caeb4efe34a8e91bd2")
+
+	for _, language := range Languages {
+		mnemonic, err := NewMnemonic(entropy, language)
+		if err != nil {
+			t.Errorf("Test failed: input: %s unexpected error: %v", language, err)
+			continue
+		}
+		detected, err := DetectMnemonicLanguage(mnemonic)
+		if err != nil {
+			t.Errorf("Test failed: input: %s unexpected error: %v", language, err)
+			continue
+		}
+		if detected != language {
+			t.Errorf("Test failed:  expected: %s received: %s ", language, detected)
+		}
+		decoded, err := mnemonicEntropy(strings.Fields(NormalizeMnemonic(mnemonic)), detected)
+		if err != nil || !bytes.Equal(decoded, entropy) {
+			t.Errorf("Test failed:  expected: %x received: %x ", entropy, decoded)
+		}
+	}
+
+	if _, err := DetectMnemonicLanguage("abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon zzz"); !errors.Is(err, ErrInvalidMnemonic) {
+		t.Errorf("Test failed:  expected: %v received: %v ", ErrInvalidMnemonic, err)
+	}
+}
+
+func TestPortugueseWordList(t *testing.T) {
+	// sha256 of bip-0039/portuguese.txt
+	var expectedHash string = "2685e9c194c82ae67e10ba59d9ea5345a23dc093e92276fc5361f6667d79cd3f"
+	hash := sha256.Sum256([]byte(strings.Join(portugueseWordList, "\n") + "\n"))
+	if len(portugueseWordList) != 2048 || hex.EncodeToString(hash[:]) != expectedHash {
+		t.Errorf("Test failed:  expected: %s received: %x ", expectedHash, hash)
+	}
+
+	// the BIP39 reference vectors written with the same word indexes, the
+	// seeds are checked against go-bip39 which stretches any mnemonic
+	for _, vector := range []string{
+		"00000000000000000000000000000000",
+		"7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f",
+		"80808080808080808080808080808080",
+		"ffffffffffffffffffffffffffffffff",
+		"7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f",
+		"808080808080808080808080808080808080808080808080",
+		"0000000000000000000000000000000000000000000000000000000000000000",
+		"9e885d952ad362caeb4efe34a8e91bd2",
+		"f585c11aec520db57dd353c69554b21a89b20fb0650966fa0a9d6f74fd989d8f",
+	} {
+		entropy, _ := hex.DecodeString(vector)
+		english, _ := bip39.NewMnemonic(entropy)
+		var expected []string
+		for _, word := range strings.Fields(english) {
+			index, _ := WordIndex(word, LanguageEnglish)
+			expected = append(expected, portugueseWordList[index])
+		}
+		mnemonic, err := NewMnemonic(entropy, LanguagePortuguese)
+		if err != nil {
+			t.Fatalf("Test failed: unexpected error: %v", err)
+		}
+		if mnemonic != strings.Join(expected, " ") {
+			t.Errorf("Test failed:  expected: %s received: %s ", strings.Join(expected, " "), mnemonic)
+		}
+		seed, language, err := MnemonicToSeed(mnemonic, "TREZOR", "")
+		if err != nil {
+			t.Fatalf("Test failed: unexpected error: %v", err)
+		}
+		if language != LanguagePortuguese {
+			t.Errorf("Test failed:  expected: %s received: %s ", LanguagePortuguese, language)
+		}
+		if expectedSeed := bip39.NewSeed(mnemonic, "TREZOR"); !bytes.Equal(seed, expectedSeed) {
+			t.Errorf("Test failed:  expected: %x received: %x ", expectedSeed, seed)
+		}
+	}
+}
+
+func TestUnsupportedLanguage(t *testing.T) {
+	if _, err := NewMnemonic(make([]byte, 16), "klingon"); !errors.Is(err, ErrUnsupportedLanguage) {
+		t.Errorf("Test failed:  expected: %v received: %v ", ErrUnsupportedLanguage, err)
+	}
+	if err := ValidateMnemonic("legal winner thank year wave sausage worth useful legal winner thank yellow", "klingon"); !errors.Is(err, ErrUnsupportedLanguage) {
+		t.Errorf("Test failed:  expected: %v received: %v ", ErrUnsupportedLanguage, err)
+	}
+}
@@ -0,0 +1,999 @@
+package managers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"btcwallet.com/src/pkg/coinselect"
+	"btcwallet.com/src/pkg/descriptors"
+	"btcwallet.com/src/pkg/helpers"
+	"btcwallet.com/src/pkg/psbt"
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/tyler-smith/go-bip32"
+)
+
+type PsbtManager interface {
+	CreatePsbt(inputs []PsbtInput, outputs []PsbtOutput, feeRate float64, changeAddress string, descriptor string, extPubKeys []string, network string) (*CreatedPsbt, error)
+	SignPsbt(encoded string, keys PsbtSigningKeys, network string) (*SignedPsbt, error)
+	CombinePsbt(psbts []string) (*CombinedPsbt, error)
+	FinalizePsbt(psbts []string) (*FinalizedPsbt, error)
+	ExtractPsbt(psbts []string) (*ExtractedTx, error)
+	EstimateFee(inputs []FeeInput, outputs []FeeOutput, feeRates []float64) (*FeeEstimate, error)
+}
+
+type psbtManager struct {
+	walletHelper helpers.WalletHelper
+}
+
+// PsbtInput is a UTXO spent by CreatePsbt, scripts and transactions are hex.
+// RedeemScript and WitnessScript are needed for P2SH and P2WSH outputs no
+// descriptor describes. PreviousTx is the raw transaction of the UTXO, it is
+// required to spend an output without witness.
+//
+// Path locates the input in the descriptor: an absolute path such as
+// "m/84'/0'/0'/0/5" is matched against the key origins, restricted to the
+// master key Fingerprint when one is set, a relative path such as "0/5"
+// against the steps after the extended keys. Without descriptor, PubKey,
+// Fingerprint and an absolute Path give the derivation of the key the UTXO
+// pays to.
+type PsbtInput struct {
+	TxId          string
+	Vout          uint32
+	Amount        int64
+	ScriptPubKey  string
+	RedeemScript  string
+	WitnessScript string
+	PreviousTx    string
+	PubKey        string
+	Fingerprint   string
+	Path          string
+}
+
+// PsbtOutput is an amount in satoshis paid to an address.
+type PsbtOutput struct {
+	Address string
+	Amount  int64
+}
+
+// CreatedPsbt is the PSBT built by CreatePsbt with the id of its unsigned
+// transaction. Fee pays the estimated signed size at the fee rate, plus what
+// is left when a change output would be dust. ChangeIndex is -1 when the
+// transaction has no change output.
+type CreatedPsbt struct {
+	Psbt         string `json:"psbt"`
+	Txid         string `json:"txid"`
+	Fee          int64  `json:"fee"`
+	VSize        int    `json:"vsize"`
+	ChangeIndex  int    `json:"changeIndex"`
+	ChangeAmount int64  `json:"changeAmount"`
+}
+
+// DustLimit is the P2PKH dust threshold, the most a transaction without change
+// address may leave to the fee on top of the estimated fee.
+const DustLimit = 546
+
+// CreatePsbt builds a version 2 transaction spending the inputs to the
+// outputs, every input signaling replaceability, and returns it as a base64
+// PSBT with the UTXO, the scripts and the key derivations of every input. A
+// change output receives what is left after the fee at feeRate sat/vB unless
+// it would be dust.
+func (pm *psbtManager) CreatePsbt(inputs []PsbtInput, outputs []PsbtOutput, feeRate float64, changeAddress string, descriptor string, extPubKeys []string, network string) (*CreatedPsbt, error) {
+	if len(inputs) == 0 || len(outputs) == 0 {
+		return nil, fmt.Errorf("%w: a transaction needs inputs and outputs", psbt.ErrInvalidPsbt)
+	}
+	if feeRate <= 0 || math.IsInf(feeRate, 0) || math.IsNaN(feeRate) {
+		return nil, fmt.Errorf("%w: fee rate must be positive. got %v", psbt.ErrInvalidPsbt, feeRate)
+	}
+	net, err := pm.walletHelper.DeriveNetworkParams(network)
+	if err != nil {
+		return nil, err
+	}
+	var parsed *descriptors.Descriptor
+	if descriptor != "" {
+		if len(extPubKeys) > 0 {
+			return nil, fmt.Errorf("%w: set either a descriptor or extPubKeys", psbt.ErrInvalidPsbt)
+		}
+		if parsed, err = descriptors.Parse(pm.walletHelper, descriptor, net); err != nil {
+			return nil, err
+		}
+	}
+	cosigners := make([]multisigKey, len(extPubKeys))
+	for i, extPubKey := range extPubKeys {
+		key, origin, err := parseOriginKey(strings.TrimSpace(extPubKey), net)
+		if err != nil {
+			return nil, fmt.Errorf("%w: extended public key %d: %v", psbt.ErrInvalidPsbt, i, err)
+		}
+		if key.IsPrivate {
+			return nil, fmt.Errorf("%w: extended public key %d is private", psbt.ErrInvalidPsbt, i)
+		}
+		cosigners[i] = multisigKey{key, origin}
+	}
+
+	tx := wire.NewMsgTx(2)
+	var available, spent int64
+	seen := map[string]bool{}
+	for i, input := range inputs {
+		hash, err := chainhash.NewHashFromStr(input.TxId)
+		if err != nil || len(input.TxId) != 2*chainhash.HashSize {
+			return nil, fmt.Errorf("%w: input %d: txid must be 64 hex characters", psbt.ErrInvalidPsbt, i)
+		}
+		outPoint := wire.NewOutPoint(hash, input.Vout)
+		if seen[outPoint.String()] {
+			return nil, fmt.Errorf("%w: input %d spends %s twice", psbt.ErrInvalidPsbt, i, outPoint)
+		}
+		seen[outPoint.String()] = true
+		if input.Amount <= 0 || input.Amount > btcutil.MaxSatoshi {
+			return nil, fmt.Errorf("%w: input %d: amount out of range. got %d", psbt.ErrInvalidPsbt, i, input.Amount)
+		}
+		available += input.Amount
+		txIn := wire.NewTxIn(outPoint, nil, nil)
+		txIn.Sequence = wire.MaxTxInSequenceNum - 2
+		tx.AddTxIn(txIn)
+	}
+	for i, output := range outputs {
+		script, err := helpers.AddressScript(output.Address, net)
+		if err != nil {
+			return nil, fmt.Errorf("%w: output %d: %v", psbt.ErrInvalidPsbt, i, err)
+		}
+		if output.Amount < psbt.DustThreshold(script) || output.Amount > btcutil.MaxSatoshi {
+			return nil, fmt.Errorf("%w: output %d: amount %d is dust or out of range, it must be at least %d", psbt.ErrInvalidPsbt, i, output.Amount, psbt.DustThreshold(script))
+		}
+		spent += output.Amount
+		tx.AddTxOut(wire.NewTxOut(output.Amount, script))
+	}
+
+	packet, err := psbt.New(tx)
+	if err != nil {
+		return nil, err
+	}
+	for i, input := range inputs {
+		psbtInput, err := psbtInputFields(input, tx.TxIn[i].PreviousOutPoint, parsed, cosigners)
+		if err != nil {
+			return nil, fmt.Errorf("input %d: %w", i, err)
+		}
+		packet.Inputs[i] = *psbtInput
+	}
+
+	result := &CreatedPsbt{ChangeIndex: -1}
+	if changeAddress != "" {
+		changeScript, err := helpers.AddressScript(changeAddress, net)
+		if err != nil {
+			return nil, fmt.Errorf("%w: change address: %v", psbt.ErrInvalidPsbt, err)
+		}
+		tx.AddTxOut(wire.NewTxOut(0, changeScript))
+		packet.Outputs = append(packet.Outputs, psbt.Output{})
+		vsize, fee, err := estimateFee(packet, feeRate)
+		if err != nil {
+			return nil, err
+		}
+		change := available - spent - fee
+		if change >= psbt.DustThreshold(changeScript) {
+			tx.TxOut[len(tx.TxOut)-1].Value = change
+			result.ChangeIndex, result.ChangeAmount = len(tx.TxOut)-1, change
+			result.Fee, result.VSize = fee, vsize
+		} else {
+			// the change is left to the fee
+			tx.TxOut = tx.TxOut[:len(tx.TxOut)-1]
+			packet.Outputs = packet.Outputs[:len(packet.Outputs)-1]
+		}
+	}
+	if result.ChangeIndex < 0 {
+		vsize, fee, err := estimateFee(packet, feeRate)
+		if err != nil {
+			return nil, err
+		}
+		if available-spent < fee {
+			return nil, fmt.Errorf("%w: inputs of %d sat do not cover outputs of %d sat and a fee of %d sat", psbt.ErrInvalidPsbt, available, spent, fee)
+		}
+		if changeAddress == "" && available-spent-fee >= DustLimit {
+			return nil, fmt.Errorf("%w: inputs exceed outputs and fee by %d sat, set a change address", psbt.ErrInvalidPsbt, available-spent-fee)
+		}
+		result.Fee, result.VSize = available-spent, vsize
+	}
+
+	if result.Psbt, err = packet.B64Encode(); err != nil {
+		return nil, err
+	}
+	result.Txid = tx.TxHash().String()
+	return result, nil
+}
+
+// estimateFee is the virtual size of the signed transaction and its fee at the
+// fee rate, rounded up.
+func estimateFee(packet *psbt.Packet, feeRate float64) (int, int64, error) {
+	weight, err := packet.EstimateWeight()
+	if err != nil {
+		return 0, 0, err
+	}
+	vsize := psbt.VirtualSize(weight)
+	return vsize, int64(math.Ceil(float64(vsize) * feeRate)), nil
+}
+
+// multisigKey is the extended public key of a multisig cosigner with its
+// origin.
+type multisigKey struct {
+	key    *bip32.Key
+	origin helpers.KeyOrigin
+}
+
+// psbtInputFields fills the PSBT fields of an input from the UTXO, the
+// descriptor it is derived from, the extended public keys of its multisig
+// cosigners or the key it pays to.
+func psbtInputFields(input PsbtInput, outPoint wire.OutPoint, descriptor *descriptors.Descriptor, cosigners []multisigKey) (*psbt.Input, error) {
+	fields := &psbt.Input{}
+	scriptPubKey, err := hex.DecodeString(input.ScriptPubKey)
+	if err != nil || len(scriptPubKey) == 0 {
+		return nil, fmt.Errorf("%w: scriptPubKey must be hex", psbt.ErrInvalidPsbt)
+	}
+	for _, script := range []struct {
+		name  string
+		value string
+		field *[]byte
+	}{
+		{"redeemScript", input.RedeemScript, &fields.RedeemScript},
+		{"witnessScript", input.WitnessScript, &fields.WitnessScript},
+	} {
+		if script.value == "" {
+			continue
+		}
+		if *script.field, err = hex.DecodeString(script.value); err != nil {
+			return nil, fmt.Errorf("%w: %s must be hex", psbt.ErrInvalidPsbt, script.name)
+		}
+	}
+
+	if input.PreviousTx != "" {
+		raw, err := hex.DecodeString(input.PreviousTx)
+		if err != nil {
+			return nil, fmt.Errorf("%w: previousTx must be hex", psbt.ErrInvalidPsbt)
+		}
+		previous := wire.NewMsgTx(wire.TxVersion)
+		if err := previous.Deserialize(bytes.NewReader(raw)); err != nil {
+			return nil, fmt.Errorf("%w: previousTx: %v", psbt.ErrInvalidPsbt, err)
+		}
+		if previous.TxHash() != outPoint.Hash {
+			return nil, fmt.Errorf("%w: previousTx %s is not transaction %s", psbt.ErrInvalidPsbt, previous.TxHash(), outPoint.Hash)
+		}
+		if outPoint.Index >= uint32(len(previous.TxOut)) {
+			return nil, fmt.Errorf("%w: previousTx has no output %d", psbt.ErrInvalidPsbt, outPoint.Index)
+		}
+		spent := previous.TxOut[outPoint.Index]
+		if spent.Value != input.Amount || !bytes.Equal(spent.PkScript, scriptPubKey) {
+			return nil, fmt.Errorf("%w: amount and scriptPubKey do not match output %d of previousTx", psbt.ErrInvalidPsbt, outPoint.Index)
+		}
+		fields.NonWitnessUtxo = previous
+	}
+
+	if input.Path != "" {
+		var fingerprint []byte
+		if input.Fingerprint != "" {
+			if fingerprint, err = hex.DecodeString(input.Fingerprint); err != nil || len(fingerprint) != 4 {
+				return nil, fmt.Errorf("%w: fingerprint must be 8 hex characters", psbt.ErrInvalidPsbt)
+			}
+		}
+		relative := !strings.HasPrefix(strings.TrimSpace(input.Path), "m")
+		var path helpers.DerivationPath
+		if relative {
+			path, err = helpers.ParseRelativePath(input.Path)
+		} else {
+			path, err = helpers.ParseDerivationPath(input.Path)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: path: %v", psbt.ErrInvalidPsbt, err)
+		}
+		switch {
+		case descriptor != nil:
+			err = descriptorFields(fields, scriptPubKey, descriptor, fingerprint, path, relative)
+		case len(cosigners) > 0:
+			err = multisigFields(fields, cosigners, path, relative)
+		default:
+			err = keyFields(fields, scriptPubKey, input.PubKey, helpers.KeyOrigin{Fingerprint: fingerprint, Path: path}, relative)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := checkInputScripts(scriptPubKey, fields.RedeemScript, fields.WitnessScript); err != nil {
+		return nil, err
+	}
+	segwit := txscript.IsWitnessProgram(scriptPubKey) || (txscript.IsPayToScriptHash(scriptPubKey) && txscript.IsWitnessProgram(fields.RedeemScript))
+	if segwit {
+		fields.WitnessUtxo = wire.NewTxOut(input.Amount, scriptPubKey)
+	} else if fields.NonWitnessUtxo == nil {
+		return nil, fmt.Errorf("%w: spending an output without witness needs its previousTx", psbt.ErrInvalidPsbt)
+	}
+	return fields, nil
+}
+
+// descriptorFields sets the scripts and key derivations of the descriptor
+// output at the path, which must be the output the input spends.
+func descriptorFields(fields *psbt.Input, scriptPubKey []byte, descriptor *descriptors.Descriptor, fingerprint []byte, path helpers.DerivationPath, relative bool) error {
+	index, multipathIndex, ok := descriptor.Locate(fingerprint, path, relative)
+	if !ok {
+		return fmt.Errorf("%w: path %s is not derived by the descriptor", psbt.ErrInvalidPsbt, path)
+	}
+	output, err := descriptor.Output(index, multipathIndex)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(output.ScriptPubKey, scriptPubKey) {
+		return fmt.Errorf("%w: the descriptor output at %s is %x, not the scriptPubKey", psbt.ErrInvalidPsbt, path, output.ScriptPubKey)
+	}
+	fields.RedeemScript, fields.WitnessScript = output.RedeemScript, output.WitnessScript
+	for _, derivation := range output.Derivations {
+		if output.InternalKey != nil {
+			fields.TaprootBip32Derivation = append(fields.TaprootBip32Derivation, psbt.TaprootBip32Derivation{XOnlyPubKey: derivation.PubKey, LeafHashes: derivation.LeafHashes, Origin: derivation.Origin})
+			continue
+		}
+		fields.Bip32Derivation = append(fields.Bip32Derivation, psbt.Bip32Derivation{PubKey: derivation.PubKey, Origin: derivation.Origin})
+	}
+	fields.TaprootInternalKey, fields.TaprootMerkleRoot = output.InternalKey, output.MerkleRoot
+	for _, leaf := range output.LeafScripts {
+		fields.TaprootLeafScripts = append(fields.TaprootLeafScripts, psbt.TaprootLeafScript{ControlBlock: leaf.ControlBlock, Script: leaf.Script, LeafVersion: helpers.TapLeafVersion})
+	}
+	return nil
+}
+
+// multisigFields sets the derivations of the keys the cosigners derive at the
+// path, relative to their extended public keys like the path of a multisig
+// address range, that are in the witness or redeem script of the input.
+func multisigFields(fields *psbt.Input, cosigners []multisigKey, path helpers.DerivationPath, relative bool) error {
+	if !relative {
+		return fmt.Errorf("%w: the path of a multisig input is relative to the extended public keys, such as 0/5", psbt.ErrInvalidPsbt)
+	}
+	script := fields.WitnessScript
+	if script == nil {
+		script = fields.RedeemScript
+	}
+	if script == nil {
+		return fmt.Errorf("%w: a multisig input needs its witnessScript or redeemScript", psbt.ErrInvalidPsbt)
+	}
+	for _, cosigner := range cosigners {
+		child := cosigner.key
+		for _, index := range path {
+			var err error
+			if child, err = child.NewChildKey(index); err != nil {
+				return fmt.Errorf("%w: path %s: %v", psbt.ErrInvalidPsbt, path.RelativeString(), err)
+			}
+		}
+		if !bytes.Contains(script, child.Key) {
+			continue
+		}
+		origin := helpers.KeyOrigin{Fingerprint: cosigner.origin.Fingerprint, Path: cosigner.origin.Path.Append(path...)}
+		fields.Bip32Derivation = append(fields.Bip32Derivation, psbt.Bip32Derivation{PubKey: child.Key, Origin: origin})
+	}
+	if len(fields.Bip32Derivation) == 0 {
+		return fmt.Errorf("%w: no extended public key derives a key of the input script at %s", psbt.ErrInvalidPsbt, path.RelativeString())
+	}
+	return nil
+}
+
+// keyFields sets the derivation of a public key the input pays to, directly
+// or in its redeem or witness script. The redeem script of a P2SH-P2WPKH
+// output is added when it is missing.
+func keyFields(fields *psbt.Input, scriptPubKey []byte, pubKeyHex string, origin helpers.KeyOrigin, relative bool) error {
+	if pubKeyHex == "" || origin.Fingerprint == nil || relative {
+		return fmt.Errorf("%w: a path without descriptor needs pubKey, fingerprint and an absolute path", psbt.ErrInvalidPsbt)
+	}
+	pubKey, err := hex.DecodeString(pubKeyHex)
+	if err != nil {
+		return fmt.Errorf("%w: pubKey must be hex", psbt.ErrInvalidPsbt)
+	}
+	key, err := btcec.ParsePubKey(pubKey, btcec.S256())
+	if err != nil {
+		return fmt.Errorf("%w: pubKey: %v", psbt.ErrInvalidPsbt, err)
+	}
+
+	if version, program, err := txscript.ExtractWitnessProgramInfo(scriptPubKey); err == nil && version == 1 {
+		outputKey, err := helpers.TaprootOutputKey(key, nil)
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(helpers.XOnlyPubKey(outputKey), program) {
+			return fmt.Errorf("%w: pubKey is not the internal key of the taproot output", psbt.ErrInvalidPsbt)
+		}
+		fields.TaprootInternalKey = helpers.XOnlyPubKey(key)
+		fields.TaprootBip32Derivation = []psbt.TaprootBip32Derivation{{XOnlyPubKey: fields.TaprootInternalKey, Origin: origin}}
+		return nil
+	}
+
+	keyHash := btcutil.Hash160(pubKey)
+	p2pkh, _ := txscript.NewScriptBuilder().AddOp(txscript.OP_DUP).AddOp(txscript.OP_HASH160).AddData(keyHash).
+		AddOp(txscript.OP_EQUALVERIFY).AddOp(txscript.OP_CHECKSIG).Script()
+	p2wpkh, _ := txscript.NewScriptBuilder().AddOp(txscript.OP_0).AddData(keyHash).Script()
+	switch {
+	case bytes.Equal(scriptPubKey, p2pkh), bytes.Equal(scriptPubKey, p2wpkh):
+	case txscript.IsPayToScriptHash(scriptPubKey) && bytes.Equal(scriptPubKey[2:22], btcutil.Hash160(p2wpkh)):
+		fields.RedeemScript = p2wpkh
+	case bytes.Contains(fields.WitnessScript, pubKey), bytes.Contains(fields.RedeemScript, pubKey):
+	default:
+		return fmt.Errorf("%w: pubKey is not a key of the scriptPubKey", psbt.ErrInvalidPsbt)
+	}
+	fields.Bip32Derivation = []psbt.Bip32Derivation{{PubKey: pubKey, Origin: origin}}
+	return nil
+}
+
+// checkInputScripts requires the redeem and witness scripts of P2SH and P2WSH
+// outputs, and that they hash to what the output commits to.
+func checkInputScripts(scriptPubKey []byte, redeemScript []byte, witnessScript []byte) error {
+	if txscript.IsPayToScriptHash(scriptPubKey) {
+		if redeemScript == nil {
+			return fmt.Errorf("%w: P2SH output needs its redeemScript", psbt.ErrInvalidPsbt)
+		}
+		if !bytes.Equal(scriptPubKey[2:22], btcutil.Hash160(redeemScript)) {
+			return fmt.Errorf("%w: redeemScript does not hash to the P2SH output", psbt.ErrInvalidPsbt)
+		}
+		scriptPubKey = redeemScript
+	}
+	if txscript.IsPayToWitnessScriptHash(scriptPubKey) {
+		if witnessScript == nil {
+			return fmt.Errorf("%w: P2WSH output needs its witnessScript", psbt.ErrInvalidPsbt)
+		}
+		if scriptHash := sha256.Sum256(witnessScript); !bytes.Equal(scriptPubKey[2:], scriptHash[:]) {
+			return fmt.Errorf("%w: witnessScript does not hash to the P2WSH output", psbt.ErrInvalidPsbt)
+		}
+	}
+	return nil
+}
+
+// PsbtSigningKeys are the private keys SignPsbt signs with. Seed is a hex
+// BIP32 seed, a Mnemonic is converted to its seed with the Passphrase in the
+// Language, which is detected when empty. ExtPrvKeys are extended private
+// keys, each may start with its [fingerprint/path] origin, a key without
+// origin is taken as a master key. Wifs sign the inputs paying to their key.
+type PsbtSigningKeys struct {
+	Seed       string
+	Mnemonic   string
+	Passphrase string
+	Language   string
+	ExtPrvKeys []string
+	Wifs       []string
+}
+
+// SignedPsbt is the PSBT with the signatures SignPsbt added, and how every
+// input went. Signed counts the inputs that have a signature of the keys.
+type SignedPsbt struct {
+	Psbt   string             `json:"psbt"`
+	Signed int                `json:"signed"`
+	Inputs []PsbtInputSigning `json:"inputs"`
+}
+
+// PsbtInputSigning reports the keys an input is signed for, hex public keys
+// and x-only internal keys for Taproot, or the Reason it is not signed.
+type PsbtInputSigning struct {
+	Index   int      `json:"index"`
+	Signed  bool     `json:"signed"`
+	PubKeys []string `json:"pubKeys,omitempty"`
+	Reason  string   `json:"reason,omitempty"`
+}
+
+// signingRoot is an extended private key and its origin, the derivations of
+// the inputs that descend from the origin are derived from it.
+type signingRoot struct {
+	key    *bip32.Key
+	origin helpers.KeyOrigin
+}
+
+// signingKey is a private key an input may be signed with. derivation is the
+// origin it was derived for, empty for a WIF.
+type signingKey struct {
+	privKey    *btcec.PrivateKey
+	compressed bool
+	derivation string
+}
+
+// SignPsbt signs every input of a base64 PSBT it has keys for and returns the
+// updated PSBT. The keys of the seed, the mnemonic and the extended keys are
+// derived for the BIP32 derivations of the inputs that start with their
+// fingerprint, and must be the keys the derivations list. WIF keys sign the
+// inputs that pay to them. P2PKH, P2WPKH, P2SH-P2WPKH and multisig inputs get
+// ECDSA partial signatures, Taproot inputs a key path Schnorr signature.
+// Inputs that are not signed are reported with the reason.
+func (pm *psbtManager) SignPsbt(encoded string, keys PsbtSigningKeys, network string) (*SignedPsbt, error) {
+	net, err := pm.walletHelper.DeriveNetworkParams(network)
+	if err != nil {
+		return nil, err
+	}
+	packet, err := psbt.ParseBase64(encoded)
+	if err != nil {
+		return nil, err
+	}
+	roots, wifs, err := pm.signingKeys(keys, net)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &SignedPsbt{Inputs: make([]PsbtInputSigning, len(packet.Inputs))}
+	for i := range packet.Inputs {
+		report := PsbtInputSigning{Index: i, PubKeys: []string{}}
+		input := packet.Inputs[i]
+		if input.IsFinal() {
+			report.Reason = "the input is already finalized"
+			result.Inputs[i] = report
+			continue
+		}
+		candidates, reason := inputSigningKeys(input, roots)
+		candidates = append(candidates, wifs...)
+		signed := map[string]bool{}
+		for _, key := range candidates {
+			pubKey, err := packet.SignInput(i, key.privKey, key.compressed)
+			if errors.Is(err, psbt.ErrKeyNotInInput) {
+				if key.derivation != "" {
+					reason = fmt.Sprintf("the key derived at %s is not a key of the input script", key.derivation)
+				}
+				continue
+			}
+			if err != nil {
+				reason = err.Error()
+				break
+			}
+			if pubKeyHex := hex.EncodeToString(pubKey); !signed[pubKeyHex] {
+				signed[pubKeyHex] = true
+				report.PubKeys = append(report.PubKeys, pubKeyHex)
+			}
+		}
+		report.Signed = len(report.PubKeys) > 0
+		if report.Signed {
+			result.Signed++
+		} else if reason == "" {
+			report.Reason = "no key matches the derivations or the scripts of the input"
+		} else {
+			report.Reason = reason
+		}
+		result.Inputs[i] = report
+	}
+
+	if result.Psbt, err = packet.B64Encode(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// signingKeys reads the master keys of the seed and the mnemonic, the
+// extended private keys with their origins and the WIF keys.
+func (pm *psbtManager) signingKeys(keys PsbtSigningKeys, net *chaincfg.Params) ([]signingRoot, []signingKey, error) {
+	var seeds [][]byte
+	if keys.Seed != "" {
+		seed, err := hex.DecodeString(keys.Seed)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: seed must be hex", helpers.ErrInvalidKeyInput)
+		}
+		seeds = append(seeds, seed)
+	}
+	if keys.Mnemonic != "" {
+		seed, _, err := pm.walletHelper.DeriveSeedFromMnemonic(keys.Mnemonic, keys.Passphrase, helpers.Language(keys.Language))
+		if err != nil {
+			return nil, nil, err
+		}
+		seeds = append(seeds, seed)
+	}
+
+	var roots []signingRoot
+	for _, seed := range seeds {
+		master, err := bip32.NewMasterKey(seed)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: seed: %v", helpers.ErrInvalidKeyInput, err)
+		}
+		roots = append(roots, signingRoot{master, helpers.KeyOrigin{Fingerprint: helpers.KeyFingerprint(master), Path: helpers.DerivationPath{}}})
+	}
+	for i, extPrvKey := range keys.ExtPrvKeys {
+		root, err := parseSigningRoot(strings.TrimSpace(extPrvKey), net)
+		if err != nil {
+			return nil, nil, fmt.Errorf("extended key %d: %w", i, err)
+		}
+		roots = append(roots, *root)
+	}
+
+	var wifs []signingKey
+	for i, encoded := range keys.Wifs {
+		wif, err := btcutil.DecodeWIF(strings.TrimSpace(encoded))
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: wif %d: %v", helpers.ErrInvalidKeyInput, i, err)
+		}
+		if !wif.IsForNet(net) {
+			return nil, nil, fmt.Errorf("%w: wif %d is not for network %s", helpers.ErrKeyNetworkMismatch, i, net.Name)
+		}
+		wifs = append(wifs, signingKey{privKey: wif.PrivKey, compressed: wif.CompressPubKey})
+	}
+	if len(roots) == 0 && len(wifs) == 0 {
+		return nil, nil, fmt.Errorf("%w: a seed, a mnemonic, extended private keys or WIFs are needed to sign", helpers.ErrInvalidKeyInput)
+	}
+	return roots, wifs, nil
+}
+
+// parseSigningRoot reads an extended private key that may start with its
+// [fingerprint/path] origin.
+func parseSigningRoot(key string, net *chaincfg.Params) (*signingRoot, error) {
+	extKey, origin, err := parseOriginKey(key, net)
+	if err != nil {
+		return nil, err
+	}
+	if !extKey.IsPrivate {
+		return nil, fmt.Errorf("%w: signing needs an extended private key", helpers.ErrInvalidKeyInput)
+	}
+	return &signingRoot{extKey, origin}, nil
+}
+
+// parseOriginKey reads an extended key that may start with its
+// [fingerprint/path] origin, a key without origin is its own master key.
+func parseOriginKey(key string, net *chaincfg.Params) (*bip32.Key, helpers.KeyOrigin, error) {
+	var origin *helpers.KeyOrigin
+	if strings.HasPrefix(key, "[") {
+		end := strings.Index(key, "]")
+		if end < 0 {
+			return nil, helpers.KeyOrigin{}, fmt.Errorf("%w: key origin is missing its ]", helpers.ErrInvalidKeyInput)
+		}
+		fields := strings.SplitN(key[1:end], "/", 2)
+		fingerprint, err := hex.DecodeString(fields[0])
+		if err != nil || len(fingerprint) != 4 {
+			return nil, helpers.KeyOrigin{}, fmt.Errorf("%w: key origin fingerprint must be 8 hex characters. got %s", helpers.ErrInvalidKeyInput, fields[0])
+		}
+		origin = &helpers.KeyOrigin{Fingerprint: fingerprint, Path: helpers.DerivationPath{}}
+		if len(fields) == 2 {
+			if origin.Path, err = helpers.ParseRelativePath(fields[1]); err != nil {
+				return nil, helpers.KeyOrigin{}, fmt.Errorf("%w: key origin path: %v", helpers.ErrInvalidKeyInput, err)
+			}
+		}
+		key = key[end+1:]
+	}
+	extKey, err := helpers.DecodeExtendedKey(key, net)
+	if errors.Is(err, helpers.ErrKeyNetworkMismatch) {
+		return nil, helpers.KeyOrigin{}, err
+	}
+	if err != nil {
+		return nil, helpers.KeyOrigin{}, fmt.Errorf("%w: %v", helpers.ErrInvalidKeyInput, err)
+	}
+	if origin == nil {
+		origin = &helpers.KeyOrigin{Fingerprint: helpers.KeyFingerprint(extKey), Path: helpers.DerivationPath{}}
+	}
+	return extKey, *origin, nil
+}
+
+// inputSigningKeys derives the private keys of the BIP32 derivations of the
+// input from the roots they descend from. A derived key that is not the key of
+// its derivation is left out and reported as the reason.
+func inputSigningKeys(input psbt.Input, roots []signingRoot) (keys []signingKey, reason string) {
+	type derivation struct {
+		pubKey []byte
+		origin helpers.KeyOrigin
+		xOnly  bool
+	}
+	var derivations []derivation
+	for _, d := range input.Bip32Derivation {
+		derivations = append(derivations, derivation{d.PubKey, d.Origin, false})
+	}
+	for _, d := range input.TaprootBip32Derivation {
+		if len(d.LeafHashes) > 0 {
+			reason = "signing a taproot script path is not supported"
+			continue
+		}
+		derivations = append(derivations, derivation{d.XOnlyPubKey, d.Origin, true})
+	}
+
+	for _, d := range derivations {
+		for _, root := range roots {
+			if !bytes.Equal(d.origin.Fingerprint, root.origin.Fingerprint) || !hasPathPrefix(d.origin.Path, root.origin.Path) {
+				continue
+			}
+			child := root.key
+			var err error
+			for _, index := range d.origin.Path[len(root.origin.Path):] {
+				if child, err = child.NewChildKey(index); err != nil {
+					break
+				}
+			}
+			if err != nil {
+				reason = fmt.Sprintf("can not derive %s: %v", d.origin, err)
+				continue
+			}
+			privKey, pubKey := btcec.PrivKeyFromBytes(btcec.S256(), child.Key)
+			derived := pubKey.SerializeCompressed()
+			if d.xOnly {
+				derived = helpers.XOnlyPubKey(pubKey)
+			}
+			if !bytes.Equal(derived, d.pubKey) {
+				reason = fmt.Sprintf("the key derived at %s is not the key of the derivation, check the seed and passphrase", d.origin)
+				continue
+			}
+			keys = append(keys, signingKey{privKey: privKey, compressed: true, derivation: d.origin.String()})
+		}
+	}
+	return keys, reason
+}
+
+// hasPathPrefix reports whether the path descends from the prefix.
+func hasPathPrefix(path helpers.DerivationPath, prefix helpers.DerivationPath) bool {
+	if len(path) < len(prefix) {
+		return false
+	}
+	for i := range prefix {
+		if path[i] != prefix[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// CombinedPsbt is the PSBT CombinePsbt merged and the id of its unsigned
+// transaction.
+type CombinedPsbt struct {
+	Psbt string `json:"psbt"`
+	Txid string `json:"txid"`
+}
+
+// FinalizedPsbt is the PSBT with the inputs FinalizePsbt finalized, Complete
+// when every input is final and the transaction can be extracted.
+type FinalizedPsbt struct {
+	Psbt     string                `json:"psbt"`
+	Complete bool                  `json:"complete"`
+	Inputs   []PsbtInputFinalizing `json:"inputs"`
+}
+
+// PsbtInputFinalizing reports whether an input is final, or the Reason it
+// can not be finalized yet.
+type PsbtInputFinalizing struct {
+	Index     int    `json:"index"`
+	Finalized bool   `json:"finalized"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+// ExtractedTx is the signed transaction of a PSBT as raw hex, with its id,
+// fee and virtual size.
+type ExtractedTx struct {
+	Hex   string `json:"hex"`
+	Txid  string `json:"txid"`
+	Fee   int64  `json:"fee"`
+	VSize int    `json:"vsize"`
+}
+
+// CombinePsbt merges base64 PSBTs of the same unsigned transaction, such as
+// the ones cosigners of a multisig address signed separately, into one PSBT
+// with all their signatures.
+func (pm *psbtManager) CombinePsbt(psbts []string) (*CombinedPsbt, error) {
+	packet, err := combinePsbts(psbts)
+	if err != nil {
+		return nil, err
+	}
+	encoded, err := packet.B64Encode()
+	if err != nil {
+		return nil, err
+	}
+	return &CombinedPsbt{Psbt: encoded, Txid: packet.UnsignedTx.TxHash().String()}, nil
+}
+
+// FinalizePsbt combines the PSBTs and builds the final scriptSig and witness
+// of every input that has the signatures its script needs. Inputs that can
+// not be finalized yet are reported with the reason.
+func (pm *psbtManager) FinalizePsbt(psbts []string) (*FinalizedPsbt, error) {
+	packet, err := combinePsbts(psbts)
+	if err != nil {
+		return nil, err
+	}
+	result := &FinalizedPsbt{Complete: true, Inputs: finalizeInputs(packet)}
+	for _, input := range result.Inputs {
+		result.Complete = result.Complete && input.Finalized
+	}
+	if result.Psbt, err = packet.B64Encode(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// ExtractPsbt combines and finalizes the PSBTs and returns the signed
+// transaction, every input must be final.
+func (pm *psbtManager) ExtractPsbt(psbts []string) (*ExtractedTx, error) {
+	packet, err := combinePsbts(psbts)
+	if err != nil {
+		return nil, err
+	}
+	for _, input := range finalizeInputs(packet) {
+		if !input.Finalized {
+			return nil, fmt.Errorf("%w: input %d is not final: %s", psbt.ErrInvalidPsbt, input.Index, input.Reason)
+		}
+	}
+	tx, err := packet.Extract()
+	if err != nil {
+		return nil, err
+	}
+
+	var fee int64
+	for i := range packet.Inputs {
+		utxo, err := packet.Utxo(i)
+		if err != nil {
+			return nil, err
+		}
+		fee += utxo.Value
+	}
+	for _, txOut := range tx.TxOut {
+		fee -= txOut.Value
+	}
+	var raw bytes.Buffer
+	if err := tx.Serialize(&raw); err != nil {
+		return nil, err
+	}
+	weight := tx.SerializeSizeStripped()*3 + tx.SerializeSize()
+	return &ExtractedTx{
+		Hex:   hex.EncodeToString(raw.Bytes()),
+		Txid:  tx.TxHash().String(),
+		Fee:   fee,
+		VSize: psbt.VirtualSize(weight),
+	}, nil
+}
+
+// combinePsbts parses the base64 PSBTs and combines them.
+func combinePsbts(psbts []string) (*psbt.Packet, error) {
+	if len(psbts) == 0 {
+		return nil, fmt.Errorf("%w: no psbt given", psbt.ErrInvalidPsbt)
+	}
+	packets := make([]*psbt.Packet, len(psbts))
+	for i, encoded := range psbts {
+		packet, err := psbt.ParseBase64(strings.TrimSpace(encoded))
+		if err != nil {
+			return nil, fmt.Errorf("psbt %d: %w", i, err)
+		}
+		packets[i] = packet
+	}
+	return psbt.Combine(packets...)
+}
+
+// finalizeInputs finalizes every input it can and reports how each went.
+func finalizeInputs(packet *psbt.Packet) []PsbtInputFinalizing {
+	inputs := make([]PsbtInputFinalizing, len(packet.Inputs))
+	for i := range packet.Inputs {
+		inputs[i] = PsbtInputFinalizing{Index: i, Finalized: true}
+		if err := packet.FinalizeInput(i); err != nil {
+			inputs[i].Finalized, inputs[i].Reason = false, err.Error()
+		}
+	}
+	return inputs
+}
+
+func NewPsbtManager(walletHelper helpers.WalletHelper) PsbtManager {
+	return &psbtManager{
+		walletHelper,
+	}
+}
+
+// FeeInput is Count inputs of a type, one when Count is zero. Type is the
+// address type of a single key output, p2pkh, p2sh-p2wpkh, p2wpkh or p2tr, or
+// with M and N set the multisig type of an m of n multisig, p2sh, p2sh-p2wsh,
+// p2wsh or p2tr. A P2TR multisig is spent through its multi_a leaf unless
+// KeyPath selects the MuSig2 key path.
+type FeeInput struct {
+	Type    string `json:"type"`
+	M       int    `json:"m,omitempty"`
+	N       int    `json:"n,omitempty"`
+	KeyPath bool   `json:"keyPath,omitempty"`
+	Count   int    `json:"count"`
+}
+
+// FeeOutput is Count outputs of a type, one when Count is zero: an address
+// type or a multisig type.
+type FeeOutput struct {
+	Type  string `json:"type"`
+	Count int    `json:"count"`
+}
+
+// EstimatedInput is a FeeInput with the weight of one of its inputs.
+type EstimatedInput struct {
+	FeeInput
+	Weight int `json:"weight"`
+}
+
+// FeeAtRate is the fee of the transaction at a fee rate in sat/vB.
+type FeeAtRate struct {
+	FeeRate float64 `json:"feeRate"`
+	Fee     int64   `json:"fee"`
+}
+
+// FeeEstimate is the weight and virtual size of a signed transaction and
+// its fee at every fee rate.
+type FeeEstimate struct {
+	Weight int              `json:"weight"`
+	VSize  int              `json:"vsize"`
+	Inputs []EstimatedInput `json:"inputs"`
+	Fees   []FeeAtRate      `json:"fees"`
+}
+
+// outputScriptSizes are the script sizes of the output types.
+var outputScriptSizes = map[string]int{
+	string(helpers.AddressTypeP2PKH):      25,
+	string(helpers.AddressTypeP2SHP2WPKH): 23,
+	string(helpers.MultisigTypeP2SH):      23,
+	string(helpers.MultisigTypeP2SHP2WSH): 23,
+	string(helpers.AddressTypeP2WPKH):     22,
+	string(helpers.MultisigTypeP2WSH):     34,
+	string(helpers.AddressTypeP2TR):       34,
+}
+
+// EstimateFee estimates the weight of a transaction spending the inputs to
+// the outputs once it is signed, signatures counted at their largest, and
+// its fee at each of the fee rates, the virtual size times the rate rounded
+// up.
+func (pm *psbtManager) EstimateFee(inputs []FeeInput, outputs []FeeOutput, feeRates []float64) (*FeeEstimate, error) {
+	if len(inputs) == 0 || len(outputs) == 0 {
+		return nil, fmt.Errorf("a transaction needs inputs and outputs")
+	}
+	result := &FeeEstimate{Inputs: make([]EstimatedInput, len(inputs)), Fees: make([]FeeAtRate, len(feeRates))}
+	var inputCount, outputCount, inputsWeight, outputsSize, withoutWitness int
+	segwit := false
+	for i, input := range inputs {
+		if input.Count < 0 {
+			return nil, fmt.Errorf("input %d: count must not be negative. got %d", i, input.Count)
+		}
+		if input.Count == 0 {
+			input.Count = 1
+		}
+		weight, witness, err := feeInputWeight(input)
+		if err != nil {
+			return nil, fmt.Errorf("input %d: %w", i, err)
+		}
+		result.Inputs[i] = EstimatedInput{FeeInput: input, Weight: weight}
+		inputCount += input.Count
+		inputsWeight += input.Count * weight
+		if witness {
+			segwit = true
+		} else {
+			withoutWitness += input.Count
+		}
+	}
+	for i, output := range outputs {
+		size, ok := outputScriptSizes[output.Type]
+		if !ok {
+			return nil, fmt.Errorf("output %d: unknown output type %q", i, output.Type)
+		}
+		if output.Count < 0 {
+			return nil, fmt.Errorf("output %d: count must not be negative. got %d", i, output.Count)
+		}
+		count := output.Count
+		if count == 0 {
+			count = 1
+		}
+		outputCount += count
+		outputsSize += count * psbt.OutputSize(make([]byte, size))
+	}
+
+	// version, the counts, the outputs and locktime
+	result.Weight = 4*(4+wire.VarIntSerializeSize(uint64(inputCount))+wire.VarIntSerializeSize(uint64(outputCount))+outputsSize+4) + inputsWeight
+	if segwit {
+		// the marker and flag bytes and the empty witness of every input
+		// without one
+		result.Weight += 2 + withoutWitness
+	}
+	result.VSize = psbt.VirtualSize(result.Weight)
+	for i, feeRate := range feeRates {
+		if feeRate <= 0 || math.IsInf(feeRate, 0) || math.IsNaN(feeRate) {
+			return nil, fmt.Errorf("fee rate must be positive. got %v", feeRate)
+		}
+		result.Fees[i] = FeeAtRate{FeeRate: feeRate, Fee: int64(math.Ceil(float64(result.VSize) * feeRate))}
+	}
+	return result, nil
+}
+
+// feeInputWeight is the weight of an input of the type once it is signed and
+// whether it has a witness.
+func feeInputWeight(input FeeInput) (int, bool, error) {
+	multisigType := helpers.MultisigType(input.Type)
+	if input.M == 0 && input.N == 0 {
+		switch multisigType {
+		case helpers.MultisigTypeP2SH, helpers.MultisigTypeP2SHP2WSH, helpers.MultisigTypeP2WSH:
+			return 0, false, fmt.Errorf("%w, a %s input needs m and n", helpers.ErrMultisigTotal, multisigType)
+		}
+		return coinselect.InputWeight(helpers.AddressType(input.Type))
+	}
+	scriptSig, witness, err := psbt.MultisigInputSize(input.M, input.N, multisigType, input.KeyPath)
+	if err != nil {
+		return 0, false, err
+	}
+	return 4*(32+4+wire.VarIntSerializeSize(uint64(scriptSig))+scriptSig+4) + witness, witness > 0, nil
+}
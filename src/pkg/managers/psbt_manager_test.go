@@ -0,0 +1,773 @@
+package managers
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+
+	"btcwallet.com/src/pkg/descriptors"
+	"btcwallet.com/src/pkg/helpers"
+	"btcwallet.com/src/pkg/psbt"
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/tyler-smith/go-bip32"
+)
+
+// seed of the "abandon abandon ... about" reference mnemonic, master fingerprint 73c5da0a
+const psbtTestSeed = "5eb00bbddcf069084889a8ab9155568165f5c453ccb85e70811aaed6f6da5fc19a5ac40b389cd370d086206dec8aa6c43daea6690f20ad3d8d48b2d2ce9e38e4"
+
+const psbtTestTxId = "0101010101010101010101010101010101010101010101010101010101010101"
+
+func addressScriptHex(t *testing.T, address string) string {
+	script, err := helpers.AddressScript(address, &chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("Test failed: unexpected error: %v", err)
+	}
+	return hex.EncodeToString(script)
+}
+
+func TestCreatePsbtFromHdWallet(t *testing.T) {
+	var walletHelper helpers.WalletHelper = helpers.NewWalletHelper()
+	var walletManager WalletManager = NewWalletManager(walletHelper)
+	var psbtManager PsbtManager = NewPsbtManager(walletHelper)
+
+	account, err := walletManager.GenerateHdWallet(psbtTestSeed, "m/84'/0'/0'", "mainnet", false)
+	if err != nil {
+		t.Fatalf("Test failed: unexpected error: %v", err)
+	}
+	// the BIP84 first receive address and its key
+	inputs := []PsbtInput{{
+		TxId:         psbtTestTxId,
+		Vout:         1,
+		Amount:       100000,
+		ScriptPubKey: addressScriptHex(t, "bc1qcr8te4kr609gcawutmrza0j4xv80jy8z306fyu"),
+		Fingerprint:  "73c5da0a",
+		Path:         "m/84'/0'/0'/0/0",
+	}}
+	outputs := []PsbtOutput{{Address: "bc1qnjg0jd8228aq7egyzacy8cys3knf9xvrerkf9g", Amount: 60000}}
+	created, err := psbtManager.CreatePsbt(inputs, outputs, 2, "bc1q8c6fshw2dlwun7ekn9qwf37cu2rn755upcp6el", account.Descriptors.Public.Multipath, nil, "mainnet")
+	if err != nil {
+		t.Fatalf("Test failed: unexpected error: %v", err)
+	}
+	// one P2WPKH input and two P2WPKH outputs
+	if created.VSize != 141 || created.Fee != 282 || created.ChangeIndex != 1 || created.ChangeAmount != 100000-60000-282 {
+		t.Errorf("Test failed:  expected: 141 vB 282 sat change 1 received: %d vB %d sat change %d ", created.VSize, created.Fee, created.ChangeIndex)
+	}
+
+	packet, err := psbt.ParseBase64(created.Psbt)
+	if err != nil {
+		t.Fatalf("Test failed: unexpected error: %v", err)
+	}
+	if packet.UnsignedTx.TxHash().String() != created.Txid {
+		t.Errorf("Test failed:  expected: %s received: %s ", created.Txid, packet.UnsignedTx.TxHash())
+	}
+	input := packet.Inputs[0]
+	if input.WitnessUtxo == nil || input.WitnessUtxo.Value != 100000 || input.NonWitnessUtxo != nil {
+		t.Errorf("Test failed:  expected: a witness utxo of 100000 received: %v ", input.WitnessUtxo)
+	}
+	if len(input.Bip32Derivation) != 1 {
+		t.Fatalf("Test failed:  expected: 1 derivation received: %d ", len(input.Bip32Derivation))
+	}
+	derivation := input.Bip32Derivation[0]
+	if pubKey := hex.EncodeToString(derivation.PubKey); pubKey != "0330d54fd0dd420a6e5f8d3624f5f3482cae350f79d5f0753bf5beef9c2d91af3c" {
+		t.Errorf("Test failed:  expected: %s received: %s ", "0330d54fd0dd420a6e5f8d3624f5f3482cae350f79d5f0753bf5beef9c2d91af3c", pubKey)
+	}
+	if origin := derivation.Origin.String(); origin != "[73c5da0a/84'/0'/0'/0/0]" {
+		t.Errorf("Test failed:  expected: [73c5da0a/84'/0'/0'/0/0] received: %s ", origin)
+	}
+}
+
+func TestCreatePsbtFromMultisig(t *testing.T) {
+	var walletHelper helpers.WalletHelper = helpers.NewWalletHelper()
+	var walletManager WalletManager = NewWalletManager(walletHelper)
+	var psbtManager PsbtManager = NewPsbtManager(walletHelper)
+	extPubKeys := []string{
+		"xpub6CatWdiZiodmUeTDp8LT5or8nmbKNcuyvz7WyksVFkKB4RHwCD3XyuvPEbvqAQY3rAPshWcMLoP2fMFMKHPJ4ZeZXYVUhLv1VMrjPC7PW6V",
+		"xpub6BgBgsespWvERF3LHQu6CnqdvfEvtMcQjYrcRzx53QJjSxarj2afYWcLteoGVky7D3UKDP9QyrLprQ3VCECoY49yfdDEHGCtMMj92pReUsQ",
+		"xpub6BosfCnifzxcFwrSzQiqu2DBVTshkCXacvNsWGYJVVhhawA7d4R5WSWGFNbi8Aw6ZRc1brxMyWMzG3DSSSSoekkudhUd9yLb6qx39T9nMdj",
+	}
+	entries, err := walletManager.GenerateMultisignatureFromExtPubKeys(context.Background(), 3, 2, extPubKeys, 1, 5, 1, true, false, helpers.MultisigTypeP2WSH, "mainnet")
+	if err != nil {
+		t.Fatalf("Test failed: unexpected error: %v", err)
+	}
+	descriptor := "wsh(sortedmulti(2," + strings.Join(extPubKeys, "/<0;1>/*,") + "/<0;1>/*))"
+	inputs := []PsbtInput{{
+		TxId:         psbtTestTxId,
+		Amount:       100000,
+		ScriptPubKey: addressScriptHex(t, entries[0].Address),
+		Path:         entries[0].Path,
+	}}
+	outputs := []PsbtOutput{{Address: "bc1qcr8te4kr609gcawutmrza0j4xv80jy8z306fyu", Amount: 99800}}
+	created, err := psbtManager.CreatePsbt(inputs, outputs, 1, "", descriptor, nil, "mainnet")
+	if err != nil {
+		t.Fatalf("Test failed: unexpected error: %v", err)
+	}
+	if created.ChangeIndex != -1 || created.Fee != 200 {
+		t.Errorf("Test failed:  expected: no change and a fee of 200 received: %d %d ", created.ChangeIndex, created.Fee)
+	}
+
+	packet, _ := psbt.ParseBase64(created.Psbt)
+	input := packet.Inputs[0]
+	if hex.EncodeToString(input.WitnessScript) != entries[0].WitnessScript {
+		t.Errorf("Test failed:  expected: %s received: %x ", entries[0].WitnessScript, input.WitnessScript)
+	}
+	if len(input.Bip32Derivation) != 3 {
+		t.Fatalf("Test failed:  expected: 3 derivations received: %d ", len(input.Bip32Derivation))
+	}
+	for _, derivation := range input.Bip32Derivation {
+		if !bytes.Contains(input.WitnessScript, derivation.PubKey) || derivation.Origin.Path.RelativeString() != "1/5" {
+			t.Errorf("Test failed:  expected: a key of the witness script at 1/5 received: %x %s ", derivation.PubKey, derivation.Origin.Path)
+		}
+	}
+
+	// the extended public keys of the address range derive the same entries
+	// from the witness script of the entry
+	inputs[0].WitnessScript = entries[0].WitnessScript
+	created, err = psbtManager.CreatePsbt(inputs, outputs, 1, "", "", extPubKeys, "mainnet")
+	if err != nil {
+		t.Fatalf("Test failed: unexpected error: %v", err)
+	}
+	packet, _ = psbt.ParseBase64(created.Psbt)
+	if len(packet.Inputs[0].Bip32Derivation) != 3 {
+		t.Fatalf("Test failed:  expected: 3 derivations received: %d ", len(packet.Inputs[0].Bip32Derivation))
+	}
+	for i, derivation := range packet.Inputs[0].Bip32Derivation {
+		expected := input.Bip32Derivation[i]
+		if !bytes.Equal(derivation.PubKey, expected.PubKey) || derivation.Origin.String() != expected.Origin.String() {
+			t.Errorf("Test failed:  expected: %x %s received: %x %s ", expected.PubKey, expected.Origin, derivation.PubKey, derivation.Origin)
+		}
+	}
+	// a key origin is prepended to the path
+	withOrigin := append([]string{"[73c5da0a/48'/0'/0'/2']" + extPubKeys[0]}, extPubKeys[1:]...)
+	created, err = psbtManager.CreatePsbt(inputs, outputs, 1, "", "", withOrigin, "mainnet")
+	if err != nil {
+		t.Fatalf("Test failed: unexpected error: %v", err)
+	}
+	packet, _ = psbt.ParseBase64(created.Psbt)
+	if origin := packet.Inputs[0].Bip32Derivation[0].Origin.String(); origin != "[73c5da0a/48'/0'/0'/2'/1/5]" {
+		t.Errorf("Test failed:  expected: [73c5da0a/48'/0'/0'/2'/1/5] received: %s ", origin)
+	}
+
+	absolute, otherIndex, withoutScript := inputs[0], inputs[0], inputs[0]
+	absolute.Path, otherIndex.Path, withoutScript.WitnessScript = "m/1/5", "1/6", ""
+	for _, test := range []struct {
+		input      PsbtInput
+		descriptor string
+		extPubKeys []string
+	}{
+		{inputs[0], descriptor, extPubKeys},
+		{absolute, "", extPubKeys},
+		{otherIndex, "", extPubKeys},
+		{withoutScript, "", extPubKeys},
+		{inputs[0], "", []string{"xpub"}},
+	} {
+		if _, err := psbtManager.CreatePsbt([]PsbtInput{test.input}, outputs, 1, "", test.descriptor, test.extPubKeys, "mainnet"); !errors.Is(err, psbt.ErrInvalidPsbt) {
+			t.Errorf("Test failed: input: %s expected: %v received: %v ", test.input.Path, psbt.ErrInvalidPsbt, err)
+		}
+	}
+}
+
+func TestCreatePsbtFromTaprootTree(t *testing.T) {
+	var walletHelper helpers.WalletHelper = helpers.NewWalletHelper()
+	var psbtManager PsbtManager = NewPsbtManager(walletHelper)
+	// the BIP86 key spends through the key path and the BIP84 key through a leaf
+	descriptor := "tr([73c5da0a/86'/0'/0']xpub6BgBgsespWvERF3LHQu6CnqdvfEvtMcQjYrcRzx53QJjSxarj2afYWcLteoGVky7D3UKDP9QyrLprQ3VCECoY49yfdDEHGCtMMj92pReUsQ/0/*,pk([73c5da0a/84'/0'/0']xpub6CatWdiZiodmUeTDp8LT5or8nmbKNcuyvz7WyksVFkKB4RHwCD3XyuvPEbvqAQY3rAPshWcMLoP2fMFMKHPJ4ZeZXYVUhLv1VMrjPC7PW6V/0/*))"
+	parsed, err := descriptors.Parse(walletHelper, descriptor, &chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("Test failed: unexpected error: %v", err)
+	}
+	address, err := parsed.Address(3, 0)
+	if err != nil {
+		t.Fatalf("Test failed: unexpected error: %v", err)
+	}
+	inputs := []PsbtInput{{
+		TxId:         psbtTestTxId,
+		Amount:       100000,
+		ScriptPubKey: addressScriptHex(t, address),
+		Fingerprint:  "73c5da0a",
+		Path:         "m/84'/0'/0'/0/3",
+	}}
+	outputs := []PsbtOutput{{Address: "bc1qcr8te4kr609gcawutmrza0j4xv80jy8z306fyu", Amount: 99800}}
+	created, err := psbtManager.CreatePsbt(inputs, outputs, 1, "", descriptor, nil, "mainnet")
+	if err != nil {
+		t.Fatalf("Test failed: unexpected error: %v", err)
+	}
+
+	packet, _ := psbt.ParseBase64(created.Psbt)
+	input := packet.Inputs[0]
+	if len(input.TaprootLeafScripts) != 1 || len(input.TaprootMerkleRoot) != 32 || len(input.TaprootBip32Derivation) != 2 {
+		t.Fatalf("Test failed:  expected: 1 leaf, a merkle root and 2 derivations received: %d %x %d ", len(input.TaprootLeafScripts), input.TaprootMerkleRoot, len(input.TaprootBip32Derivation))
+	}
+	leafHash := helpers.TapLeafHash(input.TaprootLeafScripts[0].Script)
+	if !bytes.Equal(leafHash, input.TaprootMerkleRoot) {
+		t.Errorf("Test failed:  expected: %x received: %x ", leafHash, input.TaprootMerkleRoot)
+	}
+	for _, derivation := range input.TaprootBip32Derivation {
+		inLeaf := len(derivation.LeafHashes) == 1 && bytes.Equal(derivation.LeafHashes[0], leafHash)
+		if inLeaf != (derivation.Origin.Path.String() == "m/84'/0'/0'/0/3") {
+			t.Errorf("Test failed:  expected: only the BIP84 key in the leaf received: %s %x ", derivation.Origin, derivation.LeafHashes)
+		}
+	}
+
+	// the internal key signs the key path tweaked with the merkle root
+	signed, err := psbtManager.SignPsbt(created.Psbt, PsbtSigningKeys{Seed: psbtTestSeed}, "mainnet")
+	if err != nil {
+		t.Fatalf("Test failed: unexpected error: %v", err)
+	}
+	packet, _ = psbt.ParseBase64(signed.Psbt)
+	hash, err := packet.TaprootSigHash(0, 0)
+	if err != nil {
+		t.Fatalf("Test failed: unexpected error: %v", err)
+	}
+	outputKey := packet.Inputs[0].WitnessUtxo.PkScript[2:]
+	if signed.Signed != 1 || !helpers.SchnorrVerify(outputKey, hash, packet.Inputs[0].TaprootKeySig) {
+		t.Errorf("Test failed:  expected: a key path signature of %x received: %x ", outputKey, packet.Inputs[0].TaprootKeySig)
+	}
+}
+
+func TestCreatePsbtFromKey(t *testing.T) {
+	var walletHelper helpers.WalletHelper = helpers.NewWalletHelper()
+	var psbtManager PsbtManager = NewPsbtManager(walletHelper)
+
+	// the BIP49 first receive address, the redeem script is derived from the key
+	inputs := []PsbtInput{{
+		TxId:         psbtTestTxId,
+		Amount:       50000,
+		ScriptPubKey: addressScriptHex(t, "37VucYSaXLCAsxYyAPfbSi9eh4iEcbShgf"),
+		PubKey:       "039b3b694b8fc5b5e07fb069c783cac754f5d38c3e08bed1960e31fdb1dda35c24",
+		Fingerprint:  "73c5da0a",
+		Path:         "m/49'/0'/0'/0/0",
+	}}
+	outputs := []PsbtOutput{{Address: "bc1qcr8te4kr609gcawutmrza0j4xv80jy8z306fyu", Amount: 49800}}
+	created, err := psbtManager.CreatePsbt(inputs, outputs, 1, "", "", nil, "mainnet")
+	if err != nil {
+		t.Fatalf("Test failed: unexpected error: %v", err)
+	}
+	packet, _ := psbt.ParseBase64(created.Psbt)
+	input := packet.Inputs[0]
+	if hex.EncodeToString(input.RedeemScript) != "0014f990679acafe25c27615373b40bf22446d24ff44" || len(input.Bip32Derivation) != 1 {
+		t.Errorf("Test failed:  expected: the P2WPKH redeem script received: %x %v ", input.RedeemScript, input.Bip32Derivation)
+	}
+}
+
+func TestCreatePsbtInvalid(t *testing.T) {
+	var walletHelper helpers.WalletHelper = helpers.NewWalletHelper()
+	var psbtManager PsbtManager = NewPsbtManager(walletHelper)
+	descriptor := "wpkh([73c5da0a/84'/0'/0']xpub6CatWdiZiodmUeTDp8LT5or8nmbKNcuyvz7WyksVFkKB4RHwCD3XyuvPEbvqAQY3rAPshWcMLoP2fMFMKHPJ4ZeZXYVUhLv1VMrjPC7PW6V/<0;1>/*)"
+	p2wpkh := addressScriptHex(t, "bc1qcr8te4kr609gcawutmrza0j4xv80jy8z306fyu")
+	p2pkh := addressScriptHex(t, "1cMh228HTCiwS8ZsaakH8A8wze1JR5ZsP")
+	payment := []PsbtOutput{{Address: "bc1qnjg0jd8228aq7egyzacy8cys3knf9xvrerkf9g", Amount: 60000}}
+
+	previous := wire.NewMsgTx(2)
+	previous.AddTxIn(wire.NewTxIn(&wire.OutPoint{}, nil, nil))
+	previous.AddTxOut(wire.NewTxOut(90000, []byte{0x51}))
+	var raw bytes.Buffer
+	previous.Serialize(&raw)
+
+	tests := []struct {
+		name       string
+		input      PsbtInput
+		outputs    []PsbtOutput
+		change     string
+		descriptor string
+	}{
+		{"insufficient funds", PsbtInput{TxId: psbtTestTxId, Amount: 60100, ScriptPubKey: p2wpkh}, payment, "", ""},
+		{"excess without change", PsbtInput{TxId: psbtTestTxId, Amount: 70000, ScriptPubKey: p2wpkh}, payment, "", ""},
+		{"dust output", PsbtInput{TxId: psbtTestTxId, Amount: 70000, ScriptPubKey: p2wpkh}, []PsbtOutput{{Address: payment[0].Address, Amount: 100}}, "", ""},
+		{"legacy without previous tx", PsbtInput{TxId: psbtTestTxId, Amount: 70000, ScriptPubKey: p2pkh}, payment, "", ""},
+		{"previous tx of another txid", PsbtInput{TxId: psbtTestTxId, Amount: 90000, ScriptPubKey: "51", PreviousTx: hex.EncodeToString(raw.Bytes())}, payment, "", ""},
+		{"path outside the descriptor", PsbtInput{TxId: psbtTestTxId, Amount: 70000, ScriptPubKey: p2wpkh, Path: "m/44'/0'/0'/0/0"}, payment, "", descriptor},
+		{"output of another index", PsbtInput{TxId: psbtTestTxId, Amount: 70000, ScriptPubKey: p2wpkh, Path: "m/84'/0'/0'/0/1"}, payment, "", descriptor},
+		{"path without key", PsbtInput{TxId: psbtTestTxId, Amount: 70000, ScriptPubKey: p2wpkh, Path: "m/84'/0'/0'/0/0"}, payment, "", ""},
+		{"p2wsh without witness script", PsbtInput{TxId: psbtTestTxId, Amount: 70000, ScriptPubKey: "0020" + strings.Repeat("01", 32)}, payment, "", ""},
+		{"change for another network", PsbtInput{TxId: psbtTestTxId, Amount: 70000, ScriptPubKey: p2wpkh}, payment, "tb1qw508d6qejxtdg4y5r3zarvary0c5xw7kxpjzsx", ""},
+	}
+	for _, test := range tests {
+		_, err := psbtManager.CreatePsbt([]PsbtInput{test.input}, test.outputs, 1, test.change, test.descriptor, nil, "mainnet")
+		if !errors.Is(err, psbt.ErrInvalidPsbt) {
+			t.Errorf("Test failed: input: %s expected: %v received: %v ", test.name, psbt.ErrInvalidPsbt, err)
+		}
+	}
+}
+
+// executeInput runs the scripts of input i of the signed transaction.
+func executeInput(t *testing.T, packet *psbt.Packet, i int, scriptSig []byte, witness wire.TxWitness) {
+	tx := packet.UnsignedTx.Copy()
+	tx.TxIn[i].SignatureScript, tx.TxIn[i].Witness = scriptSig, witness
+	utxo, err := packet.Utxo(i)
+	if err != nil {
+		t.Fatalf("Test failed: unexpected error: %v", err)
+	}
+	vm, err := txscript.NewEngine(utxo.PkScript, tx, i, txscript.StandardVerifyFlags, nil, nil, utxo.Value)
+	if err != nil {
+		t.Fatalf("Test failed: unexpected error: %v", err)
+	}
+	if err := vm.Execute(); err != nil {
+		t.Errorf("Test failed: input: %d expected: a valid spend received: %v ", i, err)
+	}
+}
+
+func TestSignPsbtFromSeed(t *testing.T) {
+	var walletHelper helpers.WalletHelper = helpers.NewWalletHelper()
+	var psbtManager PsbtManager = NewPsbtManager(walletHelper)
+
+	// the BIP44 first receive address is spent with its previous transaction
+	p2pkh := addressScriptHex(t, "1LqBGSKuX5yYUonjxT5qGfpUsXKYYWeabA")
+	previous := wire.NewMsgTx(2)
+	previous.AddTxIn(wire.NewTxIn(&wire.OutPoint{}, nil, nil))
+	script, _ := hex.DecodeString(p2pkh)
+	previous.AddTxOut(wire.NewTxOut(30000, script))
+	var raw bytes.Buffer
+	previous.Serialize(&raw)
+
+	inputs := []PsbtInput{
+		{
+			TxId:         psbtTestTxId,
+			Amount:       100000,
+			ScriptPubKey: addressScriptHex(t, "bc1qcr8te4kr609gcawutmrza0j4xv80jy8z306fyu"),
+			PubKey:       "0330d54fd0dd420a6e5f8d3624f5f3482cae350f79d5f0753bf5beef9c2d91af3c",
+			Fingerprint:  "73c5da0a",
+			Path:         "m/84'/0'/0'/0/0",
+		},
+		{
+			TxId:         psbtTestTxId,
+			Vout:         1,
+			Amount:       50000,
+			ScriptPubKey: addressScriptHex(t, "37VucYSaXLCAsxYyAPfbSi9eh4iEcbShgf"),
+			PubKey:       "039b3b694b8fc5b5e07fb069c783cac754f5d38c3e08bed1960e31fdb1dda35c24",
+			Fingerprint:  "73c5da0a",
+			Path:         "m/49'/0'/0'/0/0",
+		},
+		{
+			TxId:         previous.TxHash().String(),
+			Amount:       30000,
+			ScriptPubKey: p2pkh,
+			PreviousTx:   hex.EncodeToString(raw.Bytes()),
+			PubKey:       "03aaeb52dd7494c361049de67cc680e83ebcbbbdbeb13637d92cd845f70308af5e",
+			Fingerprint:  "73c5da0a",
+			Path:         "m/44'/0'/0'/0/0",
+		},
+		{
+			TxId:         psbtTestTxId,
+			Vout:         2,
+			Amount:       20000,
+			ScriptPubKey: addressScriptHex(t, "bc1p5cyxnuxmeuwuvkwfem96lqzszd02n6xdcjrs20cac6yqjjwudpxqkedrcr"),
+			PubKey:       "02cc8a4bc64d897bddc5fbc2f670f7a8ba0b386779106cf1223c6fc5d7cd6fc115",
+			Fingerprint:  "73c5da0a",
+			Path:         "m/86'/0'/0'/0/0",
+		},
+	}
+	outputs := []PsbtOutput{{Address: "bc1qnjg0jd8228aq7egyzacy8cys3knf9xvrerkf9g", Amount: 150000}}
+	created, err := psbtManager.CreatePsbt(inputs, outputs, 2, "bc1q8c6fshw2dlwun7ekn9qwf37cu2rn755upcp6el", "", nil, "mainnet")
+	if err != nil {
+		t.Fatalf("Test failed: unexpected error: %v", err)
+	}
+	signed, err := psbtManager.SignPsbt(created.Psbt, PsbtSigningKeys{Seed: psbtTestSeed}, "mainnet")
+	if err != nil {
+		t.Fatalf("Test failed: unexpected error: %v", err)
+	}
+	if signed.Signed != 4 {
+		t.Fatalf("Test failed:  expected: 4 signed inputs received: %d %v ", signed.Signed, signed.Inputs)
+	}
+	if signed.Inputs[3].PubKeys[0] != "cc8a4bc64d897bddc5fbc2f670f7a8ba0b386779106cf1223c6fc5d7cd6fc115" {
+		t.Errorf("Test failed:  expected: the taproot internal key received: %s ", signed.Inputs[3].PubKeys[0])
+	}
+
+	packet, err := psbt.ParseBase64(signed.Psbt)
+	if err != nil {
+		t.Fatalf("Test failed: unexpected error: %v", err)
+	}
+	sig := func(i int) psbt.PartialSig {
+		if len(packet.Inputs[i].PartialSigs) != 1 {
+			t.Fatalf("Test failed:  expected: 1 partial signature received: %d ", len(packet.Inputs[i].PartialSigs))
+		}
+		return packet.Inputs[i].PartialSigs[0]
+	}
+	p2wpkh, p2shP2wpkh, legacy := sig(0), sig(1), sig(2)
+	executeInput(t, packet, 0, nil, wire.TxWitness{p2wpkh.Signature, p2wpkh.PubKey})
+	redeemPush, _ := txscript.NewScriptBuilder().AddData(packet.Inputs[1].RedeemScript).Script()
+	executeInput(t, packet, 1, redeemPush, wire.TxWitness{p2shP2wpkh.Signature, p2shP2wpkh.PubKey})
+	scriptSig, _ := txscript.NewScriptBuilder().AddData(legacy.Signature).AddData(legacy.PubKey).Script()
+	executeInput(t, packet, 2, scriptSig, nil)
+
+	taprootSig := packet.Inputs[3].TaprootKeySig
+	hash, err := packet.TaprootSigHash(3, 0)
+	if err != nil {
+		t.Fatalf("Test failed: unexpected error: %v", err)
+	}
+	outputKey := packet.Inputs[3].WitnessUtxo.PkScript[2:]
+	if len(taprootSig) != 64 || !helpers.SchnorrVerify(outputKey, hash, taprootSig) {
+		t.Errorf("Test failed:  expected: a key path signature of %x received: %x ", outputKey, taprootSig)
+	}
+
+	// signing again adds nothing
+	again, err := psbtManager.SignPsbt(signed.Psbt, PsbtSigningKeys{Seed: psbtTestSeed}, "mainnet")
+	if err != nil {
+		t.Fatalf("Test failed: unexpected error: %v", err)
+	}
+	if again.Psbt != signed.Psbt {
+		t.Errorf("Test failed:  expected: %s received: %s ", signed.Psbt, again.Psbt)
+	}
+	finalized, err := psbtManager.FinalizePsbt([]string{signed.Psbt})
+	if err != nil {
+		t.Fatalf("Test failed: unexpected error: %v", err)
+	}
+	if !finalized.Complete {
+		t.Errorf("Test failed:  expected: a complete psbt received: %v ", finalized.Inputs)
+	}
+}
+
+func TestSignPsbtMultisig(t *testing.T) {
+	var walletHelper helpers.WalletHelper = helpers.NewWalletHelper()
+	var psbtManager PsbtManager = NewPsbtManager(walletHelper)
+	mnemonic := "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
+
+	seed, _ := hex.DecodeString(psbtTestSeed)
+	master, _ := bip32.NewMasterKey(seed)
+	extPrvKey := walletHelper.EncodeExtendedKey(master, &chaincfg.MainNetParams)
+	descriptor := "wsh(multi(2," + extPrvKey + "/48'/0'/0'/2'/0/*," + extPrvKey + "/48'/0'/1'/2'/0/*))"
+	parsed, err := descriptors.Parse(walletHelper, descriptor, &chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("Test failed: unexpected error: %v", err)
+	}
+	output, err := parsed.Output(3, 0)
+	if err != nil {
+		t.Fatalf("Test failed: unexpected error: %v", err)
+	}
+	inputs := []PsbtInput{{
+		TxId:         psbtTestTxId,
+		Amount:       100000,
+		ScriptPubKey: hex.EncodeToString(output.ScriptPubKey),
+		Path:         "m/48'/0'/0'/2'/0/3",
+	}}
+	outputs := []PsbtOutput{{Address: "bc1qcr8te4kr609gcawutmrza0j4xv80jy8z306fyu", Amount: 99800}}
+	created, err := psbtManager.CreatePsbt(inputs, outputs, 1, "", descriptor, nil, "mainnet")
+	if err != nil {
+		t.Fatalf("Test failed: unexpected error: %v", err)
+	}
+
+	// the first cosigner holds the account key, the second the mnemonic
+	accountKey, _, err := walletHelper.DeriveExtendedKeysFromPath(master, helpers.DerivationPath{0x80000030, 0x80000000, 0x80000000, 0x80000002})
+	if err != nil {
+		t.Fatalf("Test failed: unexpected error: %v", err)
+	}
+	first, err := psbtManager.SignPsbt(created.Psbt, PsbtSigningKeys{ExtPrvKeys: []string{"[73c5da0a/48'/0'/0'/2']" + walletHelper.EncodeExtendedKey(accountKey, &chaincfg.MainNetParams)}}, "mainnet")
+	if err != nil {
+		t.Fatalf("Test failed: unexpected error: %v", err)
+	}
+	if !first.Inputs[0].Signed || len(first.Inputs[0].PubKeys) != 1 {
+		t.Fatalf("Test failed:  expected: 1 signature received: %v ", first.Inputs[0])
+	}
+	second, err := psbtManager.SignPsbt(first.Psbt, PsbtSigningKeys{Mnemonic: mnemonic}, "mainnet")
+	if err != nil {
+		t.Fatalf("Test failed: unexpected error: %v", err)
+	}
+	if len(second.Inputs[0].PubKeys) != 2 {
+		t.Fatalf("Test failed:  expected: 2 signatures received: %v ", second.Inputs[0])
+	}
+
+	packet, _ := psbt.ParseBase64(second.Psbt)
+	input := packet.Inputs[0]
+	witness := wire.TxWitness{nil}
+	for _, derivation := range output.Derivations {
+		for _, partialSig := range input.PartialSigs {
+			if bytes.Equal(partialSig.PubKey, derivation.PubKey) {
+				witness = append(witness, partialSig.Signature)
+			}
+		}
+	}
+	executeInput(t, packet, 0, nil, append(witness, input.WitnessScript))
+}
+
+func TestSignPsbtUnsigned(t *testing.T) {
+	var walletHelper helpers.WalletHelper = helpers.NewWalletHelper()
+	var psbtManager PsbtManager = NewPsbtManager(walletHelper)
+	mnemonic := "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
+
+	inputs := []PsbtInput{
+		{
+			TxId:         psbtTestTxId,
+			Amount:       100000,
+			ScriptPubKey: addressScriptHex(t, "bc1qcr8te4kr609gcawutmrza0j4xv80jy8z306fyu"),
+			PubKey:       "0330d54fd0dd420a6e5f8d3624f5f3482cae350f79d5f0753bf5beef9c2d91af3c",
+			Fingerprint:  "73c5da0a",
+			Path:         "m/84'/0'/0'/0/0",
+		},
+		{
+			TxId:         psbtTestTxId,
+			Vout:         1,
+			Amount:       50000,
+			ScriptPubKey: addressScriptHex(t, "bc1qnjg0jd8228aq7egyzacy8cys3knf9xvrerkf9g"),
+		},
+	}
+	outputs := []PsbtOutput{{Address: "bc1q8c6fshw2dlwun7ekn9qwf37cu2rn755upcp6el", Amount: 149700}}
+	created, err := psbtManager.CreatePsbt(inputs, outputs, 1, "", "", nil, "mainnet")
+	if err != nil {
+		t.Fatalf("Test failed: unexpected error: %v", err)
+	}
+
+	// the passphrase derives another wallet with the same derivation paths
+	signed, err := psbtManager.SignPsbt(created.Psbt, PsbtSigningKeys{Mnemonic: mnemonic, Passphrase: "TREZOR"}, "mainnet")
+	if err != nil {
+		t.Fatalf("Test failed: unexpected error: %v", err)
+	}
+	if signed.Signed != 0 || signed.Psbt != created.Psbt {
+		t.Errorf("Test failed:  expected: no signature received: %d ", signed.Signed)
+	}
+	for _, input := range signed.Inputs {
+		if input.Signed || input.Reason == "" {
+			t.Errorf("Test failed:  expected: a reason for input %d received: %v ", input.Index, input)
+		}
+	}
+
+	seed, _ := hex.DecodeString(psbtTestSeed)
+	master, _ := bip32.NewMasterKey(seed)
+	key, _, _ := walletHelper.DeriveExtendedKeysFromPath(master, helpers.DerivationPath{0x80000054, 0x80000000, 0x80000000, 0, 0})
+	privKey, _ := btcec.PrivKeyFromBytes(btcec.S256(), key.Key)
+	wif, _ := btcutil.NewWIF(privKey, &chaincfg.MainNetParams, true)
+	signed, err = psbtManager.SignPsbt(created.Psbt, PsbtSigningKeys{Wifs: []string{wif.String()}}, "mainnet")
+	if err != nil {
+		t.Fatalf("Test failed: unexpected error: %v", err)
+	}
+	if signed.Signed != 1 || !signed.Inputs[0].Signed || signed.Inputs[1].Signed || signed.Inputs[1].Reason == "" {
+		t.Errorf("Test failed:  expected: only input 0 signed received: %v ", signed.Inputs)
+	}
+
+	for name, keys := range map[string]PsbtSigningKeys{
+		"no keys":         {},
+		"public key":      {ExtPrvKeys: []string{"xpub6CatWdiZiodmUeTDp8LT5or8nmbKNcuyvz7WyksVFkKB4RHwCD3XyuvPEbvqAQY3rAPshWcMLoP2fMFMKHPJ4ZeZXYVUhLv1VMrjPC7PW6V"}},
+		"invalid wif":     {Wifs: []string{"5HueCGU8rMjxEXxiPuD5BDku4MkFqeZyd4dZ1jvhTVqvbTLvyTj"}},
+		"testnet wif":     {Wifs: []string{"cNYfWuhDpbNM1JWc3c6JTrtrFVxU4AGhUKgw5f93NP2QaBqmxKkg"}},
+		"invalid origin":  {ExtPrvKeys: []string{"[73c5da0a/84'" + walletHelper.EncodeExtendedKey(master, &chaincfg.MainNetParams)}},
+		"seed is not hex": {Seed: "not hex"},
+	} {
+		_, err := psbtManager.SignPsbt(created.Psbt, keys, "mainnet")
+		if !errors.Is(err, helpers.ErrInvalidKeyInput) && !errors.Is(err, helpers.ErrKeyNetworkMismatch) {
+			t.Errorf("Test failed: input: %s expected: %v received: %v ", name, helpers.ErrInvalidKeyInput, err)
+		}
+	}
+	if _, err := psbtManager.SignPsbt("cHNidP8=", PsbtSigningKeys{Seed: psbtTestSeed}, "mainnet"); !errors.Is(err, psbt.ErrInvalidPsbt) {
+		t.Errorf("Test failed:  expected: %v received: %v ", psbt.ErrInvalidPsbt, err)
+	}
+}
+
+func TestCombineFinalizeExtractMultisig(t *testing.T) {
+	var walletHelper helpers.WalletHelper = helpers.NewWalletHelper()
+	var walletManager WalletManager = NewWalletManager(walletHelper)
+	var psbtManager PsbtManager = NewPsbtManager(walletHelper)
+
+	wifs := make([]string, 3)
+	for i := range wifs {
+		privKey, _ := btcec.PrivKeyFromBytes(btcec.S256(), bytes.Repeat([]byte{byte(i + 1)}, 32))
+		wif, _ := btcutil.NewWIF(privKey, &chaincfg.MainNetParams, true)
+		wifs[i] = wif.String()
+	}
+	for _, multisigType := range []helpers.MultisigType{helpers.MultisigTypeP2SH, helpers.MultisigTypeP2SHP2WSH, helpers.MultisigTypeP2WSH} {
+		entry, err := walletManager.GenerateMultisignature(3, 2, wifs, true, false, multisigType, "mainnet")
+		if err != nil {
+			t.Fatalf("Test failed: unexpected error: %v", err)
+		}
+		scriptPubKey := addressScriptHex(t, entry.Address)
+		script, _ := hex.DecodeString(scriptPubKey)
+		previous := wire.NewMsgTx(2)
+		previous.AddTxIn(wire.NewTxIn(&wire.OutPoint{}, nil, nil))
+		previous.AddTxOut(wire.NewTxOut(100000, script))
+		var raw bytes.Buffer
+		previous.Serialize(&raw)
+
+		inputs := []PsbtInput{{
+			TxId:          previous.TxHash().String(),
+			Amount:        100000,
+			ScriptPubKey:  scriptPubKey,
+			RedeemScript:  entry.RedeemScript,
+			WitnessScript: entry.WitnessScript,
+			PreviousTx:    hex.EncodeToString(raw.Bytes()),
+		}}
+		outputs := []PsbtOutput{{Address: "bc1qcr8te4kr609gcawutmrza0j4xv80jy8z306fyu", Amount: 99000}}
+		created, err := psbtManager.CreatePsbt(inputs, outputs, 1, "bc1q8c6fshw2dlwun7ekn9qwf37cu2rn755upcp6el", "", nil, "mainnet")
+		if err != nil {
+			t.Fatalf("Test failed: unexpected error: %v", err)
+		}
+
+		// the cosigners sign separately, the last key first
+		var signed []string
+		for _, wif := range []string{wifs[2], wifs[0]} {
+			cosigner, err := psbtManager.SignPsbt(created.Psbt, PsbtSigningKeys{Wifs: []string{wif}}, "mainnet")
+			if err != nil {
+				t.Fatalf("Test failed: unexpected error: %v", err)
+			}
+			if cosigner.Signed != 1 {
+				t.Fatalf("Test failed: input: %s expected: 1 signed input received: %v ", multisigType, cosigner.Inputs)
+			}
+			signed = append(signed, cosigner.Psbt)
+		}
+
+		partial, err := psbtManager.FinalizePsbt(signed[:1])
+		if err != nil {
+			t.Fatalf("Test failed: unexpected error: %v", err)
+		}
+		if partial.Complete || partial.Inputs[0].Finalized || partial.Inputs[0].Reason == "" {
+			t.Errorf("Test failed: input: %s expected: an input missing a signature received: %v ", multisigType, partial.Inputs)
+		}
+		combined, err := psbtManager.CombinePsbt(signed)
+		if err != nil {
+			t.Fatalf("Test failed: unexpected error: %v", err)
+		}
+		packet, _ := psbt.ParseBase64(combined.Psbt)
+		if combined.Txid != created.Txid || len(packet.Inputs[0].PartialSigs) != 2 {
+			t.Errorf("Test failed: input: %s expected: 2 partial signatures of %s received: %d of %s ", multisigType, created.Txid, len(packet.Inputs[0].PartialSigs), combined.Txid)
+		}
+		finalized, err := psbtManager.FinalizePsbt([]string{combined.Psbt})
+		if err != nil {
+			t.Fatalf("Test failed: unexpected error: %v", err)
+		}
+		if !finalized.Complete {
+			t.Fatalf("Test failed: input: %s expected: a complete psbt received: %v ", multisigType, finalized.Inputs)
+		}
+
+		extracted, err := psbtManager.ExtractPsbt(signed)
+		if err != nil {
+			t.Fatalf("Test failed: unexpected error: %v", err)
+		}
+		rawTx, _ := hex.DecodeString(extracted.Hex)
+		tx := wire.NewMsgTx(2)
+		if err := tx.Deserialize(bytes.NewReader(rawTx)); err != nil {
+			t.Fatalf("Test failed: unexpected error: %v", err)
+		}
+		// the txid of an input without witness changes with its scriptSig
+		if extracted.Txid != tx.TxHash().String() || (multisigType == helpers.MultisigTypeP2WSH && extracted.Txid != created.Txid) || extracted.Fee != created.Fee {
+			t.Errorf("Test failed: input: %s expected: %s with a fee of %d received: %s %d ", multisigType, tx.TxHash(), created.Fee, extracted.Txid, extracted.Fee)
+		}
+		vm, err := txscript.NewEngine(script, tx, 0, txscript.StandardVerifyFlags, nil, nil, 100000)
+		if err != nil {
+			t.Fatalf("Test failed: unexpected error: %v", err)
+		}
+		if err := vm.Execute(); err != nil {
+			t.Errorf("Test failed: input: %s expected: a valid spend received: %v ", multisigType, err)
+		}
+		// the estimate counts the largest signatures
+		if extracted.VSize > created.VSize || extracted.VSize < created.VSize-2 {
+			t.Errorf("Test failed: input: %s expected: about %d vB received: %d ", multisigType, created.VSize, extracted.VSize)
+		}
+	}
+}
+
+func TestCombinePsbtMismatch(t *testing.T) {
+	var walletHelper helpers.WalletHelper = helpers.NewWalletHelper()
+	var psbtManager PsbtManager = NewPsbtManager(walletHelper)
+
+	input := PsbtInput{TxId: psbtTestTxId, Amount: 100000, ScriptPubKey: addressScriptHex(t, "bc1qcr8te4kr609gcawutmrza0j4xv80jy8z306fyu")}
+	var psbts []string
+	for _, amount := range []int64{60000, 70000} {
+		outputs := []PsbtOutput{{Address: "bc1qnjg0jd8228aq7egyzacy8cys3knf9xvrerkf9g", Amount: amount}}
+		created, err := psbtManager.CreatePsbt([]PsbtInput{input}, outputs, 1, "bc1q8c6fshw2dlwun7ekn9qwf37cu2rn755upcp6el", "", nil, "mainnet")
+		if err != nil {
+			t.Fatalf("Test failed: unexpected error: %v", err)
+		}
+		psbts = append(psbts, created.Psbt)
+	}
+
+	if _, err := psbtManager.CombinePsbt(psbts); !errors.Is(err, psbt.ErrInvalidPsbt) {
+		t.Errorf("Test failed:  expected: %v received: %v ", psbt.ErrInvalidPsbt, err)
+	}
+	if _, err := psbtManager.FinalizePsbt(psbts); !errors.Is(err, psbt.ErrInvalidPsbt) {
+		t.Errorf("Test failed:  expected: %v received: %v ", psbt.ErrInvalidPsbt, err)
+	}
+	if _, err := psbtManager.CombinePsbt(nil); !errors.Is(err, psbt.ErrInvalidPsbt) {
+		t.Errorf("Test failed:  expected: %v received: %v ", psbt.ErrInvalidPsbt, err)
+	}
+	// an input without signature can not be extracted
+	if _, err := psbtManager.ExtractPsbt(psbts[:1]); !errors.Is(err, psbt.ErrInvalidPsbt) {
+		t.Errorf("Test failed:  expected: %v received: %v ", psbt.ErrInvalidPsbt, err)
+	}
+}
+
+func TestEstimateFee(t *testing.T) {
+	var walletHelper helpers.WalletHelper = helpers.NewWalletHelper()
+	var walletManager WalletManager = NewWalletManager(walletHelper)
+	var psbtManager PsbtManager = NewPsbtManager(walletHelper)
+
+	wifs := make([]string, 3)
+	for i := range wifs {
+		privKey, _ := btcec.PrivKeyFromBytes(btcec.S256(), bytes.Repeat([]byte{byte(i + 1)}, 32))
+		wif, _ := btcutil.NewWIF(privKey, &chaincfg.MainNetParams, true)
+		wifs[i] = wif.String()
+	}
+	p2wpkh, _ := hex.DecodeString(addressScriptHex(t, "bc1qcr8te4kr609gcawutmrza0j4xv80jy8z306fyu"))
+	// a 2 of 3 spend to a P2WPKH output estimates like the PSBT of the
+	// multisig address GenerateMultisignature builds
+	for _, multisigType := range []helpers.MultisigType{helpers.MultisigTypeP2SH, helpers.MultisigTypeP2SHP2WSH, helpers.MultisigTypeP2WSH} {
+		entry, err := walletManager.GenerateMultisignature(3, 2, wifs, true, false, multisigType, "mainnet")
+		if err != nil {
+			t.Fatalf("Test failed: unexpected error: %v", err)
+		}
+		script, _ := hex.DecodeString(addressScriptHex(t, entry.Address))
+		tx := wire.NewMsgTx(2)
+		tx.AddTxIn(wire.NewTxIn(&wire.OutPoint{}, nil, nil))
+		tx.AddTxOut(wire.NewTxOut(1000, p2wpkh))
+		packet, _ := psbt.New(tx)
+		packet.Inputs[0].WitnessUtxo = wire.NewTxOut(5000, script)
+		packet.Inputs[0].RedeemScript, _ = hex.DecodeString(entry.RedeemScript)
+		packet.Inputs[0].WitnessScript, _ = hex.DecodeString(entry.WitnessScript)
+		weight, err := packet.EstimateWeight()
+		if err != nil {
+			t.Fatalf("Test failed: unexpected error: %v", err)
+		}
+
+		estimate, err := psbtManager.EstimateFee([]FeeInput{{Type: string(multisigType), M: 2, N: 3}}, []FeeOutput{{Type: "p2wpkh"}}, []float64{1, 2.5})
+		if err != nil {
+			t.Fatalf("Test failed: unexpected error: %v", err)
+		}
+		if estimate.Weight != weight {
+			t.Errorf("Test failed: %s expected: %d received: %d ", multisigType, weight, estimate.Weight)
+		}
+		vsize := psbt.VirtualSize(weight)
+		if estimate.Fees[0].Fee != int64(vsize) || estimate.Fees[1].Fee != int64((vsize*5+1)/2) {
+			t.Errorf("Test failed: %s expected: %d and %d received: %v ", multisigType, vsize, (vsize*5+1)/2, estimate.Fees)
+		}
+	}
+
+	// the multi_a leaf is spent with two signatures and an empty item, the
+	// leaf and its control block
+	entry, err := walletManager.GenerateMultisignature(3, 2, wifs, true, false, helpers.MultisigTypeP2TR, "mainnet")
+	if err != nil {
+		t.Fatalf("Test failed: unexpected error: %v", err)
+	}
+	witness := 1 + 2*65 + 1 + 1 + len(entry.Leaves[0].Script)/2 + 1 + len(entry.Leaves[0].ControlBlock)/2
+	estimate, err := psbtManager.EstimateFee([]FeeInput{{Type: "p2tr", M: 2, N: 3}, {Type: "p2tr", M: 2, N: 3, KeyPath: true}}, []FeeOutput{{Type: "p2tr"}}, []float64{1})
+	if err != nil {
+		t.Fatalf("Test failed: unexpected error: %v", err)
+	}
+	if estimate.Inputs[0].Weight != 164+witness || estimate.Inputs[1].Weight != 164+66 {
+		t.Errorf("Test failed:  expected: %d and %d received: %d and %d ", 164+witness, 164+66, estimate.Inputs[0].Weight, estimate.Inputs[1].Weight)
+	}
+
+	// the usual single key sizes of one input and two outputs
+	for _, test := range []struct {
+		input  string
+		output string
+		vsize  int
+	}{
+		{"p2pkh", "p2pkh", 226},
+		{"p2wpkh", "p2wpkh", 141},
+	} {
+		estimate, err := psbtManager.EstimateFee([]FeeInput{{Type: test.input}}, []FeeOutput{{Type: test.output, Count: 2}}, []float64{1})
+		if err != nil {
+			t.Fatalf("Test failed: unexpected error: %v", err)
+		}
+		if estimate.VSize != test.vsize {
+			t.Errorf("Test failed: %s expected: %d received: %d ", test.input, test.vsize, estimate.VSize)
+		}
+	}
+
+	_, err = psbtManager.EstimateFee([]FeeInput{{Type: "p2wsh", M: 3, N: 2}}, []FeeOutput{{Type: "p2wpkh"}}, []float64{1})
+	if !errors.Is(err, helpers.ErrMultisigRequiredOverN) {
+		t.Errorf("Test failed:  expected: %v received: %v ", helpers.ErrMultisigRequiredOverN, err)
+	}
+}
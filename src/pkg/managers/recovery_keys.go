@@ -0,0 +1,109 @@
+package managers
+
+import (
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"math/big"
+
+	"btcwallet.com/src/pkg/helpers"
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcutil"
+	"github.com/tyler-smith/go-bip32"
+)
+
+// recoveryChildKey is the BIP32 child key derivation of go-bip32 computed with
+// the btcec curve. go-bip32 multiplies points with math/big, which makes the
+// recovery search, deriving keys for every candidate mnemonic, dozens of times
+// slower (BenchmarkRecoveryChildKey). Everything else derives with go-bip32.
+func recoveryChildKey(key *bip32.Key, index uint32) (*bip32.Key, error) {
+	if !key.IsPrivate && index >= bip32.FirstHardenedChild {
+		return nil, bip32.ErrHardnedChildPublicKey
+	}
+	curve := btcec.S256()
+	parentPubKey := key.Key
+	if key.IsPrivate {
+		_, pubKey := btcec.PrivKeyFromBytes(curve, key.Key)
+		parentPubKey = pubKey.SerializeCompressed()
+	}
+
+	var data []byte
+	if index >= bip32.FirstHardenedChild {
+		data = append([]byte{0x00}, key.Key...)
+	} else {
+		data = append([]byte{}, parentPubKey...)
+	}
+	childNumber := make([]byte, 4)
+	binary.BigEndian.PutUint32(childNumber, index)
+	data = append(data, childNumber...)
+	mac := hmac.New(sha512.New, key.ChainCode)
+	mac.Write(data)
+	intermediary := mac.Sum(nil)
+
+	tweak := new(big.Int).SetBytes(intermediary[:32])
+	if tweak.Cmp(curve.N) >= 0 {
+		return nil, bip32.ErrInvalidPrivateKey
+	}
+	child := &bip32.Key{
+		ChildNumber: childNumber,
+		ChainCode:   intermediary[32:],
+		Depth:       key.Depth + 1,
+		FingerPrint: btcutil.Hash160(parentPubKey)[:4],
+		IsPrivate:   key.IsPrivate,
+	}
+
+	if key.IsPrivate {
+		childKey := tweak.Add(tweak, new(big.Int).SetBytes(key.Key))
+		childKey.Mod(childKey, curve.N)
+		if childKey.Sign() == 0 {
+			return nil, bip32.ErrInvalidPrivateKey
+		}
+		child.Version = bip32.PrivateWalletVersion
+		child.Key = childKey.FillBytes(make([]byte, 32))
+		return child, nil
+	}
+
+	parent, err := btcec.ParsePubKey(key.Key, curve)
+	if err != nil {
+		return nil, err
+	}
+	tx, ty := curve.ScalarBaseMult(intermediary[:32])
+	x, y := curve.Add(tx, ty, parent.X, parent.Y)
+	if x.Sign() == 0 && y.Sign() == 0 {
+		return nil, bip32.ErrInvalidPublicKey
+	}
+	child.Version = bip32.PublicWalletVersion
+	child.Key = (&btcec.PublicKey{Curve: curve, X: x, Y: y}).SerializeCompressed()
+	return child, nil
+}
+
+// recoveryDeriveKey derives the key at the path below the key with
+// recoveryChildKey.
+func recoveryDeriveKey(key *bip32.Key, path helpers.DerivationPath) (*bip32.Key, error) {
+	child := key
+	for _, index := range path {
+		var err error
+		if child, err = recoveryChildKey(child, index); err != nil {
+			return nil, err
+		}
+	}
+	return child, nil
+}
+
+// recoveryPublicKey is the public version of an extended key, as
+// bip32.Key.PublicKey but computed with the btcec curve.
+func recoveryPublicKey(key *bip32.Key) *bip32.Key {
+	if !key.IsPrivate {
+		return key
+	}
+	_, pubKey := btcec.PrivKeyFromBytes(btcec.S256(), key.Key)
+	return &bip32.Key{
+		Version:     bip32.PublicWalletVersion,
+		Key:         pubKey.SerializeCompressed(),
+		Depth:       key.Depth,
+		ChildNumber: key.ChildNumber,
+		FingerPrint: key.FingerPrint,
+		ChainCode:   key.ChainCode,
+		IsPrivate:   false,
+	}
+}
@@ -0,0 +1,127 @@
+package managers
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"btcwallet.com/src/pkg/helpers"
+	"github.com/tyler-smith/go-bip32"
+)
+
+func TestRecoveryChildKey(t *testing.T) {
+	const h = bip32.FirstHardenedChild
+	// BIP32 test vectors 1 to 3, vector 3 has a master key with leading zeros
+	tests := []struct {
+		seed  string
+		chain []struct {
+			path helpers.DerivationPath
+			xpub string
+			xprv string
+		}
+	}{
+		{"000102030405060708090a0b0c0d0e0f", []struct {
+			path helpers.DerivationPath
+			xpub string
+			xprv string
+		}{
+			{helpers.DerivationPath{}, "xpub661MyMwAqRbcFtXgS5sYJABqqG9YLmC4Q1Rdap9gSE8NqtwybGhePY2gZ29ESFjqJoCu1Rupje8YtGqsefD265TMg7usUDFdp6W1EGMcet8", "xprv9s21ZrQH143K3QTDL4LXw2F7HEK3wJUD2nW2nRk4stbPy6cq3jPPqjiChkVvvNKmPGJxWUtg6LnF5kejMRNNU3TGtRBeJgk33yuGBxrMPHi"},
+			{helpers.DerivationPath{h}, "xpub68Gmy5EdvgibQVfPdqkBBCHxA5htiqg55crXYuXoQRKfDBFA1WEjWgP6LHhwBZeNK1VTsfTFUHCdrfp1bgwQ9xv5ski8PX9rL2dZXvgGDnw", "xprv9uHRZZhk6KAJC1avXpDAp4MDc3sQKNxDiPvvkX8Br5ngLNv1TxvUxt4cV1rGL5hj6KCesnDYUhd7oWgT11eZG7XnxHrnYeSvkzY7d2bhkJ7"},
+			{helpers.DerivationPath{h, 1}, "xpub6ASuArnXKPbfEwhqN6e3mwBcDTgzisQN1wXN9BJcM47sSikHjJf3UFHKkNAWbWMiGj7Wf5uMash7SyYq527Hqck2AxYysAA7xmALppuCkwQ", "xprv9wTYmMFdV23N2TdNG573QoEsfRrWKQgWeibmLntzniatZvR9BmLnvSxqu53Kw1UmYPxLgboyZQaXwTCg8MSY3H2EU4pWcQDnRnrVA1xe8fs"},
+			{helpers.DerivationPath{h, 1, h + 2}, "xpub6D4BDPcP2GT577Vvch3R8wDkScZWzQzMMUm3PWbmWvVJrZwQY4VUNgqFJPMM3No2dFDFGTsxxpG5uJh7n7epu4trkrX7x7DogT5Uv6fcLW5", "xprv9z4pot5VBttmtdRTWfWQmoH1taj2axGVzFqSb8C9xaxKymcFzXBDptWmT7FwuEzG3ryjH4ktypQSAewRiNMjANTtpgP4mLTj34bhnZX7UiM"},
+			{helpers.DerivationPath{h, 1, h + 2, 2}, "xpub6FHa3pjLCk84BayeJxFW2SP4XRrFd1JYnxeLeU8EqN3vDfZmbqBqaGJAyiLjTAwm6ZLRQUMv1ZACTj37sR62cfN7fe5JnJ7dh8zL4fiyLHV", "xprvA2JDeKCSNNZky6uBCviVfJSKyQ1mDYahRjijr5idH2WwLsEd4Hsb2Tyh8RfQMuPh7f7RtyzTtdrbdqqsunu5Mm3wDvUAKRHSC34sJ7in334"},
+			{helpers.DerivationPath{h, 1, h + 2, 2, 1000000000}, "xpub6H1LXWLaKsWFhvm6RVpEL9P4KfRZSW7abD2ttkWP3SSQvnyA8FSVqNTEcYFgJS2UaFcxupHiYkro49S8yGasTvXEYBVPamhGW6cFJodrTHy", "xprvA41z7zogVVwxVSgdKUHDy1SKmdb533PjDz7J6N6mV6uS3ze1ai8FHa8kmHScGpWmj4WggLyQjgPie1rFSruoUihUZREPSL39UNdE3BBDu76"},
+		}},
+		{"fffcf9f6f3f0edeae7e4e1dedbd8d5d2cfccc9c6c3c0bdbab7b4b1aeaba8a5a29f9c999693908d8a8784817e7b7875726f6c696663605d5a5754514e4b484542", []struct {
+			path helpers.DerivationPath
+			xpub string
+			xprv string
+		}{
+			{helpers.DerivationPath{}, "xpub661MyMwAqRbcFW31YEwpkMuc5THy2PSt5bDMsktWQcFF8syAmRUapSCGu8ED9W6oDMSgv6Zz8idoc4a6mr8BDzTJY47LJhkJ8UB7WEGuduB", "xprv9s21ZrQH143K31xYSDQpPDxsXRTUcvj2iNHm5NUtrGiGG5e2DtALGdso3pGz6ssrdK4PFmM8NSpSBHNqPqm55Qn3LqFtT2emdEXVYsCzC2U"},
+			{helpers.DerivationPath{0}, "xpub69H7F5d8KSRgmmdJg2KhpAK8SR3DjMwAdkxj3ZuxV27CprR9LgpeyGmXUbC6wb7ERfvrnKZjXoUmmDznezpbZb7ap6r1D3tgFxHmwMkQTPH", "xprv9vHkqa6EV4sPZHYqZznhT2NPtPCjKuDKGY38FBWLvgaDx45zo9WQRUT3dKYnjwih2yJD9mkrocEZXo1ex8G81dwSM1fwqWpWkeS3v86pgKt"},
+			{helpers.DerivationPath{0, h + 2147483647}, "xpub6ASAVgeehLbnwdqV6UKMHVzgqAG8Gr6riv3Fxxpj8ksbH9ebxaEyBLZ85ySDhKiLDBrQSARLq1uNRts8RuJiHjaDMBU4Zn9h8LZNnBC5y4a", "xprv9wSp6B7kry3Vj9m1zSnLvN3xH8RdsPP1Mh7fAaR7aRLcQMKTR2vidYEeEg2mUCTAwCd6vnxVrcjfy2kRgVsFawNzmjuHc2YmYRmagcEPdU9"},
+			{helpers.DerivationPath{0, h + 2147483647, 1}, "xpub6DF8uhdarytz3FWdA8TvFSvvAh8dP3283MY7p2V4SeE2wyWmG5mg5EwVvmdMVCQcoNJxGoWaU9DCWh89LojfZ537wTfunKau47EL2dhHKon", "xprv9zFnWC6h2cLgpmSA46vutJzBcfJ8yaJGg8cX1e5StJh45BBciYTRXSd25UEPVuesF9yog62tGAQtHjXajPPdbRCHuWS6T8XA2ECKADdw4Ef"},
+			{helpers.DerivationPath{0, h + 2147483647, 1, h + 2147483646}, "xpub6ERApfZwUNrhLCkDtcHTcxd75RbzS1ed54G1LkBUHQVHQKqhMkhgbmJbZRkrgZw4koxb5JaHWkY4ALHY2grBGRjaDMzQLcgJvLJuZZvRcEL", "xprvA1RpRA33e1JQ7ifknakTFpgNXPmW2YvmhqLQYMmrj4xJXXWYpDPS3xz7iAxn8L39njGVyuoseXzU6rcxFLJ8HFsTjSyQbLYnMpCqE2VbFWc"},
+			{helpers.DerivationPath{0, h + 2147483647, 1, h + 2147483646, 2}, "xpub6FnCn6nSzZAw5Tw7cgR9bi15UV96gLZhjDstkXXxvCLsUXBGXPdSnLFbdpq8p9HmGsApME5hQTZ3emM2rnY5agb9rXpVGyy3bdW6EEgAtqt", "xprvA2nrNbFZABcdryreWet9Ea4LvTJcGsqrMzxHx98MMrotbir7yrKCEXw7nadnHM8Dq38EGfSh6dqA9QWTyefMLEcBYJUuekgW4BYPJcr9E7j"},
+		}},
+		{"4b381541583be4423346c643850da4b320e46a87ae3d2a4e6da11eba819cd4acba45d239319ac14f863b8d5ab5a0d0c64d2e8a1e7d1457df2e5a3c51c73235be", []struct {
+			path helpers.DerivationPath
+			xpub string
+			xprv string
+		}{
+			{helpers.DerivationPath{}, "xpub661MyMwAqRbcEZVB4dScxMAdx6d4nFc9nvyvH3v4gJL378CSRZiYmhRoP7mBy6gSPSCYk6SzXPTf3ND1cZAceL7SfJ1Z3GC8vBgp2epUt13", "xprv9s21ZrQH143K25QhxbucbDDuQ4naNntJRi4KUfWT7xo4EKsHt2QJDu7KXp1A3u7Bi1j8ph3EGsZ9Xvz9dGuVrtHHs7pXeTzjuxBrCmmhgC6"},
+			{helpers.DerivationPath{h}, "xpub68NZiKmJWnxxS6aaHmn81bvJeTESw724CRDs6HbuccFQN9Ku14VQrADWgqbhhTHBaohPX4CjNLf9fq9MYo6oDaPPLPxSb7gwQN3ih19Zm4Y", "xprv9uPDJpEQgRQfDcW7BkF7eTya6RPxXeJCqCJGHuCJ4GiRVLzkTXBAJMu2qaMWPrS7AANYqdq6vcBcBUdJCVVFceUvJFjaPdGZ2y9WACViL4L"},
+		}},
+	}
+	for _, test := range tests {
+		seed, _ := hex.DecodeString(test.seed)
+		master, err := bip32.NewMasterKey(seed)
+		if err != nil {
+			t.Fatalf("Test failed: unexpected error: %v", err)
+		}
+		for _, step := range test.chain {
+			child, err := recoveryDeriveKey(master, step.path)
+			if err != nil {
+				t.Fatalf("Test failed: unexpected error: %v", err)
+			}
+			if child.String() != step.xprv {
+				t.Errorf("Test failed: %s expected: %s received: %s ", step.path, step.xprv, child)
+			}
+			if recoveryPublicKey(child).String() != step.xpub {
+				t.Errorf("Test failed: %s expected: %s received: %s ", step.path, step.xpub, recoveryPublicKey(child))
+			}
+			// a public parent derives the public child of a normal index
+			if len(step.path) == 0 || step.path[len(step.path)-1] >= h {
+				continue
+			}
+			parent, _ := recoveryDeriveKey(master, step.path[:len(step.path)-1])
+			child, err = recoveryChildKey(recoveryPublicKey(parent), step.path[len(step.path)-1])
+			if err != nil {
+				t.Fatalf("Test failed: unexpected error: %v", err)
+			}
+			if child.String() != step.xpub {
+				t.Errorf("Test failed: %s expected: %s received: %s ", step.path, step.xpub, child)
+			}
+		}
+	}
+
+	// whole account paths match go-bip32 level by level
+	for _, seed := range []string{"000102030405060708090a0b0c0d0e0f", "4b381541583be4423346c643850da4b320e46a87ae3d2a4e6da11eba819cd4acba45d239319ac14f863b8d5ab5a0d0c64d2e8a1e7d1457df2e5a3c51c73235be"} {
+		decoded, _ := hex.DecodeString(seed)
+		master, _ := bip32.NewMasterKey(decoded)
+		for _, path := range []helpers.DerivationPath{{h + 44, h, h, 0, 0}, {h + 84, h, h, 1, 19}, {h + 48, h, h, h + 2, 0, 7}, {h + 86, h + 1, h + 5, 0, 999}} {
+			expected := master
+			child := master
+			for _, index := range path {
+				expected, _ = expected.NewChildKey(index)
+				var err error
+				if child, err = recoveryChildKey(child, index); err != nil {
+					t.Fatalf("Test failed: unexpected error: %v", err)
+				}
+				if child.String() != expected.String() || recoveryPublicKey(child).String() != expected.PublicKey().String() {
+					t.Errorf("Test failed: %s expected: %s received: %s ", path, expected, child)
+				}
+			}
+		}
+	}
+	master, _ := bip32.NewMasterKey(make([]byte, 16))
+	if _, err := recoveryChildKey(master.PublicKey(), h); err != bip32.ErrHardnedChildPublicKey {
+		t.Errorf("Test failed:  expected: %v received: %v ", bip32.ErrHardnedChildPublicKey, err)
+	}
+}
+
+func BenchmarkRecoveryChildKey(b *testing.B) {
+	master, _ := bip32.NewMasterKey(make([]byte, 32))
+	receive, _ := recoveryDeriveKey(master, helpers.DerivationPath{bip32.FirstHardenedChild + 84, bip32.FirstHardenedChild, bip32.FirstHardenedChild, 0})
+	b.Run("btcec", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			recoveryPublicKey(receive)
+			recoveryChildKey(receive, uint32(i)%bip32.FirstHardenedChild)
+		}
+	})
+	b.Run("go-bip32", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			receive.PublicKey()
+			receive.NewChildKey(uint32(i) % bip32.FirstHardenedChild)
+		}
+	})
+}
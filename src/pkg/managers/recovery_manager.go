@@ -0,0 +1,418 @@
+package managers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
+	"btcwallet.com/src/pkg/helpers"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/tyler-smith/go-bip32"
+)
+
+type RecoveryManager interface {
+	RecoverMnemonic(ctx context.Context, mnemonic string, language string, passPhrase string, maxDistance int, target RecoveryTarget, network string) (*RecoveryReport, error)
+}
+
+type recoveryManager struct {
+	walletHelper helpers.WalletHelper
+	workers      int
+}
+
+// RecoveryTarget is what a recovered mnemonic must derive. Address is checked
+// on Path, a full derivation path, or on the first AddressGap receive addresses
+// of the BIP44, BIP49, BIP84 and BIP86 first accounts. ExtPubKey is checked
+// against the account key at Path or at the same standard accounts.
+type RecoveryTarget struct {
+	Address    string
+	ExtPubKey  string
+	Path       string
+	AddressGap uint32
+}
+
+// RecoveryMatch is a candidate mnemonic, with the path and address that
+// confirmed it when a target is set.
+type RecoveryMatch struct {
+	Mnemonic string `json:"mnemonic"`
+	Path     string `json:"path,omitempty"`
+	Address  string `json:"address,omitempty"`
+}
+
+// RecoveryReport counts the enumerated candidates and those with a valid
+// checksum, and lists the matches.
+type RecoveryReport struct {
+	Candidates    uint64          `json:"candidates"`
+	ChecksumValid uint64          `json:"checksumValid"`
+	Matches       []RecoveryMatch `json:"matches"`
+	Truncated     bool            `json:"truncated,omitempty"`
+}
+
+// UnknownWord marks a word position that is not known in a mnemonic to recover.
+const UnknownWord = "?"
+
+// DefaultRecoveryDistance is the edit distance used to match a misspelled word.
+const DefaultRecoveryDistance = 2
+
+// DefaultAddressGap is the number of receive addresses checked per account.
+const DefaultAddressGap = 20
+
+// MaxRecoveryCandidates caps the number of mnemonics a single recovery enumerates.
+const MaxRecoveryCandidates = 1 << 22
+
+// MaxRecoveryMatches caps the number of matches reported by a single recovery.
+const MaxRecoveryMatches = 1000
+
+// ErrTooManyCandidates is returned when a recovery would enumerate more than MaxRecoveryCandidates.
+var ErrTooManyCandidates = errors.New("too many recovery candidates")
+
+// recoveryLayout holds the candidate word indexes of every position.
+type recoveryLayout [][]int
+
+func (layout recoveryLayout) size() uint64 {
+	size := uint64(1)
+	for _, slot := range layout {
+		size *= uint64(len(slot))
+		if size > MaxRecoveryCandidates {
+			return MaxRecoveryCandidates + 1
+		}
+	}
+	return size
+}
+
+// candidate decodes the n-th combination of the layout as a mixed radix number.
+func (layout recoveryLayout) candidate(n uint64, indexes []int) {
+	for i := len(layout) - 1; i >= 0; i-- {
+		radix := uint64(len(layout[i]))
+		indexes[i] = layout[i][n%radix]
+		n /= radix
+	}
+}
+
+// RecoverMnemonic enumerates the mnemonics matching a partial one, where
+// unknown words are marked with UnknownWord and words missing from the word
+// list are replaced by the words within maxDistance edits. A mnemonic one word
+// short of a valid length is tried with the missing word at every position.
+// The candidates with a valid checksum are checked against the target in
+// parallel.
+func (rm *recoveryManager) RecoverMnemonic(ctx context.Context, mnemonic string, language string, passPhrase string, maxDistance int, target RecoveryTarget, network string) (*RecoveryReport, error) {
+	lang := helpers.Language(language)
+	if lang == "" {
+		lang = helpers.LanguageEnglish
+	}
+	if maxDistance < 0 {
+		maxDistance = DefaultRecoveryDistance
+	}
+	layouts, err := rm.recoveryLayouts(mnemonic, lang, maxDistance)
+	if err != nil {
+		return nil, err
+	}
+	total := uint64(0)
+	for _, layout := range layouts {
+		total += layout.size()
+		if total > MaxRecoveryCandidates {
+			return nil, fmt.Errorf("%w: more than %d mnemonics to try", ErrTooManyCandidates, MaxRecoveryCandidates)
+		}
+	}
+
+	verify, err := rm.targetVerifier(target, network)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &RecoveryReport{Candidates: total, Matches: []RecoveryMatch{}}
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		found   = map[string]uint64{}
+		matches = map[string]RecoveryMatch{}
+		errs    = make(chan error, rm.workers)
+	)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	for worker := 0; worker < rm.workers; worker++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			var offset, checksumValid uint64
+			defer func() {
+				mu.Lock()
+				report.ChecksumValid += checksumValid
+				mu.Unlock()
+			}()
+			for _, layout := range layouts {
+				size := layout.size()
+				indexes := make([]int, len(layout))
+				// the workers stride over the candidates of every layout
+				for n := uint64(worker); n < size; n += uint64(rm.workers) {
+					if n%1024 == uint64(worker) && ctx.Err() != nil {
+						return
+					}
+					layout.candidate(n, indexes)
+					if !helpers.ValidMnemonicIndexes(indexes) {
+						continue
+					}
+					checksumValid++
+					candidate, err := helpers.MnemonicFromIndexes(indexes, lang)
+					if err != nil {
+						errs <- err
+						cancel()
+						return
+					}
+					match := RecoveryMatch{Mnemonic: candidate}
+					if verify != nil {
+						ok, err := verify(candidate, passPhrase, lang, &match)
+						if err != nil {
+							errs <- err
+							cancel()
+							return
+						}
+						if !ok {
+							continue
+						}
+					}
+					mu.Lock()
+					if _, ok := found[candidate]; !ok {
+						if len(found) < MaxRecoveryMatches {
+							found[candidate] = offset + n
+							matches[candidate] = match
+						} else {
+							report.Truncated = true
+						}
+					}
+					mu.Unlock()
+				}
+				offset += size
+			}
+		}(worker)
+	}
+	wg.Wait()
+	close(errs)
+
+	if err := <-errs; err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	for candidate := range found {
+		report.Matches = append(report.Matches, matches[candidate])
+	}
+	// report the matches in enumeration order whatever worker found them
+	sort.Slice(report.Matches, func(i, j int) bool {
+		return found[report.Matches[i].Mnemonic] < found[report.Matches[j].Mnemonic]
+	})
+	return report, nil
+}
+
+// recoveryLayouts maps every word of the partial mnemonic to its candidates.
+func (rm *recoveryManager) recoveryLayouts(mnemonic string, language helpers.Language, maxDistance int) ([]recoveryLayout, error) {
+	words := strings.Fields(strings.ToLower(helpers.NormalizeMnemonic(mnemonic)))
+	all := make([]int, 2048)
+	for i := range all {
+		all[i] = i
+	}
+
+	layout := make(recoveryLayout, len(words))
+	for i, word := range words {
+		if word == UnknownWord {
+			layout[i] = all
+			continue
+		}
+		if index, err := helpers.WordIndex(word, language); err == nil {
+			layout[i] = []int{index}
+			continue
+		} else if errors.Is(err, helpers.ErrUnsupportedLanguage) {
+			return nil, err
+		}
+		similar, err := helpers.SimilarWords(word, language, maxDistance)
+		if err != nil {
+			return nil, err
+		}
+		if len(similar) == 0 {
+			return nil, fmt.Errorf("%w: word %d %q has no match within %d edits", helpers.ErrInvalidMnemonic, i+1, word, maxDistance)
+		}
+		layout[i] = similar
+	}
+
+	switch {
+	case len(words) >= 12 && len(words) <= 24 && len(words)%3 == 0:
+		return []recoveryLayout{layout}, nil
+	case len(words) >= 11 && len(words) <= 23 && len(words)%3 == 2:
+		// one word is missing at an unknown position
+		layouts := make([]recoveryLayout, 0, len(words)+1)
+		for position := 0; position <= len(words); position++ {
+			inserted := make(recoveryLayout, 0, len(words)+1)
+			inserted = append(inserted, layout[:position]...)
+			inserted = append(inserted, all)
+			inserted = append(inserted, layout[position:]...)
+			layouts = append(layouts, inserted)
+		}
+		return layouts, nil
+	default:
+		return nil, fmt.Errorf("%w: word count must be one of 12, 15, 18, 21 or 24, or one less. got %d", helpers.ErrInvalidMnemonic, len(words))
+	}
+}
+
+type recoveryVerifier func(mnemonic string, passPhrase string, language helpers.Language, match *RecoveryMatch) (bool, error)
+
+// targetVerifier builds the check of a candidate against the target, it is
+// nil when the target is empty.
+func (rm *recoveryManager) targetVerifier(target RecoveryTarget, network string) (recoveryVerifier, error) {
+	if target.Address == "" && target.ExtPubKey == "" {
+		return nil, nil
+	}
+	if target.Address != "" && target.ExtPubKey != "" {
+		return nil, fmt.Errorf("either an address or an extended public key can be verified, not both")
+	}
+	net, err := rm.walletHelper.DeriveNetworkParams(network)
+	if err != nil {
+		return nil, err
+	}
+	gap := target.AddressGap
+	if gap == 0 {
+		gap = DefaultAddressGap
+	}
+	if gap > MaxAddressRangeCount {
+		return nil, fmt.Errorf("address gap must be between 1 and %d. got %d", MaxAddressRangeCount, gap)
+	}
+
+	var paths []helpers.DerivationPath
+	if target.Path != "" {
+		path, err := rm.walletHelper.DerivePath(target.Path)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, path)
+	} else {
+		paths = standardAccountPaths(net)
+	}
+
+	if target.ExtPubKey != "" {
+		xPubKey, err := rm.walletHelper.DeriveExtendedPublicKey(target.ExtPubKey, net)
+		if err != nil {
+			return nil, err
+		}
+		return func(mnemonic string, passPhrase string, language helpers.Language, match *RecoveryMatch) (bool, error) {
+			master, err := rm.masterKey(mnemonic, passPhrase, language)
+			if err != nil {
+				return false, err
+			}
+			for _, path := range paths {
+				accountKey, err := recoveryDeriveKey(master, path)
+				if err != nil {
+					return false, err
+				}
+				accountKey = recoveryPublicKey(accountKey)
+				if bytes.Equal(accountKey.Key, xPubKey.Key) && bytes.Equal(accountKey.ChainCode, xPubKey.ChainCode) {
+					match.Path = path.String()
+					return true, nil
+				}
+			}
+			return false, nil
+		}, nil
+	}
+
+	return func(mnemonic string, passPhrase string, language helpers.Language, match *RecoveryMatch) (bool, error) {
+		master, err := rm.masterKey(mnemonic, passPhrase, language)
+		if err != nil {
+			return false, err
+		}
+		if target.Path != "" {
+			return rm.matchAddress(master, paths[0], target.Address, net, match)
+		}
+		// scan the receive addresses of every standard account, deriving
+		// the receive chain once per account
+		for _, path := range paths {
+			receivePath := path.Append(0)
+			receive, err := recoveryDeriveKey(master, receivePath)
+			if err != nil {
+				return false, err
+			}
+			addressType, err := helpers.AddressTypeForPurpose(path[0])
+			if err != nil {
+				return false, err
+			}
+			for index := uint32(0); index < gap; index++ {
+				child, err := recoveryChildKey(receive, index)
+				if err != nil {
+					return false, err
+				}
+				pubKey := rm.walletHelper.DerivePrivateKeyFromBytes(child.Key).PubKey()
+				address, err := rm.walletHelper.DeriveAddressForType(pubKey, net, addressType)
+				if err != nil {
+					return false, err
+				}
+				if address == target.Address {
+					match.Path = receivePath.Append(index).String()
+					match.Address = address
+					return true, nil
+				}
+			}
+		}
+		return false, nil
+	}, nil
+}
+
+// matchAddress compares the address of a full path with the target, every
+// address type is tried when the purpose of the path is not a known one.
+func (rm *recoveryManager) matchAddress(master *bip32.Key, path helpers.DerivationPath, address string, net *chaincfg.Params, match *RecoveryMatch) (bool, error) {
+	xPrvKey, err := recoveryDeriveKey(master, path)
+	if err != nil {
+		return false, err
+	}
+	pubKey := rm.walletHelper.DerivePrivateKeyFromBytes(xPrvKey.Key).PubKey()
+	addressTypes := []helpers.AddressType{helpers.AddressTypeP2PKH, helpers.AddressTypeP2SHP2WPKH, helpers.AddressTypeP2WPKH, helpers.AddressTypeP2TR}
+	if len(path) > 0 {
+		if addressType, err := helpers.AddressTypeForPurpose(path[0]); err == nil {
+			addressTypes = []helpers.AddressType{addressType}
+		}
+	}
+	for _, addressType := range addressTypes {
+		derived, err := rm.walletHelper.DeriveAddressForType(pubKey, net, addressType)
+		if err != nil {
+			return false, err
+		}
+		if derived == address {
+			match.Path = path.String()
+			match.Address = derived
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (rm *recoveryManager) masterKey(mnemonic string, passPhrase string, language helpers.Language) (*bip32.Key, error) {
+	seed, _, err := rm.walletHelper.DeriveSeedFromMnemonic(mnemonic, passPhrase, language)
+	if err != nil {
+		return nil, err
+	}
+	return bip32.NewMasterKey(seed)
+}
+
+// standardAccountPaths are the first accounts of the BIP44, BIP49, BIP84 and
+// BIP86 purposes, with the coin type of the network.
+func standardAccountPaths(net *chaincfg.Params) []helpers.DerivationPath {
+	coinType := bip32.FirstHardenedChild
+	if net.Name != chaincfg.MainNetParams.Name {
+		coinType++
+	}
+	paths := make([]helpers.DerivationPath, 0, 4)
+	for _, purpose := range []uint32{44, 49, 84, 86} {
+		paths = append(paths, helpers.DerivationPath{bip32.FirstHardenedChild + purpose, coinType, bip32.FirstHardenedChild})
+	}
+	return paths
+}
+
+func NewRecoveryManager(walletHelper helpers.WalletHelper) RecoveryManager {
+	return &recoveryManager{
+		walletHelper,
+		runtime.NumCPU(),
+	}
+}
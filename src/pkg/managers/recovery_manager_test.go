@@ -0,0 +1,79 @@
+package managers
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"btcwallet.com/src/pkg/helpers"
+)
+
+func TestRecoverMnemonicUnknownWord(t *testing.T) {
+	var walletHelper helpers.WalletHelper = helpers.NewWalletHelper()
+	var recoveryManager RecoveryManager = NewRecoveryManager(walletHelper)
+	var expected string = "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
+
+	// 4 checksum bits leave 2048 / 16 candidates for the last word of 12
+	report, err := recoveryManager.RecoverMnemonic(context.Background(), "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon ?", "", "", -1, RecoveryTarget{}, "mainnet")
+	if err != nil {
+		t.Fatalf("Test failed: unexpected error: %v", err)
+	}
+	if report.Candidates != 2048 || report.ChecksumValid != 128 || len(report.Matches) != 128 {
+		t.Errorf("Test failed:  expected: 2048 128 128 received: %d %d %d ", report.Candidates, report.ChecksumValid, len(report.Matches))
+	}
+
+	// the BIP84 first receive address of the reference mnemonic
+	target := RecoveryTarget{Address: "bc1qcr8te4kr609gcawutmrza0j4xv80jy8z306fyu"}
+	report, err = recoveryManager.RecoverMnemonic(context.Background(), "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon ?", "", "", -1, target, "mainnet")
+	if err != nil {
+		t.Fatalf("Test failed: unexpected error: %v", err)
+	}
+	if len(report.Matches) != 1 || report.Matches[0].Mnemonic != expected || report.Matches[0].Path != "m/84'/0'/0'/0/0" {
+		t.Errorf("Test failed:  expected: %s received: %v ", expected, report.Matches)
+	}
+}
+
+func TestRecoverMnemonicMisspelledWord(t *testing.T) {
+	var walletHelper helpers.WalletHelper = helpers.NewWalletHelper()
+	var recoveryManager RecoveryManager = NewRecoveryManager(walletHelper)
+	var expected string = "legal winner thank year wave sausage worth useful legal winner thank yellow"
+
+	report, err := recoveryManager.RecoverMnemonic(context.Background(), "legal winner thank year wave sausage worth usefull legal winner thnak yellow", "english", "", 1, RecoveryTarget{}, "mainnet")
+	if err != nil {
+		t.Fatalf("Test failed: unexpected error: %v", err)
+	}
+	found := false
+	for _, match := range report.Matches {
+		found = found || match.Mnemonic == expected
+	}
+	if !found {
+		t.Errorf("Test failed:  expected: %s received: %v ", expected, report.Matches)
+	}
+
+	if _, err := recoveryManager.RecoverMnemonic(context.Background(), "legal winner thank year wave sausage worth xxxxxxxx legal winner thank yellow", "english", "", 1, RecoveryTarget{}, "mainnet"); !errors.Is(err, helpers.ErrInvalidMnemonic) {
+		t.Errorf("Test failed:  expected: %v received: %v ", helpers.ErrInvalidMnemonic, err)
+	}
+}
+
+func TestRecoverMnemonicMissingWord(t *testing.T) {
+	var walletHelper helpers.WalletHelper = helpers.NewWalletHelper()
+	var recoveryManager RecoveryManager = NewRecoveryManager(walletHelper)
+	var expected string = "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
+
+	// 11 words, the position of the missing word is unknown
+	target := RecoveryTarget{ExtPubKey: "zpub6rFR7y4Q2AijBEqTUquhVz398htDFrtymD9xYYfG1m4wAcvPhXNfE3EfH1r1ADqtfSdVCToUG868RvUUkgDKf31mGDtKsAYz2oz2AGutZYs", Path: "m/84'/0'/0'"}
+	report, err := recoveryManager.RecoverMnemonic(context.Background(), "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about", "", "", -1, target, "mainnet")
+	if err != nil {
+		t.Fatalf("Test failed: unexpected error: %v", err)
+	}
+	if report.Candidates != 12*2048 {
+		t.Errorf("Test failed:  expected: %d received: %d ", 12*2048, report.Candidates)
+	}
+	if len(report.Matches) != 1 || report.Matches[0].Mnemonic != expected {
+		t.Errorf("Test failed:  expected: %s received: %v ", expected, report.Matches)
+	}
+
+	if _, err := recoveryManager.RecoverMnemonic(context.Background(), "abandon ? ? ? abandon abandon abandon abandon abandon abandon abandon about", "", "", -1, RecoveryTarget{}, "mainnet"); !errors.Is(err, ErrTooManyCandidates) {
+		t.Errorf("Test failed:  expected: %v received: %v ", ErrTooManyCandidates, err)
+	}
+}
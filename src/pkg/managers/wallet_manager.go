@@ -1,17 +1,39 @@
 package managers
 
 import (
+	"context"
+	"crypto/rand"
 	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
 
+	"btcwallet.com/src/pkg/descriptors"
 	"btcwallet.com/src/pkg/helpers"
+	"btcwallet.com/src/pkg/miniscript"
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
 	"github.com/tyler-smith/go-bip32"
 	"github.com/tyler-smith/go-bip39"
 )
 
 type WalletManager interface {
-	GenerateMnemonic(passPhrase string) (mnemonic string, seed string, err error)
-	GenerateHdWallet(seed string, path string) (extPrvKey string, extPubKey string, rootKey string, wif string, p2pkhAddress string, segwitBech32 string, segwitNested string, err error)
-	GenerateMultisignature(n int8, m int8, wif []string) (address string, err error)
+	GenerateMnemonic(passPhrase string, wordCount int, language string) (mnemonic string, seed string, entropy string, err error)
+	MnemonicToSeed(mnemonic string, passPhrase string, language string) (normalized string, seed string, detected string, err error)
+	GenerateSlip39Shares(masterSecret string, strength int, passPhrase string, groupThreshold int, groups []helpers.Slip39Group, iterationExponent int, extendable bool) (shares [][]string, seed string, err error)
+	CombineSlip39Shares(mnemonics []string, passPhrase string) (seed string, err error)
+	GenerateHdWallet(seed string, path string, network string, mixed bool) (*HdWallet, error)
+	GenerateMultisignature(n int, m int, keys []string, sorted bool, subsetLeaves bool, multisigType helpers.MultisigType, network string) (*MultisigEntry, error)
+	GenerateMultisignatureFromExtPubKeys(ctx context.Context, n int, m int, extPubKeys []string, change uint32, start uint32, count uint32, sorted bool, subsetLeaves bool, multisigType helpers.MultisigType, network string) ([]MultisigEntry, error)
+	GenerateAddressRange(ctx context.Context, seed string, accountPath string, change uint32, start uint32, count uint32, includeWif bool, network string) ([]AddressEntry, error)
+	GenerateWatchOnly(ctx context.Context, extPubKey string, path string, count uint32, network string) ([]AddressEntry, error)
+	DeriveDescriptorAddresses(ctx context.Context, descriptor string, start uint32, count uint32, multipathIndex int, network string) (*DescriptorAddresses, error)
+	DecodeMultisignatureScript(script string, network string) (*DecodedMultisig, error)
+	CompileMiniscript(expression string, isPolicy bool, ctx miniscript.Context, keys map[string]string, internalKey string, network string) (*MiniscriptOutput, error)
 }
 
 type walletManager struct {
@@ -26,72 +48,897 @@ type BIP44Params struct {
 	AddressIndex uint32 `json:"addressIndex"`
 }
 
-const bitSize = 256
+// HdWallet is the key material derived by GenerateHdWallet. Address holds the
+// address type selected by the path purpose, the mixed output fills every
+// address type instead. Descriptors are set for BIP44, BIP49, BIP84 and BIP86
+// account paths.
+type HdWallet struct {
+	ExtPrvKey    string               `json:"bip32ExtendedPrivateKey"`
+	ExtPubKey    string               `json:"bip32ExtendedPublicKey"`
+	RootKey      string               `json:"bip32RootKey"`
+	Wif          string               `json:"WIF"`
+	AddressType  helpers.AddressType  `json:"addressType,omitempty"`
+	Address      string               `json:"address,omitempty"`
+	P2pkhAddress string               `json:"p2pkhAddress,omitempty"`
+	SegwitBech32 string               `json:"segwitBech32,omitempty"`
+	SegwitNested string               `json:"segwitNested,omitempty"`
+	P2trAddress  string               `json:"p2trAddress,omitempty"`
+	InternalKey  string               `json:"taprootInternalKey,omitempty"`
+	OutputKey    string               `json:"taprootOutputKey,omitempty"`
+	Descriptors  *HdWalletDescriptors `json:"descriptors,omitempty"`
+}
+
+// HdWalletDescriptors are the watch-only and the spending output descriptors
+// of an account.
+type HdWalletDescriptors struct {
+	Public  *helpers.AccountDescriptors `json:"public"`
+	Private *helpers.AccountDescriptors `json:"private"`
+}
+
+// AddressEntry is a single child derived by GenerateAddressRange.
+type AddressEntry struct {
+	Path       string `json:"path"`
+	PubKey     string `json:"pubkey"`
+	Wif        string `json:"wif,omitempty"`
+	P2pkh      string `json:"p2pkh"`
+	P2shP2wpkh string `json:"p2sh-p2wpkh"`
+	P2wpkh     string `json:"p2wpkh"`
+	P2tr       string `json:"p2tr"`
+}
+
+// MultisigEntry is a multisig address with its output type and the scripts
+// needed to spend it. Path is relative to the extended public keys of
+// GenerateMultisignatureFromExtPubKeys. The redeem script is set for P2SH and
+// P2SH-P2WSH outputs, the witness script for P2WSH and P2SH-P2WSH outputs.
+// ScriptHash is the hash of the multisig script the output commits to and
+// PublicKeys are the keys in script order. P2TR outputs have no script hash,
+// they commit to the MuSig2 internal key and the merkle root of their leaves.
+type MultisigEntry struct {
+	Path          string               `json:"path,omitempty"`
+	MultisigType  helpers.MultisigType `json:"multisigType"`
+	Address       string               `json:"address"`
+	Descriptor    string               `json:"descriptor,omitempty"`
+	RedeemScript  string               `json:"redeemScript,omitempty"`
+	WitnessScript string               `json:"witnessScript,omitempty"`
+	ScriptAsm     string               `json:"scriptAsm,omitempty"`
+	ScriptHash    string               `json:"scriptHash,omitempty"`
+	PublicKeys    []string             `json:"publicKeys,omitempty"`
+	InternalKey   string               `json:"internalKey,omitempty"`
+	OutputKey     string               `json:"outputKey,omitempty"`
+	MerkleRoot    string               `json:"merkleRoot,omitempty"`
+	Leaves        []TaprootLeaf        `json:"leaves,omitempty"`
+}
+
+// TaprootLeaf is a tapscript leaf of a P2TR multisig with the control block
+// that spends it. Miniscript is the leaf as it is written in the script tree
+// of the tr() descriptor of the output.
+type TaprootLeaf struct {
+	Miniscript   string `json:"miniscript"`
+	Script       string `json:"script"`
+	ScriptAsm    string `json:"scriptAsm"`
+	LeafHash     string `json:"leafHash"`
+	ControlBlock string `json:"controlBlock"`
+}
+
+// DecodedMultisig is a multisig script decoded by DecodeMultisignatureScript
+// with the outputs it can be paid to.
+type DecodedMultisig struct {
+	M          int             `json:"m"`
+	N          int             `json:"n"`
+	PublicKeys []string        `json:"publicKeys"`
+	ScriptAsm  string          `json:"scriptAsm"`
+	Outputs    []MultisigEntry `json:"outputs"`
+}
+
+// MiniscriptOutput is a miniscript expression compiled by CompileMiniscript
+// with its script, output descriptor and address. Script is the witness
+// script of wsh() or the single leaf script of tr(), InternalKey, OutputKey
+// and ControlBlock are only set for tr(). The sizes are in bytes, the max
+// witness size counts the satisfaction, the script and the control block but
+// not the stack item count.
+type MiniscriptOutput struct {
+	Miniscript          string             `json:"miniscript"`
+	Type                string             `json:"type"`
+	Context             miniscript.Context `json:"context"`
+	Descriptor          string             `json:"descriptor"`
+	Address             string             `json:"address"`
+	Script              string             `json:"script"`
+	ScriptAsm           string             `json:"scriptAsm"`
+	InternalKey         string             `json:"internalKey,omitempty"`
+	OutputKey           string             `json:"outputKey,omitempty"`
+	ControlBlock        string             `json:"controlBlock,omitempty"`
+	ScriptSize          int                `json:"scriptSize"`
+	MaxSatisfactionSize int                `json:"maxSatisfactionSize"`
+	MaxWitnessSize      int                `json:"maxWitnessSize"`
+}
 
-func (wm *walletManager) GenerateMultisignature(n int8, m int8, wif []string) (address string, err error) {
+// MaxStandardWitnessScriptSize is the largest P2WSH witness script relayed by
+// default.
+const MaxStandardWitnessScriptSize = 3600
 
-	publicKeys, err := wm.walletHelper.DerivePubKeyFromWif(wif)
+// DescriptorAddresses are the addresses derived by DeriveDescriptorAddresses,
+// with the descriptor and its checksum.
+type DescriptorAddresses struct {
+	Descriptor string              `json:"descriptor"`
+	Checksum   string              `json:"checksum"`
+	IsRange    bool                `json:"isRange"`
+	Multipath  int                 `json:"multipath"`
+	Addresses  []DescriptorAddress `json:"addresses"`
+}
+
+// DescriptorAddress is the address of a descriptor at an index.
+type DescriptorAddress struct {
+	Index   uint32 `json:"index"`
+	Address string `json:"address"`
+}
+
+// DefaultWordCount is the mnemonic length used when no word count is requested.
+const DefaultWordCount = 24
+
+// DefaultSlip39Strength is the size in bits of a random SLIP-39 master secret
+// when no strength is requested.
+const DefaultSlip39Strength = 128
+
+// ErrInvalidWordCount is returned for a mnemonic length other than 12, 15, 18, 21 or 24 words.
+var ErrInvalidWordCount = errors.New("word count must be one of 12, 15, 18, 21 or 24")
+
+// ErrHardenedFromPublicKey is returned when a watch-only path contains a hardened step.
+var ErrHardenedFromPublicKey = errors.New("hardened derivation is not possible from an extended public key")
+
+// MaxAddressRangeCount caps the number of addresses derived by a single GenerateAddressRange call.
+const MaxAddressRangeCount = 1000
+
+// GenerateMultisignature builds the multisig address of the keys, P2SH unless
+// another multisig type is selected. Keys can be hex public keys, WIFs or
+// extended keys with a path, in any mix. sorted orders the keys as BIP67
+// requires, otherwise they are used in the given order.
+func (wm *walletManager) GenerateMultisignature(n int, m int, keys []string, sorted bool, subsetLeaves bool, multisigType helpers.MultisigType, network string) (*MultisigEntry, error) {
+	net, err := wm.walletHelper.DeriveNetworkParams(network)
 	if err != nil {
-		return "", nil
+		return nil, err
 	}
-	minSignature, err := wm.walletHelper.DeriveOpcodes(m)
+	publicKeys, err := wm.walletHelper.ResolvePublicKeys(keys, net)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+	return wm.multisignatureAddress(n, m, publicKeys, sorted, subsetLeaves, multisigType, net)
+}
+
+// GenerateMultisignatureFromExtPubKeys derives count multisig addresses from
+// start on the change or receive chain of every extended public key, the keys
+// of an address are the children of the same index.
+func (wm *walletManager) GenerateMultisignatureFromExtPubKeys(ctx context.Context, n int, m int, extPubKeys []string, change uint32, start uint32, count uint32, sorted bool, subsetLeaves bool, multisigType helpers.MultisigType, network string) ([]MultisigEntry, error) {
+	if count == 0 || count > MaxAddressRangeCount {
+		return nil, fmt.Errorf("count must be between 1 and %d. got %d", MaxAddressRangeCount, count)
+	}
+	if change >= bip32.FirstHardenedChild || uint64(start)+uint64(count) > uint64(bip32.FirstHardenedChild) {
+		return nil, ErrHardenedFromPublicKey
 	}
-	numOfPubKeys, err := wm.walletHelper.DeriveOpcodes(n)
+	net, err := wm.walletHelper.DeriveNetworkParams(network)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+
+	// derive the chain of every cosigner once, the addresses are its children
+	chains := make([]*bip32.Key, len(extPubKeys))
+	for i, extPubKey := range extPubKeys {
+		xPubKey, err := wm.walletHelper.DeriveExtendedPublicKey(extPubKey, net)
+		if errors.Is(err, helpers.ErrKeyNetworkMismatch) {
+			return nil, fmt.Errorf("key %d: %w", i, err)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("key %d: %w: %v", i, helpers.ErrInvalidKeyInput, err)
+		}
+		if chains[i], err = xPubKey.NewChildKey(change); err != nil {
+			return nil, err
+		}
+	}
+
+	entries := make([]MultisigEntry, 0, count)
+	for index := start; index < start+count; index++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		publicKeys := make([][]byte, len(chains))
+		for i, chain := range chains {
+			child, err := chain.NewChildKey(index)
+			if err != nil {
+				return nil, err
+			}
+			publicKeys[i] = child.Key
+		}
+		entry, err := wm.multisignatureAddress(n, m, publicKeys, sorted, subsetLeaves, multisigType, net)
+		if err != nil {
+			return nil, err
+		}
+		entry.Path = helpers.DerivationPath{change, index}.RelativeString()
+		entries = append(entries, *entry)
+	}
+	return entries, nil
+}
+
+func (wm *walletManager) multisignatureAddress(n int, m int, publicKeys [][]byte, sorted bool, subsetLeaves bool, multisigType helpers.MultisigType, net *chaincfg.Params) (*MultisigEntry, error) {
+	if err := helpers.ValidateMultisigPolicy(n, m, publicKeys, multisigType); err != nil {
+		return nil, err
+	}
+	if sorted {
+		publicKeys = helpers.SortPublicKeys(publicKeys)
+	}
+	if multisigType == helpers.MultisigTypeP2TR {
+		return taprootMultisignatureAddress(m, publicKeys, sorted, subsetLeaves, net)
+	}
+	if multisigType == "" {
+		multisigType = helpers.MultisigTypeP2SH
+	}
+	address, script, err := wm.walletHelper.GenerateMultisignatureAddress(n, m, publicKeys, net, multisigType)
+	if err != nil {
+		return nil, err
+	}
+	entry, err := multisigScripts(script, multisigType)
+	if err != nil {
+		return nil, err
+	}
+	entry.Address = address
+	if entry.ScriptAsm, err = txscript.DisasmString(script); err != nil {
+		return nil, err
+	}
+	entry.PublicKeys = make([]string, len(publicKeys))
+	for i, publicKey := range publicKeys {
+		entry.PublicKeys[i] = hex.EncodeToString(publicKey)
+	}
+	return entry, nil
+}
+
+// taprootMultisignatureAddress builds a P2TR multisig: the internal key
+// aggregates all the keys with MuSig2 for a key path spend by every cosigner
+// and a multi_a leaf lets any m of them spend through the script path.
+// sorted aggregates the keys in BIP327 KeySort order, which the caller has
+// sorted them in, and makes the leaf a sortedmulti_a of the x-only keys.
+// subsetLeaves replaces the multi_a leaf with an m of m leaf for every subset
+// of m keys, each cheaper to spend and revealing only the keys that sign.
+func taprootMultisignatureAddress(m int, publicKeys [][]byte, sorted bool, subsetLeaves bool, net *chaincfg.Params) (*MultisigEntry, error) {
+	internalKey, err := helpers.MuSig2AggregateKey(publicKeys)
+	if err != nil {
+		return nil, err
+	}
+	xOnlyKeys := make([]string, len(publicKeys))
+	for i, publicKey := range publicKeys {
+		xOnlyKeys[i] = hex.EncodeToString(publicKey[1:])
+	}
+	if sorted {
+		sort.Strings(xOnlyKeys)
 	}
-	address, err = wm.walletHelper.GenerateMultisignatureRedeemHash(numOfPubKeys, minSignature, publicKeys)
+
+	expressions := []string{fmt.Sprintf("multi_a(%d,%s)", m, strings.Join(xOnlyKeys, ","))}
+	if subsetLeaves {
+		if subsets := binomial(len(xOnlyKeys), m, helpers.MaxTaprootSubsetLeaves); subsets > helpers.MaxTaprootSubsetLeaves {
+			return nil, fmt.Errorf("%w, %d of %d keys have over %d subsets", helpers.ErrMultisigTooManyLeaves, m, len(xOnlyKeys), helpers.MaxTaprootSubsetLeaves)
+		}
+		expressions = nil
+		for _, subset := range keySubsets(xOnlyKeys, m) {
+			// and_v(v:pk(A),and_v(v:pk(B),pk(C))) checks every signature
+			expression := "pk(" + subset[len(subset)-1] + ")"
+			for i := len(subset) - 2; i >= 0; i-- {
+				expression = "and_v(v:pk(" + subset[i] + ")," + expression + ")"
+			}
+			expressions = append(expressions, expression)
+		}
+	}
+
+	leaves := make([]TaprootLeaf, len(expressions))
+	leafHashes := make([][]byte, len(expressions))
+	miniscripts := make([]string, len(expressions))
+	for i, expression := range expressions {
+		leaf, err := miniscript.Parse(expression, miniscript.Tapscript, nil)
+		if err != nil {
+			return nil, err
+		}
+		script, err := leaf.Script()
+		if err != nil {
+			return nil, err
+		}
+		leafHashes[i] = helpers.TapLeafHash(script)
+		miniscripts[i] = leaf.String()
+		if sorted && !subsetLeaves {
+			miniscripts[i] = "sorted" + miniscripts[i]
+		}
+		leaves[i] = TaprootLeaf{
+			Miniscript: miniscripts[i],
+			Script:     hex.EncodeToString(script),
+			LeafHash:   hex.EncodeToString(leafHashes[i]),
+		}
+		if leaves[i].ScriptAsm, err = miniscript.DisasmString(script); err != nil {
+			return nil, err
+		}
+	}
+
+	// a single leaf is the merkle root itself
+	merkleRoot, paths := helpers.TaprootMerkleTree(leafHashes)
+	outputKey, err := helpers.TaprootOutputKey(internalKey, merkleRoot)
+	if err != nil {
+		return nil, err
+	}
+	for i := range leaves {
+		leaves[i].ControlBlock = hex.EncodeToString(helpers.TaprootControlBlock(internalKey, outputKey, paths[i]...))
+	}
+	entry := &MultisigEntry{
+		MultisigType: helpers.MultisigTypeP2TR,
+		PublicKeys:   make([]string, len(publicKeys)),
+		InternalKey:  hex.EncodeToString(helpers.XOnlyPubKey(internalKey)),
+		OutputKey:    hex.EncodeToString(helpers.XOnlyPubKey(outputKey)),
+		MerkleRoot:   hex.EncodeToString(merkleRoot),
+		Leaves:       leaves,
+	}
+	entry.Descriptor, err = helpers.AddDescriptorChecksum("tr(" + entry.InternalKey + "," + helpers.TaprootTreeDescriptor(miniscripts) + ")")
+	if err != nil {
+		return nil, err
+	}
+	if entry.Address, err = helpers.TaprootAddress(outputKey, net); err != nil {
+		return nil, err
+	}
+	for i, publicKey := range publicKeys {
+		entry.PublicKeys[i] = hex.EncodeToString(publicKey)
+	}
+	return entry, nil
+}
+
+// binomial counts the subsets of k of n elements, stopping once the count
+// is over limit.
+func binomial(n int, k int, limit int) int {
+	count := 1
+	for i := 1; i <= k && count <= limit; i++ {
+		count = count * (n - k + i) / i
+	}
+	return count
+}
+
+// keySubsets lists every subset of k of the keys, each in the order of the
+// keys.
+func keySubsets(keys []string, k int) [][]string {
+	if k == 0 {
+		return [][]string{{}}
+	}
+	var subsets [][]string
+	for i := 0; i+k <= len(keys); i++ {
+		for _, rest := range keySubsets(keys[i+1:], k-1) {
+			subsets = append(subsets, append([]string{keys[i]}, rest...))
+		}
+	}
+	return subsets
+}
+
+// multisigScripts fills the redeem script, witness script and script hash of
+// a multisig script paid to as the multisig type.
+func multisigScripts(script []byte, multisigType helpers.MultisigType) (*MultisigEntry, error) {
+	entry := &MultisigEntry{MultisigType: multisigType}
+	redeemScript, err := helpers.MultisignatureRedeemScript(script, multisigType)
+	if err != nil {
+		return nil, err
+	}
+	entry.RedeemScript = hex.EncodeToString(redeemScript)
+	if multisigType != helpers.MultisigTypeP2SH {
+		entry.WitnessScript = hex.EncodeToString(script)
+	}
+	entry.ScriptHash = hex.EncodeToString(helpers.MultisignatureScriptHash(script, multisigType))
+	return entry, nil
+}
+
+// DecodeMultisignatureScript decodes a multisig redeem or witness script and
+// lists every output type it can be paid to. P2SH is left out for scripts
+// over the 520 byte push limit and the P2WSH types for uncompressed keys.
+func (wm *walletManager) DecodeMultisignatureScript(script string, network string) (*DecodedMultisig, error) {
+	net, err := wm.walletHelper.DeriveNetworkParams(network)
+	if err != nil {
+		return nil, err
+	}
+	decoded, err := hex.DecodeString(script)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", helpers.ErrInvalidMultisigScript, err)
+	}
+	required, publicKeys, err := helpers.DecodeMultisignatureScript(decoded)
+	if err != nil {
+		return nil, err
+	}
+	multisig := &DecodedMultisig{M: required, N: len(publicKeys), PublicKeys: make([]string, len(publicKeys)), Outputs: []MultisigEntry{}}
+	if multisig.ScriptAsm, err = txscript.DisasmString(decoded); err != nil {
+		return nil, err
+	}
+	compressed := true
+	for i, publicKey := range publicKeys {
+		multisig.PublicKeys[i] = hex.EncodeToString(publicKey)
+		compressed = compressed && len(publicKey) == btcec.PubKeyBytesLenCompressed
+	}
+
+	multisigTypes := []helpers.MultisigType{}
+	if len(decoded) <= txscript.MaxScriptElementSize {
+		multisigTypes = append(multisigTypes, helpers.MultisigTypeP2SH)
+	}
+	if compressed {
+		multisigTypes = append(multisigTypes, helpers.MultisigTypeP2WSH, helpers.MultisigTypeP2SHP2WSH)
+	}
+	for _, multisigType := range multisigTypes {
+		entry, err := multisigScripts(decoded, multisigType)
+		if err != nil {
+			return nil, err
+		}
+		if entry.Address, err = helpers.MultisignatureScriptAddress(decoded, net, multisigType); err != nil {
+			return nil, err
+		}
+		multisig.Outputs = append(multisig.Outputs, *entry)
+	}
+	return multisig, nil
+}
+
+// CompileMiniscript compiles a spending policy, or parses a miniscript
+// expression when isPolicy is false, for wsh() or tr() and builds its script,
+// descriptor and address. Keys are hex public keys, WIFs, extended keys with
+// a path or names mapped to one of those by keys. A tr() output spends only
+// through its script when no internal key is given, with the BIP341
+// unspendable key as the internal key.
+func (wm *walletManager) CompileMiniscript(expression string, isPolicy bool, ctx miniscript.Context, keys map[string]string, internalKey string, network string) (*MiniscriptOutput, error) {
+	net, err := wm.walletHelper.DeriveNetworkParams(network)
+	if err != nil {
+		return nil, err
+	}
+	resolve := func(key string) ([]byte, error) {
+		if named, ok := keys[key]; ok {
+			key = named
+		}
+		return helpers.ResolvePublicKey(key, net)
+	}
+	compile := miniscript.Parse
+	if isPolicy {
+		compile = miniscript.Compile
+	}
+	node, err := compile(expression, ctx, resolve)
+	if err != nil {
+		return nil, err
+	}
+	script, err := node.Script()
+	if err != nil {
+		return nil, err
+	}
+
+	output := &MiniscriptOutput{
+		Miniscript:          node.String(),
+		Type:                node.Type.String(),
+		Context:             ctx,
+		Script:              hex.EncodeToString(script),
+		ScriptSize:          len(script),
+		MaxSatisfactionSize: node.MaxSatisfactionSize(ctx),
+	}
+	if output.ScriptAsm, err = miniscript.DisasmString(script); err != nil {
+		return nil, err
+	}
+	if output.MaxSatisfactionSize < 0 {
+		return nil, fmt.Errorf("%w: %s can not be satisfied", miniscript.ErrInvalidMiniscript, output.Miniscript)
+	}
+	output.MaxWitnessSize = output.MaxSatisfactionSize + wire.VarIntSerializeSize(uint64(len(script))) + len(script)
+
+	var descriptor string
+	if ctx == miniscript.Segwit {
+		if internalKey != "" {
+			return nil, fmt.Errorf("%w: an internal key is only used by tr()", miniscript.ErrInvalidMiniscript)
+		}
+		if len(script) > MaxStandardWitnessScriptSize {
+			return nil, fmt.Errorf("%w: witness script is %d bytes, over the %d byte limit", miniscript.ErrInvalidMiniscript, len(script), MaxStandardWitnessScriptSize)
+		}
+		scriptHash := helpers.MultisignatureScriptHash(script, helpers.MultisigTypeP2WSH)
+		address, err := btcutil.NewAddressWitnessScriptHash(scriptHash, net)
+		if err != nil {
+			return nil, err
+		}
+		output.Address = address.EncodeAddress()
+		descriptor = "wsh(" + output.Miniscript + ")"
+	} else {
+		internal := helpers.UnspendableInternalKey()
+		if internalKey != "" {
+			if internal, err = miniscriptInternalKey(internalKey, resolve); err != nil {
+				return nil, err
+			}
+		}
+		outputKey, err := helpers.TaprootOutputKey(internal, helpers.TapLeafHash(script))
+		if err != nil {
+			return nil, err
+		}
+		if output.Address, err = helpers.TaprootAddress(outputKey, net); err != nil {
+			return nil, err
+		}
+		controlBlock := helpers.TaprootControlBlock(internal, outputKey)
+		output.InternalKey = hex.EncodeToString(helpers.XOnlyPubKey(internal))
+		output.OutputKey = hex.EncodeToString(helpers.XOnlyPubKey(outputKey))
+		output.ControlBlock = hex.EncodeToString(controlBlock)
+		output.MaxWitnessSize += 1 + len(controlBlock)
+		descriptor = "tr(" + output.InternalKey + "," + output.Miniscript + ")"
+	}
+	if output.Descriptor, err = helpers.AddDescriptorChecksum(descriptor); err != nil {
+		return nil, err
+	}
+	return output, nil
+}
+
+// miniscriptInternalKey resolves the internal key of a tr() output, which can
+// also be a 64 character hex x-only key.
+func miniscriptInternalKey(key string, resolve miniscript.KeyResolver) (*btcec.PublicKey, error) {
+	if xOnly, err := hex.DecodeString(key); err == nil && len(xOnly) == 32 {
+		publicKey, err := helpers.ParseXOnlyPubKey(xOnly)
+		if err != nil {
+			return nil, fmt.Errorf("%w: internal key: %v", miniscript.ErrInvalidMiniscript, err)
+		}
+		return publicKey, nil
+	}
+	serialized, err := resolve(key)
+	if err != nil {
+		return nil, fmt.Errorf("%w: internal key: %v", miniscript.ErrInvalidMiniscript, err)
+	}
+	return btcec.ParsePubKey(serialized, btcec.S256())
+}
+
+func (wm *walletManager) GenerateMnemonic(passPhrase string, wordCount int, language string) (mnemonic string, seed string, entropy string, err error) {
+	if wordCount == 0 {
+		wordCount = DefaultWordCount
+	}
+	bitSize, err := entropyBitSize(wordCount)
+	if err != nil {
+		return "", "", "", err
+	}
+	rawEntropy, err := bip39.NewEntropy(bitSize)
+	if err != nil {
+		return "", "", "", err
+	}
+	mnemonic, err = wm.walletHelper.DeriveMnemonic(rawEntropy, helpers.Language(language))
+	if err != nil {
+		return "", "", "", err
+	}
+	decodeSeed, _, err := wm.walletHelper.DeriveSeedFromMnemonic(mnemonic, passPhrase, helpers.Language(language))
+	if err != nil {
+		return "", "", "", err
+	}
+	return mnemonic, hex.EncodeToString(decodeSeed), hex.EncodeToString(rawEntropy), nil
+}
+
+// MnemonicToSeed converts a mnemonic of the given language, or of the detected
+// language when empty, and returns the language it was read in.
+func (wm *walletManager) MnemonicToSeed(mnemonic string, passPhrase string, language string) (normalized string, seed string, detected string, err error) {
+	decodeSeed, resolved, err := wm.walletHelper.DeriveSeedFromMnemonic(mnemonic, passPhrase, helpers.Language(language))
+	if err != nil {
+		return "", "", "", err
+	}
+	return helpers.NormalizeMnemonic(mnemonic), hex.EncodeToString(decodeSeed), string(resolved), nil
+}
+
+// GenerateSlip39Shares splits a master secret, or a random one of strength
+// bits when empty, into SLIP-39 shares. The master secret is returned as the
+// seed the shares recover.
+func (wm *walletManager) GenerateSlip39Shares(masterSecret string, strength int, passPhrase string, groupThreshold int, groups []helpers.Slip39Group, iterationExponent int, extendable bool) (shares [][]string, seed string, err error) {
+	var secret []byte
+	if masterSecret != "" {
+		secret, err = hex.DecodeString(masterSecret)
+		if err != nil {
+			return nil, "", fmt.Errorf("%w: master secret must be hex encoded", helpers.ErrInvalidSharing)
+		}
+	} else {
+		if strength == 0 {
+			strength = DefaultSlip39Strength
+		}
+		if strength < 128 || strength > 256 || strength%16 != 0 {
+			return nil, "", fmt.Errorf("%w: strength must be a multiple of 16 between 128 and 256. got %d", helpers.ErrInvalidSharing, strength)
+		}
+		secret = make([]byte, strength/8)
+		if _, err := rand.Read(secret); err != nil {
+			return nil, "", err
+		}
+	}
+	shares, err = wm.walletHelper.GenerateSlip39Shares(secret, passPhrase, groupThreshold, groups, iterationExponent, extendable)
+	if err != nil {
+		return nil, "", err
+	}
+	return shares, hex.EncodeToString(secret), nil
+}
+
+// CombineSlip39Shares recovers the master secret of SLIP-39 shares, to be used
+// as the seed of GenerateHdWallet.
+func (wm *walletManager) CombineSlip39Shares(mnemonics []string, passPhrase string) (seed string, err error) {
+	secret, err := wm.walletHelper.DeriveSeedFromSlip39Shares(mnemonics, passPhrase)
 	if err != nil {
 		return "", err
 	}
-	return address, nil
+	return hex.EncodeToString(secret), nil
+}
+
+// entropyBitSize maps a mnemonic word count to its entropy size, every 3 words
+// carry 32 bits of entropy and 1 bit of checksum.
+func entropyBitSize(wordCount int) (int, error) {
+	switch wordCount {
+	case 12, 15, 18, 21, 24:
+		return wordCount / 3 * 32, nil
+	default:
+		return 0, fmt.Errorf("%w. got %d", ErrInvalidWordCount, wordCount)
+	}
 }
 
-func (wm *walletManager) GenerateMnemonic(passPhrase string) (mnemonic string, seed string, err error) {
-	entropy, err := bip39.NewEntropy(bitSize)
+func (wm *walletManager) GenerateHdWallet(seed string, path string, network string, mixed bool) (*HdWallet, error) {
+	net, err := wm.walletHelper.DeriveNetworkParams(network)
 	if err != nil {
-		return "", "", err
+		return nil, err
 	}
-	mnemonic, err = bip39.NewMnemonic(entropy)
+	decodeSeed, err := hex.DecodeString(seed)
 	if err != nil {
-		return "", "", err
+		return nil, err
+	}
+	master, err := bip32.NewMasterKey(decodeSeed)
+	if err != nil {
+		return nil, err
+	}
+	wallet := &HdWallet{
+		RootKey: wm.walletHelper.EncodeExtendedKey(master, net),
+	}
+
+	derivationPath, err := wm.walletHelper.DerivePath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	xPrvKey, xPubKey, err := wm.walletHelper.DeriveExtendedKeysFromPath(master, derivationPath)
+	if err != nil {
+		return nil, err
+	}
+	prvKey := wm.walletHelper.DerivePrivateKeyFromBytes(xPrvKey.Key)
+
+	// the purpose of the path selects the address type, paths without a known
+	// purpose fall back to the mixed output
+	var addressType helpers.AddressType
+	if len(derivationPath) > 0 {
+		addressType, err = helpers.AddressTypeForPurpose(derivationPath[0])
+		if err != nil {
+			mixed = true
+		}
+	} else {
+		mixed = true
 	}
-	seed = hex.EncodeToString(bip39.NewSeed(mnemonic, passPhrase))
-	return mnemonic, seed, nil
+
+	// purpose, coin type and account make an account path
+	if addressType != "" && len(derivationPath) == 3 {
+		origin := helpers.KeyOrigin{Fingerprint: helpers.KeyFingerprint(master), Path: derivationPath}
+		wallet.Descriptors = &HdWalletDescriptors{}
+		if wallet.Descriptors.Public, err = wm.walletHelper.DeriveAccountDescriptors(xPubKey, origin, net, addressType); err != nil {
+			return nil, err
+		}
+		if wallet.Descriptors.Private, err = wm.walletHelper.DeriveAccountDescriptors(xPrvKey, origin, net, addressType); err != nil {
+			return nil, err
+		}
+	}
+
+	if mixed {
+		wallet.ExtPrvKey, wallet.ExtPubKey = wm.walletHelper.EncodeExtendedKey(xPrvKey, net), wm.walletHelper.EncodeExtendedKey(xPubKey, net)
+		wallet.Wif, wallet.P2pkhAddress, wallet.SegwitBech32, wallet.SegwitNested, err = wm.walletHelper.DeriveAddress(prvKey, net)
+		if err != nil {
+			return nil, err
+		}
+		if err := wm.deriveTaproot(wallet, prvKey.PubKey(), net); err != nil {
+			return nil, err
+		}
+		return wallet, nil
+	}
+
+	wallet.ExtPrvKey, err = wm.walletHelper.EncodeSlip132ExtendedKey(xPrvKey, net, addressType)
+	if err != nil {
+		return nil, err
+	}
+	wallet.ExtPubKey, err = wm.walletHelper.EncodeSlip132ExtendedKey(xPubKey, net, addressType)
+	if err != nil {
+		return nil, err
+	}
+	wallet.Wif, _, _, _, err = wm.walletHelper.DeriveAddress(prvKey, net)
+	if err != nil {
+		return nil, err
+	}
+	wallet.Address, err = wm.walletHelper.DeriveAddressForType(prvKey.PubKey(), net, addressType)
+	if err != nil {
+		return nil, err
+	}
+	wallet.AddressType = addressType
+	if addressType == helpers.AddressTypeP2TR {
+		if err := wm.deriveTaproot(wallet, prvKey.PubKey(), net); err != nil {
+			return nil, err
+		}
+	}
+
+	return wallet, nil
+}
+
+// deriveTaproot fills the BIP86 key path only P2TR address and its x-only keys.
+func (wm *walletManager) deriveTaproot(wallet *HdWallet, pubKey *btcec.PublicKey, net *chaincfg.Params) error {
+	p2trAddress, internalKey, outputKey, err := wm.walletHelper.DeriveTaprootAddress(pubKey, net)
+	if err != nil {
+		return err
+	}
+	wallet.P2trAddress = p2trAddress
+	wallet.InternalKey = hex.EncodeToString(internalKey)
+	wallet.OutputKey = hex.EncodeToString(outputKey)
+	return nil
 }
 
-func (wm *walletManager) GenerateHdWallet(seed string, path string) (extPrvKey string, extPubKey string, rootKey string, wif string, p2pkhAddress string, segwitBech32 string, segwitNested string, err error) {
+func (wm *walletManager) GenerateAddressRange(ctx context.Context, seed string, accountPath string, change uint32, start uint32, count uint32, includeWif bool, network string) ([]AddressEntry, error) {
+	if count == 0 || count > MaxAddressRangeCount {
+		return nil, fmt.Errorf("count must be between 1 and %d. got %d", MaxAddressRangeCount, count)
+	}
+	if change >= bip32.FirstHardenedChild || uint64(start)+uint64(count) > uint64(bip32.FirstHardenedChild) {
+		return nil, fmt.Errorf("change and address indexes must not be hardened")
+	}
+	net, err := wm.walletHelper.DeriveNetworkParams(network)
+	if err != nil {
+		return nil, err
+	}
 	decodeSeed, err := hex.DecodeString(seed)
 	if err != nil {
-		return "", "", "", "", "", "", "", err
+		return nil, err
 	}
 	master, err := bip32.NewMasterKey(decodeSeed)
 	if err != nil {
-		return "", "", "", "", "", "", "", err
+		return nil, err
+	}
+	derivationPath, err := wm.walletHelper.DerivePath(accountPath)
+	if err != nil {
+		return nil, err
 	}
-	rootKey = master.String()
 
-	params, err := wm.walletHelper.DeriveParamsFromPath(path)
+	// derive the change level parent once, every address is a direct child of it
+	parentPath := derivationPath.Append(change)
+	parent, _, err := wm.walletHelper.DeriveExtendedKeysFromPath(master, parentPath)
 	if err != nil {
-		return "", "", "", "", "", "", "", err
+		return nil, err
 	}
 
-	xPrvKey, xPubKey, err := wm.walletHelper.DeriveExtendedKeys(master, params)
+	entries := make([]AddressEntry, 0, count)
+	for index := start; index < start+count; index++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		child, err := parent.NewChildKey(index)
+		if err != nil {
+			return nil, err
+		}
+		prvKey := wm.walletHelper.DerivePrivateKeyFromBytes(child.Key)
+		wif, p2pkhAddress, segwitBech32, segwitNested, err := wm.walletHelper.DeriveAddress(prvKey, net)
+		if err != nil {
+			return nil, err
+		}
+		p2trAddress, _, _, err := wm.walletHelper.DeriveTaprootAddress(prvKey.PubKey(), net)
+		if err != nil {
+			return nil, err
+		}
+		entry := AddressEntry{
+			Path:       parentPath.Append(index).String(),
+			PubKey:     hex.EncodeToString(prvKey.PubKey().SerializeCompressed()),
+			P2pkh:      p2pkhAddress,
+			P2shP2wpkh: segwitNested,
+			P2wpkh:     segwitBech32,
+			P2tr:       p2trAddress,
+		}
+		if includeWif {
+			entry.Wif = wif
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+func (wm *walletManager) GenerateWatchOnly(ctx context.Context, extPubKey string, path string, count uint32, network string) ([]AddressEntry, error) {
+	if count == 0 {
+		count = 1
+	}
+	if count > MaxAddressRangeCount {
+		return nil, fmt.Errorf("count must be between 1 and %d. got %d", MaxAddressRangeCount, count)
+	}
+	net, err := wm.walletHelper.DeriveNetworkParams(network)
+	if err != nil {
+		return nil, err
+	}
+	xPubKey, err := wm.walletHelper.DeriveExtendedPublicKey(extPubKey, net)
+	if err != nil {
+		return nil, err
+	}
+	relativePath, err := wm.walletHelper.DeriveRelativePath(path)
 	if err != nil {
-		return "", "", "", "", "", "", "", err
+		return nil, err
+	}
+	if relativePath.IsHardened() {
+		return nil, fmt.Errorf("%w, got %s", ErrHardenedFromPublicKey, path)
+	}
+	if len(relativePath) == 0 {
+		return nil, fmt.Errorf("path must contain at least one index")
+	}
+	start := relativePath[len(relativePath)-1]
+	if uint64(start)+uint64(count) > uint64(bip32.FirstHardenedChild) {
+		return nil, fmt.Errorf("address indexes must not be hardened")
 	}
-	extPrvKey, extPubKey = xPrvKey.String(), xPubKey.PublicKey().String()
 
-	prvKey := wm.walletHelper.DerivePrivateKeyFromBytes(xPrvKey.Key)
+	// derive the parent once, the range iterates over the last index of the path
+	parentPath := relativePath[:len(relativePath)-1]
+	parent := xPubKey
+	for _, index := range parentPath {
+		parent, err = parent.NewChildKey(index)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	entries := make([]AddressEntry, 0, count)
+	for index := start; index < start+count; index++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		child, err := parent.NewChildKey(index)
+		if err != nil {
+			return nil, err
+		}
+		pubKey, err := btcec.ParsePubKey(child.Key, btcec.S256())
+		if err != nil {
+			return nil, err
+		}
+		p2pkhAddress, segwitBech32, segwitNested, err := wm.walletHelper.DeriveAddressFromPubKey(pubKey, net)
+		if err != nil {
+			return nil, err
+		}
+		// BIP86 tweaks the x-only child key, which public derivation gives
+		p2trAddress, _, _, err := wm.walletHelper.DeriveTaprootAddress(pubKey, net)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, AddressEntry{
+			Path:       parentPath.Append(index).RelativeString(),
+			PubKey:     hex.EncodeToString(child.Key),
+			P2pkh:      p2pkhAddress,
+			P2shP2wpkh: segwitNested,
+			P2wpkh:     segwitBech32,
+			P2tr:       p2trAddress,
+		})
+	}
+	return entries, nil
+}
 
-	wif, p2pkhAddress, segwitBech32, segwitNested, err = wm.walletHelper.DeriveAddress(prvKey)
+// DeriveDescriptorAddresses derives count addresses from start of a ranged
+// descriptor, a descriptor without wildcard has the single address of index 0.
+// multipathIndex selects a path of a multipath descriptor, 0 is the receive
+// path of <0;1>.
+func (wm *walletManager) DeriveDescriptorAddresses(ctx context.Context, descriptor string, start uint32, count uint32, multipathIndex int, network string) (*DescriptorAddresses, error) {
+	if count == 0 || count > MaxAddressRangeCount {
+		return nil, fmt.Errorf("count must be between 1 and %d. got %d", MaxAddressRangeCount, count)
+	}
+	if uint64(start)+uint64(count) > uint64(bip32.FirstHardenedChild) {
+		return nil, fmt.Errorf("address indexes must be less than %d", bip32.FirstHardenedChild)
+	}
+	net, err := wm.walletHelper.DeriveNetworkParams(network)
+	if err != nil {
+		return nil, err
+	}
+	parsed, err := descriptors.Parse(wm.walletHelper, descriptor, net)
 	if err != nil {
-		return "", "", "", "", "", "", "", err
+		return nil, err
+	}
+	if !parsed.IsRange() {
+		start, count = 0, 1
 	}
 
-	return extPrvKey, extPubKey, rootKey, wif, p2pkhAddress, segwitBech32, segwitNested, nil
+	result := &DescriptorAddresses{
+		Descriptor: parsed.String(),
+		Checksum:   parsed.Checksum,
+		IsRange:    parsed.IsRange(),
+		Multipath:  parsed.Multipath(),
+		Addresses:  make([]DescriptorAddress, 0, count),
+	}
+	for index := start; index < start+count; index++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		address, err := parsed.Address(index, multipathIndex)
+		if err != nil {
+			return nil, err
+		}
+		result.Addresses = append(result.Addresses, DescriptorAddress{Index: index, Address: address})
+	}
+	return result, nil
 }
 
 func NewWalletManager(walletHelper helpers.WalletHelper) WalletManager {
@@ -1,10 +1,19 @@
 package managers
 
 import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
 	"strings"
 	"testing"
 
 	"btcwallet.com/src/pkg/helpers"
+	"btcwallet.com/src/pkg/miniscript"
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/tyler-smith/go-bip32"
+	"github.com/tyler-smith/go-bip39"
 )
 
 func TestGenerateMnemonic(t *testing.T) {
@@ -12,7 +21,7 @@ func TestGenerateMnemonic(t *testing.T) {
 	var walletManager WalletManager = NewWalletManager(walletHelper)
 	var passPhrase string = ""
 
-	menmonic, _, _ := walletManager.GenerateMnemonic(passPhrase)
+	menmonic, _, _, _ := walletManager.GenerateMnemonic(passPhrase, 0, "")
 	menmonicArr := strings.Split(menmonic, " ")
 
 	if len(menmonicArr) != 24 {
@@ -20,6 +29,65 @@ func TestGenerateMnemonic(t *testing.T) {
 	}
 }
 
+func TestGenerateMnemonicWordCount(t *testing.T) {
+	var walletHelper helpers.WalletHelper = helpers.NewWalletHelper()
+	var walletManager WalletManager = NewWalletManager(walletHelper)
+	var passPhrase string = "TREZOR"
+	tests := map[int]int{12: 16, 15: 20, 18: 24, 21: 28, 24: 32}
+
+	for wordCount, entropyBytes := range tests {
+		menmonic, seed, entropy, err := walletManager.GenerateMnemonic(passPhrase, wordCount, "")
+		if err != nil {
+			t.Errorf("Test failed: input: %d unexpected error: %v", wordCount, err)
+			continue
+		}
+		if len(strings.Split(menmonic, " ")) != wordCount {
+			t.Errorf("Test failed:  expected: %d received: %d ", wordCount, len(strings.Split(menmonic, " ")))
+		}
+		if len(entropy) != entropyBytes*2 {
+			t.Errorf("Test failed:  expected: %d received: %d ", entropyBytes*2, len(entropy))
+		}
+		if expectedSeed := hex.EncodeToString(bip39.NewSeed(menmonic, passPhrase)); seed != expectedSeed {
+			t.Errorf("Test failed:  expected: %s received: %s ", expectedSeed, seed)
+		}
+	}
+
+	if _, _, _, err := walletManager.GenerateMnemonic(passPhrase, 13, ""); !errors.Is(err, ErrInvalidWordCount) {
+		t.Errorf("Test failed:  expected: %v received: %v ", ErrInvalidWordCount, err)
+	}
+}
+
+func TestGenerateMnemonicLanguage(t *testing.T) {
+	var walletHelper helpers.WalletHelper = helpers.NewWalletHelper()
+	var walletManager WalletManager = NewWalletManager(walletHelper)
+	var passPhrase string = "TREZOR"
+
+	for _, language := range helpers.Languages {
+		menmonic, seed, _, err := walletManager.GenerateMnemonic(passPhrase, 12, string(language))
+		if err != nil {
+			t.Errorf("Test failed: input: %s unexpected error: %v", language, err)
+			continue
+		}
+		_, expectedSeed, detected, err := walletManager.MnemonicToSeed(menmonic, passPhrase, "")
+		if err != nil {
+			t.Errorf("Test failed: input: %s unexpected error: %v", language, err)
+			continue
+		}
+		if seed != expectedSeed {
+			t.Errorf("Test failed:  expected: %s received: %s ", expectedSeed, seed)
+		}
+		// the chinese lists share many characters, a traditional mnemonic made of
+		// shared characters only can pass the simplified checksum
+		if detected != string(language) && language != helpers.LanguageChineseTraditional {
+			t.Errorf("Test failed:  expected: %s received: %s ", language, detected)
+		}
+	}
+
+	if _, _, _, err := walletManager.GenerateMnemonic(passPhrase, 12, "klingon"); !errors.Is(err, helpers.ErrUnsupportedLanguage) {
+		t.Errorf("Test failed:  expected: %v received: %v ", helpers.ErrUnsupportedLanguage, err)
+	}
+}
+
 func TestGenerateHdWallet(t *testing.T) {
 	var walletHelper helpers.WalletHelper = helpers.NewWalletHelper()
 	var walletManager WalletManager = NewWalletManager(walletHelper)
@@ -33,7 +101,9 @@ func TestGenerateHdWallet(t *testing.T) {
 	var expectedSegwitBech32 string = "bc1qvcl5rm6vz7eaxed9zrcftax0ywsc29y8zgj876"
 	var expectedSegwitNested string = "3MTm6vsDYfyQCSTeex9ZHWYd9zkUetUn7c"
 
-	extPrvKey, extPubKey, rootKey, wif, p2pkhAddress, segwitBech32, segwitNested, _ := walletManager.GenerateHdWallet(seed, path)
+	wallet, _ := walletManager.GenerateHdWallet(seed, path, "mainnet", true)
+	extPrvKey, extPubKey, rootKey, wif := wallet.ExtPrvKey, wallet.ExtPubKey, wallet.RootKey, wallet.Wif
+	p2pkhAddress, segwitBech32, segwitNested := wallet.P2pkhAddress, wallet.SegwitBech32, wallet.SegwitNested
 
 	if extPrvKey != expectedExtPrvKey {
 		t.Errorf("Test failed:  expected: %s received: %s ", expectedExtPrvKey, extPrvKey)
@@ -64,13 +134,704 @@ func TestGenerateMultisignature(t *testing.T) {
 	var wif []string
 	wif = append(wif, "cQpHXfs91s5eR9PWXui6qo2xjoJb2X3VdUspwKXe4A8Dybvut2rL")
 	wif = append(wif, "cVgxEkRBtnfvd41ssd4PCsiemahAHidFrLWYoDBMNojUeME8dojZ")
-	var n int8 = 1
-	var m int8 = 2
-	var expectedAddress string = "3MqSiHLbK6M8YUL8sXKiULeiRSvckJV74h"
+	var n int = 2
+	var m int = 2
+	var expectedAddress string = "2N6h96DjYRR9YKTZWZePcft4FFfnSQooAr3"
+
+	multisig, _ := walletManager.GenerateMultisignature(n, m, wif, true, false, "", "testnet3")
+
+	if multisig.Address != expectedAddress {
+		t.Errorf("Test failed:  expected: %s received: %s ", expectedAddress, multisig.Address)
+	}
+}
+
+func TestGenerateHdWalletArbitraryPath(t *testing.T) {
+	var walletHelper helpers.WalletHelper = helpers.NewWalletHelper()
+	var walletManager WalletManager = NewWalletManager(walletHelper)
+	var seed string = "000102030405060708090a0b0c0d0e0f"
+	var path string = "m/0H/1/2H/2/1000000000"
+	var expectedExtPrvKey string = "xprvA41z7zogVVwxVSgdKUHDy1SKmdb533PjDz7J6N6mV6uS3ze1ai8FHa8kmHScGpWmj4WggLyQjgPie1rFSruoUihUZREPSL39UNdE3BBDu76"
+	var expectedExtPubKey string = "xpub6H1LXWLaKsWFhvm6RVpEL9P4KfRZSW7abD2ttkWP3SSQvnyA8FSVqNTEcYFgJS2UaFcxupHiYkro49S8yGasTvXEYBVPamhGW6cFJodrTHy"
+
+	wallet, err := walletManager.GenerateHdWallet(seed, path, "mainnet", false)
+
+	if err != nil {
+		t.Fatalf("Test failed: unexpected error: %v", err)
+	}
+	extPrvKey, extPubKey := wallet.ExtPrvKey, wallet.ExtPubKey
+	if extPrvKey != expectedExtPrvKey {
+		t.Errorf("Test failed:  expected: %s received: %s ", expectedExtPrvKey, extPrvKey)
+	}
+	if extPubKey != expectedExtPubKey {
+		t.Errorf("Test failed:  expected: %s received: %s ", expectedExtPubKey, extPubKey)
+	}
+}
+
+func TestGenerateMultisignatureSorted(t *testing.T) {
+	var walletHelper helpers.WalletHelper = helpers.NewWalletHelper()
+	var walletManager WalletManager = NewWalletManager(walletHelper)
+	// the keys of the reversed WIFs are not in BIP67 order
+	wif := []string{"cVgxEkRBtnfvd41ssd4PCsiemahAHidFrLWYoDBMNojUeME8dojZ", "cQpHXfs91s5eR9PWXui6qo2xjoJb2X3VdUspwKXe4A8Dybvut2rL"}
+	var expectedAddress string = "2N6h96DjYRR9YKTZWZePcft4FFfnSQooAr3"
+
+	multisig, err := walletManager.GenerateMultisignature(2, 2, wif, true, false, helpers.MultisigTypeP2SH, "testnet3")
+	if err != nil {
+		t.Fatalf("Test failed: unexpected error: %v", err)
+	}
+	if multisig.Address != expectedAddress {
+		t.Errorf("Test failed:  expected: %s received: %s ", expectedAddress, multisig.Address)
+	}
+	multisig, _ = walletManager.GenerateMultisignature(2, 2, wif, false, false, helpers.MultisigTypeP2SH, "testnet3")
+	if multisig.Address == expectedAddress {
+		t.Errorf("Test failed: preserved key order expected to change the address %s ", multisig.Address)
+	}
+}
+func TestGenerateMultisignatureSegwit(t *testing.T) {
+	var walletHelper helpers.WalletHelper = helpers.NewWalletHelper()
+	var walletManager WalletManager = NewWalletManager(walletHelper)
+	wif := []string{"cQpHXfs91s5eR9PWXui6qo2xjoJb2X3VdUspwKXe4A8Dybvut2rL", "cVgxEkRBtnfvd41ssd4PCsiemahAHidFrLWYoDBMNojUeME8dojZ"}
+	var expectedWitnessScript string = "51210265e6f7fb614a369c9230912a3bb09c33c5c5be2e1bcfc2293ecaed46708e0b5c2103f546edf7b434b50aa0115c1c82a0f9a96505d9eff55d2fe3b848c4b51c06b64352ae"
+	tests := []struct {
+		multisigType helpers.MultisigType
+		descriptor   string
+	}{
+		{helpers.MultisigTypeP2WSH, "wsh(sortedmulti(1,%s,%s))"},
+		{helpers.MultisigTypeP2SHP2WSH, "sh(wsh(sortedmulti(1,%s,%s)))"},
+		{helpers.MultisigTypeP2SH, "sh(sortedmulti(1,%s,%s))"},
+	}
+
+	for _, test := range tests {
+		multisig, err := walletManager.GenerateMultisignature(2, 1, wif, true, false, test.multisigType, "testnet3")
+		if err != nil {
+			t.Fatalf("Test failed: unexpected error: %v", err)
+		}
+		expected, err := walletManager.DeriveDescriptorAddresses(context.Background(), fmt.Sprintf(test.descriptor, wif[0], wif[1]), 0, 1, 0, "testnet3")
+		if err != nil {
+			t.Fatalf("Test failed: unexpected error: %v", err)
+		}
+		if multisig.Address != expected.Addresses[0].Address || multisig.MultisigType != test.multisigType {
+			t.Errorf("Test failed:  expected: %s received: %s ", expected.Addresses[0].Address, multisig.Address)
+		}
+		if test.multisigType != helpers.MultisigTypeP2SH && multisig.WitnessScript != expectedWitnessScript {
+			t.Errorf("Test failed:  expected: %s received: %s ", expectedWitnessScript, multisig.WitnessScript)
+		}
+	}
+}
+func TestGenerateMultisignatureFromPublicKeys(t *testing.T) {
+	var walletHelper helpers.WalletHelper = helpers.NewWalletHelper()
+	var walletManager WalletManager = NewWalletManager(walletHelper)
+	// the public keys of the WIFs of TestGenerateMultisignature
+	pubKeys := []string{"0265e6f7fb614a369c9230912a3bb09c33c5c5be2e1bcfc2293ecaed46708e0b5c", "03f546edf7b434b50aa0115c1c82a0f9a96505d9eff55d2fe3b848c4b51c06b643"}
+	var expectedAddress string = "2N6h96DjYRR9YKTZWZePcft4FFfnSQooAr3"
+
+	multisig, err := walletManager.GenerateMultisignature(2, 2, pubKeys, true, false, helpers.MultisigTypeP2SH, "testnet3")
+	if err != nil {
+		t.Fatalf("Test failed: unexpected error: %v", err)
+	}
+	if multisig.Address != expectedAddress {
+		t.Errorf("Test failed:  expected: %s received: %s ", expectedAddress, multisig.Address)
+	}
+	mixed := []string{pubKeys[0], "cVgxEkRBtnfvd41ssd4PCsiemahAHidFrLWYoDBMNojUeME8dojZ"}
+	if multisig, _ = walletManager.GenerateMultisignature(2, 2, mixed, true, false, helpers.MultisigTypeP2SH, "testnet3"); multisig.Address != expectedAddress {
+		t.Errorf("Test failed:  expected: %s received: %s ", expectedAddress, multisig.Address)
+	}
+
+	// an extended key with a path stands for its child key
+	extPubKey := "xpub6CatWdiZiodmUeTDp8LT5or8nmbKNcuyvz7WyksVFkKB4RHwCD3XyuvPEbvqAQY3rAPshWcMLoP2fMFMKHPJ4ZeZXYVUhLv1VMrjPC7PW6V"
+	multisig, err = walletManager.GenerateMultisignature(2, 2, []string{pubKeys[0], extPubKey + "/0/3"}, true, false, helpers.MultisigTypeP2WSH, "mainnet")
+	if err != nil {
+		t.Fatalf("Test failed: unexpected error: %v", err)
+	}
+	expected, err := walletManager.DeriveDescriptorAddresses(context.Background(), "wsh(sortedmulti(2,"+pubKeys[0]+","+extPubKey+"/0/*))", 3, 1, 0, "mainnet")
+	if err != nil {
+		t.Fatalf("Test failed: unexpected error: %v", err)
+	}
+	if multisig.Address != expected.Addresses[0].Address {
+		t.Errorf("Test failed:  expected: %s received: %s ", expected.Addresses[0].Address, multisig.Address)
+	}
+
+	// segwit scripts only take compressed keys
+	decoded, _ := hex.DecodeString(pubKeys[0])
+	pubKey, _ := btcec.ParsePubKey(decoded, btcec.S256())
+	uncompressed := []string{hex.EncodeToString(pubKey.SerializeUncompressed()), pubKeys[1]}
+	if _, err := walletManager.GenerateMultisignature(2, 1, uncompressed, true, false, helpers.MultisigTypeP2SH, "testnet3"); err != nil {
+		t.Errorf("Test failed: unexpected error: %v", err)
+	}
+	if _, err := walletManager.GenerateMultisignature(2, 1, uncompressed, true, false, helpers.MultisigTypeP2WSH, "testnet3"); err == nil {
+		t.Errorf("Test failed: uncompressed key expected to be rejected for p2wsh")
+	}
+	if _, err := walletManager.GenerateMultisignature(2, 1, []string{pubKeys[0], "02abcd"}, true, false, "", "testnet3"); !errors.Is(err, helpers.ErrInvalidKeyInput) {
+		t.Errorf("Test failed:  expected: %v received: %v ", helpers.ErrInvalidKeyInput, err)
+	}
+}
+
+func TestGenerateTaprootMultisignature(t *testing.T) {
+	var walletHelper helpers.WalletHelper = helpers.NewWalletHelper()
+	var walletManager WalletManager = NewWalletManager(walletHelper)
+	keys := []string{"03f546edf7b434b50aa0115c1c82a0f9a96505d9eff55d2fe3b848c4b51c06b643", "0265e6f7fb614a369c9230912a3bb09c33c5c5be2e1bcfc2293ecaed46708e0b5c"}
 
-	address, _ := walletManager.GenerateMultisignature(n, m, wif)
+	multisig, err := walletManager.GenerateMultisignature(2, 1, keys, true, false, helpers.MultisigTypeP2TR, "testnet3")
+	if err != nil {
+		t.Fatalf("Test failed: unexpected error: %v", err)
+	}
+	// the key path is the MuSig2 aggregate of the sorted keys
+	sorted := make([][]byte, len(keys))
+	for i, key := range []string{keys[1], keys[0]} {
+		sorted[i], _ = hex.DecodeString(key)
+	}
+	internalKey, _ := helpers.MuSig2AggregateKey(sorted)
+	if multisig.InternalKey != hex.EncodeToString(helpers.XOnlyPubKey(internalKey)) {
+		t.Errorf("Test failed:  expected: %x received: %s ", helpers.XOnlyPubKey(internalKey), multisig.InternalKey)
+	}
+	if len(multisig.Leaves) != 1 {
+		t.Fatalf("Test failed:  expected: 1 leaf received: %d ", len(multisig.Leaves))
+	}
+	leaf := multisig.Leaves[0]
+	var expectedAsm string = "65e6f7fb614a369c9230912a3bb09c33c5c5be2e1bcfc2293ecaed46708e0b5c OP_CHECKSIG f546edf7b434b50aa0115c1c82a0f9a96505d9eff55d2fe3b848c4b51c06b643 OP_CHECKSIGADD 1 OP_NUMEQUAL"
+	var expectedLeaf string = "sortedmulti_a(1,65e6f7fb614a369c9230912a3bb09c33c5c5be2e1bcfc2293ecaed46708e0b5c,f546edf7b434b50aa0115c1c82a0f9a96505d9eff55d2fe3b848c4b51c06b643)"
+	if leaf.ScriptAsm != expectedAsm || leaf.Miniscript != expectedLeaf {
+		t.Errorf("Test failed:  expected: %s received: %s ", expectedAsm, leaf.ScriptAsm)
+	}
+	expectedDescriptor, _ := helpers.AddDescriptorChecksum("tr(" + multisig.InternalKey + "," + expectedLeaf + ")")
+	if multisig.Descriptor != expectedDescriptor {
+		t.Errorf("Test failed:  expected: %s received: %s ", expectedDescriptor, multisig.Descriptor)
+	}
+	// a single leaf is the merkle root and the control block has no path
+	script, _ := hex.DecodeString(leaf.Script)
+	outputKey, _ := helpers.TaprootOutputKey(internalKey, helpers.TapLeafHash(script))
+	address, _ := helpers.TaprootAddress(outputKey, &chaincfg.TestNet3Params)
+	if multisig.MerkleRoot != leaf.LeafHash || multisig.Address != address {
+		t.Errorf("Test failed:  expected: %s received: %s ", address, multisig.Address)
+	}
+	if controlBlock := hex.EncodeToString(helpers.TaprootControlBlock(internalKey, outputKey)); leaf.ControlBlock != controlBlock {
+		t.Errorf("Test failed:  expected: %s received: %s ", controlBlock, leaf.ControlBlock)
+	}
+	// the descriptor derives the same address
+	addresses, err := walletManager.DeriveDescriptorAddresses(context.Background(), multisig.Descriptor, 0, 1, 0, "testnet3")
+	if err != nil {
+		t.Fatalf("Test failed: unexpected error: %v", err)
+	}
+	if addresses.Addresses[0].Address != multisig.Address {
+		t.Errorf("Test failed:  expected: %s received: %s ", multisig.Address, addresses.Addresses[0].Address)
+	}
+
+	// the order of the keys changes the output when they are not sorted
+	unsorted, err := walletManager.GenerateMultisignature(2, 1, keys, false, false, helpers.MultisigTypeP2TR, "testnet3")
+	if err != nil {
+		t.Fatalf("Test failed: unexpected error: %v", err)
+	}
+	if unsorted.Address == multisig.Address || unsorted.InternalKey == multisig.InternalKey {
+		t.Errorf("Test failed:  expected: another address than %s received: %s ", multisig.Address, unsorted.Address)
+	}
+}
+
+func TestGenerateTaprootMultisignatureSubsetLeaves(t *testing.T) {
+	var walletHelper helpers.WalletHelper = helpers.NewWalletHelper()
+	var walletManager WalletManager = NewWalletManager(walletHelper)
+	keys := []string{"03f546edf7b434b50aa0115c1c82a0f9a96505d9eff55d2fe3b848c4b51c06b643", "0265e6f7fb614a369c9230912a3bb09c33c5c5be2e1bcfc2293ecaed46708e0b5c", "0279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798"}
+
+	multisig, err := walletManager.GenerateMultisignature(3, 2, keys, true, true, helpers.MultisigTypeP2TR, "testnet3")
+	if err != nil {
+		t.Fatalf("Test failed: unexpected error: %v", err)
+	}
+	// a 2 of 2 leaf for every pair of the sorted x-only keys
+	a, b, c := keys[1][2:], keys[2][2:], keys[0][2:]
+	expected := []string{"and_v(v:pk(" + a + "),pk(" + b + "))", "and_v(v:pk(" + a + "),pk(" + c + "))", "and_v(v:pk(" + b + "),pk(" + c + "))"}
+	if len(multisig.Leaves) != len(expected) {
+		t.Fatalf("Test failed:  expected: %d leaves received: %d ", len(expected), len(multisig.Leaves))
+	}
+	for i, leaf := range multisig.Leaves {
+		if leaf.Miniscript != expected[i] {
+			t.Errorf("Test failed:  expected: %s received: %s ", expected[i], leaf.Miniscript)
+		}
+		// the control block proves the leaf against the merkle root
+		node, _ := hex.DecodeString(leaf.LeafHash)
+		controlBlock, _ := hex.DecodeString(leaf.ControlBlock)
+		for path := controlBlock[33:]; len(path) > 0; path = path[32:] {
+			node = helpers.TapBranchHash(node, path[:32])
+		}
+		if hex.EncodeToString(node) != multisig.MerkleRoot {
+			t.Errorf("Test failed:  expected: %s received: %x ", multisig.MerkleRoot, node)
+		}
+	}
+	expectedDescriptor, _ := helpers.AddDescriptorChecksum("tr(" + multisig.InternalKey + ",{{" + expected[0] + "," + expected[1] + "}," + expected[2] + "})")
+	if multisig.Descriptor != expectedDescriptor {
+		t.Errorf("Test failed:  expected: %s received: %s ", expectedDescriptor, multisig.Descriptor)
+	}
+	// the descriptor derives the same address from the script tree
+	addresses, err := walletManager.DeriveDescriptorAddresses(context.Background(), multisig.Descriptor, 0, 1, 0, "testnet3")
+	if err != nil {
+		t.Fatalf("Test failed: unexpected error: %v", err)
+	}
+	if addresses.Addresses[0].Address != multisig.Address {
+		t.Errorf("Test failed:  expected: %s received: %s ", multisig.Address, addresses.Addresses[0].Address)
+	}
+
+	// 10 of 20 keys have 184756 subsets
+	many := make([]string, 20)
+	for i := range many {
+		_, publicKey := btcec.PrivKeyFromBytes(btcec.S256(), []byte{byte(i + 1)})
+		many[i] = hex.EncodeToString(publicKey.SerializeCompressed())
+	}
+	if _, err := walletManager.GenerateMultisignature(20, 10, many, true, true, helpers.MultisigTypeP2TR, "testnet3"); !errors.Is(err, helpers.ErrMultisigTooManyLeaves) {
+		t.Errorf("Test failed:  expected: %v received: %v ", helpers.ErrMultisigTooManyLeaves, err)
+	}
+}
+
+func TestDecodeMultisignatureScript(t *testing.T) {
+	var walletHelper helpers.WalletHelper = helpers.NewWalletHelper()
+	var walletManager WalletManager = NewWalletManager(walletHelper)
+	wif := []string{"cQpHXfs91s5eR9PWXui6qo2xjoJb2X3VdUspwKXe4A8Dybvut2rL", "cVgxEkRBtnfvd41ssd4PCsiemahAHidFrLWYoDBMNojUeME8dojZ"}
+	var script string = "51210265e6f7fb614a369c9230912a3bb09c33c5c5be2e1bcfc2293ecaed46708e0b5c2103f546edf7b434b50aa0115c1c82a0f9a96505d9eff55d2fe3b848c4b51c06b64352ae"
+
+	multisig, err := walletManager.DecodeMultisignatureScript(script, "testnet3")
+	if err != nil {
+		t.Fatalf("Test failed: unexpected error: %v", err)
+	}
+	if multisig.M != 1 || multisig.N != 2 || len(multisig.Outputs) != 3 {
+		t.Fatalf("Test failed:  expected: 1 of 2 with 3 outputs received: %d of %d with %d outputs ", multisig.M, multisig.N, len(multisig.Outputs))
+	}
+	// every output matches the address generated from the keys of the script
+	for _, output := range multisig.Outputs {
+		expected, err := walletManager.GenerateMultisignature(2, 1, wif, true, false, output.MultisigType, "testnet3")
+		if err != nil {
+			t.Fatalf("Test failed: unexpected error: %v", err)
+		}
+		if output.Address != expected.Address || output.RedeemScript != expected.RedeemScript || output.ScriptHash != expected.ScriptHash {
+			t.Errorf("Test failed:  expected: %+v received: %+v ", *expected, output)
+		}
+		if expected.ScriptAsm != multisig.ScriptAsm || strings.Join(expected.PublicKeys, ",") != strings.Join(multisig.PublicKeys, ",") {
+			t.Errorf("Test failed:  expected: %s received: %s ", expected.ScriptAsm, multisig.ScriptAsm)
+		}
+	}
+	if redeemScript := "0020" + multisig.Outputs[1].ScriptHash; multisig.Outputs[2].RedeemScript != redeemScript {
+		t.Errorf("Test failed:  expected: %s received: %s ", redeemScript, multisig.Outputs[2].RedeemScript)
+	}
+
+	if _, err := walletManager.DecodeMultisignatureScript("76a914d6fe8455e2a6b6411ab9c4365c66a8c84ba4cc9f88ac", "testnet3"); !errors.Is(err, helpers.ErrInvalidMultisigScript) {
+		t.Errorf("Test failed:  expected: %v received: %v ", helpers.ErrInvalidMultisigScript, err)
+	}
+}
+
+func TestGenerateMultisignatureWrongNetwork(t *testing.T) {
+	var walletHelper helpers.WalletHelper = helpers.NewWalletHelper()
+	var walletManager WalletManager = NewWalletManager(walletHelper)
+	var wif []string
+	wif = append(wif, "cQpHXfs91s5eR9PWXui6qo2xjoJb2X3VdUspwKXe4A8Dybvut2rL")
+	wif = append(wif, "cVgxEkRBtnfvd41ssd4PCsiemahAHidFrLWYoDBMNojUeME8dojZ")
+
+	if _, err := walletManager.GenerateMultisignature(2, 2, wif, true, false, "", "mainnet"); !errors.Is(err, helpers.ErrKeyNetworkMismatch) {
+		t.Errorf("Test failed:  expected: %v received: %v ", helpers.ErrKeyNetworkMismatch, err)
+	}
+}
+
+func TestGenerateAddressRange(t *testing.T) {
+	var walletHelper helpers.WalletHelper = helpers.NewWalletHelper()
+	var walletManager WalletManager = NewWalletManager(walletHelper)
+	var seed string = "3fdf3c7c40ef678dd8950caac27f8006e27fdfab5e379ff7e3cef34a0226df830a49ca85476e7873e096ca6127d365995f6f135c71c27e8efe6cd1c497f6003f"
+	var accountPath string = "m/44'/0'/0'"
+
+	entries, err := walletManager.GenerateAddressRange(context.Background(), seed, accountPath, 0, 0, 3, true, "mainnet")
+
+	if err != nil {
+		t.Fatalf("Test failed: unexpected error: %v", err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("Test failed:  expected: %d received: %d ", 3, len(entries))
+	}
+	// the first child must match the single address derivation of m/44'/0'/0'/0/0
+	expected := AddressEntry{
+		Path:       "m/44'/0'/0'/0/0",
+		PubKey:     entries[0].PubKey,
+		Wif:        "L1dbCB2GPDDzxNJJ7s7h7a84NSXViBNwJeLWTS2gJMtdapfkhmg8",
+		P2pkh:      "1AKdhnB63swG2XpSuuXWP8MqP596zRJJCz",
+		P2shP2wpkh: "3MTm6vsDYfyQCSTeex9ZHWYd9zkUetUn7c",
+		P2wpkh:     "bc1qvcl5rm6vz7eaxed9zrcftax0ywsc29y8zgj876",
+		P2tr:       "bc1p8cdgm6fcq58nqzavdl37tvjh4nzkwdje6fhyyxe3r5chl9v5emrq74qclp",
+	}
+	if entries[0] != expected {
+		t.Errorf("Test failed:  expected: %+v received: %+v ", expected, entries[0])
+	}
+	if entries[2].Path != "m/44'/0'/0'/0/2" {
+		t.Errorf("Test failed:  expected: %s received: %s ", "m/44'/0'/0'/0/2", entries[2].Path)
+	}
+}
+
+func TestGenerateAddressRangeLimits(t *testing.T) {
+	var walletHelper helpers.WalletHelper = helpers.NewWalletHelper()
+	var walletManager WalletManager = NewWalletManager(walletHelper)
+	var seed string = "3fdf3c7c40ef678dd8950caac27f8006e27fdfab5e379ff7e3cef34a0226df830a49ca85476e7873e096ca6127d365995f6f135c71c27e8efe6cd1c497f6003f"
+	var accountPath string = "m/44'/0'/0'"
 
-	if address != expectedAddress {
-		t.Errorf("Test failed:  expected: %s received: %s ", expectedAddress, address)
+	if _, err := walletManager.GenerateAddressRange(context.Background(), seed, accountPath, 0, 0, MaxAddressRangeCount+1, false, "mainnet"); err == nil {
+		t.Errorf("Test failed: count above the cap expected to be rejected")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := walletManager.GenerateAddressRange(ctx, seed, accountPath, 0, 0, 10, false, "mainnet"); err != context.Canceled {
+		t.Errorf("Test failed:  expected: %v received: %v ", context.Canceled, err)
+	}
+}
+
+func TestGenerateWatchOnly(t *testing.T) {
+	var walletHelper helpers.WalletHelper = helpers.NewWalletHelper()
+	var walletManager WalletManager = NewWalletManager(walletHelper)
+	// account extended public key of m/44'/0'/0' for the reference seed
+	var extPubKey string = "xpub6BznHD2sccQawPyqKJ4wE89HKi4Tf88zLbgGRJXeiVHpJTWJDG191vCpMBYBY13vD7Zp8XaF5F31gPQMSZCXt2kJhzV2hXJyk98AUFvqeLv"
+
+	entries, err := walletManager.GenerateWatchOnly(context.Background(), extPubKey, "0/0", 2, "mainnet")
+
+	if err != nil {
+		t.Fatalf("Test failed: unexpected error: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("Test failed:  expected: %d received: %d ", 2, len(entries))
+	}
+	if entries[0].P2pkh != "1AKdhnB63swG2XpSuuXWP8MqP596zRJJCz" {
+		t.Errorf("Test failed:  expected: %s received: %s ", "1AKdhnB63swG2XpSuuXWP8MqP596zRJJCz", entries[0].P2pkh)
+	}
+	if entries[1].Path != "0/1" || entries[1].P2pkh != "1LMPjfyXHCX5cEfZUhELG1b9sCn97SELnr" {
+		t.Errorf("Test failed:  expected: %s %s received: %s %s ", "0/1", "1LMPjfyXHCX5cEfZUhELG1b9sCn97SELnr", entries[1].Path, entries[1].P2pkh)
+	}
+	// public derivation gives the taproot address of the private range
+	if entries[0].P2tr != "bc1p8cdgm6fcq58nqzavdl37tvjh4nzkwdje6fhyyxe3r5chl9v5emrq74qclp" {
+		t.Errorf("Test failed:  expected: %s received: %s ", "bc1p8cdgm6fcq58nqzavdl37tvjh4nzkwdje6fhyyxe3r5chl9v5emrq74qclp", entries[0].P2tr)
+	}
+}
+
+func TestGenerateWatchOnlyZpub(t *testing.T) {
+	var walletHelper helpers.WalletHelper = helpers.NewWalletHelper()
+	var walletManager WalletManager = NewWalletManager(walletHelper)
+	// BIP84 test vector account 0
+	var extPubKey string = "zpub6rFR7y4Q2AijBEqTUquhVz398htDFrtymD9xYYfG1m4wAcvPhXNfE3EfH1r1ADqtfSdVCToUG868RvUUkgDKf31mGDtKsAYz2oz2AGutZYs"
+	var expectedP2wpkh string = "bc1qcr8te4kr609gcawutmrza0j4xv80jy8z306fyu"
+
+	entries, err := walletManager.GenerateWatchOnly(context.Background(), extPubKey, "0/0", 1, "mainnet")
+
+	if err != nil {
+		t.Fatalf("Test failed: unexpected error: %v", err)
+	}
+	if entries[0].P2wpkh != expectedP2wpkh {
+		t.Errorf("Test failed:  expected: %s received: %s ", expectedP2wpkh, entries[0].P2wpkh)
+	}
+}
+
+func TestGenerateWatchOnlyTaproot(t *testing.T) {
+	var walletHelper helpers.WalletHelper = helpers.NewWalletHelper()
+	var walletManager WalletManager = NewWalletManager(walletHelper)
+	// BIP86 test vector account 0
+	var extPubKey string = "xpub6BgBgsespWvERF3LHQu6CnqdvfEvtMcQjYrcRzx53QJjSxarj2afYWcLteoGVky7D3UKDP9QyrLprQ3VCECoY49yfdDEHGCtMMj92pReUsQ"
+	expected := []string{"bc1p5cyxnuxmeuwuvkwfem96lqzszd02n6xdcjrs20cac6yqjjwudpxqkedrcr", "bc1p4qhjn9zdvkux4e44uhx8tc55attvtyu358kutcqkudyccelu0was9fqzwh"}
+
+	entries, err := walletManager.GenerateWatchOnly(context.Background(), extPubKey, "0/0", 2, "mainnet")
+
+	if err != nil {
+		t.Fatalf("Test failed: unexpected error: %v", err)
+	}
+	for i, entry := range entries {
+		if entry.P2tr != expected[i] {
+			t.Errorf("Test failed:  expected: %s received: %s ", expected[i], entry.P2tr)
+		}
+	}
+}
+
+func TestGenerateWatchOnlyInvalid(t *testing.T) {
+	var walletHelper helpers.WalletHelper = helpers.NewWalletHelper()
+	var walletManager WalletManager = NewWalletManager(walletHelper)
+	var extPubKey string = "xpub6BznHD2sccQawPyqKJ4wE89HKi4Tf88zLbgGRJXeiVHpJTWJDG191vCpMBYBY13vD7Zp8XaF5F31gPQMSZCXt2kJhzV2hXJyk98AUFvqeLv"
+	var extPrvKey string = "xprv9y1RshVynErHiuuNDGXvrzCYmgDyFfR8yNkfcv83A9kqRfB9figtU7tLVuv5fyZkBcbyHAzY8EfcKn4snDFRtHb4bZvAKRJG4bZoZKh5k99"
+
+	if _, err := walletManager.GenerateWatchOnly(context.Background(), extPubKey, "0'/1", 1, "mainnet"); !errors.Is(err, ErrHardenedFromPublicKey) {
+		t.Errorf("Test failed:  expected: %v received: %v ", ErrHardenedFromPublicKey, err)
+	}
+	if _, err := walletManager.GenerateWatchOnly(context.Background(), extPubKey, "0/1", 1, "testnet3"); err == nil {
+		t.Errorf("Test failed: mainnet xpub expected to be rejected on testnet3")
+	}
+	if _, err := walletManager.GenerateWatchOnly(context.Background(), extPrvKey, "0/1", 1, "mainnet"); err == nil {
+		t.Errorf("Test failed: extended private key expected to be rejected")
+	}
+}
+
+func TestGenerateHdWalletPurpose(t *testing.T) {
+	var walletHelper helpers.WalletHelper = helpers.NewWalletHelper()
+	var walletManager WalletManager = NewWalletManager(walletHelper)
+	// seed of the "abandon abandon ... about" reference mnemonic
+	var seed string = "5eb00bbddcf069084889a8ab9155568165f5c453ccb85e70811aaed6f6da5fc19a5ac40b389cd370d086206dec8aa6c43daea6690f20ad3d8d48b2d2ce9e38e4"
+	tests := []struct {
+		path                string
+		network             string
+		expectedAddressType helpers.AddressType
+		expectedExtPubKey   string
+		expectedAddress     string
+	}{
+		{"m/84'/0'/0'", "mainnet", helpers.AddressTypeP2WPKH, "zpub6rFR7y4Q2AijBEqTUquhVz398htDFrtymD9xYYfG1m4wAcvPhXNfE3EfH1r1ADqtfSdVCToUG868RvUUkgDKf31mGDtKsAYz2oz2AGutZYs", ""},
+		{"m/84'/0'/0'/0/0", "mainnet", helpers.AddressTypeP2WPKH, "", "bc1qcr8te4kr609gcawutmrza0j4xv80jy8z306fyu"},
+		{"m/49'/0'/0'", "mainnet", helpers.AddressTypeP2SHP2WPKH, "ypub6Ww3ibxVfGzLrAH1PNcjyAWenMTbbAosGNB6VvmSEgytSER9azLDWCxoJwW7Ke7icmizBMXrzBx9979FfaHxHcrArf3zbeJJJUZPf663zsP", ""},
+		{"m/49'/0'/0'/0/0", "mainnet", helpers.AddressTypeP2SHP2WPKH, "", "37VucYSaXLCAsxYyAPfbSi9eh4iEcbShgf"},
+		{"m/49'/1'/0'/0/0", "testnet3", helpers.AddressTypeP2SHP2WPKH, "", "2Mww8dCYPUpKHofjgcXcBCEGmniw9CoaiD2"},
+		{"m/44'/0'/0'/0/0", "mainnet", helpers.AddressTypeP2PKH, "", "1LqBGSKuX5yYUonjxT5qGfpUsXKYYWeabA"},
+	}
+
+	for _, test := range tests {
+		wallet, err := walletManager.GenerateHdWallet(seed, test.path, test.network, false)
+		if err != nil {
+			t.Errorf("Test failed: input: %s unexpected error: %v", test.path, err)
+			continue
+		}
+		if wallet.AddressType != test.expectedAddressType {
+			t.Errorf("Test failed: input: %s expected: %s received: %s ", test.path, test.expectedAddressType, wallet.AddressType)
+		}
+		if test.expectedExtPubKey != "" && wallet.ExtPubKey != test.expectedExtPubKey {
+			t.Errorf("Test failed: input: %s expected: %s received: %s ", test.path, test.expectedExtPubKey, wallet.ExtPubKey)
+		}
+		if test.expectedAddress != "" && wallet.Address != test.expectedAddress {
+			t.Errorf("Test failed: input: %s expected: %s received: %s ", test.path, test.expectedAddress, wallet.Address)
+		}
+		if wallet.P2pkhAddress != "" || wallet.SegwitBech32 != "" || wallet.SegwitNested != "" {
+			t.Errorf("Test failed: input: %s mixed output expected to be empty", test.path)
+		}
+	}
+}
+
+func TestGenerateHdWalletTaproot(t *testing.T) {
+	var walletHelper helpers.WalletHelper = helpers.NewWalletHelper()
+	var walletManager WalletManager = NewWalletManager(walletHelper)
+	// BIP86 test vector for the "abandon abandon ... about" reference mnemonic
+	var seed string = "5eb00bbddcf069084889a8ab9155568165f5c453ccb85e70811aaed6f6da5fc19a5ac40b389cd370d086206dec8aa6c43daea6690f20ad3d8d48b2d2ce9e38e4"
+	var expectedAccountXPub string = "xpub6BgBgsespWvERF3LHQu6CnqdvfEvtMcQjYrcRzx53QJjSxarj2afYWcLteoGVky7D3UKDP9QyrLprQ3VCECoY49yfdDEHGCtMMj92pReUsQ"
+	var expectedAddress string = "bc1p5cyxnuxmeuwuvkwfem96lqzszd02n6xdcjrs20cac6yqjjwudpxqkedrcr"
+	var expectedOutputKey string = "a60869f0dbcf1dc659c9cecbaf8050135ea9e8cdc487053f1dc6880949dc684c"
+
+	account, _ := walletManager.GenerateHdWallet(seed, "m/86'/0'/0'", "mainnet", false)
+	if account.ExtPubKey != expectedAccountXPub {
+		t.Errorf("Test failed:  expected: %s received: %s ", expectedAccountXPub, account.ExtPubKey)
+	}
+
+	wallet, _ := walletManager.GenerateHdWallet(seed, "m/86'/0'/0'/0/0", "mainnet", false)
+	if wallet.AddressType != helpers.AddressTypeP2TR {
+		t.Errorf("Test failed:  expected: %s received: %s ", helpers.AddressTypeP2TR, wallet.AddressType)
+	}
+	if wallet.Address != expectedAddress || wallet.P2trAddress != expectedAddress {
+		t.Errorf("Test failed:  expected: %s received: %s %s ", expectedAddress, wallet.Address, wallet.P2trAddress)
+	}
+	if wallet.OutputKey != expectedOutputKey {
+		t.Errorf("Test failed:  expected: %s received: %s ", expectedOutputKey, wallet.OutputKey)
+	}
+}
+
+func TestSlip39Shares(t *testing.T) {
+	var walletHelper helpers.WalletHelper = helpers.NewWalletHelper()
+	var walletManager WalletManager = NewWalletManager(walletHelper)
+	groups := []helpers.Slip39Group{{MemberThreshold: 2, MemberCount: 3}, {MemberThreshold: 1, MemberCount: 1}}
+
+	shares, seed, err := walletManager.GenerateSlip39Shares("", 256, "TREZOR", 1, groups, 0, true)
+	if err != nil {
+		t.Fatalf("Test failed: unexpected error: %v", err)
+	}
+	if len(seed) != 64 {
+		t.Errorf("Test failed:  expected: %d received: %d ", 64, len(seed))
+	}
+	for _, mnemonics := range [][]string{shares[0][1:], shares[1]} {
+		combined, err := walletManager.CombineSlip39Shares(mnemonics, "TREZOR")
+		if err != nil {
+			t.Fatalf("Test failed: unexpected error: %v", err)
+		}
+		if combined != seed {
+			t.Errorf("Test failed:  expected: %s received: %s ", seed, combined)
+		}
+	}
+
+	// the recovered master secret is the BIP32 seed of the wallet
+	combined, _ := walletManager.CombineSlip39Shares([]string{"duckling enlarge academic academic agency result length solution fridge kidney coal piece deal husband erode duke ajar critical decision keyboard"}, "TREZOR")
+	if _, err := walletManager.GenerateHdWallet(combined, "m/84'/0'/0'/0/0", "mainnet", false); err != nil {
+		t.Errorf("Test failed: unexpected error: %v", err)
+	}
+
+	if _, _, err := walletManager.GenerateSlip39Shares("", 136, "", 1, groups, 0, false); !errors.Is(err, helpers.ErrInvalidSharing) {
+		t.Errorf("Test failed:  expected: %v received: %v ", helpers.ErrInvalidSharing, err)
+	}
+	if _, _, err := walletManager.GenerateSlip39Shares("zz", 0, "", 1, groups, 0, false); !errors.Is(err, helpers.ErrInvalidSharing) {
+		t.Errorf("Test failed:  expected: %v received: %v ", helpers.ErrInvalidSharing, err)
+	}
+}
+
+func TestGenerateHdWalletDescriptors(t *testing.T) {
+	var walletHelper helpers.WalletHelper = helpers.NewWalletHelper()
+	var walletManager WalletManager = NewWalletManager(walletHelper)
+	// seed of the "abandon abandon ... about" reference mnemonic, master fingerprint 73c5da0a
+	var seed string = "5eb00bbddcf069084889a8ab9155568165f5c453ccb85e70811aaed6f6da5fc19a5ac40b389cd370d086206dec8aa6c43daea6690f20ad3d8d48b2d2ce9e38e4"
+	var expectedReceive string = "wpkh([73c5da0a/84'/0'/0']xpub6CatWdiZiodmUeTDp8LT5or8nmbKNcuyvz7WyksVFkKB4RHwCD3XyuvPEbvqAQY3rAPshWcMLoP2fMFMKHPJ4ZeZXYVUhLv1VMrjPC7PW6V/0/*)#wc3n3van"
+	var expectedChange string = "wpkh([73c5da0a/84'/0'/0']xpub6CatWdiZiodmUeTDp8LT5or8nmbKNcuyvz7WyksVFkKB4RHwCD3XyuvPEbvqAQY3rAPshWcMLoP2fMFMKHPJ4ZeZXYVUhLv1VMrjPC7PW6V/1/*)#lv5jvedt"
+	var expectedMultipath string = "wpkh([73c5da0a/84'/0'/0']xpub6CatWdiZiodmUeTDp8LT5or8nmbKNcuyvz7WyksVFkKB4RHwCD3XyuvPEbvqAQY3rAPshWcMLoP2fMFMKHPJ4ZeZXYVUhLv1VMrjPC7PW6V/<0;1>/*)#hpg6d6w2"
+	var expectedPrivate string = "wpkh([73c5da0a/84'/0'/0']xprv9ybY78BftS5UGANki6oSifuQEjkpyAC8ZmBvBNTshQnCBcxnefjHS7buPMkkqhcRzmoGZ5bokx7GuyDAiktd5HemohAU4wV1ZPMDRmLpBMm/0/*)#jfrjsuen"
+	var expectedTaproot string = "tr([73c5da0a/86'/0'/0']xpub6BgBgsespWvERF3LHQu6CnqdvfEvtMcQjYrcRzx53QJjSxarj2afYWcLteoGVky7D3UKDP9QyrLprQ3VCECoY49yfdDEHGCtMMj92pReUsQ/0/*)#rg247h69"
+
+	wallet, err := walletManager.GenerateHdWallet(seed, "m/84'/0'/0'", "mainnet", false)
+	if err != nil {
+		t.Fatalf("Test failed: unexpected error: %v", err)
+	}
+	if wallet.Descriptors == nil {
+		t.Fatalf("Test failed: no descriptors for an account path")
+	}
+	if wallet.Descriptors.Public.Receive != expectedReceive {
+		t.Errorf("Test failed:  expected: %s received: %s ", expectedReceive, wallet.Descriptors.Public.Receive)
+	}
+	if wallet.Descriptors.Public.Change != expectedChange {
+		t.Errorf("Test failed:  expected: %s received: %s ", expectedChange, wallet.Descriptors.Public.Change)
+	}
+	if wallet.Descriptors.Public.Multipath != expectedMultipath {
+		t.Errorf("Test failed:  expected: %s received: %s ", expectedMultipath, wallet.Descriptors.Public.Multipath)
+	}
+	if wallet.Descriptors.Private.Receive != expectedPrivate {
+		t.Errorf("Test failed:  expected: %s received: %s ", expectedPrivate, wallet.Descriptors.Private.Receive)
+	}
+
+	wallet, _ = walletManager.GenerateHdWallet(seed, "m/86'/0'/0'", "mainnet", false)
+	if wallet.Descriptors.Public.Receive != expectedTaproot {
+		t.Errorf("Test failed:  expected: %s received: %s ", expectedTaproot, wallet.Descriptors.Public.Receive)
+	}
+
+	// an address path or a path without a known purpose has no account descriptors
+	for _, path := range []string{"m/84'/0'/0'/0/0", "m/0'/0'/0'"} {
+		wallet, _ = walletManager.GenerateHdWallet(seed, path, "mainnet", false)
+		if wallet.Descriptors != nil {
+			t.Errorf("Test failed:  expected no descriptors for %s received: %v ", path, wallet.Descriptors)
+		}
+	}
+}
+
+func TestDeriveDescriptorAddresses(t *testing.T) {
+	var walletHelper helpers.WalletHelper = helpers.NewWalletHelper()
+	var walletManager WalletManager = NewWalletManager(walletHelper)
+	var descriptor string = "wpkh([73c5da0a/84'/0'/0']xpub6CatWdiZiodmUeTDp8LT5or8nmbKNcuyvz7WyksVFkKB4RHwCD3XyuvPEbvqAQY3rAPshWcMLoP2fMFMKHPJ4ZeZXYVUhLv1VMrjPC7PW6V/<0;1>/*)"
+	var expectedDescriptor string = descriptor + "#hpg6d6w2"
+	expectedAddresses := []string{"bc1qcr8te4kr609gcawutmrza0j4xv80jy8z306fyu", "bc1qnjg0jd8228aq7egyzacy8cys3knf9xvrerkf9g"}
+
+	result, err := walletManager.DeriveDescriptorAddresses(context.Background(), descriptor, 0, 2, 0, "mainnet")
+	if err != nil {
+		t.Fatalf("Test failed: unexpected error: %v", err)
+	}
+	if result.Descriptor != expectedDescriptor || !result.IsRange || result.Multipath != 2 {
+		t.Errorf("Test failed:  expected: %s received: %s %v %d ", expectedDescriptor, result.Descriptor, result.IsRange, result.Multipath)
+	}
+	for i, expected := range expectedAddresses {
+		if result.Addresses[i].Index != uint32(i) || result.Addresses[i].Address != expected {
+			t.Errorf("Test failed:  expected: %s received: %s ", expected, result.Addresses[i].Address)
+		}
+	}
+
+	// a descriptor without wildcard has a single address
+	result, err = walletManager.DeriveDescriptorAddresses(context.Background(), "pkh(02c6047f9441ed7d6d3045406e95c07cd85c778e4b8cef3ca7abac09b95c709ee5)", 5, 10, 0, "mainnet")
+	if err != nil {
+		t.Fatalf("Test failed: unexpected error: %v", err)
+	}
+	if len(result.Addresses) != 1 || result.Addresses[0].Address != "1cMh228HTCiwS8ZsaakH8A8wze1JR5ZsP" {
+		t.Errorf("Test failed:  expected: %s received: %v ", "1cMh228HTCiwS8ZsaakH8A8wze1JR5ZsP", result.Addresses)
+	}
+
+	if _, err := walletManager.DeriveDescriptorAddresses(context.Background(), descriptor, 0, 2, 2, "mainnet"); !errors.Is(err, helpers.ErrInvalidDescriptor) {
+		t.Errorf("Test failed:  expected: %v received: %v ", helpers.ErrInvalidDescriptor, err)
+	}
+}
+
+func TestGenerateMultisignatureFromExtPubKeys(t *testing.T) {
+	var walletHelper helpers.WalletHelper = helpers.NewWalletHelper()
+	var walletManager WalletManager = NewWalletManager(walletHelper)
+	extPubKeys := []string{
+		"xpub6CatWdiZiodmUeTDp8LT5or8nmbKNcuyvz7WyksVFkKB4RHwCD3XyuvPEbvqAQY3rAPshWcMLoP2fMFMKHPJ4ZeZXYVUhLv1VMrjPC7PW6V",
+		"xpub6BgBgsespWvERF3LHQu6CnqdvfEvtMcQjYrcRzx53QJjSxarj2afYWcLteoGVky7D3UKDP9QyrLprQ3VCECoY49yfdDEHGCtMMj92pReUsQ",
+		"xpub6BosfCnifzxcFwrSzQiqu2DBVTshkCXacvNsWGYJVVhhawA7d4R5WSWGFNbi8Aw6ZRc1brxMyWMzG3DSSSSoekkudhUd9yLb6qx39T9nMdj",
+	}
+	reversed := []string{extPubKeys[2], extPubKeys[1], extPubKeys[0]}
+
+	entries, err := walletManager.GenerateMultisignatureFromExtPubKeys(context.Background(), 3, 2, extPubKeys, 1, 5, 3, true, false, "", "mainnet")
+	if err != nil {
+		t.Fatalf("Test failed: unexpected error: %v", err)
+	}
+	reversedEntries, _ := walletManager.GenerateMultisignatureFromExtPubKeys(context.Background(), 3, 2, reversed, 1, 5, 3, true, false, "", "mainnet")
+	// the same addresses as the sortedmulti descriptor of the keys
+	descriptor := "sh(sortedmulti(2," + strings.Join(extPubKeys, "/1/*,") + "/1/*))"
+	expected, err := walletManager.DeriveDescriptorAddresses(context.Background(), descriptor, 5, 3, 0, "mainnet")
+	if err != nil {
+		t.Fatalf("Test failed: unexpected error: %v", err)
+	}
+	for i, entry := range entries {
+		if entry.Path != fmt.Sprintf("1/%d", 5+i) {
+			t.Errorf("Test failed:  expected: 1/%d received: %s ", 5+i, entry.Path)
+		}
+		if entry.Address != expected.Addresses[i].Address || reversedEntries[i].Address != entry.Address {
+			t.Errorf("Test failed:  expected: %s received: %s %s ", expected.Addresses[i].Address, entry.Address, reversedEntries[i].Address)
+		}
+	}
+
+	if _, err := walletManager.GenerateMultisignatureFromExtPubKeys(context.Background(), 3, 2, extPubKeys, bip32.FirstHardenedChild, 0, 1, true, false, "", "mainnet"); !errors.Is(err, ErrHardenedFromPublicKey) {
+		t.Errorf("Test failed:  expected: %v received: %v ", ErrHardenedFromPublicKey, err)
+	}
+	duplicated := []string{extPubKeys[0], extPubKeys[1], extPubKeys[0]}
+	if _, err := walletManager.GenerateMultisignatureFromExtPubKeys(context.Background(), 3, 2, duplicated, 0, 0, 1, true, false, "", "mainnet"); !errors.Is(err, helpers.ErrMultisigDuplicateKey) {
+		t.Errorf("Test failed:  expected: %v received: %v ", helpers.ErrMultisigDuplicateKey, err)
+	}
+}
+
+func TestCompileMiniscript(t *testing.T) {
+	var walletHelper helpers.WalletHelper = helpers.NewWalletHelper()
+	var walletManager WalletManager = NewWalletManager(walletHelper)
+	keys := map[string]string{"A": "cQpHXfs91s5eR9PWXui6qo2xjoJb2X3VdUspwKXe4A8Dybvut2rL", "B": "cVgxEkRBtnfvd41ssd4PCsiemahAHidFrLWYoDBMNojUeME8dojZ"}
+
+	// a 1 of 2 threshold of keys is the 1 of 2 multisig witness script
+	output, err := walletManager.CompileMiniscript("thresh(1,pk(A),pk(B))", true, miniscript.Segwit, keys, "", "testnet3")
+	if err != nil {
+		t.Fatalf("Test failed: unexpected error: %v", err)
+	}
+	var expected string = "tb1qk27xsgrkuc8y7auf6etl0fy9aaetu4f7fcr02s9jv6zpjyaeausq6hxtyw"
+	if output.Address != expected {
+		t.Errorf("Test failed:  expected: %s received: %s ", expected, output.Address)
+	}
+	if script := "51210265e6f7fb614a369c9230912a3bb09c33c5c5be2e1bcfc2293ecaed46708e0b5c2103f546edf7b434b50aa0115c1c82a0f9a96505d9eff55d2fe3b848c4b51c06b64352ae"; output.Script != script {
+		t.Errorf("Test failed:  expected: %s received: %s ", script, output.Script)
+	}
+	if output.MaxSatisfactionSize != 75 || output.MaxWitnessSize != 75+1+71 {
+		t.Errorf("Test failed:  expected: 75 and 147 received: %d and %d ", output.MaxSatisfactionSize, output.MaxWitnessSize)
+	}
+	// the descriptor derives the same address
+	addresses, err := walletManager.DeriveDescriptorAddresses(context.Background(), output.Descriptor, 0, 1, 0, "testnet3")
+	if err != nil {
+		t.Fatalf("Test failed: unexpected error: %v", err)
+	}
+	if addresses.Addresses[0].Address != expected {
+		t.Errorf("Test failed:  expected: %s received: %s ", expected, addresses.Addresses[0].Address)
+	}
+
+	output, err = walletManager.CompileMiniscript("and_v(v:pk(A),older(144))", false, miniscript.Tapscript, keys, "B", "testnet3")
+	if err != nil {
+		t.Fatalf("Test failed: unexpected error: %v", err)
+	}
+	internalKey, _ := hex.DecodeString("03f546edf7b434b50aa0115c1c82a0f9a96505d9eff55d2fe3b848c4b51c06b643")
+	internal, _ := btcec.ParsePubKey(internalKey, btcec.S256())
+	script, _ := hex.DecodeString(output.Script)
+	outputKey, _ := helpers.TaprootOutputKey(internal, helpers.TapLeafHash(script))
+	if output.OutputKey != hex.EncodeToString(helpers.XOnlyPubKey(outputKey)) || output.InternalKey != hex.EncodeToString(internalKey[1:]) {
+		t.Errorf("Test failed:  expected: %x received: %s ", helpers.XOnlyPubKey(outputKey), output.OutputKey)
+	}
+	if controlBlock := hex.EncodeToString(helpers.TaprootControlBlock(internal, outputKey)); output.ControlBlock != controlBlock {
+		t.Errorf("Test failed:  expected: %s received: %s ", controlBlock, output.ControlBlock)
+	}
+	if !strings.HasPrefix(output.Address, "tb1p") || !strings.HasPrefix(output.Descriptor, "tr(f546edf7") {
+		t.Errorf("Test failed:  expected: tb1p address and tr() descriptor received: %s %s ", output.Address, output.Descriptor)
+	}
+	addresses, err = walletManager.DeriveDescriptorAddresses(context.Background(), output.Descriptor, 0, 1, 0, "testnet3")
+	if err != nil {
+		t.Fatalf("Test failed: unexpected error: %v", err)
+	}
+	if addresses.Addresses[0].Address != output.Address {
+		t.Errorf("Test failed:  expected: %s received: %s ", output.Address, addresses.Addresses[0].Address)
+	}
+
+	if _, err := walletManager.CompileMiniscript("and_v(v:pk(A),pk(A))", false, miniscript.Segwit, keys, "", "testnet3"); !errors.Is(err, miniscript.ErrInvalidMiniscript) {
+		t.Errorf("Test failed:  expected: %v received: %v ", miniscript.ErrInvalidMiniscript, err)
+	}
+	if _, err := walletManager.CompileMiniscript("pk(A)", false, miniscript.Segwit, keys, "", "mainnet"); !errors.Is(err, miniscript.ErrInvalidMiniscript) {
+		t.Errorf("Test failed:  expected: %v received: %v ", miniscript.ErrInvalidMiniscript, err)
 	}
 }
@@ -0,0 +1,411 @@
+// Package miniscript parses, type checks and compiles Miniscript expressions
+// and spending policies to P2WSH witness scripts and tapscript leaves.
+package miniscript
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"btcwallet.com/src/pkg/helpers"
+)
+
+// Context is the script context an expression is compiled for.
+type Context string
+
+const (
+	// Segwit is the P2WSH witness script context of wsh().
+	Segwit Context = "wsh"
+	// Tapscript is the BIP342 leaf script context of tr().
+	Tapscript Context = "tr"
+)
+
+// ErrInvalidMiniscript is returned for a miniscript expression or policy that
+// can not be parsed, type checked or compiled.
+var ErrInvalidMiniscript = errors.New("invalid miniscript")
+
+// maxTimelock bounds the older() and after() values, 2^31 and over are not
+// timelocks.
+const maxTimelock = 1 << 31
+
+// KeyResolver turns a key of an expression into a compressed public key,
+// so expressions can name keys as hex public keys, WIFs, extended keys with
+// a path or any alias the caller maps.
+type KeyResolver func(key string) ([]byte, error)
+
+// Node is a fragment of a miniscript expression. Wrappers are nodes with a
+// single letter fragment and one subexpression.
+type Node struct {
+	Fragment string
+	Subs     []*Node
+	// Keys are 33 byte keys in segwit and 32 byte x-only keys in tapscript.
+	Keys [][]byte
+	// K is the threshold of multi(), multi_a() and thresh() or the value of
+	// older() and after().
+	K    int64
+	Hash []byte
+	Type Type
+}
+
+// hashSizes are the image sizes of the hash fragments.
+var hashSizes = map[string]int{"sha256": 32, "hash256": 32, "ripemd160": 20, "hash160": 20}
+
+const wrappers = "asctdvjnlu"
+
+// Parse parses a miniscript expression for the context and checks that it is
+// a valid top level B expression. Keys are resolved with the resolver, and
+// tapscript also takes 64 character hex x-only keys.
+func Parse(expression string, ctx Context, resolve KeyResolver) (*Node, error) {
+	if ctx != Segwit && ctx != Tapscript {
+		return nil, fmt.Errorf("%w: unknown context %s", ErrInvalidMiniscript, ctx)
+	}
+	p := &parser{ctx: ctx, resolve: resolve}
+	node, err := p.parse(strings.Join(strings.Fields(expression), ""))
+	if err != nil {
+		return nil, err
+	}
+	if err := node.checkTopLevel(ctx); err != nil {
+		return nil, err
+	}
+	return node, nil
+}
+
+type parser struct {
+	ctx     Context
+	resolve KeyResolver
+}
+
+func (p *parser) parse(text string) (*Node, error) {
+	open := strings.Index(text, "(")
+	if colon := strings.Index(text, ":"); colon >= 0 && (open < 0 || colon < open) {
+		letters := text[:colon]
+		if letters == "" || strings.Trim(letters, wrappers) != "" {
+			return nil, fmt.Errorf("%w: unknown wrappers %s", ErrInvalidMiniscript, letters)
+		}
+		node, err := p.parse(text[colon+1:])
+		if err != nil {
+			return nil, err
+		}
+		// the wrapper closest to the expression applies first
+		for i := len(letters) - 1; i >= 0; i-- {
+			if node, err = p.wrap(letters[i], node); err != nil {
+				return nil, err
+			}
+		}
+		return node, nil
+	}
+
+	if open < 0 {
+		if text != "0" && text != "1" {
+			return nil, fmt.Errorf("%w: expected fragment(...) got %s", ErrInvalidMiniscript, text)
+		}
+		return p.node(&Node{Fragment: text})
+	}
+	if !strings.HasSuffix(text, ")") {
+		return nil, fmt.Errorf("%w: expected fragment(...) got %s", ErrInvalidMiniscript, text)
+	}
+	fragment, args, err := splitFragment(text, open)
+	if err != nil {
+		return nil, err
+	}
+	arity := func(count int) error {
+		if len(args) != count {
+			return fmt.Errorf("%w: %s() takes %d arguments. got %d", ErrInvalidMiniscript, fragment, count, len(args))
+		}
+		return nil
+	}
+
+	switch fragment {
+	case "pk", "pkh", "pk_k", "pk_h":
+		if err := arity(1); err != nil {
+			return nil, err
+		}
+		key, err := p.key(args[0])
+		if err != nil {
+			return nil, err
+		}
+		// pk() and pkh() are c:pk_k() and c:pk_h()
+		leaf := map[string]string{"pk": "pk_k", "pkh": "pk_h"}[fragment]
+		if leaf == "" {
+			return p.node(&Node{Fragment: fragment, Keys: [][]byte{key}})
+		}
+		node, err := p.node(&Node{Fragment: leaf, Keys: [][]byte{key}})
+		if err != nil {
+			return nil, err
+		}
+		return p.wrap('c', node)
+	case "older", "after":
+		if err := arity(1); err != nil {
+			return nil, err
+		}
+		value, err := strconv.ParseInt(args[0], 10, 64)
+		if err != nil || value < 1 || value >= maxTimelock {
+			return nil, fmt.Errorf("%w: %s() needs a value from 1 to 2^31-1. got %s", ErrInvalidMiniscript, fragment, args[0])
+		}
+		return p.node(&Node{Fragment: fragment, K: value})
+	case "sha256", "hash256", "ripemd160", "hash160":
+		if err := arity(1); err != nil {
+			return nil, err
+		}
+		hash, err := hex.DecodeString(args[0])
+		if err != nil || len(hash) != hashSizes[fragment] {
+			return nil, fmt.Errorf("%w: %s() needs a %d byte hex hash. got %s", ErrInvalidMiniscript, fragment, hashSizes[fragment], args[0])
+		}
+		return p.node(&Node{Fragment: fragment, Hash: hash})
+	case "andor", "and_n":
+		if err := arity(map[string]int{"andor": 3, "and_n": 2}[fragment]); err != nil {
+			return nil, err
+		}
+		if fragment == "and_n" {
+			args = append(args, "0")
+		}
+		return p.combine("andor", args)
+	case "and_v", "and_b", "or_b", "or_c", "or_d", "or_i":
+		if err := arity(2); err != nil {
+			return nil, err
+		}
+		return p.combine(fragment, args)
+	case "thresh":
+		k, err := threshold(fragment, args, len(args)-1, 0)
+		if err != nil {
+			return nil, err
+		}
+		node, err := p.combineNode("thresh", args[1:])
+		if err != nil {
+			return nil, err
+		}
+		node.K = k
+		return p.node(node)
+	case "multi", "multi_a":
+		if (fragment == "multi") != (p.ctx == Segwit) {
+			return nil, fmt.Errorf("%w: %s() is not allowed in %s", ErrInvalidMiniscript, fragment, p.ctx)
+		}
+		limit := map[string]int{"multi": helpers.MaxMultisigKeys, "multi_a": helpers.MaxTaprootMultisigKeys}[fragment]
+		k, err := threshold(fragment, args, len(args)-1, limit)
+		if err != nil {
+			return nil, err
+		}
+		node := &Node{Fragment: fragment, K: k}
+		for _, arg := range args[1:] {
+			key, err := p.key(arg)
+			if err != nil {
+				return nil, err
+			}
+			node.Keys = append(node.Keys, key)
+		}
+		return p.node(node)
+	default:
+		return nil, fmt.Errorf("%w: unknown fragment %s", ErrInvalidMiniscript, fragment)
+	}
+}
+
+// splitFragment splits fragment(args) into the fragment name and its
+// arguments.
+func splitFragment(text string, open int) (string, []string, error) {
+	var args []string
+	inner := text[open+1 : len(text)-1]
+	depth, start := 0, 0
+	for i, ch := range inner {
+		switch ch {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth < 0 {
+				return "", nil, fmt.Errorf("%w: unbalanced brackets in %s", ErrInvalidMiniscript, text)
+			}
+		case ',':
+			if depth == 0 {
+				args = append(args, inner[start:i])
+				start = i + 1
+			}
+		}
+	}
+	if depth != 0 {
+		return "", nil, fmt.Errorf("%w: unbalanced brackets in %s", ErrInvalidMiniscript, text)
+	}
+	return text[:open], append(args, inner[start:]), nil
+}
+
+// threshold parses the k of a thresh(), multi() or multi_a() of n arguments,
+// limit caps n when it is not 0.
+func threshold(fragment string, args []string, n int, limit int) (int64, error) {
+	k, err := strconv.Atoi(args[0])
+	if err != nil || n < 1 || k < 1 || k > n || (limit > 0 && n > limit) {
+		return 0, fmt.Errorf("%w: %s() needs 1 <= k <= n. got %s of %d", ErrInvalidMiniscript, fragment, args[0], n)
+	}
+	return int64(k), nil
+}
+
+func (p *parser) combine(fragment string, args []string) (*Node, error) {
+	node, err := p.combineNode(fragment, args)
+	if err != nil {
+		return nil, err
+	}
+	return p.node(node)
+}
+
+func (p *parser) combineNode(fragment string, args []string) (*Node, error) {
+	node := &Node{Fragment: fragment}
+	for _, arg := range args {
+		sub, err := p.parse(arg)
+		if err != nil {
+			return nil, err
+		}
+		node.Subs = append(node.Subs, sub)
+	}
+	return node, nil
+}
+
+// wrap applies a wrapper, t:, l: and u: are written out as the fragments
+// they stand for.
+func (p *parser) wrap(wrapper byte, node *Node) (*Node, error) {
+	switch wrapper {
+	case 't':
+		return p.node(&Node{Fragment: "and_v", Subs: []*Node{node, constant("1")}})
+	case 'l':
+		return p.node(&Node{Fragment: "or_i", Subs: []*Node{constant("0"), node}})
+	case 'u':
+		return p.node(&Node{Fragment: "or_i", Subs: []*Node{node, constant("0")}})
+	}
+	return p.node(&Node{Fragment: string(wrapper), Subs: []*Node{node}})
+}
+
+// constant is the type checked 0 or 1 fragment.
+func constant(value string) *Node {
+	node := &Node{Fragment: value}
+	node.typeCheck(Segwit)
+	return node
+}
+
+func (p *parser) node(node *Node) (*Node, error) {
+	if err := node.typeCheck(p.ctx); err != nil {
+		return nil, err
+	}
+	return node, nil
+}
+
+// key resolves a key to its serialization for the context.
+func (p *parser) key(key string) ([]byte, error) {
+	if p.ctx == Tapscript && len(key) == 64 {
+		xOnly, err := hex.DecodeString(key)
+		if err == nil {
+			if _, err := helpers.ParseXOnlyPubKey(xOnly); err != nil {
+				return nil, fmt.Errorf("%w: key %s: %v", ErrInvalidMiniscript, key, err)
+			}
+			return xOnly, nil
+		}
+	}
+	if p.resolve == nil {
+		return nil, fmt.Errorf("%w: no key resolver for key %s", ErrInvalidMiniscript, key)
+	}
+	publicKey, err := p.resolve(key)
+	if err != nil {
+		return nil, fmt.Errorf("%w: key %s: %v", ErrInvalidMiniscript, key, err)
+	}
+	if len(publicKey) != 33 {
+		return nil, fmt.Errorf("%w: key %s is uncompressed", ErrInvalidMiniscript, key)
+	}
+	if p.ctx == Tapscript {
+		return publicKey[1:], nil
+	}
+	return publicKey, nil
+}
+
+// checkTopLevel requires a B expression without repeated keys that always
+// needs a signature, can not be malleated, does not mix height and time locks
+// and fits the opcode limit of its context.
+func (n *Node) checkTopLevel(ctx Context) error {
+	if !n.Type.is('B') {
+		return fmt.Errorf("%w: top level expression must be of type B. got %s", ErrInvalidMiniscript, n.Type)
+	}
+	seen := map[string]bool{}
+	for _, key := range n.keys() {
+		if seen[string(key)] {
+			return fmt.Errorf("%w: key %x is used more than once", ErrInvalidMiniscript, key)
+		}
+		seen[string(key)] = true
+	}
+	if !n.Type.S {
+		return fmt.Errorf("%w: expression can be satisfied without a signature", ErrInvalidMiniscript)
+	}
+	if !n.Type.M {
+		return fmt.Errorf("%w: expression has malleable satisfactions", ErrInvalidMiniscript)
+	}
+	if !n.Type.K {
+		return fmt.Errorf("%w: expression mixes height and time locks in one spending path", ErrInvalidMiniscript)
+	}
+	if ctx == Segwit {
+		count, err := n.opcodeCount()
+		if err != nil {
+			return err
+		}
+		if count > maxOpcodes {
+			return fmt.Errorf("%w: witness script has %d non-push opcodes, over the limit of %d", ErrInvalidMiniscript, count, maxOpcodes)
+		}
+	}
+	return nil
+}
+
+// keys lists the keys of the expression in script order.
+func (n *Node) keys() [][]byte {
+	keys := append([][]byte{}, n.Keys...)
+	for _, sub := range n.Subs {
+		keys = append(keys, sub.keys()...)
+	}
+	return keys
+}
+
+// String formats the expression with hex keys, using the pk(), pkh(),
+// and_n() and t:, l: and u: shorthands where they apply.
+func (n *Node) String() string {
+	letters, body := n.format()
+	if letters == "" {
+		return body
+	}
+	return letters + ":" + body
+}
+
+// format splits the expression into its leading wrappers and the fragment
+// they wrap.
+func (n *Node) format() (string, string) {
+	switch {
+	case n.Fragment == "c" && (n.Subs[0].Fragment == "pk_k" || n.Subs[0].Fragment == "pk_h"):
+		return "", map[string]string{"pk_k": "pk", "pk_h": "pkh"}[n.Subs[0].Fragment] + "(" + hex.EncodeToString(n.Subs[0].Keys[0]) + ")"
+	case len(n.Fragment) == 1 && strings.Contains(wrappers, n.Fragment):
+		letters, body := n.Subs[0].format()
+		return n.Fragment + letters, body
+	case n.Fragment == "and_v" && n.Subs[1].Fragment == "1":
+		letters, body := n.Subs[0].format()
+		return "t" + letters, body
+	case n.Fragment == "or_i" && n.Subs[0].Fragment == "0":
+		letters, body := n.Subs[1].format()
+		return "l" + letters, body
+	case n.Fragment == "or_i" && n.Subs[1].Fragment == "0":
+		letters, body := n.Subs[0].format()
+		return "u" + letters, body
+	case n.Fragment == "andor" && n.Subs[2].Fragment == "0":
+		return "", "and_n(" + n.Subs[0].String() + "," + n.Subs[1].String() + ")"
+	}
+
+	var args []string
+	switch n.Fragment {
+	case "0", "1":
+		return "", n.Fragment
+	case "older", "after":
+		args = []string{strconv.FormatInt(n.K, 10)}
+	case "sha256", "hash256", "ripemd160", "hash160":
+		args = []string{hex.EncodeToString(n.Hash)}
+	case "thresh", "multi", "multi_a":
+		args = []string{strconv.FormatInt(n.K, 10)}
+	}
+	for _, key := range n.Keys {
+		args = append(args, hex.EncodeToString(key))
+	}
+	for _, sub := range n.Subs {
+		args = append(args, sub.String())
+	}
+	return "", n.Fragment + "(" + strings.Join(args, ",") + ")"
+}
@@ -0,0 +1,165 @@
+package miniscript
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcec"
+)
+
+var testKeys = map[string]string{
+	"A": "0265e6f7fb614a369c9230912a3bb09c33c5c5be2e1bcfc2293ecaed46708e0b5c",
+	"B": "03f546edf7b434b50aa0115c1c82a0f9a96505d9eff55d2fe3b848c4b51c06b643",
+	"C": "0279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798",
+}
+
+func resolveTestKey(key string) ([]byte, error) {
+	if publicKey, ok := testKeys[key]; ok {
+		return hex.DecodeString(publicKey)
+	}
+	if publicKey, err := hex.DecodeString(key); err == nil {
+		return publicKey, nil
+	}
+	return nil, fmt.Errorf("unknown key %s", key)
+}
+
+// withKeys writes the test key hex in place of the aliases, x-only in
+// tapscript.
+func withKeys(text string, ctx Context) string {
+	for alias, key := range testKeys {
+		if ctx == Tapscript {
+			key = key[2:]
+		}
+		text = strings.ReplaceAll(text, "<"+alias+">", key)
+	}
+	return text
+}
+
+func TestParseMiniscript(t *testing.T) {
+	tests := []struct {
+		expression string
+		ctx        Context
+		typ        string
+		asm        string
+		satisfy    int
+	}{
+		{"pk(A)", Segwit, "Bondu", "<A> OP_CHECKSIG", 74},
+		{"pkh(A)", Segwit, "Bndu", "OP_DUP OP_HASH160 1afdffb066544b96fb355120f1e6499ff349f45b OP_EQUALVERIFY OP_CHECKSIG", 108},
+		{"and_v(v:pk(A),or_d(pk(B),older(12960)))", Segwit, "Bn", "<A> OP_CHECKSIGVERIFY <B> OP_CHECKSIG OP_IFDUP OP_NOTIF a032 OP_CHECKSEQUENCEVERIFY OP_ENDIF", 148},
+		{"multi(2,A,B,C)", Segwit, "Bndu", "2 <A> <B> <C> 3 OP_CHECKMULTISIG", 149},
+		{"thresh(2,pk(A),s:pk(B),snl:older(10))", Segwit, "Bdu", "<A> OP_CHECKSIG OP_SWAP <B> OP_CHECKSIG OP_ADD OP_SWAP OP_IF 0 OP_ELSE 10 OP_CHECKSEQUENCEVERIFY OP_ENDIF OP_0NOTEQUAL OP_ADD 2 OP_EQUAL", 150},
+		{"or_i(and_v(v:pkh(A),sha256(e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855)),pk(B))", Segwit, "Bdu", "OP_IF OP_DUP OP_HASH160 1afdffb066544b96fb355120f1e6499ff349f45b OP_EQUALVERIFY OP_CHECKSIGVERIFY OP_SIZE 20 OP_EQUALVERIFY OP_SHA256 e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855 OP_EQUAL OP_ELSE <B> OP_CHECKSIG OP_ENDIF", 143},
+		{"multi_a(2,A,B,C)", Tapscript, "Bdu", "<A> OP_CHECKSIG <B> OP_CHECKSIGADD <C> OP_CHECKSIGADD 2 OP_NUMEQUAL", 133},
+		{"and_v(v:pk(<A>),pk(B))", Tapscript, "Bnu", "<A> OP_CHECKSIGVERIFY <B> OP_CHECKSIG", 132},
+	}
+	for _, test := range tests {
+		expression := withKeys(test.expression, test.ctx)
+		node, err := Parse(expression, test.ctx, resolveTestKey)
+		if err != nil {
+			t.Fatalf("Test failed: input: %s unexpected error: %v", test.expression, err)
+		}
+		if node.Type.String() != test.typ {
+			t.Errorf("Test failed: input: %s expected: %s received: %s ", test.expression, test.typ, node.Type)
+		}
+		script, err := node.Script()
+		if err != nil {
+			t.Fatalf("Test failed: input: %s unexpected error: %v", test.expression, err)
+		}
+		asm, _ := DisasmString(script)
+		if expected := withKeys(test.asm, test.ctx); asm != expected {
+			t.Errorf("Test failed: input: %s expected: %s received: %s ", test.expression, expected, asm)
+		}
+		if size := node.MaxSatisfactionSize(test.ctx); size != test.satisfy {
+			t.Errorf("Test failed: input: %s expected: %d received: %d ", test.expression, test.satisfy, size)
+		}
+		// the formatted expression parses back to the same script
+		again, err := Parse(node.String(), test.ctx, resolveTestKey)
+		if err != nil {
+			t.Fatalf("Test failed: input: %s unexpected error: %v", node.String(), err)
+		}
+		if againScript, _ := again.Script(); hex.EncodeToString(againScript) != hex.EncodeToString(script) {
+			t.Errorf("Test failed: input: %s expected: %x received: %x ", node.String(), script, againScript)
+		}
+	}
+}
+
+func TestParseInvalidMiniscript(t *testing.T) {
+	tests := []struct {
+		expression string
+		ctx        Context
+	}{
+		{"and_v(pk(A),pk(B))", Segwit},
+		{"v:pk(A)", Segwit},
+		{"and_v(v:pk(A),pk(A))", Segwit},
+		{"multi(1,A,B)", Tapscript},
+		{"multi_a(1,A,B)", Segwit},
+		{"multi(3,A,B)", Segwit},
+		{"older(0)", Segwit},
+		{"sha256(00)", Segwit},
+		{"pk(D)", Segwit},
+		{"x:pk(A)", Segwit},
+		{"or_b(pk(A),pk(B))", Segwit},
+		{"pk(A", Segwit},
+		// satisfied without a signature after 10 blocks
+		{"or_d(pk(A),older(10))", Segwit},
+		// a third party can swap the preimage branch for the timelock one
+		{"and_v(v:pk(A),or_i(older(10),sha256(e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855)))", Segwit},
+		// a height and a time lock in the same spending path
+		{"and_v(v:pk(A),and_v(v:after(100),after(500000001)))", Segwit},
+		{"thresh(3,pk(A),s:pk(B),sln:older(10),sln:older(4194305))", Segwit},
+	}
+	for _, test := range tests {
+		_, err := Parse(test.expression, test.ctx, resolveTestKey)
+		if !errors.Is(err, ErrInvalidMiniscript) {
+			t.Errorf("Test failed: input: %s expected: %v received: %v ", test.expression, ErrInvalidMiniscript, err)
+		}
+	}
+}
+
+func TestOpcodeLimit(t *testing.T) {
+	// and_v(v:pk(K1),and_v(v:pk(K2),...pk(K202))) has 202 non-push opcodes
+	var expression, policy string
+	for i := 1; i <= 202; i++ {
+		_, publicKey := btcec.PrivKeyFromBytes(btcec.S256(), []byte{byte(i >> 8), byte(i)})
+		key := hex.EncodeToString(publicKey.SerializeCompressed())
+		if i < 202 {
+			expression += "and_v(v:pk(" + key + "),"
+			policy += "and(pk(" + key + "),"
+		} else {
+			expression += "pk(" + key + ")" + strings.Repeat(")", 201)
+			policy += "pk(" + key + ")" + strings.Repeat(")", 201)
+		}
+	}
+	if _, err := Parse(expression, Segwit, resolveTestKey); !errors.Is(err, ErrInvalidMiniscript) {
+		t.Errorf("Test failed:  expected: %v received: %v ", ErrInvalidMiniscript, err)
+	}
+	if _, err := Compile(policy, Segwit, resolveTestKey); !errors.Is(err, ErrInvalidMiniscript) {
+		t.Errorf("Test failed:  expected: %v received: %v ", ErrInvalidMiniscript, err)
+	}
+	// tapscript has no opcode limit
+	if _, err := Parse(expression, Tapscript, resolveTestKey); err != nil {
+		t.Errorf("Test failed: unexpected error: %v", err)
+	}
+
+	// multi() counts its keys, 10 v:multi() of 20 keys are 210 opcodes
+	expression = ""
+	for i := 0; i < 10; i++ {
+		keys := make([]string, 20)
+		for j := range keys {
+			_, publicKey := btcec.PrivKeyFromBytes(btcec.S256(), []byte{byte(i + 1), byte(j + 1)})
+			keys[j] = hex.EncodeToString(publicKey.SerializeCompressed())
+		}
+		multi := "multi(1," + strings.Join(keys, ",") + ")"
+		if i < 9 {
+			expression += "and_v(v:" + multi + ","
+		} else {
+			expression += multi + strings.Repeat(")", 9)
+		}
+	}
+	if _, err := Parse(expression, Segwit, resolveTestKey); !errors.Is(err, ErrInvalidMiniscript) {
+		t.Errorf("Test failed:  expected: %v received: %v ", ErrInvalidMiniscript, err)
+	}
+}
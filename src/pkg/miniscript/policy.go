@@ -0,0 +1,221 @@
+package miniscript
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"btcwallet.com/src/pkg/helpers"
+)
+
+// weighted is a compiled branch of an or() with its probability weight.
+type weighted struct {
+	node   *Node
+	weight int
+}
+
+// Compile compiles a spending policy into a miniscript expression for the
+// context. Policies are written with pk(), after(), older(), the hash
+// fragments, and(), or() and thresh(), and the branches of an or() can be
+// weighted as N@policy so the likelier branch is the cheaper one to satisfy.
+//
+// The compiler is a straightforward translation rather than a search for the
+// smallest script: and() becomes and_v(), or() becomes or_d() when one side
+// can be dissatisfied and or_i() otherwise, and thresh() of keys becomes
+// multi() or multi_a().
+func Compile(policy string, ctx Context, resolve KeyResolver) (*Node, error) {
+	if ctx != Segwit && ctx != Tapscript {
+		return nil, fmt.Errorf("%w: unknown context %s", ErrInvalidMiniscript, ctx)
+	}
+	p := &parser{ctx: ctx, resolve: resolve}
+	node, err := p.compile(strings.Join(strings.Fields(policy), ""))
+	if err != nil {
+		return nil, err
+	}
+	if err := node.checkTopLevel(ctx); err != nil {
+		return nil, err
+	}
+	return node, nil
+}
+
+func (p *parser) compile(text string) (*Node, error) {
+	open := strings.Index(text, "(")
+	if open < 0 || !strings.HasSuffix(text, ")") {
+		return nil, fmt.Errorf("%w: expected policy(...) got %s", ErrInvalidMiniscript, text)
+	}
+	fragment, args, err := splitFragment(text, open)
+	if err != nil {
+		return nil, err
+	}
+
+	switch fragment {
+	case "pk", "after", "older", "sha256", "hash256", "ripemd160", "hash160":
+		// the policy leaves are written the same as their miniscript
+		return p.parse(text)
+	case "and":
+		if len(args) != 2 {
+			return nil, fmt.Errorf("%w: and() takes 2 arguments. got %d", ErrInvalidMiniscript, len(args))
+		}
+		subs, err := p.compileAll(args)
+		if err != nil {
+			return nil, err
+		}
+		return p.and(subs)
+	case "or":
+		if len(args) != 2 {
+			return nil, fmt.Errorf("%w: or() takes 2 arguments. got %d", ErrInvalidMiniscript, len(args))
+		}
+		var branches []weighted
+		for _, arg := range args {
+			weight := 1
+			if at := strings.Index(arg, "@"); at >= 0 && at < strings.Index(arg+"(", "(") {
+				weight, err = strconv.Atoi(arg[:at])
+				if err != nil || weight < 1 {
+					return nil, fmt.Errorf("%w: or() weights must be positive integers. got %s", ErrInvalidMiniscript, arg[:at])
+				}
+				arg = arg[at+1:]
+			}
+			node, err := p.compile(arg)
+			if err != nil {
+				return nil, err
+			}
+			branches = append(branches, weighted{node, weight})
+		}
+		return p.or(branches)
+	case "thresh":
+		k, err := threshold(fragment, args, len(args)-1, 0)
+		if err != nil {
+			return nil, err
+		}
+		return p.thresh(int(k), args[1:])
+	}
+	return nil, fmt.Errorf("%w: unknown policy %s", ErrInvalidMiniscript, fragment)
+}
+
+func (p *parser) compileAll(args []string) ([]*Node, error) {
+	nodes := make([]*Node, 0, len(args))
+	for _, arg := range args {
+		node, err := p.compile(arg)
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes, nil
+}
+
+// and chains the subexpressions as and_v(v:X,and_v(v:Y,Z)).
+func (p *parser) and(subs []*Node) (*Node, error) {
+	node := subs[len(subs)-1]
+	for i := len(subs) - 2; i >= 0; i-- {
+		verify, err := p.wrap('v', subs[i])
+		if err != nil {
+			return nil, err
+		}
+		if node, err = p.node(&Node{Fragment: "and_v", Subs: []*Node{verify, node}}); err != nil {
+			return nil, err
+		}
+	}
+	return node, nil
+}
+
+// or chains the branches from the likeliest, using or_d() when the earlier
+// branch is Bdu and or_i() otherwise.
+func (p *parser) or(branches []weighted) (*Node, error) {
+	sort.SliceStable(branches, func(i, j int) bool {
+		return branches[i].weight > branches[j].weight
+	})
+	node := branches[len(branches)-1].node
+	for i := len(branches) - 2; i >= 0; i-- {
+		first := branches[i].node
+		fragment := "or_i"
+		if first.Type.D && first.Type.U {
+			fragment = "or_d"
+		} else if node.Type.D && node.Type.U && i == len(branches)-2 && branches[i].weight == branches[i+1].weight {
+			// equally likely branches can swap to allow or_d()
+			first, node = node, first
+			fragment = "or_d"
+		}
+		var err error
+		if node, err = p.node(&Node{Fragment: fragment, Subs: []*Node{first, node}}); err != nil {
+			return nil, err
+		}
+	}
+	return node, nil
+}
+
+// thresh compiles a threshold as multi() or multi_a() when every argument is
+// a key, as an and() or or() chain when k is n or 1 and as thresh()
+// otherwise.
+func (p *parser) thresh(k int, args []string) (*Node, error) {
+	allKeys := true
+	keys := make([]string, 0, len(args))
+	for _, arg := range args {
+		if !strings.HasPrefix(arg, "pk(") || !strings.HasSuffix(arg, ")") {
+			allKeys = false
+			break
+		}
+		keys = append(keys, arg[len("pk("):len(arg)-1])
+	}
+	if allKeys && len(args) > 1 && (p.ctx == Tapscript || len(args) <= helpers.MaxMultisigKeys) {
+		fragment := "multi"
+		if p.ctx == Tapscript {
+			fragment = "multi_a"
+		}
+		return p.parse(fragment + "(" + strconv.Itoa(k) + "," + strings.Join(keys, ",") + ")")
+	}
+
+	subs, err := p.compileAll(args)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case len(subs) == 1:
+		return subs[0], nil
+	case k == len(subs):
+		return p.and(subs)
+	case k == 1:
+		branches := make([]weighted, len(subs))
+		for i, sub := range subs {
+			branches[i] = weighted{sub, 1}
+		}
+		return p.or(branches)
+	}
+
+	node := &Node{Fragment: "thresh", K: int64(k)}
+	for i, sub := range subs {
+		if sub, err = p.dissatisfiable(sub); err != nil {
+			return nil, err
+		}
+		if i > 0 {
+			// s: only swaps a single stack element past the expression
+			wrapper := byte('a')
+			if sub.Type.O {
+				wrapper = 's'
+			}
+			if sub, err = p.wrap(wrapper, sub); err != nil {
+				return nil, err
+			}
+		}
+		node.Subs = append(node.Subs, sub)
+	}
+	return p.node(node)
+}
+
+// dissatisfiable wraps a B expression to be Bdu as thresh() needs, with l:
+// for d and n: for u.
+func (p *parser) dissatisfiable(node *Node) (*Node, error) {
+	var err error
+	if !node.Type.D {
+		if node, err = p.wrap('l', node); err != nil {
+			return nil, err
+		}
+	}
+	if !node.Type.U {
+		if node, err = p.wrap('n', node); err != nil {
+			return nil, err
+		}
+	}
+	return node, nil
+}
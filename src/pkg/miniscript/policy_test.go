@@ -0,0 +1,54 @@
+package miniscript
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCompilePolicy(t *testing.T) {
+	tests := []struct {
+		policy   string
+		ctx      Context
+		expected string
+	}{
+		{"pk(A)", Segwit, "pk(<A>)"},
+		{"and(pk(A),older(144))", Segwit, "and_v(v:pk(<A>),older(144))"},
+		{"or(pk(A),pk(B))", Segwit, "or_d(pk(<A>),pk(<B>))"},
+		{"or(1@pk(A),9@and(pk(B),older(144)))", Segwit, "or_i(and_v(v:pk(<B>),older(144)),pk(<A>))"},
+		{"or(and(older(144),pk(B)),pk(A))", Segwit, "or_d(pk(<A>),and_v(v:older(144),pk(<B>)))"},
+		{"thresh(2,pk(A),pk(B),pk(C))", Segwit, "multi(2,<A>,<B>,<C>)"},
+		{"thresh(2,pk(A),pk(B),pk(C))", Tapscript, "multi_a(2,<A>,<B>,<C>)"},
+		{"thresh(2,pk(A),pk(B),older(10))", Segwit, "thresh(2,pk(<A>),s:pk(<B>),snl:older(10))"},
+		{"thresh(2,pk(A),pk(B),after(500000))", Tapscript, "thresh(2,pk(<A>),s:pk(<B>),snl:after(500000))"},
+	}
+	for _, test := range tests {
+		node, err := Compile(test.policy, test.ctx, resolveTestKey)
+		if err != nil {
+			t.Fatalf("Test failed: input: %s unexpected error: %v", test.policy, err)
+		}
+		if expected := withKeys(test.expected, test.ctx); node.String() != expected {
+			t.Errorf("Test failed: input: %s expected: %s received: %s ", test.policy, expected, node.String())
+		}
+	}
+}
+
+func TestCompileInvalidPolicy(t *testing.T) {
+	tests := []string{
+		"and(pk(A))",
+		"or(0@pk(A),pk(B))",
+		"thresh(4,pk(A),pk(B),pk(C))",
+		"and(pk(A),pk(A))",
+		"pk_k(A)",
+		"A",
+		// anyone can spend after the timelock
+		"or(older(144),pk(A))",
+		// no transaction has both a height and a time lock
+		"and(pk(A),and(after(100),after(500000001)))",
+	}
+	for _, test := range tests {
+		_, err := Compile(test, Segwit, resolveTestKey)
+		if !errors.Is(err, ErrInvalidMiniscript) {
+			t.Errorf("Test failed: input: %s expected: %v received: %v ", test, ErrInvalidMiniscript, err)
+		}
+	}
+}
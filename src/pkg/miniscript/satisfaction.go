@@ -0,0 +1,134 @@
+package miniscript
+
+import (
+	"sort"
+)
+
+// Witness element sizes, each with its one byte length prefix.
+const (
+	emptySize     = 1
+	oneSize       = 2
+	ecdsaSigSize  = 1 + 73
+	schnorrSize   = 1 + 65
+	preimageSize  = 1 + 32
+	pubKeySize    = 1 + 33
+	xOnlyKeySize  = 1 + 32
+	impossibleSat = -1
+)
+
+// satisfaction is the largest witness size in bytes that satisfies and
+// dissatisfies an expression, impossibleSat when it can not be done.
+type satisfaction struct {
+	sat    int
+	dissat int
+}
+
+func add(sizes ...int) int {
+	total := 0
+	for _, size := range sizes {
+		if size == impossibleSat {
+			return impossibleSat
+		}
+		total += size
+	}
+	return total
+}
+
+func max(sizes ...int) int {
+	largest := impossibleSat
+	for _, size := range sizes {
+		if size > largest {
+			largest = size
+		}
+	}
+	return largest
+}
+
+// MaxSatisfactionSize is the size in bytes of the largest witness stack that
+// satisfies the expression, without the witness script or control block.
+// Every signature is counted at its largest size.
+func (n *Node) MaxSatisfactionSize(ctx Context) int {
+	return n.satisfaction(ctx).sat
+}
+
+func (n *Node) satisfaction(ctx Context) satisfaction {
+	sigSize, keySize := ecdsaSigSize, pubKeySize
+	if ctx == Tapscript {
+		sigSize, keySize = schnorrSize, xOnlyKeySize
+	}
+	subs := make([]satisfaction, len(n.Subs))
+	for i, sub := range n.Subs {
+		subs[i] = sub.satisfaction(ctx)
+	}
+
+	switch n.Fragment {
+	case "0":
+		return satisfaction{impossibleSat, 0}
+	case "1", "older", "after":
+		return satisfaction{0, impossibleSat}
+	case "pk_k":
+		return satisfaction{sigSize, emptySize}
+	case "pk_h":
+		return satisfaction{sigSize + keySize, emptySize + keySize}
+	case "sha256", "hash256", "ripemd160", "hash160":
+		return satisfaction{preimageSize, preimageSize}
+	case "andor":
+		x, y, z := subs[0], subs[1], subs[2]
+		return satisfaction{max(add(y.sat, x.sat), add(z.sat, x.dissat)), add(z.dissat, x.dissat)}
+	case "and_v":
+		return satisfaction{add(subs[1].sat, subs[0].sat), impossibleSat}
+	case "and_b":
+		return satisfaction{add(subs[1].sat, subs[0].sat), add(subs[1].dissat, subs[0].dissat)}
+	case "or_b":
+		x, z := subs[0], subs[1]
+		return satisfaction{max(add(z.dissat, x.sat), add(z.sat, x.dissat)), add(z.dissat, x.dissat)}
+	case "or_c":
+		x, z := subs[0], subs[1]
+		return satisfaction{max(x.sat, add(z.sat, x.dissat)), impossibleSat}
+	case "or_d":
+		x, z := subs[0], subs[1]
+		return satisfaction{max(x.sat, add(z.sat, x.dissat)), add(z.dissat, x.dissat)}
+	case "or_i":
+		x, z := subs[0], subs[1]
+		return satisfaction{max(add(x.sat, oneSize), add(z.sat, emptySize)), max(add(x.dissat, oneSize), add(z.dissat, emptySize))}
+	case "thresh":
+		return thresholdSatisfaction(int(n.K), subs)
+	case "multi":
+		// the extra element is the dummy CHECKMULTISIG pops
+		return satisfaction{emptySize + int(n.K)*sigSize, emptySize + int(n.K)*emptySize}
+	case "multi_a":
+		signers := int(n.K)
+		return satisfaction{signers*sigSize + (len(n.Keys)-signers)*emptySize, len(n.Keys) * emptySize}
+	case "a", "s", "c", "n":
+		return subs[0]
+	case "d":
+		return satisfaction{add(subs[0].sat, oneSize), emptySize}
+	case "v":
+		return satisfaction{subs[0].sat, impossibleSat}
+	case "j":
+		return satisfaction{subs[0].sat, emptySize}
+	}
+	return satisfaction{impossibleSat, impossibleSat}
+}
+
+// thresholdSatisfaction satisfies the k subexpressions that add the most
+// size over their dissatisfaction and dissatisfies the others.
+func thresholdSatisfaction(k int, subs []satisfaction) satisfaction {
+	dissat := 0
+	extra := make([]int, 0, len(subs))
+	for _, sub := range subs {
+		dissat = add(dissat, sub.dissat)
+		if sub.sat != impossibleSat && sub.dissat != impossibleSat {
+			extra = append(extra, sub.sat-sub.dissat)
+		}
+	}
+	if dissat == impossibleSat || len(extra) < k {
+		return satisfaction{impossibleSat, dissat}
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(extra)))
+	sat := dissat
+	for _, size := range extra[:k] {
+		sat += size
+	}
+	return satisfaction{sat, dissat}
+}
@@ -0,0 +1,207 @@
+package miniscript
+
+import (
+	"strings"
+
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcutil"
+)
+
+// opCheckSigAdd is the BIP342 OP_CHECKSIGADD, which btcd still names
+// OP_UNKNOWN186.
+const opCheckSigAdd = txscript.OP_UNKNOWN186
+
+// hashOpcodes are the opcodes of the hash fragments.
+var hashOpcodes = map[string]byte{
+	"sha256":    txscript.OP_SHA256,
+	"hash256":   txscript.OP_HASH256,
+	"ripemd160": txscript.OP_RIPEMD160,
+	"hash160":   txscript.OP_HASH160,
+}
+
+// verifyOpcodes are the opcodes v: merges with a VERIFY.
+var verifyOpcodes = map[byte]byte{
+	txscript.OP_EQUAL:         txscript.OP_EQUALVERIFY,
+	txscript.OP_CHECKSIG:      txscript.OP_CHECKSIGVERIFY,
+	txscript.OP_CHECKMULTISIG: txscript.OP_CHECKMULTISIGVERIFY,
+	txscript.OP_NUMEQUAL:      txscript.OP_NUMEQUALVERIFY,
+}
+
+// maxOpcodes is the consensus limit of non-push opcodes in a witness script,
+// tapscript has none.
+const maxOpcodes = 201
+
+// opcodeCount counts the non-push opcodes of the witness script, and the keys
+// of every multi() as CHECKMULTISIG adds them to the count when it runs. The
+// count covers every branch, so it bounds the count of any spending path.
+func (n *Node) opcodeCount() (int, error) {
+	script, err := n.Script()
+	if err != nil {
+		return 0, err
+	}
+	asm, err := txscript.DisasmString(script)
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	for _, token := range strings.Fields(asm) {
+		// pushes disassemble as numbers and hex data
+		if strings.HasPrefix(token, "OP_") {
+			count++
+		}
+	}
+	var multiKeys func(*Node) int
+	multiKeys = func(node *Node) int {
+		keys := 0
+		if node.Fragment == "multi" {
+			keys = len(node.Keys)
+		}
+		for _, sub := range node.Subs {
+			keys += multiKeys(sub)
+		}
+		return keys
+	}
+	return count + multiKeys(n), nil
+}
+
+// Script encodes the expression as a witness script or a tapscript leaf.
+func (n *Node) Script() ([]byte, error) {
+	builder := txscript.NewScriptBuilder()
+	n.encode(builder)
+	return builder.Script()
+}
+
+func (n *Node) encode(b *txscript.ScriptBuilder) {
+	switch n.Fragment {
+	case "0":
+		b.AddOp(txscript.OP_0)
+	case "1":
+		b.AddOp(txscript.OP_1)
+	case "pk_k":
+		b.AddData(n.Keys[0])
+	case "pk_h":
+		b.AddOp(txscript.OP_DUP).AddOp(txscript.OP_HASH160).AddData(btcutil.Hash160(n.Keys[0])).AddOp(txscript.OP_EQUALVERIFY)
+	case "older":
+		b.AddInt64(n.K).AddOp(txscript.OP_CHECKSEQUENCEVERIFY)
+	case "after":
+		b.AddInt64(n.K).AddOp(txscript.OP_CHECKLOCKTIMEVERIFY)
+	case "sha256", "hash256", "ripemd160", "hash160":
+		b.AddOp(txscript.OP_SIZE).AddInt64(32).AddOp(txscript.OP_EQUALVERIFY).AddOp(hashOpcodes[n.Fragment]).AddData(n.Hash).AddOp(txscript.OP_EQUAL)
+	case "andor":
+		n.Subs[0].encode(b)
+		b.AddOp(txscript.OP_NOTIF)
+		n.Subs[2].encode(b)
+		b.AddOp(txscript.OP_ELSE)
+		n.Subs[1].encode(b)
+		b.AddOp(txscript.OP_ENDIF)
+	case "and_v":
+		n.Subs[0].encode(b)
+		n.Subs[1].encode(b)
+	case "and_b":
+		n.Subs[0].encode(b)
+		n.Subs[1].encode(b)
+		b.AddOp(txscript.OP_BOOLAND)
+	case "or_b":
+		n.Subs[0].encode(b)
+		n.Subs[1].encode(b)
+		b.AddOp(txscript.OP_BOOLOR)
+	case "or_c":
+		n.Subs[0].encode(b)
+		b.AddOp(txscript.OP_NOTIF)
+		n.Subs[1].encode(b)
+		b.AddOp(txscript.OP_ENDIF)
+	case "or_d":
+		n.Subs[0].encode(b)
+		b.AddOp(txscript.OP_IFDUP).AddOp(txscript.OP_NOTIF)
+		n.Subs[1].encode(b)
+		b.AddOp(txscript.OP_ENDIF)
+	case "or_i":
+		b.AddOp(txscript.OP_IF)
+		n.Subs[0].encode(b)
+		b.AddOp(txscript.OP_ELSE)
+		n.Subs[1].encode(b)
+		b.AddOp(txscript.OP_ENDIF)
+	case "thresh":
+		for i, sub := range n.Subs {
+			sub.encode(b)
+			if i > 0 {
+				b.AddOp(txscript.OP_ADD)
+			}
+		}
+		b.AddInt64(n.K).AddOp(txscript.OP_EQUAL)
+	case "multi":
+		b.AddInt64(n.K)
+		for _, key := range n.Keys {
+			b.AddData(key)
+		}
+		b.AddInt64(int64(len(n.Keys))).AddOp(txscript.OP_CHECKMULTISIG)
+	case "multi_a":
+		for i, key := range n.Keys {
+			b.AddData(key)
+			if i == 0 {
+				b.AddOp(txscript.OP_CHECKSIG)
+			} else {
+				b.AddOp(opCheckSigAdd)
+			}
+		}
+		b.AddInt64(n.K).AddOp(txscript.OP_NUMEQUAL)
+	case "a":
+		b.AddOp(txscript.OP_TOALTSTACK)
+		n.Subs[0].encode(b)
+		b.AddOp(txscript.OP_FROMALTSTACK)
+	case "s":
+		b.AddOp(txscript.OP_SWAP)
+		n.Subs[0].encode(b)
+	case "c":
+		n.Subs[0].encode(b)
+		b.AddOp(txscript.OP_CHECKSIG)
+	case "d":
+		b.AddOp(txscript.OP_DUP).AddOp(txscript.OP_IF)
+		n.Subs[0].encode(b)
+		b.AddOp(txscript.OP_ENDIF)
+	case "v":
+		// the VERIFY merges into the last opcode of the expression when it
+		// has a VERIFY form
+		if op, ok := verifyOpcodes[n.Subs[0].lastOpcode()]; ok {
+			sub := txscript.NewScriptBuilder()
+			n.Subs[0].encode(sub)
+			script, _ := sub.Script()
+			b.AddOps(script[:len(script)-1]).AddOp(op)
+			return
+		}
+		n.Subs[0].encode(b)
+		b.AddOp(txscript.OP_VERIFY)
+	case "j":
+		b.AddOp(txscript.OP_SIZE).AddOp(txscript.OP_0NOTEQUAL).AddOp(txscript.OP_IF)
+		n.Subs[0].encode(b)
+		b.AddOp(txscript.OP_ENDIF)
+	case "n":
+		n.Subs[0].encode(b)
+		b.AddOp(txscript.OP_0NOTEQUAL)
+	}
+}
+
+// DisasmString disassembles a script like txscript.DisasmString, naming
+// OP_CHECKSIGADD which btcd does not know.
+func DisasmString(script []byte) (string, error) {
+	asm, err := txscript.DisasmString(script)
+	return strings.ReplaceAll(asm, "OP_UNKNOWN186", "OP_CHECKSIGADD"), err
+}
+
+// lastOpcode is the opcode a B expression ends with, 0 when it ends with
+// another expression's push.
+func (n *Node) lastOpcode() byte {
+	switch n.Fragment {
+	case "c":
+		return txscript.OP_CHECKSIG
+	case "multi":
+		return txscript.OP_CHECKMULTISIG
+	case "multi_a":
+		return txscript.OP_NUMEQUAL
+	case "sha256", "hash256", "ripemd160", "hash160", "thresh":
+		return txscript.OP_EQUAL
+	case "and_v":
+		return n.Subs[1].lastOpcode()
+	}
+	return 0
+}
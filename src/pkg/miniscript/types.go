@@ -0,0 +1,351 @@
+package miniscript
+
+import (
+	"fmt"
+)
+
+// Type is the miniscript type of an expression: its basic type B, V, K or W
+// and the z, o, n, d and u properties of the correctness type system, along
+// with the malleability and timelock properties.
+type Type struct {
+	Base byte
+	// Z consumes no stack elements, O exactly one.
+	Z bool
+	O bool
+	// N has a non zero top stack element when satisfied.
+	N bool
+	// D can be dissatisfied, U leaves exactly 1 when satisfied.
+	D bool
+	U bool
+
+	// S needs a signature for every satisfaction, F for every
+	// dissatisfaction. E has a unique dissatisfaction without a signature.
+	S bool
+	F bool
+	E bool
+	// M can always be satisfied without a third party malleating the
+	// witness.
+	M bool
+
+	// G and H are relative time and height locks, I and J absolute time and
+	// height locks. K never needs a height and a time lock of the same kind
+	// in one spending path, which no transaction can satisfy.
+	G bool
+	H bool
+	I bool
+	J bool
+	K bool
+}
+
+// String formats a type as its basic type followed by its correctness
+// properties, such as Bondu.
+func (t Type) String() string {
+	s := string(t.Base)
+	for _, property := range []struct {
+		set  bool
+		name string
+	}{{t.Z, "z"}, {t.O, "o"}, {t.N, "n"}, {t.D, "d"}, {t.U, "u"}} {
+		if property.set {
+			s += property.name
+		}
+	}
+	return s
+}
+
+func (t Type) is(base byte) bool {
+	return t.Base == base
+}
+
+// typeCheck computes the type of a node from the types of its subexpressions
+// and rejects the subexpressions of the wrong type.
+func (n *Node) typeCheck(ctx Context) error {
+	sub := func(i int) Type {
+		return n.Subs[i].Type
+	}
+	fail := func(rule string) error {
+		if len(n.Fragment) == 1 {
+			return fmt.Errorf("%w: %s: %s", ErrInvalidMiniscript, n.Fragment, rule)
+		}
+		return fmt.Errorf("%w: %s() %s", ErrInvalidMiniscript, n.Fragment, rule)
+	}
+
+	switch n.Fragment {
+	case "0":
+		n.Type = Type{Base: 'B', Z: true, U: true, D: true}
+	case "1":
+		n.Type = Type{Base: 'B', Z: true, U: true}
+	case "pk_k":
+		n.Type = Type{Base: 'K', O: true, N: true, D: true, U: true}
+	case "pk_h":
+		n.Type = Type{Base: 'K', N: true, D: true, U: true}
+	case "older", "after":
+		n.Type = Type{Base: 'B', Z: true}
+	case "sha256", "hash256", "ripemd160", "hash160":
+		n.Type = Type{Base: 'B', O: true, N: true, D: true, U: true}
+	case "multi", "multi_a":
+		n.Type = Type{Base: 'B', N: n.Fragment == "multi", D: true, U: true}
+
+	case "andor":
+		x, y, z := sub(0), sub(1), sub(2)
+		if !x.is('B') || !x.D || !x.U {
+			return fail("needs a Bdu first argument")
+		}
+		if y.Base != z.Base || y.is('W') {
+			return fail("needs second and third arguments of the same B, K or V type")
+		}
+		n.Type = Type{
+			Base: y.Base,
+			Z:    x.Z && y.Z && z.Z,
+			O:    (x.Z && y.O && z.O) || (x.O && y.Z && z.Z),
+			U:    y.U && z.U,
+			D:    z.D,
+		}
+	case "and_v":
+		x, y := sub(0), sub(1)
+		if !x.is('V') || y.is('W') {
+			return fail("needs a V first argument and a B, K or V second argument")
+		}
+		n.Type = Type{
+			Base: y.Base,
+			Z:    x.Z && y.Z,
+			O:    (x.Z && y.O) || (x.O && y.Z),
+			N:    x.N || (x.Z && y.N),
+			U:    y.U,
+		}
+	case "and_b":
+		x, y := sub(0), sub(1)
+		if !x.is('B') || !y.is('W') {
+			return fail("needs a B first argument and a W second argument")
+		}
+		n.Type = Type{
+			Base: 'B',
+			Z:    x.Z && y.Z,
+			O:    (x.Z && y.O) || (x.O && y.Z),
+			N:    x.N || (x.Z && y.N),
+			D:    x.D && y.D,
+			U:    true,
+		}
+	case "or_b":
+		x, z := sub(0), sub(1)
+		if !x.is('B') || !x.D || !z.is('W') || !z.D {
+			return fail("needs a Bd first argument and a Wd second argument")
+		}
+		n.Type = Type{
+			Base: 'B',
+			Z:    x.Z && z.Z,
+			O:    (x.Z && z.O) || (x.O && z.Z),
+			D:    true,
+			U:    true,
+		}
+	case "or_c":
+		x, z := sub(0), sub(1)
+		if !x.is('B') || !x.D || !x.U || !z.is('V') {
+			return fail("needs a Bdu first argument and a V second argument")
+		}
+		n.Type = Type{Base: 'V', Z: x.Z && z.Z, O: x.O && z.Z}
+	case "or_d":
+		x, z := sub(0), sub(1)
+		if !x.is('B') || !x.D || !x.U || !z.is('B') {
+			return fail("needs a Bdu first argument and a B second argument")
+		}
+		n.Type = Type{Base: 'B', Z: x.Z && z.Z, O: x.O && z.Z, D: z.D, U: z.U}
+	case "or_i":
+		x, z := sub(0), sub(1)
+		if x.Base != z.Base || x.is('W') {
+			return fail("needs two arguments of the same B, K or V type")
+		}
+		n.Type = Type{Base: x.Base, O: x.Z && z.Z, D: x.D || z.D, U: x.U && z.U}
+	case "thresh":
+		zs, os := 0, 0
+		for i, s := range n.Subs {
+			if (i == 0 && !s.Type.is('B')) || (i > 0 && !s.Type.is('W')) || !s.Type.D || !s.Type.U {
+				return fail("needs a Bdu first argument and Wdu other arguments")
+			}
+			if s.Type.Z {
+				zs++
+			}
+			if s.Type.O {
+				os++
+			}
+		}
+		n.Type = Type{Base: 'B', Z: zs == len(n.Subs), O: os == 1 && zs == len(n.Subs)-1, D: true, U: true}
+
+	case "a":
+		x := sub(0)
+		if !x.is('B') {
+			return fail("needs a B argument")
+		}
+		n.Type = Type{Base: 'W', D: x.D, U: x.U}
+	case "s":
+		x := sub(0)
+		if !x.is('B') || !x.O {
+			return fail("needs a Bo argument")
+		}
+		n.Type = Type{Base: 'W', D: x.D, U: x.U}
+	case "c":
+		x := sub(0)
+		if !x.is('K') {
+			return fail("needs a K argument")
+		}
+		n.Type = Type{Base: 'B', O: x.O, N: x.N, D: x.D, U: true}
+	case "d":
+		x := sub(0)
+		if !x.is('V') || !x.Z {
+			return fail("needs a Vz argument")
+		}
+		// MINIMALIF is only consensus in tapscript
+		n.Type = Type{Base: 'B', O: true, N: true, D: true, U: ctx == Tapscript}
+	case "v":
+		x := sub(0)
+		if !x.is('B') {
+			return fail("needs a B argument")
+		}
+		n.Type = Type{Base: 'V', Z: x.Z, O: x.O, N: x.N}
+	case "j":
+		x := sub(0)
+		if !x.is('B') || !x.N {
+			return fail("needs a Bn argument")
+		}
+		n.Type = Type{Base: 'B', O: x.O, N: true, D: true, U: x.U}
+	case "n":
+		x := sub(0)
+		if !x.is('B') {
+			return fail("needs a B argument")
+		}
+		n.Type = Type{Base: 'B', Z: x.Z, O: x.O, N: x.N, D: x.D, U: true}
+	default:
+		return fmt.Errorf("%w: unknown fragment %s", ErrInvalidMiniscript, n.Fragment)
+	}
+	n.malleability()
+	n.timelocks()
+	return nil
+}
+
+// malleability computes the s, f, e and m properties from the ones of the
+// subexpressions.
+func (n *Node) malleability() {
+	t := &n.Type
+	sub := func(i int) Type {
+		return n.Subs[i].Type
+	}
+
+	switch n.Fragment {
+	case "0", "pk_k", "pk_h", "multi", "multi_a":
+		t.S, t.E, t.M = true, true, true
+	case "1":
+		t.S, t.F, t.M = true, true, true
+	case "older", "after":
+		t.F, t.M = true, true
+	case "sha256", "hash256", "ripemd160", "hash160":
+		t.M = true
+
+	case "andor":
+		x, y, z := sub(0), sub(1), sub(2)
+		t.S = z.S && (x.S || y.S)
+		t.F = z.F && (x.S || y.F)
+		t.E = z.E && (x.S || y.F)
+		t.M = x.M && y.M && z.M && x.E && (x.S || y.S || z.S)
+	case "and_v":
+		x, y := sub(0), sub(1)
+		t.S = x.S || y.S
+		t.F = x.S || y.F
+		t.M = x.M && y.M
+	case "and_b":
+		x, y := sub(0), sub(1)
+		t.S = x.S || y.S
+		t.F = (x.F && y.F) || (x.S && x.F) || (y.S && y.F)
+		t.E = x.E && y.E && x.S && y.S
+		t.M = x.M && y.M
+	case "or_b":
+		x, z := sub(0), sub(1)
+		t.S = x.S && z.S
+		t.E = x.E && z.E
+		t.M = x.M && z.M && x.E && z.E && (x.S || z.S)
+	case "or_c":
+		x, z := sub(0), sub(1)
+		t.S = x.S && z.S
+		t.F = true
+		t.M = x.M && z.M && x.E && (x.S || z.S)
+	case "or_d":
+		x, z := sub(0), sub(1)
+		t.S = x.S && z.S
+		t.F = z.F
+		t.E = x.E && z.E
+		t.M = x.M && z.M && x.E && (x.S || z.S)
+	case "or_i":
+		x, z := sub(0), sub(1)
+		t.S = x.S && z.S
+		t.F = x.F && z.F
+		t.E = (x.E && z.F) || (z.E && x.F)
+		t.M = x.M && z.M && (x.S || z.S)
+	case "thresh":
+		// a satisfaction picks k of the subexpressions, it needs a
+		// signature when fewer than k of them can do without one
+		signed, e, m := 0, true, true
+		for _, s := range n.Subs {
+			if s.Type.S {
+				signed++
+			}
+			e = e && s.Type.E
+			m = m && s.Type.M
+		}
+		t.S = signed >= len(n.Subs)-int(n.K)+1
+		t.E = e && signed == len(n.Subs)
+		t.M = e && m && signed >= len(n.Subs)-int(n.K)
+
+	case "a", "s", "c", "n":
+		x := sub(0)
+		t.S, t.F, t.E, t.M = x.S, x.F, x.E, x.M
+	case "d":
+		x := sub(0)
+		t.S, t.E, t.M = x.S, true, x.M
+	case "v":
+		x := sub(0)
+		t.S, t.F, t.M = x.S, true, x.M
+	case "j":
+		x := sub(0)
+		t.S, t.E, t.M = x.S, x.F, x.M
+	}
+}
+
+// sequenceLockTimeTypeFlag marks a relative time lock in older().
+const sequenceLockTimeTypeFlag = 1 << 22
+
+// lockTimeThreshold is the first after() value that is a time.
+const lockTimeThreshold = 500000000
+
+// timelocks computes the g, h, i, j and k properties from the ones of the
+// subexpressions.
+func (n *Node) timelocks() {
+	t := &n.Type
+	switch n.Fragment {
+	case "older":
+		t.G, t.H = n.K&sequenceLockTimeTypeFlag != 0, n.K&sequenceLockTimeTypeFlag == 0
+	case "after":
+		t.I, t.J = n.K >= lockTimeThreshold, n.K < lockTimeThreshold
+	}
+	t.K = true
+	for _, sub := range n.Subs {
+		t.G, t.H, t.I, t.J = t.G || sub.Type.G, t.H || sub.Type.H, t.I || sub.Type.I, t.J || sub.Type.J
+		t.K = t.K && sub.Type.K
+	}
+
+	// the subexpressions that are all satisfied together must not mix
+	// heights and times
+	switch n.Fragment {
+	case "and_v", "and_b", "andor":
+		t.K = t.K && !timelockConflict(n.Subs[0].Type, n.Subs[1].Type)
+	case "thresh":
+		for i := 0; n.K > 1 && i < len(n.Subs); i++ {
+			for j := i + 1; j < len(n.Subs); j++ {
+				t.K = t.K && !timelockConflict(n.Subs[i].Type, n.Subs[j].Type)
+			}
+		}
+	}
+}
+
+// timelockConflict reports whether x and y use a height and a time lock of
+// the same kind.
+func timelockConflict(x Type, y Type) bool {
+	return (x.G && y.H) || (x.H && y.G) || (x.I && y.J) || (x.J && y.I)
+}
@@ -0,0 +1,160 @@
+package psbt
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// Combine merges PSBTs of the same unsigned transaction into one with the
+// fields of all of them, as the BIP174 combiner does, so the partial
+// signatures of cosigners that signed separately end up in one PSBT. A field
+// several PSBTs have keeps the value of the first one, except that they must
+// agree on the UTXOs and scripts of the inputs.
+func Combine(packets ...*Packet) (*Packet, error) {
+	if len(packets) == 0 {
+		return nil, fmt.Errorf("%w: no psbt to combine", ErrInvalidPsbt)
+	}
+	first := packets[0]
+	txid := first.UnsignedTx.TxHash()
+	for i, p := range packets[1:] {
+		if p.UnsignedTx.TxHash() != txid {
+			return nil, fmt.Errorf("%w: psbt %d is of transaction %s, psbt 0 of %s", ErrInvalidPsbt, i+1, p.UnsignedTx.TxHash(), txid)
+		}
+	}
+
+	combined := &Packet{
+		UnsignedTx: first.UnsignedTx.Copy(),
+		Inputs:     make([]Input, len(first.Inputs)),
+		Outputs:    make([]Output, len(first.Outputs)),
+	}
+	for n, p := range packets {
+		combined.Unknowns = mergeUnknowns(combined.Unknowns, p.Unknowns)
+		for i, in := range p.Inputs {
+			if err := combined.Inputs[i].merge(in); err != nil {
+				return nil, fmt.Errorf("%w: psbt %d input %d: %v", ErrInvalidPsbt, n, i, err)
+			}
+		}
+		for i, out := range p.Outputs {
+			combined.Outputs[i].merge(out)
+		}
+	}
+	return combined, nil
+}
+
+// merge adds the fields of another map of the input.
+func (in *Input) merge(other Input) error {
+	if in.NonWitnessUtxo == nil {
+		in.NonWitnessUtxo = other.NonWitnessUtxo
+	}
+	if in.WitnessUtxo == nil {
+		in.WitnessUtxo = other.WitnessUtxo
+	} else if other.WitnessUtxo != nil && (in.WitnessUtxo.Value != other.WitnessUtxo.Value || !bytes.Equal(in.WitnessUtxo.PkScript, other.WitnessUtxo.PkScript)) {
+		return fmt.Errorf("the witness utxo differs")
+	}
+	for _, field := range []struct {
+		name  string
+		value *[]byte
+		other []byte
+	}{
+		{"redeem script", &in.RedeemScript, other.RedeemScript},
+		{"witness script", &in.WitnessScript, other.WitnessScript},
+		{"taproot internal key", &in.TaprootInternalKey, other.TaprootInternalKey},
+		{"taproot merkle root", &in.TaprootMerkleRoot, other.TaprootMerkleRoot},
+	} {
+		if *field.value == nil {
+			*field.value = field.other
+		} else if field.other != nil && !bytes.Equal(*field.value, field.other) {
+			return fmt.Errorf("the %s differs", field.name)
+		}
+	}
+	if in.SighashType == 0 {
+		in.SighashType = other.SighashType
+	}
+	if in.FinalScriptSig == nil {
+		in.FinalScriptSig = other.FinalScriptSig
+	}
+	if in.FinalScriptWitness == nil {
+		in.FinalScriptWitness = other.FinalScriptWitness
+	}
+	if in.TaprootKeySig == nil {
+		in.TaprootKeySig = other.TaprootKeySig
+	}
+
+	for _, sig := range other.PartialSigs {
+		if !hasKey(len(in.PartialSigs), func(i int) []byte { return in.PartialSigs[i].PubKey }, sig.PubKey) {
+			in.PartialSigs = append(in.PartialSigs, sig)
+		}
+	}
+	for _, derivation := range other.Bip32Derivation {
+		if !hasKey(len(in.Bip32Derivation), func(i int) []byte { return in.Bip32Derivation[i].PubKey }, derivation.PubKey) {
+			in.Bip32Derivation = append(in.Bip32Derivation, derivation)
+		}
+	}
+	for _, sig := range other.TaprootScriptSigs {
+		key := append(append([]byte{}, sig.XOnlyPubKey...), sig.LeafHash...)
+		if !hasKey(len(in.TaprootScriptSigs), func(i int) []byte {
+			return append(append([]byte{}, in.TaprootScriptSigs[i].XOnlyPubKey...), in.TaprootScriptSigs[i].LeafHash...)
+		}, key) {
+			in.TaprootScriptSigs = append(in.TaprootScriptSigs, sig)
+		}
+	}
+	for _, leaf := range other.TaprootLeafScripts {
+		if !hasKey(len(in.TaprootLeafScripts), func(i int) []byte { return in.TaprootLeafScripts[i].ControlBlock }, leaf.ControlBlock) {
+			in.TaprootLeafScripts = append(in.TaprootLeafScripts, leaf)
+		}
+	}
+	for _, derivation := range other.TaprootBip32Derivation {
+		if !hasKey(len(in.TaprootBip32Derivation), func(i int) []byte { return in.TaprootBip32Derivation[i].XOnlyPubKey }, derivation.XOnlyPubKey) {
+			in.TaprootBip32Derivation = append(in.TaprootBip32Derivation, derivation)
+		}
+	}
+	in.Unknowns = mergeUnknowns(in.Unknowns, other.Unknowns)
+	return nil
+}
+
+// merge adds the fields of another map of the output.
+func (out *Output) merge(other Output) {
+	for _, field := range []struct {
+		value *[]byte
+		other []byte
+	}{
+		{&out.RedeemScript, other.RedeemScript},
+		{&out.WitnessScript, other.WitnessScript},
+		{&out.TaprootInternalKey, other.TaprootInternalKey},
+		{&out.TaprootTree, other.TaprootTree},
+	} {
+		if *field.value == nil {
+			*field.value = field.other
+		}
+	}
+	for _, derivation := range other.Bip32Derivation {
+		if !hasKey(len(out.Bip32Derivation), func(i int) []byte { return out.Bip32Derivation[i].PubKey }, derivation.PubKey) {
+			out.Bip32Derivation = append(out.Bip32Derivation, derivation)
+		}
+	}
+	for _, derivation := range other.TaprootBip32Derivation {
+		if !hasKey(len(out.TaprootBip32Derivation), func(i int) []byte { return out.TaprootBip32Derivation[i].XOnlyPubKey }, derivation.XOnlyPubKey) {
+			out.TaprootBip32Derivation = append(out.TaprootBip32Derivation, derivation)
+		}
+	}
+	out.Unknowns = mergeUnknowns(out.Unknowns, other.Unknowns)
+}
+
+func mergeUnknowns(unknowns []Unknown, other []Unknown) []Unknown {
+	for _, unknown := range other {
+		if !hasKey(len(unknowns), func(i int) []byte { return unknowns[i].Key }, unknown.Key) {
+			unknowns = append(unknowns, unknown)
+		}
+	}
+	return unknowns
+}
+
+// hasKey reports whether one of the n keys is the key.
+func hasKey(n int, keyAt func(i int) []byte, key []byte) bool {
+	for i := 0; i < n; i++ {
+		if bytes.Equal(keyAt(i), key) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,203 @@
+package psbt
+
+import (
+	"bytes"
+	"fmt"
+
+	"btcwallet.com/src/pkg/helpers"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+)
+
+// FinalizeInput builds the final scriptSig and witness of input i from its
+// signatures, the BIP174 finalizer, and clears the fields only signers need.
+// Multisig signatures are ordered as the keys of the redeem or witness script
+// and the first of them the script requires are used. The final input must
+// pass script verification, a Taproot key path signature is checked against
+// the output key. An input that is already final is left as it is.
+func (p *Packet) FinalizeInput(i int) error {
+	if i < 0 || i >= len(p.Inputs) {
+		return fmt.Errorf("%w: no input %d", ErrInvalidPsbt, i)
+	}
+	in := &p.Inputs[i]
+	if in.IsFinal() {
+		return nil
+	}
+	utxo, err := p.Utxo(i)
+	if err != nil {
+		return err
+	}
+	script, subScript, witness, err := p.inputScripts(i)
+	if err != nil {
+		return err
+	}
+
+	var scriptSig []byte
+	var stack wire.TxWitness
+	if isTaproot(script) {
+		if err := p.verifyTaprootKeySig(i, script[2:]); err != nil {
+			return err
+		}
+		stack = wire.TxWitness{in.TaprootKeySig}
+	} else {
+		items, err := satisfy(subScript, in.PartialSigs)
+		if err != nil {
+			return err
+		}
+		if witness {
+			stack = items
+			if !bytes.Equal(subScript, script) {
+				stack = append(stack, subScript)
+			}
+		} else {
+			builder := txscript.NewScriptBuilder()
+			for _, item := range items {
+				builder.AddData(item)
+			}
+			if scriptSig, err = builder.Script(); err != nil {
+				return err
+			}
+		}
+		if txscript.IsPayToScriptHash(utxo.PkScript) {
+			redeemPush, err := txscript.NewScriptBuilder().AddData(in.RedeemScript).Script()
+			if err != nil {
+				return err
+			}
+			scriptSig = append(scriptSig, redeemPush...)
+		}
+		if err := p.verifyInput(i, utxo, scriptSig, stack); err != nil {
+			return err
+		}
+	}
+
+	*in = Input{
+		NonWitnessUtxo:     in.NonWitnessUtxo,
+		WitnessUtxo:        in.WitnessUtxo,
+		FinalScriptSig:     scriptSig,
+		FinalScriptWitness: stack,
+		Unknowns:           in.Unknowns,
+	}
+	return nil
+}
+
+// satisfy returns the stack that spends the script with the partial
+// signatures: the signature and key of a key hash, the signature of a bare
+// key or the signatures of a multisig script in key order.
+func satisfy(script []byte, partialSigs []PartialSig) ([][]byte, error) {
+	signature := func(pubKey []byte) []byte {
+		for _, sig := range partialSigs {
+			if bytes.Equal(sig.PubKey, pubKey) {
+				return sig.Signature
+			}
+		}
+		return nil
+	}
+
+	class := txscript.GetScriptClass(script)
+	if _, _, err := helpers.DecodeMultisignatureScript(script); err == nil {
+		// txscript does not classify the scripts of 17 to 20 keys
+		class = txscript.MultiSigTy
+	}
+	switch class {
+	case txscript.PubKeyHashTy, txscript.WitnessV0PubKeyHashTy:
+		pushes, err := txscript.PushedData(script)
+		if err != nil {
+			return nil, err
+		}
+		// the OP_0 of a witness program is an empty push
+		keyHash := pushes[len(pushes)-1]
+		for _, sig := range partialSigs {
+			if bytes.Equal(btcutil.Hash160(sig.PubKey), keyHash) {
+				return [][]byte{sig.Signature, sig.PubKey}, nil
+			}
+		}
+		return nil, fmt.Errorf("no signature of the key hash %x", keyHash)
+	case txscript.PubKeyTy:
+		pushes, err := txscript.PushedData(script)
+		if err != nil {
+			return nil, err
+		}
+		if sig := signature(pushes[0]); sig != nil {
+			return [][]byte{sig}, nil
+		}
+		return nil, fmt.Errorf("no signature of the key %x", pushes[0])
+	case txscript.MultiSigTy:
+		required, pubKeys, err := helpers.DecodeMultisignatureScript(script)
+		if err != nil {
+			return nil, err
+		}
+		// the extra item CHECKMULTISIG pops
+		items := [][]byte{{}}
+		for _, pubKey := range pubKeys {
+			if sig := signature(pubKey); sig != nil && len(items) <= required {
+				items = append(items, sig)
+			}
+		}
+		if len(items)-1 < required {
+			return nil, fmt.Errorf("%d of the %d signatures the multisig script requires", len(items)-1, required)
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("can not finalize a script of type %s", class)
+	}
+}
+
+// verifyInput executes the scripts of input i with the final scriptSig and
+// witness.
+func (p *Packet) verifyInput(i int, utxo *wire.TxOut, scriptSig []byte, witness wire.TxWitness) error {
+	tx := p.UnsignedTx.Copy()
+	tx.TxIn[i].SignatureScript, tx.TxIn[i].Witness = scriptSig, witness
+	vm, err := txscript.NewEngine(utxo.PkScript, tx, i, txscript.StandardVerifyFlags, nil, nil, utxo.Value)
+	if err == nil {
+		err = vm.Execute()
+	}
+	if err != nil {
+		return fmt.Errorf("the signatures do not verify: %v", err)
+	}
+	return nil
+}
+
+// verifyTaprootKeySig checks the key path signature of input i against the
+// output key.
+func (p *Packet) verifyTaprootKeySig(i int, outputKey []byte) error {
+	in := p.Inputs[i]
+	if in.TaprootKeySig == nil {
+		return fmt.Errorf("no taproot key path signature")
+	}
+	signature, hashType := in.TaprootKeySig, sigHashDefault
+	if len(signature) == 65 {
+		signature, hashType = signature[:64], txscript.SigHashType(signature[64])
+		if hashType == sigHashDefault {
+			return fmt.Errorf("a 65 byte taproot signature can not use SIGHASH_DEFAULT")
+		}
+	}
+	hash, err := p.TaprootSigHash(i, hashType)
+	if err != nil {
+		return err
+	}
+	if !helpers.SchnorrVerify(outputKey, hash, signature) {
+		return fmt.Errorf("the taproot key path signature does not verify")
+	}
+	return nil
+}
+
+// IsFinal reports whether the input has its final scriptSig or witness.
+func (in *Input) IsFinal() bool {
+	return in.FinalScriptSig != nil || in.FinalScriptWitness != nil
+}
+
+// Extract returns the signed transaction of a PSBT whose inputs are all
+// final, the BIP174 transaction extractor.
+func (p *Packet) Extract() (*wire.MsgTx, error) {
+	tx := p.UnsignedTx.Copy()
+	for i := range p.Inputs {
+		in := p.Inputs[i]
+		if !in.IsFinal() {
+			return nil, fmt.Errorf("%w: input %d is not finalized", ErrInvalidPsbt, i)
+		}
+		tx.TxIn[i].SignatureScript = in.FinalScriptSig
+		tx.TxIn[i].Witness = in.FinalScriptWitness
+	}
+	return tx, nil
+}
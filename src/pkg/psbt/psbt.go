@@ -0,0 +1,620 @@
+// Package psbt reads and writes BIP174 partially signed bitcoin transactions,
+// with the BIP371 Taproot fields.
+package psbt
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"btcwallet.com/src/pkg/helpers"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// ErrInvalidPsbt is returned for a partially signed transaction that can not
+// be parsed or built from its inputs.
+var ErrInvalidPsbt = errors.New("invalid psbt")
+
+// magic are the bytes every PSBT starts with, "psbt" and 0xff.
+var magic = []byte{0x70, 0x73, 0x62, 0x74, 0xff}
+
+// maxFieldSize bounds a single key or value, no valid field comes close.
+const maxFieldSize = 4000000
+
+// Global, input and output key types.
+const (
+	globalUnsignedTx = 0x00
+
+	inNonWitnessUtxo         = 0x00
+	inWitnessUtxo            = 0x01
+	inPartialSig             = 0x02
+	inSighashType            = 0x03
+	inRedeemScript           = 0x04
+	inWitnessScript          = 0x05
+	inBip32Derivation        = 0x06
+	inFinalScriptSig         = 0x07
+	inFinalScriptWitness     = 0x08
+	inTaprootKeySig          = 0x13
+	inTaprootScriptSig       = 0x14
+	inTaprootLeafScript      = 0x15
+	inTaprootBip32Derivation = 0x16
+	inTaprootInternalKey     = 0x17
+	inTaprootMerkleRoot      = 0x18
+
+	outRedeemScript           = 0x00
+	outWitnessScript          = 0x01
+	outBip32Derivation        = 0x02
+	outTaprootInternalKey     = 0x05
+	outTaprootTree            = 0x06
+	outTaprootBip32Derivation = 0x07
+)
+
+// Packet is a PSBT: the unsigned transaction and a map of fields for every
+// input and output. Fields the package does not know are kept as they are.
+type Packet struct {
+	UnsignedTx *wire.MsgTx
+	Unknowns   []Unknown
+	Inputs     []Input
+	Outputs    []Output
+}
+
+// Input are the fields of a transaction input.
+type Input struct {
+	NonWitnessUtxo         *wire.MsgTx
+	WitnessUtxo            *wire.TxOut
+	PartialSigs            []PartialSig
+	SighashType            uint32
+	RedeemScript           []byte
+	WitnessScript          []byte
+	Bip32Derivation        []Bip32Derivation
+	FinalScriptSig         []byte
+	FinalScriptWitness     wire.TxWitness
+	TaprootKeySig          []byte
+	TaprootScriptSigs      []TaprootScriptSig
+	TaprootLeafScripts     []TaprootLeafScript
+	TaprootBip32Derivation []TaprootBip32Derivation
+	TaprootInternalKey     []byte
+	TaprootMerkleRoot      []byte
+	Unknowns               []Unknown
+}
+
+// Output are the fields of a transaction output.
+type Output struct {
+	RedeemScript           []byte
+	WitnessScript          []byte
+	Bip32Derivation        []Bip32Derivation
+	TaprootInternalKey     []byte
+	TaprootTree            []byte
+	TaprootBip32Derivation []TaprootBip32Derivation
+	Unknowns               []Unknown
+}
+
+// PartialSig is an ECDSA signature of a key with its sighash byte.
+type PartialSig struct {
+	PubKey    []byte
+	Signature []byte
+}
+
+// Bip32Derivation is the master key fingerprint and derivation path of a
+// public key.
+type Bip32Derivation struct {
+	PubKey []byte
+	Origin helpers.KeyOrigin
+}
+
+// TaprootBip32Derivation is the origin of an x-only key and the leaves it is
+// used in, none for the internal key.
+type TaprootBip32Derivation struct {
+	XOnlyPubKey []byte
+	LeafHashes  [][]byte
+	Origin      helpers.KeyOrigin
+}
+
+// TaprootScriptSig is a Schnorr signature of a key for a leaf.
+type TaprootScriptSig struct {
+	XOnlyPubKey []byte
+	LeafHash    []byte
+	Signature   []byte
+}
+
+// TaprootLeafScript is a leaf script with the control block that spends it.
+type TaprootLeafScript struct {
+	ControlBlock []byte
+	Script       []byte
+	LeafVersion  byte
+}
+
+// Unknown is a field kept with its raw key and value.
+type Unknown struct {
+	Key   []byte
+	Value []byte
+}
+
+// New creates a PSBT of an unsigned transaction with empty input and output
+// maps.
+func New(tx *wire.MsgTx) (*Packet, error) {
+	for i, txIn := range tx.TxIn {
+		if len(txIn.SignatureScript) > 0 || len(txIn.Witness) > 0 {
+			return nil, fmt.Errorf("%w: input %d of the unsigned transaction is signed", ErrInvalidPsbt, i)
+		}
+	}
+	return &Packet{
+		UnsignedTx: tx,
+		Inputs:     make([]Input, len(tx.TxIn)),
+		Outputs:    make([]Output, len(tx.TxOut)),
+	}, nil
+}
+
+// Utxo is the output spent by an input, taken from its witness utxo or else
+// from its non witness utxo.
+func (p *Packet) Utxo(i int) (*wire.TxOut, error) {
+	input := p.Inputs[i]
+	if input.WitnessUtxo != nil {
+		return input.WitnessUtxo, nil
+	}
+	if input.NonWitnessUtxo != nil {
+		outPoint := p.UnsignedTx.TxIn[i].PreviousOutPoint
+		if outPoint.Index >= uint32(len(input.NonWitnessUtxo.TxOut)) {
+			return nil, fmt.Errorf("%w: input %d spends output %d of a transaction with %d outputs", ErrInvalidPsbt, i, outPoint.Index, len(input.NonWitnessUtxo.TxOut))
+		}
+		return input.NonWitnessUtxo.TxOut[outPoint.Index], nil
+	}
+	return nil, fmt.Errorf("%w: input %d has no utxo", ErrInvalidPsbt, i)
+}
+
+// ParseBase64 parses a base64 encoded PSBT.
+func ParseBase64(encoded string) (*Packet, error) {
+	raw, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPsbt, err)
+	}
+	return Parse(raw)
+}
+
+// Parse parses a serialized PSBT.
+func Parse(raw []byte) (*Packet, error) {
+	if !bytes.HasPrefix(raw, magic) {
+		return nil, fmt.Errorf("%w: missing magic bytes", ErrInvalidPsbt)
+	}
+	r := bytes.NewReader(raw[len(magic):])
+
+	var p *Packet
+	var unknowns []Unknown
+	err := readMap(r, func(keyType uint64, keyData []byte, value []byte) error {
+		if keyType != globalUnsignedTx {
+			unknowns = append(unknowns, Unknown{encodeKey(keyType, keyData), value})
+			return nil
+		}
+		if len(keyData) != 0 {
+			return fmt.Errorf("unsigned transaction key has data")
+		}
+		tx := wire.NewMsgTx(wire.TxVersion)
+		if err := tx.DeserializeNoWitness(bytes.NewReader(value)); err != nil {
+			return err
+		}
+		var err error
+		p, err = New(tx)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: global map: %v", ErrInvalidPsbt, err)
+	}
+	if p == nil {
+		return nil, fmt.Errorf("%w: missing unsigned transaction", ErrInvalidPsbt)
+	}
+	p.Unknowns = unknowns
+
+	for i := range p.Inputs {
+		input := &p.Inputs[i]
+		if err := readMap(r, input.read); err != nil {
+			return nil, fmt.Errorf("%w: input %d: %v", ErrInvalidPsbt, i, err)
+		}
+		if input.NonWitnessUtxo != nil && input.NonWitnessUtxo.TxHash() != p.UnsignedTx.TxIn[i].PreviousOutPoint.Hash {
+			return nil, fmt.Errorf("%w: input %d: non witness utxo is not the spent transaction", ErrInvalidPsbt, i)
+		}
+	}
+	for i := range p.Outputs {
+		if err := readMap(r, p.Outputs[i].read); err != nil {
+			return nil, fmt.Errorf("%w: output %d: %v", ErrInvalidPsbt, i, err)
+		}
+	}
+	if r.Len() != 0 {
+		return nil, fmt.Errorf("%w: %d bytes after the last output", ErrInvalidPsbt, r.Len())
+	}
+	return p, nil
+}
+
+// readMap reads the key value pairs of a map up to its separator, rejecting
+// repeated keys.
+func readMap(r *bytes.Reader, field func(keyType uint64, keyData []byte, value []byte) error) error {
+	seen := map[string]bool{}
+	for {
+		key, err := wire.ReadVarBytes(r, 0, maxFieldSize, "key")
+		if err != nil {
+			return err
+		}
+		if len(key) == 0 {
+			return nil
+		}
+		if seen[string(key)] {
+			return fmt.Errorf("duplicate key %x", key)
+		}
+		seen[string(key)] = true
+		value, err := wire.ReadVarBytes(r, 0, maxFieldSize, "value")
+		if err != nil {
+			return err
+		}
+		keyReader := bytes.NewReader(key)
+		keyType, err := wire.ReadVarInt(keyReader, 0)
+		if err != nil {
+			return err
+		}
+		if err := field(keyType, key[len(key)-keyReader.Len():], value); err != nil {
+			return fmt.Errorf("key type %d: %v", keyType, err)
+		}
+	}
+}
+
+func (in *Input) read(keyType uint64, keyData []byte, value []byte) error {
+	// the single value fields take no key data
+	single := map[uint64]bool{
+		inNonWitnessUtxo: true, inWitnessUtxo: true, inSighashType: true, inRedeemScript: true,
+		inWitnessScript: true, inFinalScriptSig: true, inFinalScriptWitness: true, inTaprootKeySig: true,
+		inTaprootInternalKey: true, inTaprootMerkleRoot: true,
+	}
+	if single[keyType] && len(keyData) != 0 {
+		return fmt.Errorf("unexpected key data")
+	}
+
+	var err error
+	switch keyType {
+	case inNonWitnessUtxo:
+		in.NonWitnessUtxo = wire.NewMsgTx(wire.TxVersion)
+		err = in.NonWitnessUtxo.Deserialize(bytes.NewReader(value))
+	case inWitnessUtxo:
+		in.WitnessUtxo, err = parseTxOut(value)
+	case inPartialSig:
+		if err = checkPubKey(keyData); err == nil {
+			in.PartialSigs = append(in.PartialSigs, PartialSig{keyData, value})
+		}
+	case inSighashType:
+		if len(value) != 4 {
+			return fmt.Errorf("sighash type must be 4 bytes")
+		}
+		in.SighashType = binary.LittleEndian.Uint32(value)
+	case inRedeemScript:
+		in.RedeemScript = value
+	case inWitnessScript:
+		in.WitnessScript = value
+	case inBip32Derivation:
+		var derivation *Bip32Derivation
+		if derivation, err = parseBip32Derivation(keyData, value); err == nil {
+			in.Bip32Derivation = append(in.Bip32Derivation, *derivation)
+		}
+	case inFinalScriptSig:
+		in.FinalScriptSig = value
+	case inFinalScriptWitness:
+		in.FinalScriptWitness, err = parseWitness(value)
+	case inTaprootKeySig:
+		if len(value) != 64 && len(value) != 65 {
+			return fmt.Errorf("taproot key signature must be 64 or 65 bytes")
+		}
+		in.TaprootKeySig = value
+	case inTaprootScriptSig:
+		if len(keyData) != 64 || (len(value) != 64 && len(value) != 65) {
+			return fmt.Errorf("taproot script signature must be keyed by a key and leaf hash")
+		}
+		in.TaprootScriptSigs = append(in.TaprootScriptSigs, TaprootScriptSig{keyData[:32], keyData[32:], value})
+	case inTaprootLeafScript:
+		if len(keyData) < 33 || (len(keyData)-33)%32 != 0 || len(value) == 0 {
+			return fmt.Errorf("invalid taproot leaf script")
+		}
+		in.TaprootLeafScripts = append(in.TaprootLeafScripts, TaprootLeafScript{keyData, value[:len(value)-1], value[len(value)-1]})
+	case inTaprootBip32Derivation:
+		var derivation *TaprootBip32Derivation
+		if derivation, err = parseTaprootBip32Derivation(keyData, value); err == nil {
+			in.TaprootBip32Derivation = append(in.TaprootBip32Derivation, *derivation)
+		}
+	case inTaprootInternalKey:
+		if len(value) != 32 {
+			return fmt.Errorf("taproot internal key must be 32 bytes")
+		}
+		in.TaprootInternalKey = value
+	case inTaprootMerkleRoot:
+		if len(value) != 32 {
+			return fmt.Errorf("taproot merkle root must be 32 bytes")
+		}
+		in.TaprootMerkleRoot = value
+	default:
+		in.Unknowns = append(in.Unknowns, Unknown{encodeKey(keyType, keyData), value})
+	}
+	return err
+}
+
+func (out *Output) read(keyType uint64, keyData []byte, value []byte) error {
+	switch keyType {
+	case outRedeemScript, outWitnessScript, outTaprootInternalKey, outTaprootTree:
+		if len(keyData) != 0 {
+			return fmt.Errorf("unexpected key data")
+		}
+	}
+
+	var err error
+	switch keyType {
+	case outRedeemScript:
+		out.RedeemScript = value
+	case outWitnessScript:
+		out.WitnessScript = value
+	case outBip32Derivation:
+		var derivation *Bip32Derivation
+		if derivation, err = parseBip32Derivation(keyData, value); err == nil {
+			out.Bip32Derivation = append(out.Bip32Derivation, *derivation)
+		}
+	case outTaprootInternalKey:
+		if len(value) != 32 {
+			return fmt.Errorf("taproot internal key must be 32 bytes")
+		}
+		out.TaprootInternalKey = value
+	case outTaprootTree:
+		out.TaprootTree = value
+	case outTaprootBip32Derivation:
+		var derivation *TaprootBip32Derivation
+		if derivation, err = parseTaprootBip32Derivation(keyData, value); err == nil {
+			out.TaprootBip32Derivation = append(out.TaprootBip32Derivation, *derivation)
+		}
+	default:
+		out.Unknowns = append(out.Unknowns, Unknown{encodeKey(keyType, keyData), value})
+	}
+	return err
+}
+
+func checkPubKey(pubKey []byte) error {
+	if len(pubKey) != 33 && len(pubKey) != 65 {
+		return fmt.Errorf("public key must be 33 or 65 bytes. got %d", len(pubKey))
+	}
+	return nil
+}
+
+func parseTxOut(value []byte) (*wire.TxOut, error) {
+	r := bytes.NewReader(value)
+	var amount int64
+	if err := binary.Read(r, binary.LittleEndian, &amount); err != nil {
+		return nil, err
+	}
+	pkScript, err := wire.ReadVarBytes(r, 0, maxFieldSize, "pkScript")
+	if err != nil {
+		return nil, err
+	}
+	if r.Len() != 0 {
+		return nil, fmt.Errorf("trailing bytes after the output")
+	}
+	return wire.NewTxOut(amount, pkScript), nil
+}
+
+func parseWitness(value []byte) (wire.TxWitness, error) {
+	r := bytes.NewReader(value)
+	count, err := wire.ReadVarInt(r, 0)
+	if err != nil {
+		return nil, err
+	}
+	if count > uint64(len(value)) {
+		return nil, fmt.Errorf("witness of %d items is too short", count)
+	}
+	witness := make(wire.TxWitness, count)
+	for i := range witness {
+		if witness[i], err = wire.ReadVarBytes(r, 0, maxFieldSize, "witness"); err != nil {
+			return nil, err
+		}
+	}
+	if r.Len() != 0 {
+		return nil, fmt.Errorf("trailing bytes after the witness")
+	}
+	return witness, nil
+}
+
+// parseOrigin reads a fingerprint followed by little endian path indexes.
+func parseOrigin(value []byte) (helpers.KeyOrigin, error) {
+	if len(value) < 4 || len(value)%4 != 0 {
+		return helpers.KeyOrigin{}, fmt.Errorf("key origin must be a fingerprint and 4 byte indexes")
+	}
+	origin := helpers.KeyOrigin{Fingerprint: value[:4], Path: helpers.DerivationPath{}}
+	for i := 4; i < len(value); i += 4 {
+		origin.Path = append(origin.Path, binary.LittleEndian.Uint32(value[i:]))
+	}
+	return origin, nil
+}
+
+func parseBip32Derivation(keyData []byte, value []byte) (*Bip32Derivation, error) {
+	if err := checkPubKey(keyData); err != nil {
+		return nil, err
+	}
+	origin, err := parseOrigin(value)
+	if err != nil {
+		return nil, err
+	}
+	return &Bip32Derivation{PubKey: keyData, Origin: origin}, nil
+}
+
+func parseTaprootBip32Derivation(keyData []byte, value []byte) (*TaprootBip32Derivation, error) {
+	if len(keyData) != 32 {
+		return nil, fmt.Errorf("taproot derivation key must be 32 bytes")
+	}
+	r := bytes.NewReader(value)
+	count, err := wire.ReadVarInt(r, 0)
+	if err != nil {
+		return nil, err
+	}
+	if count*32 > uint64(r.Len()) {
+		return nil, fmt.Errorf("taproot derivation of %d leaf hashes is too short", count)
+	}
+	derivation := &TaprootBip32Derivation{XOnlyPubKey: keyData, LeafHashes: make([][]byte, count)}
+	for i := range derivation.LeafHashes {
+		derivation.LeafHashes[i] = make([]byte, 32)
+		io.ReadFull(r, derivation.LeafHashes[i])
+	}
+	derivation.Origin, err = parseOrigin(value[len(value)-r.Len():])
+	if err != nil {
+		return nil, err
+	}
+	return derivation, nil
+}
+
+// B64Encode serializes the PSBT as base64.
+func (p *Packet) B64Encode() (string, error) {
+	raw, err := p.Serialize()
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(raw), nil
+}
+
+// Serialize writes the PSBT in the BIP174 format, the fields of every map in
+// the order of their key types.
+func (p *Packet) Serialize() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.Write(magic)
+
+	var tx bytes.Buffer
+	if err := p.UnsignedTx.SerializeNoWitness(&tx); err != nil {
+		return nil, err
+	}
+	w := &mapWriter{buf: &buf}
+	w.field(globalUnsignedTx, nil, tx.Bytes())
+	w.unknowns(p.Unknowns)
+	buf.WriteByte(0)
+
+	for _, input := range p.Inputs {
+		if err := input.write(w); err != nil {
+			return nil, err
+		}
+		buf.WriteByte(0)
+	}
+	for _, output := range p.Outputs {
+		output.write(w)
+		buf.WriteByte(0)
+	}
+	return buf.Bytes(), nil
+}
+
+func (in *Input) write(w *mapWriter) error {
+	if in.NonWitnessUtxo != nil {
+		var tx bytes.Buffer
+		if err := in.NonWitnessUtxo.Serialize(&tx); err != nil {
+			return err
+		}
+		w.field(inNonWitnessUtxo, nil, tx.Bytes())
+	}
+	if in.WitnessUtxo != nil {
+		var txOut bytes.Buffer
+		binary.Write(&txOut, binary.LittleEndian, in.WitnessUtxo.Value)
+		wire.WriteVarBytes(&txOut, 0, in.WitnessUtxo.PkScript)
+		w.field(inWitnessUtxo, nil, txOut.Bytes())
+	}
+	for _, sig := range in.PartialSigs {
+		w.field(inPartialSig, sig.PubKey, sig.Signature)
+	}
+	if in.SighashType != 0 {
+		sighashType := make([]byte, 4)
+		binary.LittleEndian.PutUint32(sighashType, in.SighashType)
+		w.field(inSighashType, nil, sighashType)
+	}
+	w.optional(inRedeemScript, in.RedeemScript)
+	w.optional(inWitnessScript, in.WitnessScript)
+	for _, derivation := range in.Bip32Derivation {
+		w.field(inBip32Derivation, derivation.PubKey, encodeOrigin(derivation.Origin))
+	}
+	w.optional(inFinalScriptSig, in.FinalScriptSig)
+	if in.FinalScriptWitness != nil {
+		w.field(inFinalScriptWitness, nil, encodeWitness(in.FinalScriptWitness))
+	}
+	w.optional(inTaprootKeySig, in.TaprootKeySig)
+	for _, sig := range in.TaprootScriptSigs {
+		w.field(inTaprootScriptSig, append(append([]byte{}, sig.XOnlyPubKey...), sig.LeafHash...), sig.Signature)
+	}
+	for _, leaf := range in.TaprootLeafScripts {
+		w.field(inTaprootLeafScript, leaf.ControlBlock, append(append([]byte{}, leaf.Script...), leaf.LeafVersion))
+	}
+	for _, derivation := range in.TaprootBip32Derivation {
+		w.field(inTaprootBip32Derivation, derivation.XOnlyPubKey, encodeTaprootOrigin(derivation))
+	}
+	w.optional(inTaprootInternalKey, in.TaprootInternalKey)
+	w.optional(inTaprootMerkleRoot, in.TaprootMerkleRoot)
+	w.unknowns(in.Unknowns)
+	return nil
+}
+
+func (out *Output) write(w *mapWriter) {
+	w.optional(outRedeemScript, out.RedeemScript)
+	w.optional(outWitnessScript, out.WitnessScript)
+	for _, derivation := range out.Bip32Derivation {
+		w.field(outBip32Derivation, derivation.PubKey, encodeOrigin(derivation.Origin))
+	}
+	w.optional(outTaprootInternalKey, out.TaprootInternalKey)
+	w.optional(outTaprootTree, out.TaprootTree)
+	for _, derivation := range out.TaprootBip32Derivation {
+		w.field(outTaprootBip32Derivation, derivation.XOnlyPubKey, encodeTaprootOrigin(derivation))
+	}
+	w.unknowns(out.Unknowns)
+}
+
+// mapWriter writes the key value pairs of a map.
+type mapWriter struct {
+	buf *bytes.Buffer
+}
+
+func (w *mapWriter) field(keyType uint64, keyData []byte, value []byte) {
+	wire.WriteVarBytes(w.buf, 0, encodeKey(keyType, keyData))
+	wire.WriteVarBytes(w.buf, 0, value)
+}
+
+// optional writes a field without key data when it has a value.
+func (w *mapWriter) optional(keyType uint64, value []byte) {
+	if value != nil {
+		w.field(keyType, nil, value)
+	}
+}
+
+func (w *mapWriter) unknowns(unknowns []Unknown) {
+	for _, unknown := range unknowns {
+		wire.WriteVarBytes(w.buf, 0, unknown.Key)
+		wire.WriteVarBytes(w.buf, 0, unknown.Value)
+	}
+}
+
+func encodeKey(keyType uint64, keyData []byte) []byte {
+	var key bytes.Buffer
+	wire.WriteVarInt(&key, 0, keyType)
+	key.Write(keyData)
+	return key.Bytes()
+}
+
+func encodeOrigin(origin helpers.KeyOrigin) []byte {
+	value := append([]byte{}, origin.Fingerprint...)
+	for _, index := range origin.Path {
+		value = append(value, 0, 0, 0, 0)
+		binary.LittleEndian.PutUint32(value[len(value)-4:], index)
+	}
+	return value
+}
+
+func encodeTaprootOrigin(derivation TaprootBip32Derivation) []byte {
+	var value bytes.Buffer
+	wire.WriteVarInt(&value, 0, uint64(len(derivation.LeafHashes)))
+	for _, leafHash := range derivation.LeafHashes {
+		value.Write(leafHash)
+	}
+	value.Write(encodeOrigin(derivation.Origin))
+	return value.Bytes()
+}
+
+func encodeWitness(witness wire.TxWitness) []byte {
+	var value bytes.Buffer
+	wire.WriteVarInt(&value, 0, uint64(len(witness)))
+	for _, item := range witness {
+		wire.WriteVarBytes(&value, 0, item)
+	}
+	return value.Bytes()
+}
@@ -0,0 +1,236 @@
+package psbt
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"btcwallet.com/src/pkg/helpers"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+)
+
+func testPacket(t *testing.T) *Packet {
+	previous := wire.NewMsgTx(2)
+	previous.AddTxIn(wire.NewTxIn(&wire.OutPoint{Index: 1}, nil, nil))
+	previous.AddTxOut(wire.NewTxOut(50000, bytes.Repeat([]byte{0x51}, 3)))
+
+	tx := wire.NewMsgTx(2)
+	hash := previous.TxHash()
+	tx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&hash, 0), nil, nil))
+	tx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&chainhash.Hash{1}, 3), nil, nil))
+	tx.AddTxOut(wire.NewTxOut(40000, []byte{0x00, 0x14}))
+	p, err := New(tx)
+	if err != nil {
+		t.Fatalf("Test failed: unexpected error: %v", err)
+	}
+
+	pubKey, _ := hex.DecodeString("0265e6f7fb614a369c9230912a3bb09c33c5c5be2e1bcfc2293ecaed46708e0b5c")
+	origin := helpers.KeyOrigin{Fingerprint: []byte{0x73, 0xc5, 0xda, 0x0a}, Path: helpers.DerivationPath{0x80000054, 0x80000001, 0x80000000, 0, 5}}
+	p.Inputs[0] = Input{
+		NonWitnessUtxo:  previous,
+		PartialSigs:     []PartialSig{{pubKey, []byte{0x30, 0x01}}},
+		SighashType:     1,
+		RedeemScript:    []byte{0x00, 0x14},
+		Bip32Derivation: []Bip32Derivation{{pubKey, origin}},
+		Unknowns:        []Unknown{{[]byte{0xfc, 0x01}, []byte{0x02}}},
+	}
+	p.Inputs[1] = Input{
+		WitnessUtxo:            wire.NewTxOut(20000, bytes.Repeat([]byte{0x51}, 34)),
+		FinalScriptWitness:     wire.TxWitness{{0x01}, {}},
+		TaprootKeySig:          bytes.Repeat([]byte{0x02}, 64),
+		TaprootScriptSigs:      []TaprootScriptSig{{pubKey[1:], bytes.Repeat([]byte{0x03}, 32), bytes.Repeat([]byte{0x04}, 65)}},
+		TaprootLeafScripts:     []TaprootLeafScript{{append([]byte{0xc0}, pubKey[1:]...), []byte{0x51}, 0xc0}},
+		TaprootBip32Derivation: []TaprootBip32Derivation{{pubKey[1:], [][]byte{bytes.Repeat([]byte{0x03}, 32)}, origin}},
+		TaprootInternalKey:     pubKey[1:],
+		TaprootMerkleRoot:      bytes.Repeat([]byte{0x03}, 32),
+	}
+	p.Outputs[0] = Output{
+		WitnessScript:          []byte{0x51},
+		Bip32Derivation:        []Bip32Derivation{{pubKey, origin}},
+		TaprootInternalKey:     pubKey[1:],
+		TaprootBip32Derivation: []TaprootBip32Derivation{{pubKey[1:], nil, origin}},
+	}
+	p.Unknowns = []Unknown{{[]byte{0xfb}, []byte{0, 0, 0, 0}}}
+	return p
+}
+
+func TestSerializePsbt(t *testing.T) {
+	p := testPacket(t)
+	encoded, err := p.B64Encode()
+	if err != nil {
+		t.Fatalf("Test failed: unexpected error: %v", err)
+	}
+	parsed, err := ParseBase64(encoded)
+	if err != nil {
+		t.Fatalf("Test failed: unexpected error: %v", err)
+	}
+	again, err := parsed.B64Encode()
+	if err != nil {
+		t.Fatalf("Test failed: unexpected error: %v", err)
+	}
+	if again != encoded {
+		t.Errorf("Test failed:  expected: %s received: %s ", encoded, again)
+	}
+
+	derivation := parsed.Inputs[0].Bip32Derivation[0]
+	if path := derivation.Origin.Path.String(); path != "m/84'/1'/0'/0/5" {
+		t.Errorf("Test failed:  expected: m/84'/1'/0'/0/5 received: %s ", path)
+	}
+	if parsed.Inputs[1].WitnessUtxo.Value != 20000 || len(parsed.Inputs[1].FinalScriptWitness) != 2 {
+		t.Errorf("Test failed:  expected: the witness utxo and final witness received: %v %v ", parsed.Inputs[1].WitnessUtxo, parsed.Inputs[1].FinalScriptWitness)
+	}
+	if len(parsed.Unknowns) != 1 || len(parsed.Inputs[0].Unknowns) != 1 {
+		t.Errorf("Test failed:  expected: the unknown fields to be kept received: %v %v ", parsed.Unknowns, parsed.Inputs[0].Unknowns)
+	}
+}
+
+func TestParseInvalidPsbt(t *testing.T) {
+	valid, err := testPacket(t).Serialize()
+	if err != nil {
+		t.Fatalf("Test failed: unexpected error: %v", err)
+	}
+	// the same sighash type field twice in the first input
+	tx, _ := hex.DecodeString("0200000001" + hex.EncodeToString(bytes.Repeat([]byte{0x01}, 32)) + "00000000" + "00" + "ffffffff" + "00" + "00000000")
+	var duplicate bytes.Buffer
+	duplicate.Write(magic)
+	wire.WriteVarBytes(&duplicate, 0, []byte{globalUnsignedTx})
+	wire.WriteVarBytes(&duplicate, 0, tx)
+	duplicate.WriteByte(0)
+	for i := 0; i < 2; i++ {
+		wire.WriteVarBytes(&duplicate, 0, []byte{inSighashType})
+		wire.WriteVarBytes(&duplicate, 0, []byte{1, 0, 0, 0})
+	}
+	duplicate.WriteByte(0)
+
+	tests := map[string][]byte{
+		"no magic":   valid[1:],
+		"truncated":  valid[:len(valid)-1],
+		"trailing":   append(append([]byte{}, valid...), 0),
+		"duplicate":  duplicate.Bytes(),
+		"only magic": magic,
+	}
+	for name, raw := range tests {
+		if _, err := Parse(raw); !errors.Is(err, ErrInvalidPsbt) {
+			t.Errorf("Test failed: input: %s expected: %v received: %v ", name, ErrInvalidPsbt, err)
+		}
+	}
+	if _, err := ParseBase64("not base64!"); !errors.Is(err, ErrInvalidPsbt) {
+		t.Errorf("Test failed:  expected: %v received: %v ", ErrInvalidPsbt, err)
+	}
+}
+
+func TestEstimateWeight(t *testing.T) {
+	p2pkh, _ := hex.DecodeString("76a914243f1394f44554f4ce3fd68649c19adc483ce92488ac")
+	p2wpkh, _ := hex.DecodeString("0014751e76e8199196d454941c45d1b3a323f1433bd6")
+	multisig, _ := hex.DecodeString("52210265e6f7fb614a369c9230912a3bb09c33c5c5be2e1bcfc2293ecaed46708e0b5c2103f546edf7b434b50aa0115c1c82a0f9a96505d9eff55d2fe3b848c4b51c06b6432102c6047f9441ed7d6d3045406e95c07cd85c778e4b8cef3ca7abac09b95c709ee553ae")
+	p2wsh := append([]byte{0x00, 0x20}, bytes.Repeat([]byte{0x01}, 32)...)
+	tests := []struct {
+		scriptPubKey  []byte
+		witnessScript []byte
+		output        []byte
+		vsize         int
+	}{
+		// the usual one input two output sizes
+		{p2pkh, nil, p2pkh, 226},
+		{p2wpkh, nil, p2wpkh, 141},
+		// 2 of 3 P2WSH
+		{p2wsh, multisig, p2wsh, 201},
+	}
+	for _, test := range tests {
+		tx := wire.NewMsgTx(2)
+		tx.AddTxIn(wire.NewTxIn(&wire.OutPoint{}, nil, nil))
+		tx.AddTxOut(wire.NewTxOut(1000, test.output))
+		tx.AddTxOut(wire.NewTxOut(1000, test.output))
+		p, _ := New(tx)
+		p.Inputs[0].WitnessUtxo = wire.NewTxOut(5000, test.scriptPubKey)
+		p.Inputs[0].WitnessScript = test.witnessScript
+		weight, err := p.EstimateWeight()
+		if err != nil {
+			t.Fatalf("Test failed: unexpected error: %v", err)
+		}
+		if vsize := VirtualSize(weight); vsize != test.vsize {
+			t.Errorf("Test failed: input: %x expected: %d received: %d ", test.scriptPubKey, test.vsize, vsize)
+		}
+	}
+	if DustThreshold(p2pkh) != 546 || DustThreshold(p2wpkh) != 294 {
+		t.Errorf("Test failed:  expected: 546 and 294 received: %d and %d ", DustThreshold(p2pkh), DustThreshold(p2wpkh))
+	}
+}
+
+func TestMultisigInputSize(t *testing.T) {
+	multisig, _ := hex.DecodeString("52210265e6f7fb614a369c9230912a3bb09c33c5c5be2e1bcfc2293ecaed46708e0b5c2103f546edf7b434b50aa0115c1c82a0f9a96505d9eff55d2fe3b848c4b51c06b6432102c6047f9441ed7d6d3045406e95c07cd85c778e4b8cef3ca7abac09b95c709ee553ae")
+	p2wsh := append([]byte{0x00, 0x20}, bytes.Repeat([]byte{0x01}, 32)...)
+	p2sh := append(append([]byte{0xa9, 0x14}, bytes.Repeat([]byte{0x01}, 20)...), 0x87)
+	tests := []struct {
+		multisigType  helpers.MultisigType
+		scriptPubKey  []byte
+		redeemScript  []byte
+		witnessScript []byte
+	}{
+		{helpers.MultisigTypeP2SH, p2sh, multisig, nil},
+		{helpers.MultisigTypeP2SHP2WSH, p2sh, p2wsh, multisig},
+		{helpers.MultisigTypeP2WSH, p2wsh, nil, multisig},
+	}
+	for _, test := range tests {
+		expectedScriptSig, expectedWitness, err := InputSize(test.scriptPubKey, test.redeemScript, test.witnessScript)
+		if err != nil {
+			t.Fatalf("Test failed: unexpected error: %v", err)
+		}
+		scriptSig, witness, err := MultisigInputSize(2, 3, test.multisigType, false)
+		if err != nil {
+			t.Fatalf("Test failed: unexpected error: %v", err)
+		}
+		if scriptSig != expectedScriptSig || witness != expectedWitness {
+			t.Errorf("Test failed: %s expected: %d %d received: %d %d ", test.multisigType, expectedScriptSig, expectedWitness, scriptSig, witness)
+		}
+	}
+
+	// two signatures and an empty item, the 104 byte multi_a leaf and the
+	// 33 byte control block
+	if _, witness, _ := MultisigInputSize(2, 3, helpers.MultisigTypeP2TR, false); witness != 1+2*65+1+1+104+1+33 {
+		t.Errorf("Test failed:  expected: %d received: %d ", 1+2*65+1+1+104+1+33, witness)
+	}
+	if _, witness, _ := MultisigInputSize(2, 3, helpers.MultisigTypeP2TR, true); witness != 66 {
+		t.Errorf("Test failed:  expected: 66 received: %d ", witness)
+	}
+	if _, _, err := MultisigInputSize(3, 2, helpers.MultisigTypeP2WSH, false); !errors.Is(err, helpers.ErrMultisigRequiredOverN) {
+		t.Errorf("Test failed:  expected: %v received: %v ", helpers.ErrMultisigRequiredOverN, err)
+	}
+	if _, _, err := MultisigInputSize(16, 16, helpers.MultisigTypeP2SH, false); !errors.Is(err, helpers.ErrMultisigScriptTooLarge) {
+		t.Errorf("Test failed:  expected: %v received: %v ", helpers.ErrMultisigScriptTooLarge, err)
+	}
+	// m and n of 17 and more are two byte pushes in the 685 byte script
+	if _, witness, err := MultisigInputSize(20, 20, helpers.MultisigTypeP2WSH, false); err != nil || witness != multisigWitnessItemsSize(20, 685) {
+		t.Errorf("Test failed:  expected: %d received: %d %v ", multisigWitnessItemsSize(20, 685), witness, err)
+	}
+	if _, _, err := MultisigInputSize(1, 21, helpers.MultisigTypeP2WSH, false); !errors.Is(err, helpers.ErrMultisigTotal) {
+		t.Errorf("Test failed:  expected: %v received: %v ", helpers.ErrMultisigTotal, err)
+	}
+}
+
+func TestCombinePsbt(t *testing.T) {
+	first, second := testPacket(t), testPacket(t)
+	pubKey, _ := hex.DecodeString("03f546edf7b434b50aa0115c1c82a0f9a96505d9eff55d2fe3b848c4b51c06b643")
+	second.Inputs[0].PartialSigs = []PartialSig{{pubKey, []byte{0x30, 0x02}}}
+	second.Inputs[1].Unknowns = []Unknown{{[]byte{0xfc, 0x02}, []byte{0x03}}}
+
+	combined, err := Combine(first, second)
+	if err != nil {
+		t.Fatalf("Test failed: unexpected error: %v", err)
+	}
+	if len(combined.Inputs[0].PartialSigs) != 2 || len(combined.Inputs[1].Unknowns) != 1 || len(combined.Inputs[0].Bip32Derivation) != 1 {
+		t.Errorf("Test failed:  expected: the fields of both psbts received: %v ", combined.Inputs)
+	}
+
+	conflicting := testPacket(t)
+	conflicting.Inputs[1].WitnessUtxo = wire.NewTxOut(30000, conflicting.Inputs[1].WitnessUtxo.PkScript)
+	other := testPacket(t)
+	other.UnsignedTx.TxOut[0].Value = 30000
+	for name, packet := range map[string]*Packet{"conflicting utxo": conflicting, "other transaction": other} {
+		if _, err := Combine(first, packet); !errors.Is(err, ErrInvalidPsbt) {
+			t.Errorf("Test failed: input: %s expected: %v received: %v ", name, ErrInvalidPsbt, err)
+		}
+	}
+}
@@ -0,0 +1,254 @@
+package psbt
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"btcwallet.com/src/pkg/helpers"
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+)
+
+// ErrKeyNotInInput is returned by SignInput for a key the input does not pay to.
+var ErrKeyNotInInput = errors.New("key is not a key of the input")
+
+// sigHashDefault is the BIP341 hash type that signs like SIGHASH_ALL with a
+// 64 byte signature.
+const sigHashDefault txscript.SigHashType = 0
+
+// SignInput signs input i with the private key and adds the signature to the
+// input: a partial signature for P2PKH, P2WPKH, P2SH-P2WPKH and multisig
+// inputs, the key path signature for a Taproot input whose output key is the
+// key tweaked with the merkle root of the input. The signature commits to the
+// sighash type of the input, SIGHASH_ALL or SIGHASH_DEFAULT when it has none.
+// It returns the public key the input is signed for, an input that already
+// has its signature is left as it is.
+func (p *Packet) SignInput(i int, privKey *btcec.PrivateKey, compressed bool) ([]byte, error) {
+	if i < 0 || i >= len(p.Inputs) {
+		return nil, fmt.Errorf("%w: no input %d", ErrInvalidPsbt, i)
+	}
+	in := &p.Inputs[i]
+	utxo, err := p.Utxo(i)
+	if err != nil {
+		return nil, err
+	}
+	script, subScript, witness, err := p.inputScripts(i)
+	if err != nil {
+		return nil, err
+	}
+	if isTaproot(script) {
+		return p.signTaprootKeyPath(i, privKey, script[2:])
+	}
+
+	pubKey := privKey.PubKey().SerializeUncompressed()
+	if compressed {
+		pubKey = privKey.PubKey().SerializeCompressed()
+	}
+	if witness && !compressed {
+		return nil, fmt.Errorf("%w: segwit inputs need compressed keys", ErrKeyNotInInput)
+	}
+	if !scriptHasKey(subScript, pubKey) {
+		return nil, ErrKeyNotInInput
+	}
+	for _, partialSig := range in.PartialSigs {
+		if bytes.Equal(partialSig.PubKey, pubKey) {
+			return pubKey, nil
+		}
+	}
+
+	hashType := txscript.SigHashAll
+	if in.SighashType != 0 {
+		hashType = txscript.SigHashType(in.SighashType)
+	}
+	if base := hashType &^ txscript.SigHashAnyOneCanPay; hashType > 0xff || base < txscript.SigHashAll || base > txscript.SigHashSingle {
+		return nil, fmt.Errorf("%w: input %d: invalid sighash type %d", ErrInvalidPsbt, i, in.SighashType)
+	}
+	var signature []byte
+	if witness {
+		signature, err = txscript.RawTxInWitnessSignature(p.UnsignedTx, txscript.NewTxSigHashes(p.UnsignedTx), i, utxo.Value, subScript, hashType, privKey)
+	} else {
+		if in.NonWitnessUtxo == nil {
+			return nil, fmt.Errorf("%w: input %d without witness needs its previous transaction", ErrInvalidPsbt, i)
+		}
+		signature, err = txscript.RawTxInSignature(p.UnsignedTx, i, subScript, hashType, privKey)
+	}
+	if err != nil {
+		return nil, err
+	}
+	in.PartialSigs = append(in.PartialSigs, PartialSig{PubKey: pubKey, Signature: signature})
+	return pubKey, nil
+}
+
+// signTaprootKeyPath adds the key path signature of the private key tweaked
+// for the output key of input i.
+func (p *Packet) signTaprootKeyPath(i int, privKey *btcec.PrivateKey, outputKey []byte) ([]byte, error) {
+	in := &p.Inputs[i]
+	internalKey := helpers.XOnlyPubKey(privKey.PubKey())
+	if in.TaprootInternalKey != nil && !bytes.Equal(in.TaprootInternalKey, internalKey) {
+		return nil, ErrKeyNotInInput
+	}
+	tweaked, err := helpers.TaprootTweakPrivKey(privKey, in.TaprootMerkleRoot)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(helpers.XOnlyPubKey(tweaked.PubKey()), outputKey) {
+		return nil, ErrKeyNotInInput
+	}
+	if in.TaprootKeySig != nil {
+		return internalKey, nil
+	}
+
+	hashType := txscript.SigHashType(in.SighashType)
+	hash, err := p.TaprootSigHash(i, hashType)
+	if err != nil {
+		return nil, err
+	}
+	aux := make([]byte, 32)
+	if _, err := rand.Read(aux); err != nil {
+		return nil, err
+	}
+	signature, err := helpers.SchnorrSign(tweaked, hash, aux)
+	if err != nil {
+		return nil, err
+	}
+	if hashType != sigHashDefault {
+		signature = append(signature, byte(hashType))
+	}
+	in.TaprootKeySig = signature
+	return internalKey, nil
+}
+
+// TaprootSigHash is the BIP341 signature hash of a key path spend of input
+// i, which commits to the amounts and scripts of every input.
+func (p *Packet) TaprootSigHash(i int, hashType txscript.SigHashType) ([]byte, error) {
+	base := hashType &^ txscript.SigHashAnyOneCanPay
+	if hashType > 0xff || base > txscript.SigHashSingle || hashType == txscript.SigHashAnyOneCanPay {
+		return nil, fmt.Errorf("%w: input %d: invalid taproot sighash type %d", ErrInvalidPsbt, i, hashType)
+	}
+	tx := p.UnsignedTx
+	utxos := make([]*wire.TxOut, len(tx.TxIn))
+	for j := range tx.TxIn {
+		utxo, err := p.Utxo(j)
+		if err != nil {
+			return nil, fmt.Errorf("taproot signature hash: %w", err)
+		}
+		utxos[j] = utxo
+	}
+	anyoneCanPay := hashType&txscript.SigHashAnyOneCanPay != 0
+
+	var msg bytes.Buffer
+	// the epoch
+	msg.WriteByte(0)
+	msg.WriteByte(byte(hashType))
+	binary.Write(&msg, binary.LittleEndian, tx.Version)
+	binary.Write(&msg, binary.LittleEndian, tx.LockTime)
+	if !anyoneCanPay {
+		var prevouts, amounts, scriptPubKeys, sequences bytes.Buffer
+		for j, txIn := range tx.TxIn {
+			writeOutPoint(&prevouts, txIn.PreviousOutPoint)
+			binary.Write(&amounts, binary.LittleEndian, utxos[j].Value)
+			wire.WriteVarBytes(&scriptPubKeys, 0, utxos[j].PkScript)
+			binary.Write(&sequences, binary.LittleEndian, txIn.Sequence)
+		}
+		for _, field := range []*bytes.Buffer{&prevouts, &amounts, &scriptPubKeys, &sequences} {
+			hash := sha256.Sum256(field.Bytes())
+			msg.Write(hash[:])
+		}
+	}
+	if base != txscript.SigHashNone && base != txscript.SigHashSingle {
+		var outputs bytes.Buffer
+		for _, txOut := range tx.TxOut {
+			wire.WriteTxOut(&outputs, 0, tx.Version, txOut)
+		}
+		hash := sha256.Sum256(outputs.Bytes())
+		msg.Write(hash[:])
+	}
+	// the spend type of a key path spend without annex
+	msg.WriteByte(0)
+	if anyoneCanPay {
+		writeOutPoint(&msg, tx.TxIn[i].PreviousOutPoint)
+		binary.Write(&msg, binary.LittleEndian, utxos[i].Value)
+		wire.WriteVarBytes(&msg, 0, utxos[i].PkScript)
+		binary.Write(&msg, binary.LittleEndian, tx.TxIn[i].Sequence)
+	} else {
+		binary.Write(&msg, binary.LittleEndian, uint32(i))
+	}
+	if base == txscript.SigHashSingle {
+		if i >= len(tx.TxOut) {
+			return nil, fmt.Errorf("%w: input %d signs with SIGHASH_SINGLE without a matching output", ErrInvalidPsbt, i)
+		}
+		var output bytes.Buffer
+		wire.WriteTxOut(&output, 0, tx.Version, tx.TxOut[i])
+		hash := sha256.Sum256(output.Bytes())
+		msg.Write(hash[:])
+	}
+	return helpers.TaggedHash("TapSighash", msg.Bytes()), nil
+}
+
+// inputScripts returns the script input i pays to once a P2SH output is
+// unwrapped, the script its signatures commit to and whether the input is
+// spent with a witness. The redeem and witness scripts must hash to the
+// outputs they unwrap.
+func (p *Packet) inputScripts(i int) (script []byte, subScript []byte, witness bool, err error) {
+	in := p.Inputs[i]
+	utxo, err := p.Utxo(i)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	script = utxo.PkScript
+	wrapped := txscript.IsPayToScriptHash(script)
+	if wrapped {
+		if in.RedeemScript == nil {
+			return nil, nil, false, fmt.Errorf("%w: input %d spends P2SH without redeem script", ErrInvalidPsbt, i)
+		}
+		if !bytes.Equal(script[2:22], btcutil.Hash160(in.RedeemScript)) {
+			return nil, nil, false, fmt.Errorf("%w: input %d redeem script does not hash to the P2SH output", ErrInvalidPsbt, i)
+		}
+		script = in.RedeemScript
+	}
+	version, program, err := txscript.ExtractWitnessProgramInfo(script)
+	switch {
+	case err != nil:
+		return script, script, false, nil
+	case version == 0 && len(program) == 20:
+		return script, script, true, nil
+	case version == 0 && len(program) == 32:
+		if in.WitnessScript == nil {
+			return nil, nil, false, fmt.Errorf("%w: input %d spends P2WSH without witness script", ErrInvalidPsbt, i)
+		}
+		if hash := sha256.Sum256(in.WitnessScript); !bytes.Equal(program, hash[:]) {
+			return nil, nil, false, fmt.Errorf("%w: input %d witness script does not hash to the P2WSH output", ErrInvalidPsbt, i)
+		}
+		return script, in.WitnessScript, true, nil
+	case isTaproot(script) && !wrapped:
+		return script, nil, true, nil
+	default:
+		return nil, nil, false, fmt.Errorf("%w: input %d spends an unknown witness version %d", ErrInvalidPsbt, i, version)
+	}
+}
+
+// scriptHasKey reports whether the script pushes the public key or its hash.
+func scriptHasKey(script []byte, pubKey []byte) bool {
+	pushes, err := txscript.PushedData(script)
+	if err != nil {
+		return false
+	}
+	keyHash := btcutil.Hash160(pubKey)
+	for _, push := range pushes {
+		if bytes.Equal(push, pubKey) || bytes.Equal(push, keyHash) {
+			return true
+		}
+	}
+	return false
+}
+
+func writeOutPoint(w *bytes.Buffer, outPoint wire.OutPoint) {
+	w.Write(outPoint.Hash[:])
+	binary.Write(w, binary.LittleEndian, outPoint.Index)
+}
@@ -0,0 +1,201 @@
+package psbt
+
+import (
+	"fmt"
+
+	"btcwallet.com/src/pkg/helpers"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// Sizes of what a signature adds to an input, an ECDSA signature is counted
+// at its largest DER encoding with the sighash byte and keys are compressed.
+const (
+	ecdsaSignatureSize   = 72
+	schnorrSignatureSize = 64
+	compressedKeySize    = 33
+)
+
+// InputSize estimates the size of the scriptSig and of the witness of an
+// input spending the script once it is signed. P2SH and P2WSH outputs are
+// estimated from their redeem and witness scripts, which must be multisig
+// scripts or wrap a P2WPKH output. Taproot outputs are counted as key path
+// spends. The witness size includes the stack item count and is zero for an
+// input without witness.
+func InputSize(scriptPubKey []byte, redeemScript []byte, witnessScript []byte) (scriptSig int, witness int, err error) {
+	switch {
+	case txscript.GetScriptClass(scriptPubKey) == txscript.PubKeyHashTy:
+		return 1 + ecdsaSignatureSize + 1 + compressedKeySize, 0, nil
+	case txscript.GetScriptClass(scriptPubKey) == txscript.PubKeyTy:
+		return 1 + ecdsaSignatureSize, 0, nil
+	case txscript.IsPayToWitnessPubKeyHash(scriptPubKey):
+		return 0, 1 + 1 + ecdsaSignatureSize + 1 + compressedKeySize, nil
+	case txscript.IsPayToWitnessScriptHash(scriptPubKey):
+		witness, err := multisigWitnessSize(witnessScript)
+		return 0, witness, err
+	case isTaproot(scriptPubKey):
+		return 0, 1 + 1 + schnorrSignatureSize, nil
+	case txscript.IsPayToScriptHash(scriptPubKey):
+		if redeemScript == nil {
+			return 0, 0, fmt.Errorf("%w: P2SH input without redeem script", ErrInvalidPsbt)
+		}
+		if txscript.IsWitnessProgram(redeemScript) {
+			_, witness, err := InputSize(redeemScript, nil, witnessScript)
+			return pushSize(len(redeemScript)), witness, err
+		}
+		required, _, err := helpers.DecodeMultisignatureScript(redeemScript)
+		if err != nil {
+			return 0, 0, fmt.Errorf("%w: can not estimate the redeem script: %v", ErrInvalidPsbt, err)
+		}
+		return multisigScriptSigSize(required, len(redeemScript)), 0, nil
+	default:
+		return 0, 0, fmt.Errorf("%w: can not estimate an input spending %x", ErrInvalidPsbt, scriptPubKey)
+	}
+}
+
+// MultisigInputSize estimates the scriptSig and witness of an input spending
+// an m of n multisig of compressed keys paid to as the multisig type, the
+// scripts GenerateMultisignature builds. A P2TR multisig is counted as the
+// spend of its multi_a leaf by m keys, or as the spend of its MuSig2 key path
+// when keyPath is set.
+func MultisigInputSize(m int, n int, multisigType helpers.MultisigType, keyPath bool) (scriptSig int, witness int, err error) {
+	limit := helpers.MaxMultisigKeys
+	if multisigType == helpers.MultisigTypeP2TR {
+		limit = helpers.MaxTaprootMultisigKeys
+	}
+	if n < 1 || n > limit {
+		return 0, 0, fmt.Errorf("%w, it must be between 1 and %d. got %d", helpers.ErrMultisigTotal, limit, n)
+	}
+	if m < 1 {
+		return 0, 0, fmt.Errorf("%w, it must be between 1 and %d. got %d", helpers.ErrMultisigRequired, limit, m)
+	}
+	if m > n {
+		return 0, 0, fmt.Errorf("%w. got %d of %d", helpers.ErrMultisigRequiredOverN, m, n)
+	}
+	if multisigType == helpers.MultisigTypeP2TR {
+		if keyPath {
+			return 0, 1 + 1 + schnorrSignatureSize, nil
+		}
+		required, err := txscript.NewScriptBuilder().AddInt64(int64(m)).Script()
+		if err != nil {
+			return 0, 0, err
+		}
+		// <key> CHECKSIG <key> CHECKSIGADD ... <m> NUMEQUAL
+		script := n*(1+32+1) + len(required) + 1
+		// a signature for m of the keys and an empty item for the others,
+		// then the leaf script and the control block of the single leaf
+		return 0, wire.VarIntSerializeSize(uint64(n+2)) + m*(1+schnorrSignatureSize) + (n - m) +
+			wire.VarIntSerializeSize(uint64(script)) + script + 1 + 33, nil
+	}
+
+	script := helpers.MultisignatureScriptSize(n, m, compressedKeySize)
+	switch multisigType {
+	case helpers.MultisigTypeP2SH, "":
+		if script > txscript.MaxScriptElementSize {
+			return 0, 0, fmt.Errorf("%w. got %d bytes", helpers.ErrMultisigScriptTooLarge, script)
+		}
+		return multisigScriptSigSize(m, script), 0, nil
+	case helpers.MultisigTypeP2WSH:
+		return 0, multisigWitnessItemsSize(m, script), nil
+	case helpers.MultisigTypeP2SHP2WSH:
+		// the P2WSH witness program is the redeem script
+		return pushSize(34), multisigWitnessItemsSize(m, script), nil
+	default:
+		return 0, 0, fmt.Errorf("%w %s", helpers.ErrMultisigType, multisigType)
+	}
+}
+
+// multisigScriptSigSize is the scriptSig of a P2SH multisig: the extra OP_0
+// CHECKMULTISIG pops, the signatures and the redeem script.
+func multisigScriptSigSize(required int, redeemScript int) int {
+	return 1 + required*(1+ecdsaSignatureSize) + pushSize(redeemScript)
+}
+
+// multisigWitnessSize is the witness of a P2WSH multisig: the empty item
+// CHECKMULTISIG pops, the signatures and the witness script.
+func multisigWitnessSize(witnessScript []byte) (int, error) {
+	if witnessScript == nil {
+		return 0, fmt.Errorf("%w: P2WSH input without witness script", ErrInvalidPsbt)
+	}
+	required, _, err := helpers.DecodeMultisignatureScript(witnessScript)
+	if err != nil {
+		return 0, fmt.Errorf("%w: can not estimate the witness script: %v", ErrInvalidPsbt, err)
+	}
+	return multisigWitnessItemsSize(required, len(witnessScript)), nil
+}
+
+func multisigWitnessItemsSize(required int, witnessScript int) int {
+	return wire.VarIntSerializeSize(uint64(required+2)) + 1 + required*(1+ecdsaSignatureSize) +
+		wire.VarIntSerializeSize(uint64(witnessScript)) + witnessScript
+}
+
+// OutputSize is the serialized size of an output paying to the script.
+func OutputSize(scriptPubKey []byte) int {
+	return 8 + wire.VarIntSerializeSize(uint64(len(scriptPubKey))) + len(scriptPubKey)
+}
+
+// DustThreshold is the smallest amount an output paying to the script is
+// relayed with: three times the cost at 1 sat/vB of the output and of an
+// input spending it, as Bitcoin Core computes it.
+func DustThreshold(scriptPubKey []byte) int64 {
+	spend := 32 + 4 + 1 + 107 + 4
+	if txscript.IsWitnessProgram(scriptPubKey) {
+		spend = 32 + 4 + 1 + 107/4 + 4
+	}
+	return int64(3 * (OutputSize(scriptPubKey) + spend))
+}
+
+// EstimateWeight estimates the weight of the transaction once every input is
+// signed, from the output and the scripts each input spends.
+func (p *Packet) EstimateWeight() (int, error) {
+	tx := p.UnsignedTx
+	base := 4 + wire.VarIntSerializeSize(uint64(len(tx.TxIn))) + wire.VarIntSerializeSize(uint64(len(tx.TxOut))) + 4
+	witness, segwit := 0, false
+	for i, input := range p.Inputs {
+		utxo, err := p.Utxo(i)
+		if err != nil {
+			return 0, err
+		}
+		scriptSig, witnessSize, err := InputSize(utxo.PkScript, input.RedeemScript, input.WitnessScript)
+		if err != nil {
+			return 0, fmt.Errorf("input %d: %w", i, err)
+		}
+		base += 32 + 4 + wire.VarIntSerializeSize(uint64(scriptSig)) + scriptSig + 4
+		if witnessSize == 0 {
+			// the empty witness of an input without one
+			witnessSize = 1
+		} else {
+			segwit = true
+		}
+		witness += witnessSize
+	}
+	for _, txOut := range tx.TxOut {
+		base += OutputSize(txOut.PkScript)
+	}
+	if !segwit {
+		return base * 4, nil
+	}
+	// the marker and flag bytes
+	return base*4 + 2 + witness, nil
+}
+
+// VirtualSize is the weight in virtual bytes, rounded up.
+func VirtualSize(weight int) int {
+	return (weight + 3) / 4
+}
+
+func isTaproot(script []byte) bool {
+	return len(script) == 34 && script[0] == txscript.OP_1 && script[1] == txscript.OP_DATA_32
+}
+
+// pushSize is the size of a push of data of the length in a scriptSig.
+func pushSize(length int) int {
+	switch {
+	case length < txscript.OP_PUSHDATA1:
+		return 1 + length
+	case length <= 0xff:
+		return 2 + length
+	default:
+		return 3 + length
+	}
+}